@@ -1071,18 +1071,13 @@ func subscriptionsToResponse(config *configuration, subscriptions []*subscriptio
 	subscriptionResponses := make([]SubscriptionResponse, 0, len(subscriptions))
 
 	for _, subscription := range subscriptions {
-		features := []string{}
-		if len(subscription.Features) > 0 {
-			features = strings.Split(subscription.Features, ",")
-		}
-
 		repositoryURL := *gitlabURL
 		repositoryURL.Path = path.Join(gitlabURL.EscapedPath(), subscription.Repository)
 
 		subscriptionResponses = append(subscriptionResponses, SubscriptionResponse{
 			RepositoryName: subscription.Repository,
 			RepositoryURL:  repositoryURL.String(),
-			Features:       features,
+			Features:       subscription.Tokens(),
 			CreatorID:      subscription.CreatorID,
 		})
 	}
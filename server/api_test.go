@@ -75,7 +75,7 @@ func TestGetChannelSubscriptions(t *testing.T) {
 		plugin, mock := setupPlugin(t)
 
 		mock.On("HasPermissionToChannel", "user_id", "id", model.PermissionReadChannel).Return(true, nil).Once()
-		mock.On("KVGet", SubscriptionsKey).Return([]byte(`{"Repositories":{"repo1":[]}}`), nil)
+		mock.On("KVGet", channelSubscriptionsKey("id")).Return(nil, nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/api/v1/channel/id/subscriptions", nil)
@@ -97,7 +97,9 @@ func TestGetChannelSubscriptions(t *testing.T) {
 		plugin, mock := setupPlugin(t)
 
 		mock.On("HasPermissionToChannel", "user_id", "id", model.PermissionReadChannel).Return(true, nil).Once()
-		mock.On("KVGet", SubscriptionsKey).Return([]byte(`{"Repositories":{"namespace":[{"ChannelID":"other","Repository":"repo1"}]}}`), nil)
+		// The index can be stale after an interrupted unsubscription.
+		mock.On("KVGet", channelSubscriptionsKey("id")).Return([]byte(`["repo1"]`), nil)
+		mock.On("KVGet", repositorySubscriptionsKey("repo1")).Return([]byte(`[{"ChannelID":"other","Repository":"repo1"}]`), nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/api/v1/channel/id/subscriptions", nil)
@@ -119,7 +121,10 @@ func TestGetChannelSubscriptions(t *testing.T) {
 		plugin, mock := setupPlugin(t)
 
 		mock.On("HasPermissionToChannel", "user_id", "id", model.PermissionReadChannel).Return(true, nil).Once()
-		mock.On("KVGet", SubscriptionsKey).Return([]byte(`{"Repositories":{"namespace":[{"ChannelID":"other","Repository":"repo1","Features":"feature1,feature2","CreatorID":"creator1"},{"ChannelID":"id","Repository":"repo2","Features":"feature3,feature4","CreatorID":"creator2"},{"ChannelID":"id", "Repository":"repo3","Features":"feature5","CreatorID":"creator3"},{"ChannelID":"id","Repository":"repo4-empty"}]}}`), nil)
+		mock.On("KVGet", channelSubscriptionsKey("id")).Return([]byte(`["repo2","repo3","repo4-empty"]`), nil)
+		mock.On("KVGet", repositorySubscriptionsKey("repo2")).Return([]byte(`[{"ChannelID":"other","Repository":"repo2","Features":{"merges":true,"issues":true},"CreatorID":"creator1"},{"ChannelID":"id","Repository":"repo2","Features":{"pushes":true,"tag":true},"CreatorID":"creator2"}]`), nil)
+		mock.On("KVGet", repositorySubscriptionsKey("repo3")).Return([]byte(`[{"ChannelID":"id", "Repository":"repo3","Features":{"issues":true},"Filters":{"Labels":["bug"]},"CreatorID":"creator3"}]`), nil)
+		mock.On("KVGet", repositorySubscriptionsKey("repo4-empty")).Return([]byte(`[{"ChannelID":"id","Repository":"repo4-empty"}]`), nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/api/v1/channel/id/subscriptions", nil)
@@ -134,7 +139,7 @@ func TestGetChannelSubscriptions(t *testing.T) {
 
 		assert.NotNil(t, result)
 		assert.Equal(t, http.StatusOK, result.StatusCode)
-		assert.Equal(t, `[{"repository_name":"repo2","repository_url":"https://example.com/repo2","features":["feature3","feature4"],"creator_id":"creator2"},{"repository_name":"repo3","repository_url":"https://example.com/repo3","features":["feature5"],"creator_id":"creator3"},{"repository_name":"repo4-empty","repository_url":"https://example.com/repo4-empty","features":[],"creator_id":""}]`, string(data))
+		assert.Equal(t, `[{"repository_name":"repo2","repository_url":"https://example.com/repo2","features":["pushes","tag"],"creator_id":"creator2"},{"repository_name":"repo3","repository_url":"https://example.com/repo3","features":["issues","label:\"bug\""],"creator_id":"creator3"},{"repository_name":"repo4-empty","repository_url":"https://example.com/repo4-empty","features":[],"creator_id":""}]`, string(data))
 	})
 }
 
@@ -10,7 +10,8 @@ import (
 )
 
 const (
-	oauthCompleteEventID = "oauth-complete"
+	oauthCompleteEventID           = "oauth-complete"
+	messageTemplatesUpdatedEventID = "message-templates-updated"
 )
 
 func (p *Plugin) sendOAuthCompleteEvent(event OAuthCompleteEvent) {
@@ -50,6 +51,8 @@ func (p *Plugin) HandleClusterEvent(ev model.PluginClusterEvent) {
 		}
 
 		p.oauthBroker.publishOAuthComplete(event.UserID, event.Err, true)
+	case messageTemplatesUpdatedEventID:
+		p.clearMessageTemplates()
 	default:
 		p.client.Log.Warn("unknown cluster event", "id", ev.Id)
 	}
@@ -9,15 +9,18 @@ import (
 	"runtime/debug"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/command"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/pkg/errors"
 	gitlabLib "github.com/xanzy/go-gitlab"
 	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
 )
 
 const commandHelp = `* |/gitlab connect| - Connect your Mattermost account to your GitLab account
@@ -33,13 +36,40 @@ const commandHelp = `* |/gitlab connect| - Connect your Mattermost account to yo
     * pushes - includes pushes
 	* issue_comments - includes new issue comments
 	* merge_request_comments - include new merge-request comments
+	* commit_comments - includes new commit comments
+	* snippet_comments - includes new snippet comments
 	* merge_request_assigns - includes merge request assignment and unassignment notifications
-	* pipeline - includes pipeline runs
+	* pipeline - includes pipeline runs, each one in a single post updated with the status of its jobs
+	* pipeline:failed - only includes failed pipeline runs
+	* pipeline:fixed - only includes pipeline runs succeeding after a failure on the same branch
 	* tag - include tag creation
     * pull_reviews - includes merge request reviews
-	* label:"<label-1-name>","<label-2-name>" - must include "merges" or "issues" in feature list when using labels
+	* label:"<label-1-name>","<label-2-name>" - must include "merges" or "issues" in feature list when using labels, * matches any text, e.g. label:"priority::*"
+	* label_all:"<label-name>" - only includes merge requests and issues having every one of these labels
+	* -label:"<label-name>" - excludes merge requests and issues having this label, e.g. -label:"wontfix"
+	* branch:"<branch-glob>" - only includes pushes, pipelines, jobs and tags of matching branches or tags, e.g. branch:"main",branch:"release/*"
+	* target_branch:"<branch-glob>" - only includes merge requests targeting matching branches, e.g. target_branch:"main"
+	* no_drafts - excludes draft merge requests, which are announced once marked as ready for review
+	* path:"<path-glob>" - only includes pushes and merge requests changing matching files, where ** matches any number of directories, e.g. path:"services/billing/**"
+	* author:"<username-glob>" - only includes events triggered by matching users, e.g. author:"alice",author:"ops-*"
+	* exclude_author:"<username-glob>" - excludes events triggered by matching users, e.g. exclude_author:"renovate*"
+	* exclude_bots - excludes events triggered by GitLab bot users, such as project and group access token bots
 	* deployments - includes deployments
+	* deployments:<status> - only includes deployments reaching this status: success, failed or canceled
+	* environment:"<environment-glob>" - only includes deployments to matching environments, e.g. environment:"production",environment:"review/*"
 	* releases - includes releases
+	* wiki - includes wiki page creations, updates and deletions
+	* feature_flags - includes feature flag activations and deactivations
+	* members - includes group members added, updated or removed and access requests, for group subscriptions only
+	* reactions - includes thumbs up and down reactions on merge requests and issues, at most one per merge request or issue every 10 minutes
+	* milestones - includes milestone creations, closings, reopenings and deletions
+	* vulnerabilities - includes vulnerabilities found by security scanners and their state changes
+	* vulnerabilities:<severity> - only includes vulnerabilities of this severity or higher: info, unknown, low, medium, high or critical
+	* quiet_hours:"<HH:MM-HH:MM>" - posts nothing during this daily window, which may span midnight, e.g. quiet_hours:"19:00-08:00"
+	* quiet_weekends - posts nothing on Saturdays and Sundays
+	* timezone:"<timezone>" - timezone of the quiet hours and weekends, UTC by default, e.g. timezone:"Europe/Paris"
+	* quiet_summary - holds the notifications of the quiet hours and weekends and posts them after a summary once they end, instead of dropping them
+	* threads - posts the notifications of a merge request or issue, its comments and pipelines as replies in a single thread
     * Defaults to "merges,issues,tag"
 * |/gitlab subscriptions delete owner/repo| - Unsubscribe the current channel from a repository
 * |/gitlab pipelines run [owner]/repo [ref]| - Run a pipeline for specific repository and ref (branch/tag)
@@ -64,36 +94,110 @@ const commandHelp = `* |/gitlab connect| - Connect your Mattermost account to yo
 	 * WikiPageEvents
 	 * DeploymentEvents
 	 * ReleaseEvents
+	 * FeatureFlagEvents - project hooks only
+	 * MemberEvents - group hooks only
+	 * EmojiEvents
 	 * SSLverification
   * |url| is the URL that will be called when triggered. Defaults to this plugins URL
-  * |token| Secret token. Defaults to secret token used in plugin's settings.
+  * |token| Secret token. Defaults to the secret of the project or group, generated on its first webhook.
+* |/gitlab webhook rotate owner[/repo]| - Generate a new secret for the project or group and set it on its webhooks to Mattermost.
+* |/gitlab webhook signing-token owner[/repo] token| - Save the signing token set on the project or group webhooks in GitLab, so their requests must be signed.
+* |/gitlab deliveries list| - (System Admins only) List webhook notifications that could not be delivered
+* |/gitlab deliveries replay [id|all]| - (System Admins only) Retry delivering one or all failed webhook notifications
+* |/gitlab deliveries discard [id|all]| - (System Admins only) Drop one or all failed webhook notifications
+* |/gitlab templates view [name]| - (System Admins only) List the messages posted to channels whose wording can be changed, or show the template of one
+* |/gitlab templates set name template| - (System Admins only) Set the Go text/template of a message, which can span several lines, e.g. |/gitlab templates set merge_request.merge :tada: [{{.Title}}]({{.URL}}) merged by {{.Author}}|
+  * |template| is executed with .Message, the default message, .Event, the GitLab event, .Author, .AuthorURL, .Project, .ProjectURL, .Title and .URL
+* |/gitlab templates reset name| - (System Admins only) Post the default message again
+* |/gitlab templates preview name [template]| - (System Admins only) Show the message posted for a sample event with the given template, or the current one
 * |/gitlab about| - Display build information about the plugin
 `
 
-const (
-	inboundWebhookURL                 = "plugins/com.github.manland.mattermost-plugin-gitlab/webhook"
-	specifyRepositoryMessage          = "Please specify a repository."
-	specifyRepositoryAndBranchMessage = "Please specify a repository and a branch."
-	unknownActionMessage              = "Unknown action, please use `/gitlab help` to see all actions available."
-	newWebhookEmptySiteURLmessage     = "Unable to create webhook. The Mattermot Site URL is not set. " +
-		"Set it in the Admin Console or rerun /gitlab webhook add group/project URL including the desired URL."
+const inboundWebhookURL = "plugins/com.github.manland.mattermost-plugin-gitlab/webhook"
+
+var (
+	specifyRepositoryMessage = &i18n.Message{
+		ID:    "command.specify_repository",
+		Other: "Please specify a repository.",
+	}
+	specifyRepositoryAndBranchMessage = &i18n.Message{
+		ID:    "command.specify_repository_and_branch",
+		Other: "Please specify a repository and a branch.",
+	}
+	unknownActionMessage = &i18n.Message{
+		ID:    "command.unknown_action",
+		Other: "Unknown action, please use `/gitlab help` to see all actions available.",
+	}
+	newWebhookEmptySiteURLmessage = &i18n.Message{
+		ID: "command.webhook.empty_site_url",
+		Other: "Unable to create webhook. The Mattermot Site URL is not set. " +
+			"Set it in the Admin Console or rerun /gitlab webhook add group/project URL including the desired URL.",
+	}
+	checkPermissionsErrorMessage = &i18n.Message{
+		ID:    "command.check_permissions_error",
+		Other: "Error checking user's permissions",
+	}
+	unknownErrorMessage = &i18n.Message{
+		ID:    "command.settings.unknown_error",
+		Other: "Unknown error please retry or ask to an administrator to look at logs",
+	}
+	specifyInstanceNameMessage = &i18n.Message{
+		ID:    "command.instance.specify_name",
+		Other: "Please specify the instance name.",
+	}
+	failedDeliveriesErrorMessage = &i18n.Message{
+		ID:    "command.deliveries.error",
+		Other: "Error retrieving failed deliveries.",
+	}
+	messageTemplatesErrorMessage = &i18n.Message{
+		ID:    "command.templates.error",
+		Other: "Error retrieving message templates.",
+	}
+	invalidTemplateMessage = &i18n.Message{
+		ID:    "command.templates.invalid",
+		Other: "Invalid template: {{.Error}}",
+	}
 )
 
-const (
-	groupNotFoundMessage = "Unable to find GitLab group: "
+var (
+	groupNotFoundMessage = &i18n.Message{
+		ID:    "command.webhook.group_not_found",
+		Other: "Unable to find GitLab group: {{.Group}}",
+	}
 
-	projectNotFoundMessage = "Unable to find project with namespace: "
+	projectNotFoundMessage = &i18n.Message{
+		ID:    "command.webhook.project_not_found",
+		Other: "Unable to find project with namespace: {{.Namespace}}",
+	}
 
-	invalidSubscribeSubCommand           = "Invalid subscribe command. Available commands are add, delete, and list"
-	missingOrgOrRepoFromSubscribeCommand = "Please provide the owner[/repo]"
+	invalidSubscribeSubCommand = &i18n.Message{
+		ID:    "command.subscriptions.invalid",
+		Other: "Invalid subscribe command. Available commands are add, delete, and list",
+	}
+	missingOrgOrRepoFromSubscribeCommand = &i18n.Message{
+		ID:    "command.subscriptions.missing_owner",
+		Other: "Please provide the owner[/repo]",
+	}
 
-	invalidPipelinesSubCommand = "Invalid pipelines command. Available commands are run, list"
+	invalidPipelinesSubCommand = &i18n.Message{
+		ID:    "command.pipelines.invalid",
+		Other: "Invalid pipelines command. Available commands are run, list",
+	}
 )
 
 const (
-	commandAdd    = "add"
-	commandDelete = "delete"
-	commandList   = "list"
+	commandAdd     = "add"
+	commandDelete  = "delete"
+	commandList    = "list"
+	commandReplay  = "replay"
+	commandDiscard = "discard"
+	commandRotate  = "rotate"
+	commandView    = "view"
+	commandSet     = "set"
+	commandReset   = "reset"
+	commandPreview = "preview"
+
+	commandSigningToken = "signing-token"
 
 	commandRun = "run"
 )
@@ -111,7 +215,7 @@ func (p *Plugin) getCommand(config *configuration) (*model.Command, error) {
 	return &model.Command{
 		Trigger:              "gitlab",
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Available commands: connect, disconnect, instance, todo, subscriptions, me, pipelines, settings, webhook, setup, help, about",
+		AutoCompleteDesc:     "Available commands: connect, disconnect, instance, todo, subscriptions, me, pipelines, settings, webhook, setup, deliveries, templates, help, about",
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     p.getAutocompleteData(config),
 		AutocompleteIconData: iconData,
@@ -169,12 +273,14 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (res
 	defer p.recoverFromPanic(args)
 
 	unauthenticatedHandlers := map[string]unauthenticatedCommandHandlerFunc{
-		"about":    p.handleAbout,
-		"setup":    p.handleSetup,
-		"instance": p.handleInstance,
-		"connect":  p.handleConnect,
-		"help":     p.handleHelp,
-		"":         p.handleHelp,
+		"about":      p.handleAbout,
+		"setup":      p.handleSetup,
+		"instance":   p.handleInstance,
+		"deliveries": p.handleDeliveries,
+		"templates":  p.handleTemplates,
+		"connect":    p.handleConnect,
+		"help":       p.handleHelp,
+		"":           p.handleHelp,
 	}
 	if handler, ok := unauthenticatedHandlers[action]; ok {
 		return handler(args, parameters)
@@ -207,23 +313,29 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (res
 		return handler(ctx, args, parameters, info)
 	}
 
-	return p.getCommandResponse(args, unknownActionMessage, true), nil
+	return p.getCommandResponse(args, p.localizeForUser(args.UserId, unknownActionMessage, nil), true), nil
 }
 
 func (p *Plugin) handleConfigError(args *model.CommandArgs, err error) (*model.CommandResponse, *model.AppError) {
 	isSysAdmin, sysErr := p.isAuthorizedSysAdmin(args.UserId)
-	var text string
+	var message *i18n.Message
 	switch {
 	case sysErr != nil:
-		text = "Error checking user's permissions"
-		p.client.Log.Warn(text, "error", sysErr.Error())
+		message = checkPermissionsErrorMessage
+		p.client.Log.Warn(message.Other, "error", sysErr.Error())
 	case isSysAdmin:
-		text = "Before using this plugin, you'll need to configure it by running `/gitlab setup`"
+		message = &i18n.Message{
+			ID:    "command.config_error.admin",
+			Other: "Before using this plugin, you'll need to configure it by running `/gitlab setup`",
+		}
 	default:
-		text = "Please contact your system administrator to configure the GitLab plugin."
+		message = &i18n.Message{
+			ID:    "command.config_error.user",
+			Other: "Please contact your system administrator to configure the GitLab plugin.",
+		}
 	}
 
-	p.postCommandResponse(args, text, true)
+	p.postCommandResponse(args, p.localizeForUser(args.UserId, message, nil), true)
 	return &model.CommandResponse{}, nil
 }
 
@@ -231,14 +343,21 @@ func (p *Plugin) handleInstance(args *model.CommandArgs, parameters []string) (*
 	isSysAdmin, sysErr := p.isAuthorizedSysAdmin(args.UserId)
 	if sysErr != nil {
 		p.client.Log.Warn("Failed to check if user is System Admin", "error", sysErr.Error())
-		return p.getCommandResponse(args, "Error checking user's permissions", true), nil
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, checkPermissionsErrorMessage, nil), true), nil
 	}
 
+	locale := p.getUserLocale(args.UserId)
 	if !isSysAdmin {
-		return p.getCommandResponse(args, "Only System Admins are allowed to manage instances.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.instance.not_admin",
+			Other: "Only System Admins are allowed to manage instances.",
+		}, nil), true), nil
 	}
 	if len(parameters) < 1 {
-		return p.getCommandResponse(args, "Please specify the instance command.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.instance.specify_command",
+			Other: "Please specify the instance command.",
+		}, nil), true), nil
 	}
 
 	switch parameters[0] {
@@ -251,7 +370,10 @@ func (p *Plugin) handleInstance(args *model.CommandArgs, parameters []string) (*
 	case "list":
 		return p.handleListInstance(args, parameters[1:])
 	default:
-		return p.getCommandResponse(args, "Unknown instance command. Available commands: install, uninstall, set-default, list", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.instance.unknown",
+			Other: "Unknown instance command. Available commands: install, uninstall, set-default, list",
+		}, nil), true), nil
 	}
 }
 
@@ -266,7 +388,7 @@ func (p *Plugin) handleInstallInstance(args *model.CommandArgs, parameters []str
 
 func (p *Plugin) handleUnInstallInstance(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
 	if len(parameters) < 1 {
-		return p.getCommandResponse(args, "Please specify the instance name.", true), nil
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, specifyInstanceNameMessage, nil), true), nil
 	}
 	instanceName := strings.TrimSpace(strings.Join(parameters, " "))
 
@@ -275,12 +397,15 @@ func (p *Plugin) handleUnInstallInstance(args *model.CommandArgs, parameters []s
 		return p.getCommandResponse(args, err.Error(), true), nil
 	}
 
-	return p.getCommandResponse(args, fmt.Sprintf("Instance '%s' has been uninstalled.", instanceName), true), nil
+	return p.getCommandResponse(args, p.localizeForUser(args.UserId, &i18n.Message{
+		ID:    "command.instance.uninstalled",
+		Other: "Instance '{{.Name}}' has been uninstalled.",
+	}, map[string]any{"Name": instanceName}), true), nil
 }
 
 func (p *Plugin) handleSetDefaultInstance(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
 	if len(parameters) < 1 {
-		return p.getCommandResponse(args, "Please specify the instance name.", true), nil
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, specifyInstanceNameMessage, nil), true), nil
 	}
 
 	instanceName := strings.TrimSpace(strings.Join(parameters, " "))
@@ -289,23 +414,35 @@ func (p *Plugin) handleSetDefaultInstance(args *model.CommandArgs, parameters []
 		return p.getCommandResponse(args, err.Error(), true), nil
 	}
 
-	return p.getCommandResponse(args, fmt.Sprintf("Instance '%s' has been set as the default.", instanceName), true), nil
+	return p.getCommandResponse(args, p.localizeForUser(args.UserId, &i18n.Message{
+		ID:    "command.instance.default_set",
+		Other: "Instance '{{.Name}}' has been set as the default.",
+	}, map[string]any{"Name": instanceName}), true), nil
 }
 
 func (p *Plugin) handleListInstance(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	locale := p.getUserLocale(args.UserId)
 	instanceDetailMap, err := p.getInstanceConfigMap()
 	if err != nil {
 		p.client.Log.Warn("Failed to get instance list", "error", err.Error())
-		return p.getCommandResponse(args, "Error retrieving instance list.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.instance.list_error",
+			Other: "Error retrieving instance list.",
+		}, nil), true), nil
 	}
 
 	if len(instanceDetailMap) == 0 {
-		return p.getCommandResponse(args, "No GitLab instances are currently installed.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.instance.list_empty",
+			Other: "No GitLab instances are currently installed.",
+		}, nil), true), nil
 	}
 
 	var builder strings.Builder
-	builder.WriteString("### Installed GitLab Instances\n")
-	builder.WriteString("| Instance Name | Instance URL |\n")
+	builder.WriteString(p.localize(locale, &i18n.Message{
+		ID:    "command.instance.list",
+		Other: "### Installed GitLab Instances\n| Instance Name | Instance URL |",
+	}, nil) + "\n")
 	builder.WriteString("|--------------|--------------|\n")
 	for name, instanceConfiguration := range instanceDetailMap {
 		builder.WriteString(fmt.Sprintf("| %s | %s |\n", name, instanceConfiguration.GitlabURL))
@@ -314,12 +451,299 @@ func (p *Plugin) handleListInstance(args *model.CommandArgs, parameters []string
 	return p.getCommandResponse(args, builder.String(), true), nil
 }
 
+func (p *Plugin) handleDeliveries(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	isSysAdmin, sysErr := p.isAuthorizedSysAdmin(args.UserId)
+	if sysErr != nil {
+		p.client.Log.Warn("Failed to check if user is System Admin", "error", sysErr.Error())
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, checkPermissionsErrorMessage, nil), true), nil
+	}
+
+	locale := p.getUserLocale(args.UserId)
+	if !isSysAdmin {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.deliveries.not_admin",
+			Other: "Only System Admins are allowed to manage webhook deliveries.",
+		}, nil), true), nil
+	}
+	if len(parameters) < 1 {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.deliveries.specify_command",
+			Other: "Please specify the deliveries command.",
+		}, nil), true), nil
+	}
+
+	switch parameters[0] {
+	case commandList:
+		return p.getCommandResponse(args, p.deadLettersListMessage(locale), true), nil
+	case commandReplay, commandDiscard:
+		if len(parameters) < 2 {
+			return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+				ID:    "command.deliveries.specify_id",
+				Other: "Please specify a delivery id or `all`.",
+			}, nil), true), nil
+		}
+		apply := p.replayDeadLetter
+		done := &i18n.Message{
+			ID:    "command.deliveries.replayed",
+			Other: "Requeued {{.Count}} failed deliveries.",
+		}
+		if parameters[0] == commandDiscard {
+			apply = p.discardDeadLetter
+			done = &i18n.Message{
+				ID:    "command.deliveries.discarded",
+				Other: "Discarded {{.Count}} failed deliveries.",
+			}
+		}
+
+		ids := []string{parameters[1]}
+		if parameters[1] == "all" {
+			deadLetters, err := p.getDeadLetters()
+			if err != nil {
+				p.client.Log.Warn("Failed to get dead-lettered deliveries", "error", err.Error())
+				return p.getCommandResponse(args, p.localize(locale, failedDeliveriesErrorMessage, nil), true), nil
+			}
+			ids = ids[:0]
+			for _, d := range deadLetters {
+				ids = append(ids, d.ID)
+			}
+		}
+
+		for _, id := range ids {
+			if err := apply(id); err != nil {
+				return p.getCommandResponse(args, err.Error(), true), nil
+			}
+		}
+		return p.getCommandResponse(args, p.localize(locale, done, map[string]any{"Count": len(ids)}), true), nil
+	default:
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.deliveries.unknown",
+			Other: "Unknown deliveries command. Available commands: list, replay, discard",
+		}, nil), true), nil
+	}
+}
+
+func (p *Plugin) deadLettersListMessage(locale string) string {
+	deadLetters, err := p.getDeadLetters()
+	if err != nil {
+		p.client.Log.Warn("Failed to get dead-lettered deliveries", "error", err.Error())
+		return p.localize(locale, failedDeliveriesErrorMessage, nil)
+	}
+
+	if len(deadLetters) == 0 {
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.deliveries.list_empty",
+			Other: "There are no failed webhook deliveries.",
+		}, nil)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(p.localize(locale, &i18n.Message{
+		ID:    "command.deliveries.list",
+		Other: "### Failed webhook deliveries\n| ID | Destination | Created | Attempts | Last error |",
+	}, nil) + "\n")
+	builder.WriteString("|----|-------------|---------|----------|------------|\n")
+	for _, d := range deadLetters {
+		destination := p.localize(locale, &i18n.Message{
+			ID:    "command.deliveries.channel",
+			Other: "Channel {{.ID}}",
+		}, map[string]any{"ID": d.ChannelID})
+		if d.DMUserID != "" {
+			destination = p.localize(locale, &i18n.Message{
+				ID:    "command.deliveries.dm",
+				Other: "DM {{.ID}}",
+			}, map[string]any{"ID": d.DMUserID})
+		}
+		builder.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %s |\n", d.ID, destination, time.UnixMilli(d.CreatedAt).UTC().Format(time.RFC3339), d.Attempts, strings.ReplaceAll(d.LastError, "|", "\\|")))
+	}
+
+	return builder.String()
+}
+
+func (p *Plugin) handleTemplates(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	isSysAdmin, sysErr := p.isAuthorizedSysAdmin(args.UserId)
+	if sysErr != nil {
+		p.client.Log.Warn("Failed to check if user is System Admin", "error", sysErr.Error())
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, checkPermissionsErrorMessage, nil), true), nil
+	}
+
+	locale := p.getUserLocale(args.UserId)
+	if !isSysAdmin {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.not_admin",
+			Other: "Only System Admins are allowed to manage message templates.",
+		}, nil), true), nil
+	}
+	if len(parameters) < 1 {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.specify_command",
+			Other: "Please specify the templates command.",
+		}, nil), true), nil
+	}
+
+	switch parameters[0] {
+	case commandView, commandSet, commandReset, commandPreview:
+	default:
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.unknown",
+			Other: "Unknown templates command. Available commands: view, set, reset, preview",
+		}, nil), true), nil
+	}
+	if parameters[0] == commandView && len(parameters) < 2 {
+		return p.getCommandResponse(args, p.messageTemplatesListMessage(locale), true), nil
+	}
+	if len(parameters) < 2 {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.specify_name",
+			Other: "Please specify a template name, `/gitlab templates view` lists them.",
+		}, nil), true), nil
+	}
+	name := parameters[1]
+	if !webhook.IsMessageTemplate(name) {
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.unknown_name",
+			Other: "Unknown template `{{.Name}}`, `/gitlab templates view` lists them.",
+		}, map[string]any{"Name": name}), true), nil
+	}
+	// The template keeps the spaces and new lines of the command.
+	text := commandArgument(args.Command, 4)
+
+	switch parameters[0] {
+	case commandView:
+		return p.getCommandResponse(args, p.messageTemplateMessage(locale, name), true), nil
+	case commandSet:
+		if text == "" {
+			return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+				ID:    "command.templates.specify_template",
+				Other: "Please specify the template.",
+			}, nil), true), nil
+		}
+		preview, err := p.previewMessageTemplate(name, text)
+		if err != nil {
+			return p.getCommandResponse(args, p.localize(locale, invalidTemplateMessage, map[string]any{"Error": err.Error()}), true), nil
+		}
+		if err := p.setMessageTemplate(name, text); err != nil {
+			p.client.Log.Warn("Failed to store message template", "name", name, "error", err.Error())
+			return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+				ID:    "command.templates.save_error",
+				Other: "Error saving the template.",
+			}, nil), true), nil
+		}
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.saved",
+			Other: "Saved the template of `{{.Name}}`, a sample event is now posted as:\n\n{{.Preview}}",
+		}, map[string]any{"Name": name, "Preview": preview}), true), nil
+	case commandReset:
+		if err := p.setMessageTemplate(name, ""); err != nil {
+			p.client.Log.Warn("Failed to remove message template", "name", name, "error", err.Error())
+			return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+				ID:    "command.templates.reset_error",
+				Other: "Error resetting the template.",
+			}, nil), true), nil
+		}
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.reset",
+			Other: "The default message of `{{.Name}}` is posted again.",
+		}, map[string]any{"Name": name}), true), nil
+	default:
+		preview, err := p.previewMessageTemplate(name, text)
+		if err != nil {
+			return p.getCommandResponse(args, p.localize(locale, invalidTemplateMessage, map[string]any{"Error": err.Error()}), true), nil
+		}
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.templates.preview",
+			Other: "A sample event of `{{.Name}}` is posted as:\n\n{{.Preview}}",
+		}, map[string]any{"Name": name, "Preview": preview}), true), nil
+	}
+}
+
+// previewMessageTemplate returns the message posted for the sample event of the template name with the given text,
+// or with the current template when text is empty.
+func (p *Plugin) previewMessageTemplate(name, text string) (string, error) {
+	if text == "" {
+		return webhook.PreviewMessageTemplate(context.Background(), &gitlabRetreiver{p: p}, name, p.getMessageTemplate(name))
+	}
+	tmpl, err := webhook.ParseMessageTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	return webhook.PreviewMessageTemplate(context.Background(), &gitlabRetreiver{p: p}, name, tmpl)
+}
+
+func (p *Plugin) messageTemplatesListMessage(locale string) string {
+	texts, err := p.getMessageTemplates()
+	if err != nil {
+		p.client.Log.Warn("Failed to get message templates", "error", err.Error())
+		return p.localize(locale, messageTemplatesErrorMessage, nil)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(p.localize(locale, &i18n.Message{
+		ID:    "command.templates.list",
+		Other: "### Message templates\n| Name | Message | Template |",
+	}, nil) + "\n")
+	builder.WriteString("|------|---------|----------|\n")
+	for _, messageTemplate := range webhook.MessageTemplates {
+		state := &i18n.Message{
+			ID:    "command.templates.default",
+			Other: "Default",
+		}
+		if _, ok := texts[messageTemplate.Name]; ok {
+			state = &i18n.Message{
+				ID:    "command.templates.custom",
+				Other: "Custom",
+			}
+		}
+		builder.WriteString(fmt.Sprintf("| %s | %s | %s |\n", messageTemplate.Name, p.localize(locale, messageTemplate.Description, nil), p.localize(locale, state, nil)))
+	}
+
+	return builder.String()
+}
+
+func (p *Plugin) messageTemplateMessage(locale, name string) string {
+	texts, err := p.getMessageTemplates()
+	if err != nil {
+		p.client.Log.Warn("Failed to get message templates", "error", err.Error())
+		return p.localize(locale, messageTemplatesErrorMessage, nil)
+	}
+
+	text, ok := texts[name]
+	if !ok {
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.templates.view_default",
+			Other: "`{{.Name}}` posts the default message.",
+		}, map[string]any{"Name": name})
+	}
+	return p.localize(locale, &i18n.Message{
+		ID:    "command.templates.view",
+		Other: "Template of `{{.Name}}`:\n```\n{{.Template}}\n```",
+	}, map[string]any{"Name": name, "Template": text})
+}
+
+// commandArgument returns the text of a command after its first n words, as typed.
+func commandArgument(command string, n int) string {
+	rest := strings.TrimSpace(command)
+	for range n {
+		i := strings.IndexFunc(rest, unicode.IsSpace)
+		if i < 0 {
+			return ""
+		}
+		rest = strings.TrimLeftFunc(rest[i:], unicode.IsSpace)
+	}
+	return rest
+}
+
 func (p *Plugin) handleUserNotConnected(args *model.CommandArgs, apiErr *APIErrorResponse) (*model.CommandResponse, *model.AppError) {
-	text := "Unknown error."
+	message := &i18n.Message{
+		ID:    "command.unknown_error",
+		Other: "Unknown error.",
+	}
 	if apiErr.ID == APIErrorIDNotConnected {
-		text = "You must connect your account to GitLab first. Either click on the GitLab logo in the bottom left of the screen or enter `/gitlab connect`."
+		message = &i18n.Message{
+			ID:    "command.not_connected",
+			Other: "You must connect your account to GitLab first. Either click on the GitLab logo in the bottom left of the screen or enter `/gitlab connect`.",
+		}
 	}
-	return p.getCommandResponse(args, text, true), nil
+	return p.getCommandResponse(args, p.localizeForUser(args.UserId, message, nil), true), nil
 }
 
 func (p *Plugin) handleAbout(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
@@ -336,20 +760,34 @@ func (p *Plugin) handleAbout(args *model.CommandArgs, parameters []string) (*mod
 }
 
 func (p *Plugin) handleConnect(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	locale := p.getUserLocale(args.UserId)
 	if !p.canConnect() {
-		return p.getCommandResponse(args, "No instance is configured. Please specify an instance name or ask your system administrator to configure the plugin.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.connect.no_instance",
+			Other: "No instance is configured. Please specify an instance name or ask your system administrator to configure the plugin.",
+		}, nil), true), nil
 	}
 
 	pluginURL := getPluginURL(p.client)
 	if pluginURL == "" {
-		return p.getCommandResponse(args, "Encountered an error connecting to GitLab.", true), nil
-	}
-	resp := p.getCommandResponse(args, fmt.Sprintf("[Click here to link your GitLab account.](%s/oauth/connect)", pluginURL), true)
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.connect.error",
+			Other: "Encountered an error connecting to GitLab.",
+		}, nil), true), nil
+	}
+	resp := p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+		ID:    "command.connect",
+		Other: "[Click here to link your GitLab account.]({{.PluginURL}}/oauth/connect)",
+	}, map[string]any{"PluginURL": pluginURL}), true)
 	return resp, nil
 }
 
 func (p *Plugin) handleHelp(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
-	text := "###### Mattermost GitLab Plugin - Slash Command Help\n" + strings.ReplaceAll(commandHelp, "|", "`")
+	// The commands are described in English, like their syntax.
+	text := p.localizeForUser(args.UserId, &i18n.Message{
+		ID:    "command.help",
+		Other: "###### Mattermost GitLab Plugin - Slash Command Help",
+	}, nil) + "\n" + strings.ReplaceAll(commandHelp, "|", "`")
 	return p.getCommandResponse(args, text, true), nil
 }
 
@@ -360,7 +798,10 @@ func (p *Plugin) recoverFromPanic(args *model.CommandArgs) {
 			"UserId", args.UserId,
 			"error", r,
 			"stack", string(debug.Stack()))
-		p.postCommandResponse(args, "An unexpected error occurred. Please try again later.", true)
+		p.postCommandResponse(args, p.localizeForUser(args.UserId, &i18n.Message{
+			ID:    "command.panic",
+			Other: "An unexpected error occurred. Please try again later.",
+		}, nil), true)
 		if *p.client.Configuration.GetConfig().ServiceSettings.EnableDeveloper {
 			p.postCommandResponse(args, fmt.Sprintf("error: %v, \nstack:\n```%s```", r, string(debug.Stack())), true)
 		}
@@ -369,15 +810,19 @@ func (p *Plugin) recoverFromPanic(args *model.CommandArgs) {
 
 func (p *Plugin) handleSetup(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
 	userID := args.UserId
+	locale := p.getUserLocale(userID)
 	isSysAdmin, err := p.isAuthorizedSysAdmin(userID)
 	if err != nil {
 		p.client.Log.Warn("Failed to check if user is System Admin", "error", err.Error())
-		p.postCommandResponse(args, "Error checking user's permissions", true)
+		p.postCommandResponse(args, p.localize(locale, checkPermissionsErrorMessage, nil), true)
 		return &model.CommandResponse{}, nil
 	}
 
 	if !isSysAdmin {
-		p.postCommandResponse(args, "Only System Admins are allowed to set up the plugin.", true)
+		p.postCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.setup.not_admin",
+			Other: "Only System Admins are allowed to set up the plugin.",
+		}, nil), true)
 		return &model.CommandResponse{}, nil
 	}
 
@@ -392,7 +837,10 @@ func (p *Plugin) handleSetup(args *model.CommandArgs, parameters []string) (*mod
 		case "announcement":
 			err = p.flowManager.StartAnnouncementWizard(userID)
 		default:
-			p.postCommandResponse(args, fmt.Sprintf("Unknown subcommand %v", parameters[0]), true)
+			p.postCommandResponse(args, p.localize(locale, &i18n.Message{
+				ID:    "command.setup.unknown",
+				Other: "Unknown subcommand {{.Subcommand}}",
+			}, map[string]any{"Subcommand": parameters[0]}), true)
 			return &model.CommandResponse{}, nil
 		}
 	}
@@ -416,9 +864,9 @@ func (p *Plugin) handleUnsubscribe(ctx context.Context, args *model.CommandArgs,
 	var err error
 	var isEphemeralPost bool
 	if len(parameters) == 0 {
-		message = specifyRepositoryMessage
+		message = p.localizeForUser(args.UserId, specifyRepositoryMessage, nil)
 	} else {
-		message, isEphemeralPost, err = p.subscriptionDelete(info, config, parameters[0], args.ChannelId)
+		message, isEphemeralPost, err = p.subscriptionDelete(p.getUserLocale(args.UserId), info, config, parameters[0], args.ChannelId)
 		if err != nil {
 			message = err.Error()
 		}
@@ -428,14 +876,20 @@ func (p *Plugin) handleUnsubscribe(ctx context.Context, args *model.CommandArgs,
 
 func (p *Plugin) handleDisconnect(ctx context.Context, args *model.CommandArgs, parameters []string, info *gitlab.UserInfo) (*model.CommandResponse, *model.AppError) {
 	p.disconnectGitlabAccount(args.UserId)
-	return p.getCommandResponse(args, "Disconnected your GitLab account.", true), nil
+	return p.getCommandResponse(args, p.localizeForUser(args.UserId, &i18n.Message{
+		ID:    "command.disconnect",
+		Other: "Disconnected your GitLab account.",
+	}, nil), true), nil
 }
 
 func (p *Plugin) handleTodo(ctx context.Context, args *model.CommandArgs, parameters []string, info *gitlab.UserInfo) (*model.CommandResponse, *model.AppError) {
 	_, text, err := p.GetToDo(ctx, info)
 	if err != nil {
 		p.client.Log.Warn("can't get todo in command", "err", err.Error())
-		return p.getCommandResponse(args, "Encountered an error getting your todo items.", true), nil
+		return p.getCommandResponse(args, p.localizeForUser(args.UserId, &i18n.Message{
+			ID:    "command.todo.error",
+			Other: "Encountered an error getting your todo items.",
+		}, nil), true), nil
 	}
 	return p.getCommandResponse(args, text, true), nil
 }
@@ -458,17 +912,33 @@ func (p *Plugin) handleMe(ctx context.Context, args *model.CommandArgs, paramete
 		gitUser = resp
 		return nil
 	})
+	locale := p.getUserLocale(args.UserId)
 	if err != nil {
-		return p.getCommandResponse(args, "Encountered an error getting your GitLab profile.", true), nil
-	}
-
-	text := fmt.Sprintf("You are connected to GitLab as:\n# [![image](%s =40x40)](%s) [%s](%s)", gitUser.AvatarURL, gitUser.WebURL, gitUser.Username, gitUser.WebsiteURL)
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.me.error",
+			Other: "Encountered an error getting your GitLab profile.",
+		}, nil), true), nil
+	}
+
+	text := p.localize(locale, &i18n.Message{
+		ID:    "command.me",
+		Other: "You are connected to GitLab as:\n# [![image]({{.AvatarURL}} =40x40)]({{.WebURL}}) [{{.Username}}]({{.WebsiteURL}})",
+	}, map[string]any{
+		"AvatarURL":  gitUser.AvatarURL,
+		"WebURL":     gitUser.WebURL,
+		"Username":   gitUser.Username,
+		"WebsiteURL": gitUser.WebsiteURL,
+	})
 	return p.getCommandResponse(args, text, true), nil
 }
 
 func (p *Plugin) handleSettings(ctx context.Context, args *model.CommandArgs, parameters []string, info *gitlab.UserInfo) (*model.CommandResponse, *model.AppError) {
+	locale := p.getUserLocale(args.UserId)
 	if len(parameters) < 2 {
-		return p.getCommandResponse(args, "Please specify both a setting and value. Use `/gitlab help` for more usage information.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.settings.specify",
+			Other: "Please specify both a setting and value. Use `/gitlab help` for more usage information.",
+		}, nil), true), nil
 	}
 
 	setting := parameters[0]
@@ -477,7 +947,10 @@ func (p *Plugin) handleSettings(ctx context.Context, args *model.CommandArgs, pa
 	if strValue == SettingOn {
 		value = true
 	} else if strValue != SettingOff {
-		return p.getCommandResponse(args, "Invalid value. Accepted values are: \"on\" or \"off\".", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.settings.invalid_value",
+			Other: "Invalid value. Accepted values are: \"on\" or \"off\".",
+		}, nil), true), nil
 	}
 
 	switch setting {
@@ -485,29 +958,35 @@ func (p *Plugin) handleSettings(ctx context.Context, args *model.CommandArgs, pa
 		if value {
 			if err := p.storeGitlabToUserIDMapping(info.GitlabUsername, info.UserID); err != nil {
 				p.client.Log.Warn("can't store GitLab to user id mapping", "err", err.Error())
-				return p.getCommandResponse(args, "Unknown error please retry or ask to an administrator to look at logs", true), nil
+				return p.getCommandResponse(args, p.localize(locale, unknownErrorMessage, nil), true), nil
 			}
 			if err := p.storeGitlabIDToUserIDMapping(info.GitlabUsername, info.GitlabUserID); err != nil {
 				p.client.Log.Warn("can't store GitLab to GitLab id mapping", "err", err.Error())
-				return p.getCommandResponse(args, "Unknown error please retry or ask to an administrator to look at logs", true), nil
+				return p.getCommandResponse(args, p.localize(locale, unknownErrorMessage, nil), true), nil
 			}
 		} else if err := p.deleteGitlabToUserIDMapping(info.GitlabUsername); err != nil {
 			p.client.Log.Warn("can't delete GitLab username in kvstore", "err", err.Error())
-			return p.getCommandResponse(args, "Unknown error please retry or ask to an administrator to look at logs", true), nil
+			return p.getCommandResponse(args, p.localize(locale, unknownErrorMessage, nil), true), nil
 		}
 		info.Settings.Notifications = value
 	case SettingReminders:
 		info.Settings.DailyReminder = value
 	default:
-		return p.getCommandResponse(args, "Unknown setting.", true), nil
+		return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+			ID:    "command.settings.unknown",
+			Other: "Unknown setting.",
+		}, nil), true), nil
 	}
 
 	if err := p.storeGitlabUserInfo(info); err != nil {
 		p.client.Log.Warn("can't store user info after update by command", "err", err.Error())
-		return p.getCommandResponse(args, "Unknown error please retry or ask to an administrator to look at logs", true), nil
+		return p.getCommandResponse(args, p.localize(locale, unknownErrorMessage, nil), true), nil
 	}
 
-	return p.getCommandResponse(args, "Settings updated.", true), nil
+	return p.getCommandResponse(args, p.localize(locale, &i18n.Message{
+		ID:    "command.settings.updated",
+		Other: "Settings updated.",
+	}, nil), true), nil
 }
 
 func (p *Plugin) handleWebhookHandler(ctx context.Context, args *model.CommandArgs, parameters []string, info *gitlab.UserInfo) (*model.CommandResponse, *model.AppError) {
@@ -523,7 +1002,10 @@ func (p *Plugin) handlePipelines(ctx context.Context, args *model.CommandArgs, p
 
 func (p *Plugin) handleIssueHelper(_ *plugin.Context, args *model.CommandArgs, parameters []string) string {
 	if len(parameters) == 0 {
-		return "Invalid issue command. Available command is 'create'."
+		return p.localizeForUser(args.UserId, &i18n.Message{
+			ID:    "command.issue.invalid",
+			Other: "Invalid issue command. Available command is 'create'.",
+		}, nil)
 	}
 
 	command := parameters[0]
@@ -534,31 +1016,41 @@ func (p *Plugin) handleIssueHelper(_ *plugin.Context, args *model.CommandArgs, p
 		p.openIssueCreateModal(args.UserId, args.ChannelId, strings.Join(parameters, " "))
 		return ""
 	default:
-		return fmt.Sprintf("This command is not implemented yet. Command: %v", command)
+		return p.localizeForUser(args.UserId, &i18n.Message{
+			ID:    "command.issue.not_implemented",
+			Other: "This command is not implemented yet. Command: {{.Command}}",
+		}, map[string]any{"Command": command})
 	}
 }
 
 // webhookPermissionMessage builds a user-friendly message for GitLab permission
 // failures when managing webhooks, naming the scope and the access required.
-func webhookPermissionMessage(group, project string) string {
-	scope := "group"
+func (p *Plugin) webhookPermissionMessage(locale, group, project string) string {
+	message := &i18n.Message{
+		ID:    "command.webhook.group_permission",
+		Other: "You don't have permission to manage webhooks for the group `{{.Namespace}}`. You need Maintainer or Owner access in GitLab.",
+	}
 	if project != "" {
-		scope = "repository"
+		message = &i18n.Message{
+			ID:    "command.webhook.repository_permission",
+			Other: "You don't have permission to manage webhooks for the repository `{{.Namespace}}`. You need Maintainer or Owner access in GitLab.",
+		}
 	}
-	return fmt.Sprintf("You don't have permission to manage webhooks for the %s `%s`. You need Maintainer or Owner access in GitLab.", scope, namespaceFromGroupAndProject(group, project))
+	return p.localize(locale, message, map[string]any{"Namespace": namespaceFromGroupAndProject(group, project)})
 }
 
 // webhookCommand processes the /gitlab webhook commands
 func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *gitlab.UserInfo, enablePrivateRepo bool) string {
+	locale := p.getUserLocale(info.UserID)
 	if len(parameters) < 1 {
-		return unknownActionMessage
+		return p.localize(locale, unknownActionMessage, nil)
 	}
 	subCommand := parameters[0]
 
 	switch subCommand {
 	case commandList:
 		if len(parameters) != 2 {
-			return unknownActionMessage
+			return p.localize(locale, unknownActionMessage, nil)
 		}
 
 		namespace := parameters[1]
@@ -592,10 +1084,10 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 			})
 			if err != nil {
 				if errors.Is(err, gitlab.ErrForbidden) {
-					return webhookPermissionMessage(group, project)
+					return p.webhookPermissionMessage(locale, group, project)
 				}
 				if errors.Is(err, gitlab.ErrNotFound) {
-					return projectNotFoundMessage + namespace
+					return p.localize(locale, projectNotFoundMessage, map[string]any{"Namespace": namespace})
 				}
 				return err.Error()
 			}
@@ -610,16 +1102,19 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 			})
 			if err != nil {
 				if errors.Is(err, gitlab.ErrForbidden) {
-					return webhookPermissionMessage(group, project)
+					return p.webhookPermissionMessage(locale, group, project)
 				}
 				if errors.Is(err, gitlab.ErrNotFound) {
-					return groupNotFoundMessage + group
+					return p.localize(locale, groupNotFoundMessage, map[string]any{"Group": group})
 				}
 				return err.Error()
 			}
 		}
 		if len(webhookInfo) == 0 {
-			return fmt.Sprintf("No webhooks found in %s", namespace)
+			return p.localize(locale, &i18n.Message{
+				ID:    "command.webhook.list_empty",
+				Other: "No webhooks found in {{.Namespace}}",
+			}, map[string]any{"Namespace": namespace})
 		}
 		var sb strings.Builder
 		for _, hook := range webhookInfo {
@@ -629,12 +1124,12 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 
 	case commandAdd:
 		if len(parameters) < 2 {
-			return unknownActionMessage
+			return p.localize(locale, unknownActionMessage, nil)
 		}
 
 		siteURL := getSiteURL(p.client)
 		if siteURL == "" {
-			return newWebhookEmptySiteURLmessage
+			return p.localize(locale, newWebhookEmptySiteURLmessage, nil)
 		}
 
 		urlPath := fmt.Sprintf("%v/%s", siteURL, inboundWebhookURL)
@@ -650,12 +1145,6 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 		}
 		hookOptions.URL = urlPath
 
-		if len(parameters) > 4 {
-			hookOptions.Token = parameters[4]
-		} else {
-			hookOptions.Token = p.getConfiguration().WebhookSecret
-		}
-
 		namespace := parameters[1]
 		var group, project string
 		namespaceErr := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
@@ -690,11 +1179,35 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 			return err.Error()
 		}
 
+		var token string
+		if len(parameters) > 4 {
+			token = parameters[4]
+		}
+		secret, secretNote, err := p.webhookSecretForNewHook(ctx, info, group, project, token)
+		if err != nil {
+			auditRec.AddErrorDesc(err.Error())
+			if errors.Is(err, gitlab.ErrForbidden) {
+				return p.webhookPermissionMessage(locale, group, project)
+			}
+			if errors.Is(err, errWebhookSecretMismatch) {
+				return p.localize(locale, &i18n.Message{
+					ID:    "command.webhook.secret_mismatch",
+					Other: "`{{.Namespace}}` already has a webhook secret, which its existing webhooks use. Leave the token out to use it for the new webhook, or run `/gitlab webhook rotate {{.Namespace}}` to change it for every webhook.",
+				}, map[string]any{"Namespace": namespaceFromGroupAndProject(group, project)})
+			}
+			p.client.Log.Warn("can't record webhook secret", "namespace", resolvedNamespace, "err", err.Error())
+			return p.localize(locale, &i18n.Message{
+				ID:    "command.webhook.secret_error",
+				Other: "Failed to store the webhook secret.",
+			}, nil)
+		}
+		hookOptions.Token = secret
+
 		newWebhook, err := p.createHook(ctx, p.GitlabClient, info, group, project, hookOptions)
 		if err != nil {
 			auditRec.AddErrorDesc(err.Error())
 			if errors.Is(err, gitlab.ErrForbidden) {
-				return webhookPermissionMessage(group, project)
+				return p.webhookPermissionMessage(locale, group, project)
 			}
 			return err.Error()
 		}
@@ -705,16 +1218,44 @@ func (p *Plugin) webhookCommand(ctx context.Context, parameters []string, info *
 			URL:    newWebhook.URL,
 			Scope:  newWebhook.Scope.String(),
 		})
-		return fmt.Sprintf("Webhook Created:\n%s", newWebhook.String())
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.created",
+			Other: "Webhook Created:\n{{.Webhook}}",
+		}, map[string]any{"Webhook": newWebhook.String()}) + secretNote
+
+	case commandRotate:
+		if len(parameters) != 2 {
+			return p.localize(locale, unknownActionMessage, nil)
+		}
+
+		group, project, err := p.resolveWebhookNamespace(ctx, info, parameters[1], enablePrivateRepo)
+		if err != nil {
+			return err.Error()
+		}
+		return p.rotateWebhookSecret(ctx, locale, info, group, project)
+
+	case commandSigningToken:
+		if len(parameters) != 3 {
+			return p.localize(locale, unknownActionMessage, nil)
+		}
+
+		group, project, err := p.resolveWebhookNamespace(ctx, info, parameters[1], enablePrivateRepo)
+		if err != nil {
+			return err.Error()
+		}
+		return p.setWebhookSigningToken(ctx, locale, info, group, project, parameters[2])
 
 	default:
-		return fmt.Sprintf("Unknown webhook command: %s", subCommand)
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.unknown",
+			Other: "Unknown webhook command: {{.Subcommand}}",
+		}, map[string]any{"Subcommand": subCommand})
 	}
 }
 
 func parseTriggers(triggersCsv string) *gitlab.AddWebhookOptions {
 	var sslVerification, pushEvents, tagPushEvents, issuesEvents, confidentialIssuesEvents, noteEvents bool
-	var confidentialNoteEvents, mergeRequestsEvents, jobEvents, pipelineEvents, wikiPageEvents, deploymentEvents, releaseEvents bool
+	var confidentialNoteEvents, mergeRequestsEvents, jobEvents, pipelineEvents, wikiPageEvents, deploymentEvents, releaseEvents, featureFlagEvents, memberEvents, emojiEvents bool
 	var all bool
 	if triggersCsv == "*" {
 		all = true
@@ -764,6 +1305,15 @@ func parseTriggers(triggersCsv string) *gitlab.AddWebhookOptions {
 		if all || strings.EqualFold(trigger, "ReleaseEvents") {
 			releaseEvents = true
 		}
+		if all || strings.EqualFold(trigger, "FeatureFlagEvents") {
+			featureFlagEvents = true
+		}
+		if all || strings.EqualFold(trigger, "MemberEvents") {
+			memberEvents = true
+		}
+		if all || strings.EqualFold(trigger, "EmojiEvents") {
+			emojiEvents = true
+		}
 	}
 
 	return &gitlab.AddWebhookOptions{
@@ -780,22 +1330,31 @@ func parseTriggers(triggersCsv string) *gitlab.AddWebhookOptions {
 		WikiPageEvents:           wikiPageEvents,
 		DeploymentEvents:         deploymentEvents,
 		ReleaseEvents:            releaseEvents,
+		FeatureFlagEvents:        featureFlagEvents,
+		MemberEvents:             memberEvents,
+		EmojiEvents:              emojiEvents,
 	}
 }
 
-func (p *Plugin) subscriptionDelete(userInfo *gitlab.UserInfo, config *configuration, fullPath, channelID string) (string, bool, error) {
+func (p *Plugin) subscriptionDelete(locale string, userInfo *gitlab.UserInfo, config *configuration, fullPath, channelID string) (string, bool, error) {
 	normalizedPath := normalizePath(fullPath, config.GitlabURL)
-	deleted, updatedSubscriptions, err := p.Unsubscribe(channelID, normalizedPath)
+	deleted, err := p.Unsubscribe(channelID, normalizedPath)
 	if err != nil {
 		p.client.Log.Warn("can't unsubscribe channel in command", "err", err.Error())
-		return "Encountered an error trying to unsubscribe. Please try again.", true, nil
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.delete_error",
+			Other: "Encountered an error trying to unsubscribe. Please try again.",
+		}, nil), true, nil
 	}
 
 	if !deleted {
-		return "Subscription not found, please check repository name.", true, nil
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.not_found",
+			Other: "Subscription not found, please check repository name.",
+		}, nil), true, nil
 	}
 
-	p.sendChannelSubscriptionsUpdated(updatedSubscriptions, channelID)
+	p.sendChannelSubscriptionsUpdated(channelID)
 
 	baseURL := config.GitlabURL
 	if !strings.HasSuffix(baseURL, "/") {
@@ -826,9 +1385,13 @@ func (p *Plugin) subscriptionDelete(userInfo *gitlab.UserInfo, config *configura
 		}
 	}
 
+	deleteWebhookMessage := &i18n.Message{
+		ID:    "command.subscriptions.delete_webhook",
+		Other: "Please delete the [webhook]({{.URL}}) for this subscription unless it's required for other subscriptions.",
+	}
 	var webhookMsg string
 	if getProjectError == nil && project != nil {
-		webhookMsg = fmt.Sprintf("\n Please delete the [webhook](%s) for this subscription unless it's required for other subscriptions.", fmt.Sprintf("%s%s/-/hooks", baseURL, normalizedPath))
+		webhookMsg = "\n " + p.localize(locale, deleteWebhookMessage, map[string]any{"URL": fmt.Sprintf("%s%s/-/hooks", baseURL, normalizedPath)})
 	} else {
 		var group *gitlabLib.Group
 		var getGroupError error
@@ -848,20 +1411,26 @@ func (p *Plugin) subscriptionDelete(userInfo *gitlab.UserInfo, config *configura
 			}
 		}
 		if getGroupError == nil && group != nil {
-			webhookMsg = fmt.Sprintf("\n Please delete the [webhook](%s) for this subscription unless it's required for other subscriptions.", fmt.Sprintf("%sgroups/%s/-/hooks", baseURL, normalizedPath))
+			webhookMsg = "\n " + p.localize(locale, deleteWebhookMessage, map[string]any{"URL": fmt.Sprintf("%sgroups/%s/-/hooks", baseURL, normalizedPath)})
 		} else {
-			webhookMsg = "\n Please delete the webhook for this subscription unless it's required for other subscriptions."
+			webhookMsg = "\n " + p.localize(locale, &i18n.Message{
+				ID:    "command.subscriptions.delete_unknown_webhook",
+				Other: "Please delete the webhook for this subscription unless it's required for other subscriptions.",
+			}, nil)
 		}
 	}
 
-	unsubscribeMessage := fmt.Sprintf("Successfully deleted subscription for %s.", fmt.Sprintf("[%s](%s)", normalizedPath, baseURL+normalizedPath))
+	unsubscribeMessage := p.localize(locale, &i18n.Message{
+		ID:    "command.subscriptions.deleted",
+		Other: "Successfully deleted subscription for [{{.Path}}]({{.URL}}).",
+	}, map[string]any{"Path": normalizedPath, "URL": baseURL + normalizedPath})
 	unsubscribeMessage += webhookMsg
 
 	return unsubscribeMessage, false, nil
 }
 
 // subscriptionsListCommand list GitLab subscriptions in a channel
-func (p *Plugin) subscriptionsListCommand(channelID string) string {
+func (p *Plugin) subscriptionsListCommand(locale, channelID string) string {
 	var txt string
 	subs, err := p.GetSubscriptionsByChannel(channelID)
 	if err != nil {
@@ -869,18 +1438,24 @@ func (p *Plugin) subscriptionsListCommand(channelID string) string {
 	}
 
 	if len(subs) == 0 {
-		txt = "Currently there are no subscriptions in this channel"
+		txt = p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.list_empty",
+			Other: "Currently there are no subscriptions in this channel",
+		}, nil)
 	} else {
-		txt = "### Subscriptions in this channel\n"
+		txt = p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.list",
+			Other: "### Subscriptions in this channel",
+		}, nil) + "\n"
 	}
 	for _, sub := range subs {
-		txt += fmt.Sprintf("* `%s` - %s\n", strings.Trim(sub.Repository, "/"), sub.Features)
+		txt += fmt.Sprintf("* `%s` - %s\n", strings.Trim(sub.Repository, "/"), sub)
 	}
 	return txt
 }
 
 // subscriptionsAddCommand subscripes to A GitLab Project
-func (p *Plugin) subscriptionsAddCommand(ctx context.Context, info *gitlab.UserInfo, config *configuration, fullPath, channelID, features string) string {
+func (p *Plugin) subscriptionsAddCommand(ctx context.Context, locale string, info *gitlab.UserInfo, config *configuration, fullPath, channelID, features string) string {
 	var namespace, project string
 	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
 		respGroup, respProject, err := p.GitlabClient.ResolveNamespaceAndProject(ctx, info, token, fullPath, config.EnablePrivateRepo)
@@ -893,9 +1468,15 @@ func (p *Plugin) subscriptionsAddCommand(ctx context.Context, info *gitlab.UserI
 	})
 	if err != nil {
 		if errors.Is(err, gitlab.ErrNotFound) {
-			return "Resource with such path is not found."
+			return p.localize(locale, &i18n.Message{
+				ID:    "command.subscriptions.not_found_resource",
+				Other: "Resource with such path is not found.",
+			}, nil)
 		} else if errors.Is(err, gitlab.ErrPrivateResource) {
-			return "Requested resource is private."
+			return p.localize(locale, &i18n.Message{
+				ID:    "command.subscriptions.private_resource",
+				Other: "Requested resource is private.",
+			}, nil)
 		}
 		p.client.Log.Warn(
 			"unable to resolve subscription namespace and project name",
@@ -907,13 +1488,16 @@ func (p *Plugin) subscriptionsAddCommand(ctx context.Context, info *gitlab.UserI
 	// Only check the permissions for a project if the project subscription is created (Not a group or a subgroup subscription)
 	if project != "" {
 		if hasPermission := p.permissionToProject(ctx, info.UserID, namespace, project); !hasPermission {
-			msg := "You don't have the permissions to create subscriptions for this project."
-			p.client.Log.Warn(msg)
-			return msg
+			msg := &i18n.Message{
+				ID:    "command.subscriptions.no_permission",
+				Other: "You don't have the permissions to create subscriptions for this project.",
+			}
+			p.client.Log.Warn(msg.Other)
+			return p.localize(locale, msg, nil)
 		}
 	}
 
-	updatedSubscriptions, subscribeErr := p.Subscribe(info, namespace, project, channelID, features)
+	subscribeErr := p.Subscribe(info, namespace, project, channelID, features)
 	if subscribeErr != nil {
 		p.client.Log.Warn(
 			"failed to subscribe",
@@ -942,78 +1526,89 @@ func (p *Plugin) subscriptionsAddCommand(ctx context.Context, info *gitlab.UserI
 
 	hookErrorMessage := ""
 	if hasHookError {
-		hookErrorMessage = "\n**Note:** We are unable to determine the webhook status for this project. Please contact your project administrator"
+		hookErrorMessage = "\n" + p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.webhook_status_unknown",
+			Other: "**Note:** We are unable to determine the webhook status for this project. Please contact your project administrator",
+		}, nil)
 	}
 
 	var hookStatusMessage string
 	if !hasHook {
 		// no web hook found
-		hookStatusMessage = fmt.Sprintf("\nA Webhook is needed, run ```/gitlab webhook add %s``` to create one now.%s", fullPath, hookErrorMessage)
+		hookStatusMessage = "\n" + p.localize(locale, &i18n.Message{
+			ID:    "command.subscriptions.webhook_needed",
+			Other: "A Webhook is needed, run ```/gitlab webhook add {{.Path}}``` to create one now.",
+		}, map[string]any{"Path": fullPath}) + hookErrorMessage
 	}
 
-	p.sendChannelSubscriptionsUpdated(updatedSubscriptions, channelID)
+	p.sendChannelSubscriptionsUpdated(channelID)
 
-	return fmt.Sprintf("Successfully subscribed to %s.%s", fullPath, hookStatusMessage)
+	return p.localize(locale, &i18n.Message{
+		ID:    "command.subscriptions.added",
+		Other: "Successfully subscribed to {{.Path}}.",
+	}, map[string]any{"Path": fullPath}) + hookStatusMessage
 }
 
 // subscribeCommand process the /gitlab subscribe command.
 // It returns a message and handles all errors my including helpful information in the message
 func (p *Plugin) subscribeCommand(ctx context.Context, parameters []string, channelID string, config *configuration, info *gitlab.UserInfo) (string, bool) {
+	locale := p.getUserLocale(info.UserID)
 	if len(parameters) == 0 {
-		return invalidSubscribeSubCommand, true
+		return p.localize(locale, invalidSubscribeSubCommand, nil), true
 	}
 
 	subcommand := parameters[0]
 
 	switch subcommand {
 	case commandList:
-		return p.subscriptionsListCommand(channelID), true
+		return p.subscriptionsListCommand(locale, channelID), true
 	case commandAdd:
 		features := "merges,issues,tag"
 		if len(parameters) < 2 {
-			return missingOrgOrRepoFromSubscribeCommand, true
+			return p.localize(locale, missingOrgOrRepoFromSubscribeCommand, nil), true
 		} else if len(parameters) > 2 {
 			features = strings.Join(parameters[2:], " ")
 		}
 		// Resolve namespace and project name
 		fullPath := normalizePath(parameters[1], config.GitlabURL)
 
-		return p.subscriptionsAddCommand(ctx, info, config, fullPath, channelID, features), false
+		return p.subscriptionsAddCommand(ctx, locale, info, config, fullPath, channelID, features), false
 	case commandDelete:
 		if len(parameters) < 2 {
-			return specifyRepositoryMessage, true
+			return p.localize(locale, specifyRepositoryMessage, nil), true
 		}
 
-		message, isEphemeralPost, err := p.subscriptionDelete(info, config, parameters[1], channelID)
+		message, isEphemeralPost, err := p.subscriptionDelete(locale, info, config, parameters[1], channelID)
 		if err != nil {
 			return err.Error(), true
 		}
 		return message, isEphemeralPost
 	default:
-		return invalidSubscribeSubCommand, true
+		return p.localize(locale, invalidSubscribeSubCommand, nil), true
 	}
 }
 
 func (p *Plugin) pipelinesCommand(ctx context.Context, parameters []string, channelID string, info *gitlab.UserInfo) string {
+	locale := p.getUserLocale(info.UserID)
 	if len(parameters) == 0 {
-		return invalidPipelinesSubCommand
+		return p.localize(locale, invalidPipelinesSubCommand, nil)
 	}
 	subcommand := parameters[0]
 	switch subcommand {
 	case commandRun:
 		if len(parameters) < 3 {
-			return specifyRepositoryAndBranchMessage
+			return p.localize(locale, specifyRepositoryAndBranchMessage, nil)
 		}
 		namespace := parameters[1]
 		ref := parameters[2]
-		return p.pipelineRunCommand(ctx, namespace, ref, channelID, info)
+		return p.pipelineRunCommand(ctx, locale, namespace, ref, channelID, info)
 	default:
-		return unknownActionMessage
+		return p.localize(locale, unknownActionMessage, nil)
 	}
 }
 
 // pipelineRunCommand run a pipeline in a project
-func (p *Plugin) pipelineRunCommand(ctx context.Context, namespace, ref, channelID string, info *gitlab.UserInfo) string {
+func (p *Plugin) pipelineRunCommand(ctx context.Context, locale, namespace, ref, channelID string, info *gitlab.UserInfo) string {
 	var pipelineInfo *gitlab.PipelineInfo
 	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
 		groupName, projectName, err := p.GitlabClient.ResolveNamespaceAndProject(ctx, info, token, namespace, true)
@@ -1035,17 +1630,22 @@ func (p *Plugin) pipelineRunCommand(ctx context.Context, namespace, ref, channel
 		return err.Error()
 	}
 
-	var txt string
 	if pipelineInfo == nil {
-		txt = "Currently there is no pipeline info"
-		return txt
-	}
-	txt = "### Pipeline info\n"
-	txt += fmt.Sprintf("**Status**: %s\n", pipelineInfo.Status)
-	txt += fmt.Sprintf("**SHA**: %s\n", pipelineInfo.SHA)
-	txt += fmt.Sprintf("**Ref**: %s\n", pipelineInfo.Ref)
-	txt += fmt.Sprintf("**Triggered By**: %s\n", pipelineInfo.User)
-	txt += fmt.Sprintf("**Visit pipeline [here](%s)** \n\n", pipelineInfo.WebURL)
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.pipelines.no_info",
+			Other: "Currently there is no pipeline info",
+		}, nil)
+	}
+	txt := p.localize(locale, &i18n.Message{
+		ID:    "command.pipelines.info",
+		Other: "### Pipeline info\n**Status**: {{.Status}}\n**SHA**: {{.SHA}}\n**Ref**: {{.Ref}}\n**Triggered By**: {{.User}}\n**Visit pipeline [here]({{.URL}})** ",
+	}, map[string]any{
+		"Status": pipelineInfo.Status,
+		"SHA":    pipelineInfo.SHA,
+		"Ref":    pipelineInfo.Ref,
+		"User":   pipelineInfo.User,
+		"URL":    pipelineInfo.WebURL,
+	}) + "\n\n"
 
 	foundPipelineSubscription := false
 	subs, err := p.GetSubscriptionsByChannel(channelID)
@@ -1062,7 +1662,10 @@ func (p *Plugin) pipelineRunCommand(ctx context.Context, namespace, ref, channel
 	}
 
 	if !foundPipelineSubscription {
-		txt += fmt.Sprintf("\n\n**Note:** This channel is currently not subscribed to pipeline event for `%s`. Run the command below if would you like to create a subscription.\n\n`/gitlab subscriptions add %s pipeline`", namespace, namespace)
+		txt += "\n\n" + p.localize(locale, &i18n.Message{
+			ID:    "command.pipelines.not_subscribed",
+			Other: "**Note:** This channel is currently not subscribed to pipeline event for `{{.Namespace}}`. Run the command below if would you like to create a subscription.\n\n`/gitlab subscriptions add {{.Namespace}} pipeline`",
+		}, map[string]any{"Namespace": namespace})
 	}
 
 	return txt
@@ -1092,7 +1695,7 @@ func (p *Plugin) getAutocompleteData(config *configuration) *model.AutocompleteD
 		return gitlab
 	}
 
-	gitlab := model.NewAutocompleteData("gitlab", "[command]", "Available commands: connect, disconnect, todo, subscriptions, me, pipelines, settings, webhook, instance, setup, help, about")
+	gitlab := model.NewAutocompleteData("gitlab", "[command]", "Available commands: connect, disconnect, todo, subscriptions, me, pipelines, settings, webhook, instance, setup, deliveries, templates, help, about")
 
 	connect := model.NewAutocompleteData("connect", "", "Connect your GitLab account")
 	connect.AddStaticListArgument("Instance Name", true, p.getConnectInstanceAutoCompleteData())
@@ -1136,7 +1739,7 @@ func (p *Plugin) getAutocompleteData(config *configuration) *model.AutocompleteD
 
 	subscriptionsAdd := model.NewAutocompleteData(commandAdd, "owner[/repo] [features]", "Subscribe the current channel to receive notifications from a project")
 	subscriptionsAdd.AddTextArgument("Project path: includes user or group name with optional slash project name", "owner[/repo]", "")
-	subscriptionsAdd.AddTextArgument("comma-delimited list of features to subscribe to: issues, confidential_issues, merges, pushes, issue_comments, merge_request_comments, merge_request_assigns, pipeline, tag, pull_reviews, label:<labelName>, deployments, releases", "[features] (optional)", `/[^,-\s]+(,[^,-\s]+)*/`)
+	subscriptionsAdd.AddTextArgument("comma-delimited list of features to subscribe to: issues, confidential_issues, merges, pushes, issue_comments, merge_request_comments, commit_comments, snippet_comments, merge_request_assigns, pipeline, pipeline:failed, pipeline:fixed, tag, pull_reviews, label:<labelName>, label_all:<labelName>, -label:<labelName>, branch:<branchGlob>, target_branch:<branchGlob>, no_drafts, path:<pathGlob>, author:<usernameGlob>, exclude_author:<usernameGlob>, exclude_bots, deployments, deployments:<status>, environment:<environmentGlob>, releases, wiki, feature_flags, members, reactions, milestones, vulnerabilities, vulnerabilities:<severity>, quiet_hours:<HH:MM-HH:MM>, quiet_weekends, timezone:<timezone>, quiet_summary, threads", "[features] (optional)", `/[^,-\s]+(,[^,-\s]+)*/`)
 	subscriptions.AddCommand(subscriptionsAdd)
 
 	subscriptionsDelete := model.NewAutocompleteData(commandDelete, "owner[/repo]", "Unsubscribe the current channel from a repository")
@@ -1175,18 +1778,27 @@ func (p *Plugin) getAutocompleteData(config *configuration) *model.AutocompleteD
 	settings.AddStaticListArgument("New value", true, value)
 	gitlab.AddCommand(settings)
 
-	webhook := model.NewAutocompleteData("webhook", "[command]", "Available Commands: list, add")
+	webhook := model.NewAutocompleteData("webhook", "[command]", "Available Commands: list, add, rotate, signing-token")
 	webhookList := model.NewAutocompleteData(commandList, "owner/[repo]", "List existing project or group webhooks")
 	webhookList.AddTextArgument("Project path: includes user or group name with optional slash project name", "owner[/repo]", "")
 	webhook.AddCommand(webhookList)
 
 	webhookAdd := model.NewAutocompleteData(commandAdd, "owner/[repo] [options] [url] [token]", "Add a project or group webhook")
 	webhookAdd.AddTextArgument("Group or Project path: includes user or group name with optional slash project name", "owner[/repo]", "")
-	webhookAdd.AddTextArgument("[Optional] options: comma-delimited list of actions to trigger a webhook, defaults to all with SSL verification", "[* or *noSSL] or [PushEvents,][TagPushEvents,][Comments,][ConfidentialComments,][IssuesEvents,][ConfidentialIssuesEvents,][MergeRequestsEvents,][JobEvents,][PipelineEvents,][WikiPageEvents,][SSLverification]", "")
+	webhookAdd.AddTextArgument("[Optional] options: comma-delimited list of actions to trigger a webhook, defaults to all with SSL verification", "[* or *noSSL] or [PushEvents,][TagPushEvents,][Comments,][ConfidentialComments,][IssuesEvents,][ConfidentialIssuesEvents,][MergeRequestsEvents,][JobEvents,][PipelineEvents,][WikiPageEvents,][DeploymentEvents,][ReleaseEvents,][FeatureFlagEvents,][MemberEvents,][EmojiEvents,][SSLverification]", "")
 	webhookAdd.AddTextArgument("[Optional] url: URL to be triggered triggered. Defaults to this plugins URL", "[url]", "")
-	webhookAdd.AddTextArgument("[Optional] token: Secret for webhook. Defaults to token used in plugin's settings.", "[token]", "")
+	webhookAdd.AddTextArgument("[Optional] token: Secret for webhook. Defaults to the secret of the project or group.", "[token]", "")
 	webhook.AddCommand(webhookAdd)
 
+	webhookRotate := model.NewAutocompleteData(commandRotate, "owner/[repo]", "Generate a new secret for the project or group webhooks")
+	webhookRotate.AddTextArgument("Group or Project path: includes user or group name with optional slash project name", "owner[/repo]", "")
+	webhook.AddCommand(webhookRotate)
+
+	webhookSigningToken := model.NewAutocompleteData(commandSigningToken, "owner/[repo] token", "Save the signing token of the project or group webhooks")
+	webhookSigningToken.AddTextArgument("Group or Project path: includes user or group name with optional slash project name", "owner[/repo]", "")
+	webhookSigningToken.AddTextArgument("Signing token shown by GitLab in the webhook settings", "token", "")
+	webhook.AddCommand(webhookSigningToken)
+
 	gitlab.AddCommand(webhook)
 
 	setup := model.NewAutocompleteData("setup", "[command]", "Available commands: oauth, webhook, announcement")
@@ -1196,6 +1808,36 @@ func (p *Plugin) getAutocompleteData(config *configuration) *model.AutocompleteD
 	setup.AddCommand(model.NewAutocompleteData("announcement", "", "Announce to your team that they can use GitLab integration"))
 	gitlab.AddCommand(setup)
 
+	deliveries := model.NewAutocompleteData("deliveries", "[command]", "Available commands: list, replay, discard")
+	deliveries.RoleID = model.SystemAdminRoleId
+	deliveries.AddCommand(model.NewAutocompleteData(commandList, "", "List webhook notifications that could not be delivered"))
+	deliveriesReplay := model.NewAutocompleteData(commandReplay, "[id|all]", "Retry delivering failed webhook notifications")
+	deliveriesReplay.AddTextArgument("Delivery id from the list command, or all", "[id|all]", "")
+	deliveries.AddCommand(deliveriesReplay)
+	deliveriesDiscard := model.NewAutocompleteData(commandDiscard, "[id|all]", "Drop failed webhook notifications")
+	deliveriesDiscard.AddTextArgument("Delivery id from the list command, or all", "[id|all]", "")
+	deliveries.AddCommand(deliveriesDiscard)
+	gitlab.AddCommand(deliveries)
+
+	templates := model.NewAutocompleteData("templates", "[command]", "Available commands: view, set, reset, preview")
+	templates.RoleID = model.SystemAdminRoleId
+	templateNames := getMessageTemplateAutocompleteItems()
+	templatesView := model.NewAutocompleteData(commandView, "[name]", "List the message templates, or show one")
+	templatesView.AddStaticListArgument("Template name", false, templateNames)
+	templates.AddCommand(templatesView)
+	templatesSet := model.NewAutocompleteData(commandSet, "name template", "Set the Go text/template of a message posted to channels")
+	templatesSet.AddStaticListArgument("Template name", true, templateNames)
+	templatesSet.AddTextArgument("Template, e.g. [{{.Title}}]({{.URL}}) merged by {{.Author}}", "template", "")
+	templates.AddCommand(templatesSet)
+	templatesReset := model.NewAutocompleteData(commandReset, "name", "Post the default message again")
+	templatesReset.AddStaticListArgument("Template name", true, templateNames)
+	templates.AddCommand(templatesReset)
+	templatesPreview := model.NewAutocompleteData(commandPreview, "name [template]", "Show the message posted for a sample event")
+	templatesPreview.AddStaticListArgument("Template name", true, templateNames)
+	templatesPreview.AddTextArgument("Template to preview instead of the current one", "[template]", "")
+	templates.AddCommand(templatesPreview)
+	gitlab.AddCommand(templates)
+
 	help := model.NewAutocompleteData("help", "", "Display GiLab Plug Help.")
 	gitlab.AddCommand(help)
 
@@ -1205,6 +1847,14 @@ func (p *Plugin) getAutocompleteData(config *configuration) *model.AutocompleteD
 	return gitlab
 }
 
+func getMessageTemplateAutocompleteItems() []model.AutocompleteListItem {
+	items := make([]model.AutocompleteListItem, 0, len(webhook.MessageTemplates))
+	for _, messageTemplate := range webhook.MessageTemplates {
+		items = append(items, model.AutocompleteListItem{Item: messageTemplate.Name, HelpText: messageTemplate.Description.Other})
+	}
+	return items
+}
+
 func (p *Plugin) getDefaultInstanceAutoCompleteData() []model.AutocompleteListItem {
 	return buildInstanceAutocompleteItems(p.getInstanceList(), "Set '%s' as the default instance")
 }
@@ -6,6 +6,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/mattermost/mattermost/server/public/model"
@@ -49,7 +50,7 @@ var subscribeCommandTests = []subscribeCommandTest{
 	{
 		testName:   "No Subcommand",
 		parameters: []string{},
-		want:       invalidSubscribeSubCommand,
+		want:       invalidSubscribeSubCommand.Other,
 	},
 	{
 		testName:      "No Repository permissions",
@@ -106,7 +107,7 @@ var subscribeCommandTests = []subscribeCommandTest{
 	{
 		testName:   "Missing Organization/Repository",
 		parameters: []string{"add"},
-		want:       missingOrgOrRepoFromSubscribeCommand,
+		want:       missingOrgOrRepoFromSubscribeCommand.Other,
 	},
 
 	{
@@ -120,7 +121,7 @@ var subscribeCommandTests = []subscribeCommandTest{
 	{
 		testName:   "Delete Missing Repository",
 		parameters: []string{"delete"},
-		want:       specifyRepositoryMessage,
+		want:       specifyRepositoryMessage.Other,
 	},
 	{
 		testName:   "Error Deleting Subscription",
@@ -130,7 +131,7 @@ var subscribeCommandTests = []subscribeCommandTest{
 	{
 		testName:   "Invalid Subcommand",
 		parameters: []string{"unknown"},
-		want:       invalidSubscribeSubCommand,
+		want:       invalidSubscribeSubCommand.Other,
 	},
 }
 
@@ -371,7 +372,7 @@ func getTestPlugin(t *testing.T, mockCtrl *gomock.Controller, hooks []*gitlab.We
 	api.On("KVGet", "user_id_usertoken").Return([]byte(encryptedToken), nil)
 	api.On("KVGet", "user_id_userinfo").Return(subVal, nil).Once()
 	api.On("KVGet", "user_id_gitlabtoken").Return(jsonInfo, nil).Once()
-	api.On("KVGet", "subscriptions").Return(subVal, nil)
+	api.On("KVGet", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, "subscriptions_") })).Return(subVal, nil)
 
 	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
 	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
@@ -483,7 +484,7 @@ Triggers:
 	{
 		testName:   "Create Group hook with defaults",
 		parameters: []string{"add", "group"},
-		want:       "Webhook Created:\n\n\n`https://example.com`" + allTriggersFormated,
+		want:       "Webhook Created:\n\n\n`https://example.com`" + allTriggersFormated + "\nWebhooks of the projects and subgroups of `group` using the secret from the plugin settings keep working for 24h0m0s. Run `/gitlab webhook rotate` for each of them to give them their own secret.",
 		siteURL:    "https://example.com",
 		scope:      "group",
 		webhook:    exampleWebhookWithAlltriggers,
@@ -498,12 +499,16 @@ func TestAddWebhookCommand(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			mockedClient := mocks.NewMockGitlab(mockCtrl)
 
+			namespace := "group/project"
 			if test.scope == "group" {
+				namespace = "group"
 				mockedClient.EXPECT().NewGroupHook(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(test.webhook, nil)
 				mockedClient.EXPECT().ResolveNamespaceAndProject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), true).Return("group", "", nil)
+				mockedClient.EXPECT().GetGroupHooks(gomock.Any(), gomock.Any(), gomock.Any(), "group").Return(nil, nil)
 			} else {
 				project := &gitLabAPI.Project{ID: 4}
 				mockedClient.EXPECT().ResolveNamespaceAndProject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), true).Return("group", "project", nil)
+				mockedClient.EXPECT().GetProjectHooks(gomock.Any(), gomock.Any(), gomock.Any(), "group", "project").Return(nil, nil)
 				mockedClient.EXPECT().GetProject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(project, nil)
 				mockedClient.EXPECT().NewProjectHook(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(test.webhook, nil)
 			}
@@ -521,6 +526,8 @@ func TestAddWebhookCommand(t *testing.T) {
 			api := &plugintest.API{}
 			api.On("GetConfig", mock.Anything).Return(conf)
 			api.On("KVGet", "_usertoken").Return([]byte(encryptedToken), nil)
+			api.On("KVGet", webhookSecretKey(namespace)).Return(nil, nil)
+			api.On("KVSetWithOptions", webhookSecretKey(namespace), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
 			api.On("LogAuditRec", mock.Anything).Maybe()
 			p.SetAPI(api)
 			p.client = pluginapi.NewClient(api, p.Driver)
@@ -568,8 +575,7 @@ func TestAddWebhookCommandForbidden(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockedClient := mocks.NewMockGitlab(mockCtrl)
 	mockedClient.EXPECT().ResolveNamespaceAndProject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), true).Return("group", "project", nil)
-	mockedClient.EXPECT().GetProject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&gitLabAPI.Project{ID: 4}, nil)
-	mockedClient.EXPECT().NewProjectHook(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, gitlab.ErrForbidden)
+	mockedClient.EXPECT().GetProjectHooks(gomock.Any(), gomock.Any(), gomock.Any(), "group", "project").Return(nil, gitlab.ErrForbidden)
 	p.GitlabClient = mockedClient
 
 	conf := &model.Config{}
@@ -582,6 +588,7 @@ func TestAddWebhookCommandForbidden(t *testing.T) {
 	api := &plugintest.API{}
 	api.On("GetConfig", mock.Anything).Return(conf)
 	api.On("KVGet", "_usertoken").Return([]byte(encryptedToken), nil)
+	api.On("KVGet", webhookSecretKey("group/project")).Return(nil, nil)
 	api.On("LogAuditRec", mock.Anything).Maybe()
 	p.SetAPI(api)
 	p.client = pluginapi.NewClient(api, p.Driver)
@@ -663,6 +670,12 @@ func setupInstanceCommandTest(t *testing.T, instanceList []string, instanceConfi
 	return p, &capturedMessage, api
 }
 
+func TestParseTriggersFeatureFlagEvents(t *testing.T) {
+	assert.True(t, parseTriggers("*").FeatureFlagEvents)
+	assert.True(t, parseTriggers("PushEvents,featureflagevents").FeatureFlagEvents)
+	assert.False(t, parseTriggers("PushEvents").FeatureFlagEvents)
+}
+
 func TestAdminProtectedCommands(t *testing.T) {
 	t.Run("instance commands require admin", func(t *testing.T) {
 		testCases := []struct {
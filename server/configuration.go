@@ -45,6 +45,10 @@ type configuration struct {
 	// reads can fall back to the old key while background re-encryption runs.
 	// It is never persisted to the plugin settings.
 	PreviousEncryptionKey string `json:"-"`
+
+	// ServerLocale is the default language of the Mattermost server, in which messages are posted to channels.
+	// It is set from the server configuration.
+	ServerLocale string `json:"-"`
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -202,6 +206,9 @@ func (p *Plugin) OnConfigurationChange() error {
 	configuration.sanitize()
 
 	serverConfiguration := p.client.Configuration.GetConfig()
+	if serverConfiguration != nil && serverConfiguration.LocalizationSettings.DefaultServerLocale != nil {
+		configuration.ServerLocale = *serverConfiguration.LocalizationSettings.DefaultServerLocale
+	}
 	p.configurationLock.RLock()
 	hadConfig := p.configuration != nil
 	var previousGitlabGroup string
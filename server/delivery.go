@@ -0,0 +1,419 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	deliveryQueueKeyPrefix      = "deliveryqueue_"
+	deliveryDeadLetterKeyPrefix = "deliverydeadletter_"
+	deliveryLeaseKeyPrefix      = "deliverylease_"
+	// deliveryQueueIndexKey holds the IDs of the queued deliveries, so polling the queue doesn't list the whole KV store.
+	deliveryQueueIndexKey = "deliveryqueueindex"
+
+	deliveryWorkerCount    = 4
+	deliveryMaxAttempts    = 8
+	deliveryInitialBackoff = 5 * time.Second
+	deliveryMaxBackoff     = 30 * time.Minute
+	deliveryLeaseDuration  = 2 * time.Minute
+	deliveryPollInterval   = 15 * time.Second
+)
+
+// pendingDelivery is a post produced by a webhook event that still has to be created in Mattermost.
+// Either ChannelID or DMUserID is set; the latter is delivered through the bot's DM channel.
+// Channel posts with a ThreadKey are posted as replies to the thread of their merge request or issue,
+// the ones with an UpdateKey replace the message of the post previously created for the same key, unless a post of
+// a later UpdateRevision already did.
+// When set, the Attachment is posted instead of the Message, which is then only its fallback.
+type pendingDelivery struct {
+	ID             string                   `json:"id"`
+	ChannelID      string                   `json:"channel_id,omitempty"`
+	DMUserID       string                   `json:"dm_user_id,omitempty"`
+	Message        string                   `json:"message"`
+	PostType       string                   `json:"post_type,omitempty"`
+	ThreadKey      string                   `json:"thread_key,omitempty"`
+	UpdateKey      string                   `json:"update_key,omitempty"`
+	UpdateRevision int64                    `json:"update_revision,omitempty"`
+	Attachment     *model.MessageAttachment `json:"attachment,omitempty"`
+	Attempts       int                      `json:"attempts"`
+	LastError      string                   `json:"last_error,omitempty"`
+	CreatedAt      int64                    `json:"created_at"`
+	NextAttemptAt  int64                    `json:"next_attempt_at"`
+}
+
+// deliveryQueue runs the worker pool that drains the persistent delivery queue, and the worker handling
+// the webhook events queued by the HTTP handler, which produce these deliveries.
+// Queue entries live in the KV store so they survive restarts and can be picked up by any node,
+// a short-lived lease key makes sure a single node handles a given entry at a time.
+// Each queue has an index key with the time of the next attempt of its entries, which the polls read to find the due ones.
+type deliveryQueue struct {
+	p      *Plugin
+	work   chan string
+	events chan string
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newDeliveryQueue(p *Plugin) *deliveryQueue {
+	return &deliveryQueue{
+		p:      p,
+		work:   make(chan string, 256),
+		events: make(chan string, 256),
+		done:   make(chan struct{}),
+	}
+}
+
+func (q *deliveryQueue) start() {
+	for range deliveryWorkerCount {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	// A single worker handles the webhook events, so the ones of a pipeline update its post in the order GitLab sent them.
+	q.wg.Add(1)
+	go q.eventWorker()
+
+	q.wg.Add(1)
+	go q.poll()
+}
+
+func (q *deliveryQueue) stop() {
+	close(q.done)
+	q.wg.Wait()
+}
+
+// notify hands an entry to the worker pool without blocking. If the pool is busy,
+// the entry is left for the next poll.
+func (q *deliveryQueue) notify(id string) {
+	select {
+	case q.work <- id:
+	default:
+	}
+}
+
+// notifyEvent hands a webhook event to the event worker without blocking. If it is busy,
+// the event is left for the next poll.
+func (q *deliveryQueue) notifyEvent(id string) {
+	select {
+	case q.events <- id:
+	default:
+	}
+}
+
+func (q *deliveryQueue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.done:
+			return
+		case id := <-q.work:
+			q.p.processDelivery(id)
+		}
+	}
+}
+
+func (q *deliveryQueue) eventWorker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.done:
+			return
+		case id := <-q.events:
+			q.p.processWebhookEvent(id)
+		}
+	}
+}
+
+func (q *deliveryQueue) poll() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+			if !q.requeue(webhookEventQueueIndexKey, "webhook events", q.events) {
+				return
+			}
+			if !q.requeue(deliveryQueueIndexKey, "deliveries", q.work) {
+				return
+			}
+		}
+	}
+}
+
+// requeue hands the due entries of the queue indexed under indexKey to its workers. It returns false once the queue is stopped.
+func (q *deliveryQueue) requeue(indexKey, name string, work chan<- string) bool {
+	ids, err := q.p.getDueQueueIDs(indexKey, model.GetMillis())
+	if err != nil {
+		q.p.client.Log.Warn("can't list pending "+name, "err", err.Error())
+		return true
+	}
+	for _, id := range ids {
+		select {
+		case <-q.done:
+			return false
+		case work <- id:
+		}
+	}
+	return true
+}
+
+// deliveryBackoff returns how long to wait before the next attempt, doubling from
+// deliveryInitialBackoff for each failed attempt up to deliveryMaxBackoff.
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := deliveryInitialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= deliveryMaxBackoff {
+			return deliveryMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// enqueueDelivery persists a post to the delivery queue and wakes up a worker.
+// If the queue can't be written to, the post is delivered inline as a last resort.
+func (p *Plugin) enqueueDelivery(d *pendingDelivery) {
+	now := model.GetMillis()
+	d.ID = model.NewId()
+	d.CreatedAt = now
+	d.NextAttemptAt = now
+
+	if err := p.storeQueueEntry(deliveryQueueKeyPrefix, deliveryQueueIndexKey, d.ID, d, d.NextAttemptAt); err != nil {
+		p.client.Log.Warn("can't enqueue webhook post, delivering it directly", "err", err.Error())
+		if err := p.deliver(d); err != nil {
+			p.client.Log.Warn("can't create post for webhook event", "err", err.Error())
+		}
+		return
+	}
+
+	if p.deliveryQueue != nil {
+		p.deliveryQueue.notify(d.ID)
+	}
+}
+
+func (p *Plugin) deliver(d *pendingDelivery) error {
+	if d.DMUserID != "" {
+		return p.CreateBotDMPost(d.DMUserID, d.Message, d.PostType)
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		Message:   d.Message,
+		ChannelId: d.ChannelID,
+		Type:      d.PostType,
+	}
+	if d.Attachment != nil {
+		post.Message = ""
+		model.ParseMessageAttachment(post, []*model.MessageAttachment{d.Attachment})
+	}
+	if d.UpdateKey != "" {
+		return p.createOrUpdatePost(post, d.UpdateKey, d.ThreadKey, d.UpdateRevision)
+	}
+	if d.ThreadKey != "" {
+		return p.createThreadedPost(post, d.ThreadKey)
+	}
+	return p.client.Post.CreatePost(post)
+}
+
+// processDelivery makes one delivery attempt for a queued entry, rescheduling it with
+// exponential backoff on failure and moving it to the dead-letter list once deliveryMaxAttempts is reached.
+func (p *Plugin) processDelivery(id string) {
+	leaseKey := deliveryLeaseKeyPrefix + id
+	acquired, err := p.client.KV.Set(leaseKey, []byte{1}, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(deliveryLeaseDuration))
+	if err != nil || !acquired {
+		return
+	}
+	defer func() {
+		if err := p.client.KV.Delete(leaseKey); err != nil {
+			p.client.Log.Warn("can't release delivery lease", "id", id, "err", err.Error())
+		}
+	}()
+
+	key := deliveryQueueKeyPrefix + id
+	var d *pendingDelivery
+	if err := p.client.KV.Get(key, &d); err != nil {
+		p.client.Log.Warn("can't load pending delivery", "id", id, "err", err.Error())
+		return
+	}
+	if d == nil {
+		p.removeFromQueueIndex(deliveryQueueIndexKey, id)
+		return
+	}
+	if d.NextAttemptAt > model.GetMillis() {
+		return
+	}
+
+	deliverErr := p.deliver(d)
+	if deliverErr == nil {
+		if err := p.client.KV.Delete(key); err != nil {
+			p.client.Log.Warn("can't remove delivered post from queue", "id", id, "err", err.Error())
+			return
+		}
+		p.removeFromQueueIndex(deliveryQueueIndexKey, id)
+		return
+	}
+
+	d.Attempts++
+	d.LastError = deliverErr.Error()
+	if d.Attempts >= deliveryMaxAttempts {
+		p.client.Log.Warn("giving up on webhook post, moving it to the dead-letter list", "id", id, "attempts", d.Attempts, "err", d.LastError)
+		if _, err := p.client.KV.Set(deliveryDeadLetterKeyPrefix+id, d); err != nil {
+			p.client.Log.Warn("can't store dead-lettered post", "id", id, "err", err.Error())
+			return
+		}
+		if err := p.client.KV.Delete(key); err != nil {
+			p.client.Log.Warn("can't remove dead-lettered post from queue", "id", id, "err", err.Error())
+			return
+		}
+		p.removeFromQueueIndex(deliveryQueueIndexKey, id)
+		return
+	}
+
+	d.NextAttemptAt = model.GetMillis() + deliveryBackoff(d.Attempts).Milliseconds()
+	p.client.Log.Debug("webhook post delivery failed, will retry", "id", id, "attempts", d.Attempts, "err", d.LastError)
+	if _, err := p.client.KV.Set(key, d); err != nil {
+		p.client.Log.Warn("can't reschedule pending delivery", "id", id, "err", err.Error())
+		return
+	}
+	// The entry stays due in the index if this fails, which only makes the next polls try it early.
+	if err := p.updateQueueIndex(deliveryQueueIndexKey, func(index map[string]int64) { index[id] = d.NextAttemptAt }); err != nil {
+		p.client.Log.Warn("can't reschedule pending delivery in the queue index", "id", id, "err", err.Error())
+	}
+}
+
+// storeQueueEntry stores the entry of a queue under prefix and adds it to the index of the queue, with the time of its
+// next attempt in milliseconds. An entry missing from the index would never be polled, so it is removed if it can't be added.
+func (p *Plugin) storeQueueEntry(prefix, indexKey, id string, entry any, nextAttemptAt int64) error {
+	if _, err := p.client.KV.Set(prefix+id, entry); err != nil {
+		return errors.Wrap(err, "failed to store queue entry")
+	}
+	if err := p.updateQueueIndex(indexKey, func(index map[string]int64) { index[id] = nextAttemptAt }); err != nil {
+		if deleteErr := p.client.KV.Delete(prefix + id); deleteErr != nil {
+			p.client.Log.Warn("can't remove unindexed queue entry", "key", prefix+id, "err", deleteErr.Error())
+		}
+		return errors.Wrap(err, "failed to index queue entry")
+	}
+	return nil
+}
+
+// updateQueueIndex applies the change to the index stored under indexKey, which maps the IDs of the entries of a queue
+// to the time of their next attempt in milliseconds.
+func (p *Plugin) updateQueueIndex(indexKey string, change func(index map[string]int64)) error {
+	p.queueIndexLock.Lock()
+	defer p.queueIndexLock.Unlock()
+	return p.client.KV.SetAtomicWithRetries(indexKey, func(oldValue []byte) (any, error) {
+		index := map[string]int64{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &index); err != nil {
+				return nil, err
+			}
+		}
+		change(index)
+		return index, nil
+	})
+}
+
+// removeFromQueueIndex removes a handled entry from the index of its queue. An entry left in the index is removed
+// by the next poll, which finds it gone.
+func (p *Plugin) removeFromQueueIndex(indexKey, id string) {
+	if err := p.updateQueueIndex(indexKey, func(index map[string]int64) { delete(index, id) }); err != nil {
+		p.client.Log.Warn("can't remove entry from the queue index", "key", indexKey, "id", id, "err", err.Error())
+	}
+}
+
+// getDueQueueIDs returns the IDs of the entries of the queue indexed under indexKey whose next attempt is due at now,
+// in the order of these attempts.
+func (p *Plugin) getDueQueueIDs(indexKey string, now int64) ([]string, error) {
+	var index map[string]int64
+	if err := p.client.KV.Get(indexKey, &index); err != nil {
+		return nil, errors.Wrap(err, "failed to load queue index")
+	}
+	ids := make([]string, 0, len(index))
+	for id, nextAttemptAt := range index {
+		if nextAttemptAt <= now {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(index[a], index[b]) })
+	return ids, nil
+}
+
+func (p *Plugin) listDeliveryIDs(prefix string) ([]string, error) {
+	keys, err := listKeysWithPrefix(p.client, prefix, keysPerPage)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key[len(prefix):])
+	}
+	return ids, nil
+}
+
+// getDeadLetters returns every post that exhausted its delivery attempts.
+func (p *Plugin) getDeadLetters() ([]*pendingDelivery, error) {
+	ids, err := p.listDeliveryIDs(deliveryDeadLetterKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*pendingDelivery, 0, len(ids))
+	for _, id := range ids {
+		var d *pendingDelivery
+		if err := p.client.KV.Get(deliveryDeadLetterKeyPrefix+id, &d); err != nil {
+			return nil, errors.Wrapf(err, "failed to load dead-lettered post %s", id)
+		}
+		if d != nil {
+			deadLetters = append(deadLetters, d)
+		}
+	}
+	return deadLetters, nil
+}
+
+// replayDeadLetter moves a dead-lettered post back to the delivery queue with a fresh attempt budget.
+func (p *Plugin) replayDeadLetter(id string) error {
+	key := deliveryDeadLetterKeyPrefix + id
+	var d *pendingDelivery
+	if err := p.client.KV.Get(key, &d); err != nil {
+		return errors.Wrap(err, "failed to load dead-lettered post")
+	}
+	if d == nil {
+		return errors.Errorf("dead-lettered post %s not found", id)
+	}
+
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptAt = model.GetMillis()
+	if err := p.storeQueueEntry(deliveryQueueKeyPrefix, deliveryQueueIndexKey, id, d, d.NextAttemptAt); err != nil {
+		return errors.Wrap(err, "failed to requeue dead-lettered post")
+	}
+	if err := p.client.KV.Delete(key); err != nil {
+		return errors.Wrap(err, "failed to remove dead-lettered post")
+	}
+
+	if p.deliveryQueue != nil {
+		p.deliveryQueue.notify(id)
+	}
+	return nil
+}
+
+func (p *Plugin) discardDeadLetter(id string) error {
+	if err := p.client.KV.Delete(deliveryDeadLetterKeyPrefix + id); err != nil {
+		return errors.Wrap(err, "failed to discard dead-lettered post")
+	}
+	return nil
+}
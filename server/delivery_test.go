@@ -0,0 +1,229 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testDeliveryID = "deliveryid"
+
+func makeDeliveryPlugin(t *testing.T, api *plugintest.API, d *pendingDelivery) *Plugin {
+	t.Helper()
+	p := &Plugin{configuration: &configuration{}, BotUserID: "bot-user-id"}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(api, p.Driver)
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	api.On("KVSetWithOptions", deliveryLeaseKeyPrefix+testDeliveryID, []byte{1}, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+	api.On("KVSetWithOptions", deliveryLeaseKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	api.On("KVGet", deliveryQueueKeyPrefix+testDeliveryID).Return(data, nil).Once()
+	return p
+}
+
+// expectQueueIndexUpdate expects the index stored under indexKey, which holds the entry of testDeliveryID, to be updated
+// once to an index passing check.
+func expectQueueIndexUpdate(t *testing.T, api *plugintest.API, indexKey string, check func(index map[string]int64) bool) {
+	t.Helper()
+	oldIndex, err := json.Marshal(map[string]int64{testDeliveryID: 0, "otherid": 0})
+	require.NoError(t, err)
+	api.On("KVGet", indexKey).Return(oldIndex, nil).Once()
+	api.On("KVSetWithOptions", indexKey, mock.MatchedBy(func(value []byte) bool {
+		var index map[string]int64
+		return json.Unmarshal(value, &index) == nil && check(index)
+	}), mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+}
+
+// allowQueueIndexUpdates lets the queue index stored under indexKey be updated any number of times.
+func allowQueueIndexUpdates(api *plugintest.API, indexKey string) {
+	api.On("KVGet", indexKey).Return(nil, nil).Maybe()
+	api.On("KVSetWithOptions", indexKey, mock.Anything, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Maybe()
+}
+
+// isRemovedFromIndex tells whether the index only lost the entry of testDeliveryID.
+func isRemovedFromIndex(index map[string]int64) bool {
+	_, ok := index[testDeliveryID]
+	return !ok && len(index) == 1
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	assert.Equal(t, deliveryInitialBackoff, deliveryBackoff(1))
+	assert.Equal(t, 2*deliveryInitialBackoff, deliveryBackoff(2))
+	assert.Equal(t, 8*deliveryInitialBackoff, deliveryBackoff(4))
+	assert.Equal(t, deliveryMaxBackoff, deliveryBackoff(100))
+}
+
+func TestProcessDelivery(t *testing.T) {
+	t.Run("delivered post is removed from the queue", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "hello"})
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel-id" && post.Message == "hello" && post.UserId == "bot-user-id"
+		})).Return(&model.Post{}, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("attachment is posted instead of the message", func(t *testing.T) {
+		api := &plugintest.API{}
+		attachment := &model.MessageAttachment{Fallback: "hello", Title: "manland/webhook!4 Master", Text: "Merged"}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "hello", Attachment: attachment})
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			attachments := post.Attachments()
+			return post.Message == "" && post.Type == model.PostTypeMessageAttachment && len(attachments) == 1 && attachments[0].Title == attachment.Title
+		})).Return(&model.Post{}, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("failed post is rescheduled with backoff", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "hello", Attempts: 1})
+		api.On("CreatePost", mock.Anything).Return(nil, &model.AppError{Message: "db down"}).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, mock.MatchedBy(func(value []byte) bool {
+			var d pendingDelivery
+			if json.Unmarshal(value, &d) != nil {
+				return false
+			}
+			return d.Attempts == 2 && d.LastError != "" && d.NextAttemptAt >= model.GetMillis()+deliveryBackoff(1).Milliseconds()
+		}), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, func(index map[string]int64) bool {
+			return index[testDeliveryID] >= model.GetMillis()+deliveryBackoff(1).Milliseconds() && len(index) == 2
+		})
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("post is dead-lettered after the last attempt", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "hello", Attempts: deliveryMaxAttempts - 1})
+		api.On("CreatePost", mock.Anything).Return(nil, &model.AppError{Message: "db down"}).Once()
+		api.On("KVSetWithOptions", deliveryDeadLetterKeyPrefix+testDeliveryID, isNonNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("entry leased by another node is skipped", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVSetWithOptions", deliveryLeaseKeyPrefix+testDeliveryID, []byte{1}, mock.AnythingOfType("model.PluginKVSetOptions")).Return(false, nil).Once()
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+	})
+
+	t.Run("entry already delivered is removed from the index", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVSetWithOptions", deliveryLeaseKeyPrefix+testDeliveryID, []byte{1}, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryLeaseKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("KVGet", deliveryQueueKeyPrefix+testDeliveryID).Return(nil, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("entry not yet due is left alone", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", NextAttemptAt: model.GetMillis() + time.Hour.Milliseconds()})
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestGetDueQueueIDs(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{configuration: &configuration{}}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(api, p.Driver)
+	index, err := json.Marshal(map[string]int64{"later": 3000, "second": 2000, "first": 1000, "due": 2500})
+	require.NoError(t, err)
+	api.On("KVGet", deliveryQueueIndexKey).Return(index, nil).Once()
+
+	ids, err := p.getDueQueueIDs(deliveryQueueIndexKey, 2500)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "due"}, ids)
+	api.AssertExpectations(t)
+	// Polling reads the index rather than listing the keys of the KV store.
+	api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+}
+
+func TestEnqueueDelivery(t *testing.T) {
+	t.Run("post is stored and indexed", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		var index map[string]int64
+		api.On("KVSetWithOptions", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, deliveryQueueKeyPrefix) }), isNonNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("KVGet", deliveryQueueIndexKey).Return(nil, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueIndexKey, mock.MatchedBy(func(value []byte) bool {
+			index = nil
+			return json.Unmarshal(value, &index) == nil
+		}), mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+
+		d := &pendingDelivery{ChannelID: "channel-id", Message: "hello"}
+		p.enqueueDelivery(d)
+
+		api.AssertExpectations(t)
+		assert.Equal(t, map[string]int64{d.ID: d.NextAttemptAt}, index)
+	})
+
+	t.Run("post that can't be indexed is delivered directly", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}, BotUserID: "bot-user-id"}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVSetWithOptions", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, deliveryQueueKeyPrefix) }), isNonNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("KVGet", deliveryQueueIndexKey).Return(nil, &model.AppError{Message: "db down"}).Once()
+		api.On("KVSetWithOptions", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, deliveryQueueKeyPrefix) }), isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == "hello" })).Return(&model.Post{}, nil).Once()
+
+		p.enqueueDelivery(&pendingDelivery{ChannelID: "channel-id", Message: "hello"})
+
+		api.AssertExpectations(t)
+	})
+}
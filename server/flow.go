@@ -31,6 +31,7 @@ type FlowManager struct {
 	getGitlabClient                 func() gitlab.Gitlab
 	useGitlabClient                 func(info *gitlab.UserInfo, toRun func(info *gitlab.UserInfo, token *oauth2.Token) error) error
 	createHook                      func(ctx context.Context, gitlabClient gitlab.Gitlab, info *gitlab.UserInfo, group, project string, hookOptions *gitlab.AddWebhookOptions) (*gitlab.WebhookInfo, error)
+	webhookSecretForNewHook         func(ctx context.Context, info *gitlab.UserInfo, group, project, token string) (string, string, error)
 	saveInstanceDetails             func(instanceName string, config *InstanceConfiguration) error
 	setDefaultInstance              func(instanceName string) error
 	isAuthorizedSysAdmin            func(userID string) (bool, error)
@@ -52,6 +53,7 @@ func (p *Plugin) NewFlowManager() (*FlowManager, error) {
 		getGitlabClient:                 p.getGitlabClient,
 		useGitlabClient:                 p.useGitlabClient,
 		createHook:                      p.createHook,
+		webhookSecretForNewHook:         p.webhookSecretForNewHook,
 		saveInstanceDetails:             p.installInstance,
 		setDefaultInstance:              p.setDefaultInstance,
 		isAuthorizedSysAdmin:            p.isAuthorizedSysAdmin,
@@ -659,8 +661,10 @@ func (fm *FlowManager) submitWebhook(f *flow.Flow, submitted map[string]any) (fl
 		WikiPageEvents:           true,
 		DeploymentEvents:         true,
 		ReleaseEvents:            true,
+		FeatureFlagEvents:        true,
+		MemberEvents:             true,
+		EmojiEvents:              true,
 		EnableSSLVerification:    true,
-		Token:                    config.WebhookSecret,
 	}
 
 	var fullName string
@@ -674,6 +678,17 @@ func (fm *FlowManager) submitWebhook(f *flow.Flow, submitted map[string]any) (fl
 		repoOrGroup = "repository"
 	}
 
+	secret, _, err := fm.webhookSecretForNewHook(ctx, info, group, project, "")
+	if err != nil {
+		if errors.Is(err, gitlab.ErrForbidden) {
+			err = errors.Errorf("It seems like you don't have privileges to create webhooks in %s. Ask an admin of that %s to run /gitlab setup webhook for you.", fullName, repoOrGroup)
+			return "", nil, nil, err
+		}
+
+		return "", nil, nil, errors.Wrap(err, "failed to store webhook secret")
+	}
+	hookOptions.Token = secret
+
 	_, err = fm.createHook(ctx, gitlabClient, info, group, project, hookOptions)
 	if err != nil {
 		if errors.Is(err, gitlab.ErrNotFound) {
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -21,6 +22,7 @@ import (
 
 const (
 	stateOpened   = "opened"
+	stateClosed   = "closed"
 	scopeAll      = "all"
 	getLabelsTrue = true
 
@@ -85,7 +87,7 @@ func (g *gitlab) NewGroupHook(ctx context.Context, user *UserInfo, token *oauth2
 		return nil, err
 	}
 
-	groupHookOptions := internGitlab.AddGroupHookOptions{
+	groupHookOptions := addGroupHookOptions{AddGroupHookOptions: internGitlab.AddGroupHookOptions{
 		URL:                      &webhookOptions.URL,
 		ConfidentialNoteEvents:   &webhookOptions.ConfidentialNoteEvents,
 		PushEvents:               &webhookOptions.PushEvents,
@@ -101,9 +103,15 @@ func (g *gitlab) NewGroupHook(ctx context.Context, user *UserInfo, token *oauth2
 		ReleasesEvents:           &webhookOptions.ReleaseEvents,
 		EnableSSLVerification:    &webhookOptions.EnableSSLVerification,
 		Token:                    &webhookOptions.Token,
+	}}
+	if webhookOptions.MemberEvents {
+		groupHookOptions.MemberEvents = &webhookOptions.MemberEvents
+	}
+	if webhookOptions.EmojiEvents {
+		groupHookOptions.EmojiEvents = &webhookOptions.EmojiEvents
 	}
 
-	groupHook, resp, err := client.Groups.AddGroupHook(group.ID, &groupHookOptions, internGitlab.WithContext(ctx))
+	groupHook, resp, err := addGroupHook(client, group.ID, &groupHookOptions, internGitlab.WithContext(ctx))
 	if err != nil {
 		if resp.StatusCode == http.StatusForbidden {
 			return nil, ErrForbidden
@@ -124,7 +132,7 @@ func (g *gitlab) NewProjectHook(ctx context.Context, user *UserInfo, token *oaut
 		return nil, err
 	}
 
-	projectHookOptions := internGitlab.AddProjectHookOptions{
+	projectHookOptions := addProjectHookOptions{AddProjectHookOptions: internGitlab.AddProjectHookOptions{
 		URL:                      &webhookOptions.URL,
 		ConfidentialNoteEvents:   &webhookOptions.ConfidentialNoteEvents,
 		PushEvents:               &webhookOptions.PushEvents,
@@ -140,9 +148,15 @@ func (g *gitlab) NewProjectHook(ctx context.Context, user *UserInfo, token *oaut
 		ReleasesEvents:           &webhookOptions.ReleaseEvents,
 		EnableSSLVerification:    &webhookOptions.EnableSSLVerification,
 		Token:                    &webhookOptions.Token,
+	}}
+	if webhookOptions.FeatureFlagEvents {
+		projectHookOptions.FeatureFlagEvents = &webhookOptions.FeatureFlagEvents
+	}
+	if webhookOptions.EmojiEvents {
+		projectHookOptions.EmojiEvents = &webhookOptions.EmojiEvents
 	}
 
-	projectHook, resp, err := client.Projects.AddProjectHook(projectID, &projectHookOptions, internGitlab.WithContext(ctx))
+	projectHook, resp, err := addProjectHook(client, projectID, &projectHookOptions, internGitlab.WithContext(ctx))
 	if respErr := checkResponse(resp); respErr != nil {
 		return nil, respErr
 	}
@@ -155,6 +169,62 @@ func (g *gitlab) NewProjectHook(ctx context.Context, user *UserInfo, token *oaut
 	return projectHookInfo, nil
 }
 
+// addProjectHookOptions adds the options of project hooks the GitLab client library doesn't know about.
+type addProjectHookOptions struct {
+	internGitlab.AddProjectHookOptions
+	FeatureFlagEvents *bool `url:"feature_flag_events,omitempty" json:"feature_flag_events,omitempty"`
+	EmojiEvents       *bool `url:"emoji_events,omitempty" json:"emoji_events,omitempty"`
+}
+
+// addProjectHook is client.Projects.AddProjectHook accepting the additional project hook options.
+func addProjectHook(client *internGitlab.Client, projectID any, opt *addProjectHookOptions, options ...internGitlab.RequestOptionFunc) (*internGitlab.ProjectHook, *internGitlab.Response, error) {
+	var project string
+	switch id := projectID.(type) {
+	case int:
+		project = strconv.Itoa(id)
+	case string:
+		project = id
+	default:
+		return nil, nil, fmt.Errorf("invalid ID type %#v, the ID must be an int or a string", projectID)
+	}
+
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("projects/%s/hooks", internGitlab.PathEscape(project)), opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projectHook := new(internGitlab.ProjectHook)
+	resp, err := client.Do(req, projectHook)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return projectHook, resp, nil
+}
+
+// addGroupHookOptions adds the options of group hooks the GitLab client library doesn't know about.
+type addGroupHookOptions struct {
+	internGitlab.AddGroupHookOptions
+	MemberEvents *bool `url:"member_events,omitempty" json:"member_events,omitempty"`
+	EmojiEvents  *bool `url:"emoji_events,omitempty" json:"emoji_events,omitempty"`
+}
+
+// addGroupHook is client.Groups.AddGroupHook accepting the additional group hook options.
+func addGroupHook(client *internGitlab.Client, groupID int, opt *addGroupHookOptions, options ...internGitlab.RequestOptionFunc) (*internGitlab.GroupHook, *internGitlab.Response, error) {
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("groups/%d/hooks", groupID), opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groupHook := new(internGitlab.GroupHook)
+	resp, err := client.Do(req, groupHook)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return groupHook, resp, nil
+}
+
 // GetGroupHooks gathers all the group level hooks for a GitLab group.
 func (g *gitlab) GetGroupHooks(ctx context.Context, user *UserInfo, token *oauth2.Token, owner string) ([]*WebhookInfo, error) {
 	client, err := g.GitlabConnect(*token)
@@ -309,6 +379,42 @@ func (g *gitlab) GetProjectHooks(ctx context.Context, user *UserInfo, token *oau
 	return webhooks, nil
 }
 
+// SetProjectHookToken replaces the secret token GitLab sends with every request of a project hook.
+func (g *gitlab) SetProjectHookToken(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, hook *WebhookInfo, secret string) error {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return err
+	}
+
+	opt := &internGitlab.EditProjectHookOptions{
+		URL:   &hook.URL,
+		Token: &secret,
+	}
+	_, resp, err := client.Projects.EditProjectHook(fmt.Sprintf("%s/%s", owner, repo), hook.ID, opt, internGitlab.WithContext(ctx))
+	if respErr := checkResponse(resp); respErr != nil {
+		return respErr
+	}
+	return err
+}
+
+// SetGroupHookToken replaces the secret token GitLab sends with every request of a group hook.
+func (g *gitlab) SetGroupHookToken(ctx context.Context, user *UserInfo, token *oauth2.Token, owner string, hook *WebhookInfo, secret string) error {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return err
+	}
+
+	opt := &internGitlab.EditGroupHookOptions{
+		URL:   &hook.URL,
+		Token: &secret,
+	}
+	_, resp, err := client.Groups.EditGroupHook(owner, hook.ID, opt, internGitlab.WithContext(ctx))
+	if respErr := checkResponse(resp); respErr != nil {
+		return respErr
+	}
+	return err
+}
+
 func (g *gitlab) GetProject(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string) (*internGitlab.Project, error) {
 	client, err := g.GitlabConnect(*token)
 	if err != nil {
@@ -905,6 +1011,52 @@ func (g *gitlab) GetMilestones(ctx context.Context, user *UserInfo, projectID st
 	return all, nil
 }
 
+func (g *gitlab) GetMilestoneIssueCounts(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo, milestoneTitle string) (int, int, error) {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = g.checkGroup(strings.TrimSuffix(fmt.Sprintf("%s/%s", owner, repo), "/")); err != nil {
+		return 0, 0, err
+	}
+
+	countIssues := func(state string) (int, error) {
+		var resp *internGitlab.Response
+		var err error
+		if repo == "" {
+			_, resp, err = client.Issues.ListGroupIssues(owner, &internGitlab.ListGroupIssuesOptions{
+				ListOptions: internGitlab.ListOptions{PerPage: 1},
+				State:       &state,
+				Milestone:   &milestoneTitle,
+			}, internGitlab.WithContext(ctx))
+		} else {
+			_, resp, err = client.Issues.ListProjectIssues(fmt.Sprintf("%s/%s", owner, repo), &internGitlab.ListProjectIssuesOptions{
+				ListOptions: internGitlab.ListOptions{PerPage: 1},
+				State:       &state,
+				Milestone:   &milestoneTitle,
+			}, internGitlab.WithContext(ctx))
+		}
+		if respErr := checkResponse(resp); respErr != nil {
+			return 0, respErr
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "can't list milestone issues in GitLab api")
+		}
+		return resp.TotalItems, nil
+	}
+
+	opened, err := countIssues(stateOpened)
+	if err != nil {
+		return 0, 0, err
+	}
+	closed, err := countIssues(stateClosed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return opened, closed, nil
+}
+
 // ensureProjectInAllowedGroup fetches the project by ID or path and returns an error if it is not in the allowed GitLab group.
 // projectID may be an int (numeric ID) or string (ID or "namespace/project" path).
 func (g *gitlab) ensureProjectInAllowedGroup(ctx context.Context, client *internGitlab.Client, projectID any) error {
@@ -1195,6 +1347,85 @@ func (g *gitlab) GetMergeRequestByID(ctx context.Context, user *UserInfo, owner,
 	return gitlabMergeRequest, nil
 }
 
+func (g *gitlab) GetMergeRequestChangedPaths(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, mergeRequestIID int) ([]string, error) {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return nil, err
+	}
+	projectPath := fmt.Sprintf("%s/%s", owner, repo)
+	if err = g.checkGroup(projectPath); err != nil {
+		return nil, err
+	}
+
+	opt := &internGitlab.ListMergeRequestDiffsOptions{
+		ListOptions: internGitlab.ListOptions{Page: 1, PerPage: perPage},
+	}
+
+	var paths []string
+	for {
+		diffs, resp, err := client.MergeRequests.ListMergeRequestDiffs(projectPath, mergeRequestIID, opt, internGitlab.WithContext(ctx))
+		if respErr := checkResponse(resp); respErr != nil {
+			return nil, respErr
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "can't list merge request diffs in GitLab api")
+		}
+		for _, diff := range diffs {
+			paths = append(paths, diff.NewPath)
+			if diff.OldPath != diff.NewPath {
+				paths = append(paths, diff.OldPath)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return paths, nil
+}
+
+func (g *gitlab) GetPreviousDeploymentSHA(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo, environment string, deploymentID int) (string, error) {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return "", err
+	}
+	projectPath := fmt.Sprintf("%s/%s", owner, repo)
+	if err = g.checkGroup(projectPath); err != nil {
+		return "", err
+	}
+
+	orderBy, sort, status := "id", "desc", "success"
+	opt := &internGitlab.ListProjectDeploymentsOptions{
+		ListOptions: internGitlab.ListOptions{Page: 1, PerPage: perPage},
+		OrderBy:     &orderBy,
+		Sort:        &sort,
+		Environment: &environment,
+		Status:      &status,
+	}
+
+	for {
+		deployments, resp, err := client.Deployments.ListProjectDeployments(projectPath, opt, internGitlab.WithContext(ctx))
+		if respErr := checkResponse(resp); respErr != nil {
+			return "", respErr
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "can't list deployments in GitLab api")
+		}
+		for _, deployment := range deployments {
+			if deployment.ID < deploymentID {
+				return deployment.SHA, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return "", nil
+}
+
 // TriggerProjectPipeline runs a pipeline in a specific project.
 // The project must be in the allowed GitLab group (group lock); otherwise an error is returned.
 func (g *gitlab) TriggerProjectPipeline(userInfo *UserInfo, token *oauth2.Token, projectID string, ref string) (*PipelineInfo, error) {
@@ -1224,3 +1455,59 @@ func (g *gitlab) TriggerProjectPipeline(userInfo *UserInfo, token *oauth2.Token,
 		User:       pipeline.User.Name,
 	}, nil
 }
+
+func (g *gitlab) GetMilestoneTitle(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, milestoneID int) (string, error) {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return "", err
+	}
+	projectPath := fmt.Sprintf("%s/%s", owner, repo)
+	if err = g.checkGroup(projectPath); err != nil {
+		return "", err
+	}
+
+	milestone, resp, err := client.Milestones.GetMilestone(projectPath, milestoneID, internGitlab.WithContext(ctx))
+	if err == nil {
+		return milestone.Title, nil
+	}
+	if respErr := checkResponse(resp); !errors.Is(respErr, ErrNotFound) {
+		if respErr != nil {
+			return "", respErr
+		}
+		return "", errors.Wrap(err, "can't get milestone in GitLab api")
+	}
+
+	// The milestone of a merge request or issue can belong to the group of the project.
+	groupMilestone, resp, err := client.GroupMilestones.GetGroupMilestone(owner, milestoneID, internGitlab.WithContext(ctx))
+	if respErr := checkResponse(resp); respErr != nil {
+		return "", respErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "can't get group milestone in GitLab api")
+	}
+	return groupMilestone.Title, nil
+}
+
+func (g *gitlab) GetCommitAuthorUsername(ctx context.Context, user *UserInfo, token *oauth2.Token, authorEmail string) (string, error) {
+	client, err := g.GitlabConnect(*token)
+	if err != nil {
+		return "", err
+	}
+
+	// GitLab only searches the emails users made public on their profile, which it verified, unless the token is an admin's.
+	users, resp, err := client.Users.ListUsers(&internGitlab.ListUsersOptions{
+		Search: &authorEmail,
+	}, internGitlab.WithContext(ctx))
+	if respErr := checkResponse(resp); respErr != nil {
+		return "", respErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "can't search users in GitLab api")
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.PublicEmail, authorEmail) || strings.EqualFold(u.Email, authorEmail) {
+			return u.Username, nil
+		}
+	}
+	return "", nil
+}
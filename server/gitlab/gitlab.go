@@ -35,8 +35,20 @@ type Gitlab interface {
 	GetLabels(ctx context.Context, user *UserInfo, projectID string, token *oauth2.Token) ([]*internGitlab.Label, error)
 	GetProjectMembers(ctx context.Context, user *UserInfo, projectID string, token *oauth2.Token) ([]*internGitlab.ProjectMember, error)
 	GetMilestones(ctx context.Context, user *UserInfo, projectID string, token *oauth2.Token) ([]*internGitlab.Milestone, error)
+	// GetMilestoneIssueCounts returns the number of open and closed issues of a milestone of a project, or of a group when repo is empty.
+	GetMilestoneIssueCounts(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo, milestoneTitle string) (opened int, closed int, err error)
 	GetIssueByID(ctx context.Context, user *UserInfo, owner, repo string, issueID int, token *oauth2.Token) (*Issue, error)
 	GetMergeRequestByID(ctx context.Context, user *UserInfo, owner, repo string, mergeRequestID int, token *oauth2.Token) (*MergeRequest, error)
+	// GetMergeRequestChangedPaths returns the paths of the files added, modified, renamed or removed by a merge request.
+	// A renamed file is listed with both its old and new paths.
+	GetMergeRequestChangedPaths(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, mergeRequestIID int) ([]string, error)
+	// GetPreviousDeploymentSHA returns the commit of the last successful deployment to the environment before the given one,
+	// or an empty string if there is none.
+	GetPreviousDeploymentSHA(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo, environment string, deploymentID int) (string, error)
+	// GetMilestoneTitle returns the title of a milestone of the project, or of its group.
+	GetMilestoneTitle(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, milestoneID int) (string, error)
+	// GetCommitAuthorUsername returns the username of the GitLab user showing the author email of a commit, or an empty string if there is none.
+	GetCommitAuthorUsername(ctx context.Context, user *UserInfo, token *oauth2.Token, authorEmail string) (string, error)
 	GetUserDetails(ctx context.Context, user *UserInfo, token *oauth2.Token) (*internGitlab.User, error)
 	GetProject(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string) (*internGitlab.Project, error)
 	GetGroup(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string) (*internGitlab.Group, error)
@@ -50,6 +62,8 @@ type Gitlab interface {
 	GetGroupHooks(ctx context.Context, user *UserInfo, token *oauth2.Token, owner string) ([]*WebhookInfo, error)
 	NewProjectHook(ctx context.Context, user *UserInfo, token *oauth2.Token, projectID any, projectHookOptions *AddWebhookOptions) (*WebhookInfo, error)
 	NewGroupHook(ctx context.Context, user *UserInfo, token *oauth2.Token, groupName string, groupHookOptions *AddWebhookOptions) (*WebhookInfo, error)
+	SetProjectHookToken(ctx context.Context, user *UserInfo, token *oauth2.Token, owner, repo string, hook *WebhookInfo, secret string) error
+	SetGroupHookToken(ctx context.Context, user *UserInfo, token *oauth2.Token, owner string, hook *WebhookInfo, secret string) error
 	TriggerProjectPipeline(userInfo *UserInfo, token *oauth2.Token, projectID string, ref string) (*PipelineInfo, error)
 	// ResolveNamespaceAndProject accepts full path to User, Group or namespaced Project and returns corresponding
 	// namespace and project name.
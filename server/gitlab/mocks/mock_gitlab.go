@@ -53,7 +53,7 @@ func (m *MockGitlab) AttachCommentToIssue(arg0 context.Context, arg1 *gitlab.Use
 }
 
 // AttachCommentToIssue indicates an expected call of AttachCommentToIssue.
-func (mr *MockGitlabMockRecorder) AttachCommentToIssue(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) AttachCommentToIssue(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachCommentToIssue", reflect.TypeOf((*MockGitlab)(nil).AttachCommentToIssue), arg0, arg1, arg2, arg3, arg4, arg5)
 }
@@ -68,11 +68,26 @@ func (m *MockGitlab) CreateIssue(arg0 context.Context, arg1 *gitlab.UserInfo, ar
 }
 
 // CreateIssue indicates an expected call of CreateIssue.
-func (mr *MockGitlabMockRecorder) CreateIssue(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) CreateIssue(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIssue", reflect.TypeOf((*MockGitlab)(nil).CreateIssue), arg0, arg1, arg2, arg3)
 }
 
+// GetCommitAuthorUsername mocks base method.
+func (m *MockGitlab) GetCommitAuthorUsername(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommitAuthorUsername", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommitAuthorUsername indicates an expected call of GetCommitAuthorUsername.
+func (mr *MockGitlabMockRecorder) GetCommitAuthorUsername(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommitAuthorUsername", reflect.TypeOf((*MockGitlab)(nil).GetCommitAuthorUsername), arg0, arg1, arg2, arg3)
+}
+
 // GetCurrentUser mocks base method.
 func (m *MockGitlab) GetCurrentUser(arg0 context.Context, arg1 string, arg2 oauth2.Token) (*gitlab.UserInfo, error) {
 	m.ctrl.T.Helper()
@@ -88,6 +103,21 @@ func (mr *MockGitlabMockRecorder) GetCurrentUser(arg0, arg1, arg2 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentUser", reflect.TypeOf((*MockGitlab)(nil).GetCurrentUser), arg0, arg1, arg2)
 }
 
+// GetGroup mocks base method.
+func (m *MockGitlab) GetGroup(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string) (*gitlab0.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroup", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*gitlab0.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroup indicates an expected call of GetGroup.
+func (mr *MockGitlabMockRecorder) GetGroup(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroup", reflect.TypeOf((*MockGitlab)(nil).GetGroup), arg0, arg1, arg2, arg3, arg4)
+}
+
 // GetGroupHooks mocks base method.
 func (m *MockGitlab) GetGroupHooks(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3 string) ([]*gitlab.WebhookInfo, error) {
 	m.ctrl.T.Helper()
@@ -143,7 +173,7 @@ func (m *MockGitlab) GetLabels(arg0 context.Context, arg1 *gitlab.UserInfo, arg2
 }
 
 // GetLabels indicates an expected call of GetLabels.
-func (mr *MockGitlabMockRecorder) GetLabels(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) GetLabels(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLabels", reflect.TypeOf((*MockGitlab)(nil).GetLabels), arg0, arg1, arg2, arg3)
 }
@@ -163,6 +193,52 @@ func (mr *MockGitlabMockRecorder) GetMergeRequestByID(arg0, arg1, arg2, arg3, ar
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMergeRequestByID", reflect.TypeOf((*MockGitlab)(nil).GetMergeRequestByID), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// GetMergeRequestChangedPaths mocks base method.
+func (m *MockGitlab) GetMergeRequestChangedPaths(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string, arg5 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMergeRequestChangedPaths", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMergeRequestChangedPaths indicates an expected call of GetMergeRequestChangedPaths.
+func (mr *MockGitlabMockRecorder) GetMergeRequestChangedPaths(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMergeRequestChangedPaths", reflect.TypeOf((*MockGitlab)(nil).GetMergeRequestChangedPaths), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// GetMilestoneIssueCounts mocks base method.
+func (m *MockGitlab) GetMilestoneIssueCounts(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4, arg5 string) (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMilestoneIssueCounts", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetMilestoneIssueCounts indicates an expected call of GetMilestoneIssueCounts.
+func (mr *MockGitlabMockRecorder) GetMilestoneIssueCounts(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMilestoneIssueCounts", reflect.TypeOf((*MockGitlab)(nil).GetMilestoneIssueCounts), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// GetMilestoneTitle mocks base method.
+func (m *MockGitlab) GetMilestoneTitle(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string, arg5 int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMilestoneTitle", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMilestoneTitle indicates an expected call of GetMilestoneTitle.
+func (mr *MockGitlabMockRecorder) GetMilestoneTitle(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMilestoneTitle", reflect.TypeOf((*MockGitlab)(nil).GetMilestoneTitle), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // GetMilestones mocks base method.
 func (m *MockGitlab) GetMilestones(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 string, arg3 *oauth2.Token) ([]*gitlab0.Milestone, error) {
 	m.ctrl.T.Helper()
@@ -173,25 +249,31 @@ func (m *MockGitlab) GetMilestones(arg0 context.Context, arg1 *gitlab.UserInfo,
 }
 
 // GetMilestones indicates an expected call of GetMilestones.
-func (mr *MockGitlabMockRecorder) GetMilestones(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) GetMilestones(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMilestones", reflect.TypeOf((*MockGitlab)(nil).GetMilestones), arg0, arg1, arg2, arg3)
 }
 
-// GetProject mocks base method.
-func (m *MockGitlab) GetProject(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string) (*gitlab0.Project, error) {
+// GetPreviousDeploymentSHA mocks base method.
+func (m *MockGitlab) GetPreviousDeploymentSHA(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4, arg5 string, arg6 int) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProject", arg0, arg1, arg2, arg3, arg4)
-	ret0, _ := ret[0].(*gitlab0.Project)
+	ret := m.ctrl.Call(m, "GetPreviousDeploymentSHA", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetGroup mocks base method.
-func (m *MockGitlab) GetGroup(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string) (*gitlab0.Group, error) {
+// GetPreviousDeploymentSHA indicates an expected call of GetPreviousDeploymentSHA.
+func (mr *MockGitlabMockRecorder) GetPreviousDeploymentSHA(arg0, arg1, arg2, arg3, arg4, arg5, arg6 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPreviousDeploymentSHA", reflect.TypeOf((*MockGitlab)(nil).GetPreviousDeploymentSHA), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// GetProject mocks base method.
+func (m *MockGitlab) GetProject(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string) (*gitlab0.Project, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetGroup", arg0, arg1, arg2, arg3, arg4)
-	ret0, _ := ret[0].(*gitlab0.Group)
+	ret := m.ctrl.Call(m, "GetProject", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*gitlab0.Project)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -227,7 +309,7 @@ func (m *MockGitlab) GetProjectMembers(arg0 context.Context, arg1 *gitlab.UserIn
 }
 
 // GetProjectMembers indicates an expected call of GetProjectMembers.
-func (mr *MockGitlabMockRecorder) GetProjectMembers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) GetProjectMembers(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProjectMembers", reflect.TypeOf((*MockGitlab)(nil).GetProjectMembers), arg0, arg1, arg2, arg3)
 }
@@ -332,7 +414,7 @@ func (m *MockGitlab) GetYourProjects(arg0 context.Context, arg1 *gitlab.UserInfo
 }
 
 // GetYourProjects indicates an expected call of GetYourProjects.
-func (mr *MockGitlabMockRecorder) GetYourProjects(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) GetYourProjects(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetYourProjects", reflect.TypeOf((*MockGitlab)(nil).GetYourProjects), arg0, arg1, arg2)
 }
@@ -408,11 +490,39 @@ func (m *MockGitlab) SearchIssues(arg0 context.Context, arg1 *gitlab.UserInfo, a
 }
 
 // SearchIssues indicates an expected call of SearchIssues.
-func (mr *MockGitlabMockRecorder) SearchIssues(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockGitlabMockRecorder) SearchIssues(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchIssues", reflect.TypeOf((*MockGitlab)(nil).SearchIssues), arg0, arg1, arg2, arg3)
 }
 
+// SetGroupHookToken mocks base method.
+func (m *MockGitlab) SetGroupHookToken(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3 string, arg4 *gitlab.WebhookInfo, arg5 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGroupHookToken", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetGroupHookToken indicates an expected call of SetGroupHookToken.
+func (mr *MockGitlabMockRecorder) SetGroupHookToken(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGroupHookToken", reflect.TypeOf((*MockGitlab)(nil).SetGroupHookToken), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// SetProjectHookToken mocks base method.
+func (m *MockGitlab) SetProjectHookToken(arg0 context.Context, arg1 *gitlab.UserInfo, arg2 *oauth2.Token, arg3, arg4 string, arg5 *gitlab.WebhookInfo, arg6 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProjectHookToken", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetProjectHookToken indicates an expected call of SetProjectHookToken.
+func (mr *MockGitlabMockRecorder) SetProjectHookToken(arg0, arg1, arg2, arg3, arg4, arg5, arg6 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProjectHookToken", reflect.TypeOf((*MockGitlab)(nil).SetProjectHookToken), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // TriggerProjectPipeline mocks base method.
 func (m *MockGitlab) TriggerProjectPipeline(arg0 *gitlab.UserInfo, arg1 *oauth2.Token, arg2, arg3 string) (*gitlab.PipelineInfo, error) {
 	m.ctrl.T.Helper()
@@ -43,6 +43,9 @@ type AddWebhookOptions struct {
 	WikiPageEvents           bool
 	DeploymentEvents         bool
 	ReleaseEvents            bool
+	FeatureFlagEvents        bool
+	MemberEvents             bool
+	EmojiEvents              bool
 	EnableSSLVerification    bool
 	Token                    string
 }
@@ -0,0 +1,81 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// i18nPath is where the translations of the plugin messages are in its bundle, English being the language of
+// their default text.
+const i18nPath = "assets/i18n"
+
+// englishBundle localizes the messages when no translation could be loaded.
+var englishBundle = goi18n.NewBundle(language.English)
+
+// getServerLocale returns the language of the messages posted to channels, as Mattermost has no channel or team
+// language: the default one of the server.
+func (p *Plugin) getServerLocale() string {
+	if locale := p.getConfiguration().ServerLocale; locale != "" {
+		return locale
+	}
+	return language.English.String()
+}
+
+// getUserLocale returns the language of a Mattermost user, or the default one of the server if it can't be found.
+func (p *Plugin) getUserLocale(userID string) string {
+	// Without translations every language is English, so the user isn't looked up.
+	if p.i18nBundle == nil || userID == "" {
+		return p.getServerLocale()
+	}
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Warn("can't get the language of the user", "user_id", userID, "err", err.Error())
+		return p.getServerLocale()
+	}
+	if user.Locale == "" {
+		return p.getServerLocale()
+	}
+	return user.Locale
+}
+
+// localize returns the message in the language, filled with the data.
+func (p *Plugin) localize(locale string, message *i18n.Message, data any) string {
+	return p.localizeWithConfig(locale, &i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData:   data,
+	})
+}
+
+// localizePlural returns the plural form of the message for the count in the language, the count being
+// its {{.Count}}.
+func (p *Plugin) localizePlural(locale string, message *i18n.Message, count int) string {
+	return p.localizeWithConfig(locale, &i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData:   map[string]any{"Count": count},
+		PluralCount:    count,
+	})
+}
+
+// localizeWithConfig returns the message of the config in the language. The default English text is used
+// when the message has no translation in this language.
+func (p *Plugin) localizeWithConfig(locale string, config *i18n.LocalizeConfig) string {
+	bundle := englishBundle
+	if p.i18nBundle != nil {
+		bundle = p.i18nBundle.Bundle
+	}
+	text, err := goi18n.NewLocalizer(bundle, locale).Localize(config)
+	if err != nil && text == "" {
+		p.client.Log.Warn("can't localize message", "id", config.DefaultMessage.ID, "locale", locale, "err", err.Error())
+		return config.DefaultMessage.Other
+	}
+	return text
+}
+
+// localizeForUser returns the message in the language of a Mattermost user, filled with the data.
+func (p *Plugin) localizeForUser(userID string, message *i18n.Message, data any) string {
+	return p.localize(p.getUserLocale(userID), message, data)
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var templateFieldRegexp = regexp.MustCompile(`{{\.\w+}}`)
+
+// readTranslations returns the text of every message of a translation file, the plural forms being joined.
+func readTranslations(t *testing.T, path string) map[string]string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	texts := make(map[string]string, len(raw))
+	for id, value := range raw {
+		switch v := value.(type) {
+		case string:
+			texts[id] = v
+		case map[string]any:
+			require.Contains(t, v, "other", "message %s of %s has no other form", id, path)
+			for _, form := range v {
+				texts[id] += form.(string)
+			}
+		default:
+			t.Fatalf("message %s of %s is neither a text nor plural forms", id, path)
+		}
+	}
+	return texts
+}
+
+// templateFields returns the fields a message text is filled with, once each as a translation may use them
+// more or less often.
+func templateFields(text string) []string {
+	fields := templateFieldRegexp.FindAllString(text, -1)
+	sort.Strings(fields)
+	return slices.Compact(fields)
+}
+
+func TestTranslations(t *testing.T) {
+	english := readTranslations(t, filepath.Join("..", i18nPath, "active.en.json"))
+	paths, err := filepath.Glob(filepath.Join("..", i18nPath, "active.*.json"))
+	require.NoError(t, err)
+
+	for _, path := range paths {
+		if filepath.Base(path) == "active.en.json" {
+			continue
+		}
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			for id, text := range readTranslations(t, path) {
+				englishText, ok := english[id]
+				if !assert.True(t, ok, "message %s isn't in active.en.json", id) {
+					continue
+				}
+				assert.Equal(t, templateFields(englishText), templateFields(text), "message %s", id)
+			}
+		})
+	}
+}
+
+func newLocalizedTestPlugin(t *testing.T, api *plugintest.API, serverLocale string) *Plugin {
+	p := newQuietHoursTestPlugin(api)
+	p.configuration.ServerLocale = serverLocale
+	api.On("GetBundlePath").Return("..", nil)
+	bundle, err := i18n.InitBundle(api, i18nPath)
+	require.NoError(t, err)
+	p.i18nBundle = bundle
+	return p
+}
+
+func TestLocalizeForUser(t *testing.T) {
+	message := &i18n.Message{
+		ID:    "command.instance.uninstalled",
+		Other: "Instance '{{.Name}}' has been uninstalled.",
+	}
+	data := map[string]any{"Name": "gitlab.com"}
+
+	t.Run("language of the user", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newLocalizedTestPlugin(t, api, "en")
+		api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Locale: "de"}, nil).Once()
+
+		assert.Equal(t, "Die Instanz 'gitlab.com' wurde deinstalliert.", p.localizeForUser("user-id", message, data))
+	})
+
+	t.Run("user without language gets the server one", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newLocalizedTestPlugin(t, api, "ja")
+		api.On("GetUser", "user-id").Return(&model.User{Id: "user-id"}, nil).Once()
+
+		assert.Equal(t, "インスタンス 'gitlab.com' をアンインストールしました。", p.localizeForUser("user-id", message, data))
+	})
+
+	t.Run("unknown user gets the server language", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newLocalizedTestPlugin(t, api, "de")
+		api.On("GetUser", "user-id").Return(nil, &model.AppError{Message: "not found"}).Once()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		assert.Equal(t, "Die Instanz 'gitlab.com' wurde deinstalliert.", p.localizeForUser("user-id", message, data))
+	})
+
+	t.Run("language without translation is English", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newLocalizedTestPlugin(t, api, "en")
+		api.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Locale: "fr"}, nil).Once()
+
+		assert.Equal(t, "Instance 'gitlab.com' has been uninstalled.", p.localizeForUser("user-id", message, data))
+	})
+
+	t.Run("plural forms", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newLocalizedTestPlugin(t, api, "de")
+		todos := &i18n.Message{
+			ID:    "todo.todos",
+			One:   "You have {{.Count}} todo:",
+			Other: "You have {{.Count}} todos:",
+		}
+
+		assert.Equal(t, "Du hast 1 To-do:", p.localizePlural("de", todos, 1))
+		assert.Equal(t, "Du hast 3 To-dos:", p.localizePlural("de", todos, 3))
+		assert.Equal(t, "You have 1 todo:", p.localizePlural("en", todos, 1))
+	})
+}
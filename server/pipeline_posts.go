@@ -0,0 +1,154 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
+)
+
+const (
+	// pipelineCardKeyPrefix prefixes the KV keys recording the content of the post of a pipeline.
+	pipelineCardKeyPrefix = "pipelinecard_"
+	// updatedPostKeyPrefix prefixes the KV keys mapping the UpdateKey of a webhook post to the post created for it in a channel.
+	updatedPostKeyPrefix = "updatedpost_"
+	// updatedPostTTL is how long a pipeline post is updated after its last event, later events create a new one.
+	updatedPostTTL = 7 * 24 * time.Hour
+
+	pipelineCardMaxRetries = 5
+)
+
+func pipelineCardKey(projectID, pipelineID int) string {
+	return fmt.Sprintf("%s%d_%d", pipelineCardKeyPrefix, projectID, pipelineID)
+}
+
+// updatedPostKey hashes the update key, as the channel ID and the key don't always fit together in a KV key.
+func updatedPostKey(channelID, updateKey string) string {
+	hash := sha256.Sum256([]byte(channelID + "/" + updateKey))
+	return updatedPostKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// updatePipelineCard merges the update into the recorded card of a pipeline and returns the result.
+// It retries like KV.SetAtomicWithRetries, which can't set an expiry, so the cards of old pipelines don't pile up.
+func (p *Plugin) updatePipelineCard(projectID, pipelineID int, update *webhook.PipelineCard) *webhook.PipelineCard {
+	key := pipelineCardKey(projectID, pipelineID)
+	for range pipelineCardMaxRetries {
+		var oldValue []byte
+		if err := p.client.KV.Get(key, &oldValue); err != nil {
+			p.client.Log.Warn("can't load the pipeline card", "project_id", projectID, "pipeline_id", pipelineID, "err", err.Error())
+			return update
+		}
+
+		card := &webhook.PipelineCard{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, card); err != nil {
+				p.client.Log.Warn("can't read the pipeline card", "project_id", projectID, "pipeline_id", pipelineID, "err", err.Error())
+				return update
+			}
+		}
+		revision := card.Revision + 1
+		card.Merge(update)
+		card.Revision = revision
+
+		saved, err := p.client.KV.Set(key, card, pluginapi.SetAtomic(oldValue), pluginapi.SetExpiry(updatedPostTTL))
+		if err != nil {
+			p.client.Log.Warn("can't record the pipeline card", "project_id", projectID, "pipeline_id", pipelineID, "err", err.Error())
+			return update
+		}
+		if saved {
+			return card
+		}
+	}
+	p.client.Log.Warn("can't record the pipeline card, it keeps being updated concurrently", "project_id", projectID, "pipeline_id", pipelineID)
+	return update
+}
+
+// updatedPost is the post created for an update key in a channel, with the revision of its content.
+type updatedPost struct {
+	PostID   string `json:"post_id"`
+	Revision int64  `json:"revision,omitempty"`
+}
+
+// createOrUpdatePost replaces the message of the post previously created for the update key in the channel,
+// or creates it, in the thread of threadKey when set, if there is none yet or it was deleted. The delivery workers
+// don't make the posts in order, so a post of a revision older than the one already made is skipped; a revision
+// of zero is always made. The posts of a key are made one at a time across the cluster.
+func (p *Plugin) createOrUpdatePost(post *model.Post, updateKey, threadKey string, revision int64) error {
+	key := updatedPostKey(post.ChannelId, updateKey)
+	mutex, err := cluster.NewMutex(p.API, key)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the mutex of the post to update")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var current updatedPost
+	if err := p.client.KV.Get(key, &current); err != nil {
+		return errors.Wrap(err, "failed to load the post to update")
+	}
+	if revision != 0 && revision <= current.Revision {
+		p.client.Log.Debug("skipping outdated post update", "channel_id", post.ChannelId, "revision", revision, "current_revision", current.Revision)
+		return nil
+	}
+
+	updated := false
+	if current.PostID != "" {
+		updated, err = p.updatePostContent(current.PostID, post.Clone())
+		if err != nil {
+			return err
+		}
+	}
+	if !updated {
+		if threadKey != "" {
+			err = p.createThreadedPost(post, threadKey)
+		} else {
+			err = p.client.Post.CreatePost(post)
+		}
+		if err != nil {
+			return err
+		}
+		current.PostID = post.Id
+	}
+
+	current.Revision = max(current.Revision, revision)
+	if _, err := p.client.KV.Set(key, current, pluginapi.SetExpiry(updatedPostTTL)); err != nil {
+		p.client.Log.Warn("can't store the post to update", "channel_id", post.ChannelId, "err", err.Error())
+	}
+	return nil
+}
+
+// updatePostContent replaces the message and attachments of a post, it returns false if the post was deleted.
+func (p *Plugin) updatePostContent(postID string, content *model.Post) (bool, error) {
+	post, err := p.client.Post.GetPost(postID)
+	if errors.Is(err, pluginapi.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to load the post to update")
+	}
+	if post.DeleteAt != 0 {
+		return false, nil
+	}
+
+	post.Message = content.Message
+	if attachments := content.Attachments(); len(attachments) > 0 {
+		post.AddProp(model.PostPropsAttachments, attachments)
+	} else {
+		post.DelProp(model.PostPropsAttachments)
+	}
+	if err := p.client.Post.UpdatePost(post); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
+)
+
+func TestUpdatePipelineCard(t *testing.T) {
+	key := pipelineCardKey(24, 62)
+	isUpdatedPostTTL := func(oldValue []byte) any {
+		return mock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+			return o.Atomic && string(o.OldValue) == string(oldValue) && o.ExpireInSeconds == int64(updatedPostTTL.Seconds())
+		})
+	}
+
+	t.Run("job status is recorded before the pipeline one", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		update := &webhook.PipelineCard{Jobs: []webhook.PipelineJob{{ID: 1, Stage: "test", Name: "unit", Status: "pending"}}}
+		card := &webhook.PipelineCard{Jobs: update.Jobs, Revision: 1}
+		expected, err := json.Marshal(card)
+		require.NoError(t, err)
+		api.On("KVGet", key).Return(nil, nil).Once()
+		api.On("KVSetWithOptions", key, expected, isUpdatedPostTTL(nil)).Return(true, nil).Once()
+
+		assert.Equal(t, card, p.updatePipelineCard(24, 62, update))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("job status is merged into the recorded card", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		existing, err := json.Marshal(&webhook.PipelineCard{
+			Header: "Pipeline running",
+			Jobs: []webhook.PipelineJob{
+				{ID: 1, Stage: "test", Name: "unit", Status: "running"},
+				{ID: 2, Stage: "test", Name: "lint", Status: "running"},
+			},
+			Revision: 3,
+		})
+		require.NoError(t, err)
+		card := &webhook.PipelineCard{
+			Header: "Pipeline running",
+			Jobs: []webhook.PipelineJob{
+				{ID: 1, Stage: "test", Name: "unit", Status: "success"},
+				{ID: 2, Stage: "test", Name: "lint", Status: "running"},
+			},
+			Revision: 4,
+		}
+		expected, err := json.Marshal(card)
+		require.NoError(t, err)
+		// The first attempt loses the race against another event of the pipeline.
+		api.On("KVGet", key).Return(existing, nil).Twice()
+		api.On("KVSetWithOptions", key, expected, isUpdatedPostTTL(existing)).Return(false, nil).Once()
+		api.On("KVSetWithOptions", key, expected, isUpdatedPostTTL(existing)).Return(true, nil).Once()
+
+		assert.Equal(t, card, p.updatePipelineCard(24, 62, &webhook.PipelineCard{
+			Jobs: []webhook.PipelineJob{{ID: 1, Stage: "test", Name: "unit", Status: "success"}},
+		}))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("update alone is returned when the store fails", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		api.On("KVGet", key).Return(nil, &model.AppError{Message: "db down"}).Once()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		update := &webhook.PipelineCard{Header: "Pipeline running"}
+
+		assert.Equal(t, update, p.updatePipelineCard(24, 62, update))
+		api.AssertExpectations(t)
+	})
+}
+
+func TestCreateOrUpdatePost(t *testing.T) {
+	key := updatedPostKey("channel-id", "pipeline/24/62")
+	newPost := func() *model.Post {
+		return &model.Post{ChannelId: "channel-id", UserId: "bot-user-id", Message: "Pipeline success"}
+	}
+	mockMutex := func(api *plugintest.API) {
+		api.On("KVSetWithOptions", "mutex_"+key, []byte{1}, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+		api.On("KVSetWithOptions", "mutex_"+key, isNilBytes, model.PluginKVSetOptions{}).Return(true, nil).Once()
+	}
+	isStored := func(postID string, revision int64) any {
+		return mock.MatchedBy(func(value []byte) bool {
+			var stored updatedPost
+			return json.Unmarshal(value, &stored) == nil && stored == updatedPost{PostID: postID, Revision: revision}
+		})
+	}
+	isUpdatedPostTTL := mock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.ExpireInSeconds == int64(updatedPostTTL.Seconds())
+	})
+	recorded := func(postID string, revision int64) []byte {
+		value, err := json.Marshal(&updatedPost{PostID: postID, Revision: revision})
+		require.NoError(t, err)
+		return value
+	}
+
+	t.Run("first post is created and recorded", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		mockMutex(api)
+		api.On("KVGet", key).Return(nil, nil).Once()
+		api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "post-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, isStored("post-id", 1), isUpdatedPostTTL).Return(true, nil).Once()
+
+		require.NoError(t, p.createOrUpdatePost(newPost(), "pipeline/24/62", "", 1))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("next posts update the recorded one", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		mockMutex(api)
+		api.On("KVGet", key).Return(recorded("post-id", 1), nil).Once()
+		api.On("GetPost", "post-id").Return(&model.Post{Id: "post-id", Message: "Pipeline running"}, nil).Once()
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Id == "post-id" && post.Message == "Pipeline success"
+		})).Return(&model.Post{Id: "post-id", Message: "Pipeline success"}, nil).Once()
+		api.On("KVSetWithOptions", key, isStored("post-id", 2), isUpdatedPostTTL).Return(true, nil).Once()
+
+		require.NoError(t, p.createOrUpdatePost(newPost(), "pipeline/24/62", "", 2))
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("post older than the recorded one is skipped", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		mockMutex(api)
+		api.On("KVGet", key).Return(recorded("post-id", 3), nil).Once()
+		api.On("LogDebug", "skipping outdated post update", "channel_id", "channel-id", "revision", int64(2), "current_revision", int64(3)).Once()
+
+		require.NoError(t, p.createOrUpdatePost(newPost(), "pipeline/24/62", "", 2))
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+	})
+
+	t.Run("attachments of the recorded post are replaced", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		mockMutex(api)
+		post := newPost()
+		post.Message = ""
+		model.ParseMessageAttachment(post, []*model.MessageAttachment{{Fallback: "Pipeline success", Text: "Succeeded"}})
+		existing := &model.Post{Id: "post-id"}
+		model.ParseMessageAttachment(existing, []*model.MessageAttachment{{Fallback: "Pipeline running", Text: "Running"}})
+		api.On("KVGet", key).Return(recorded("post-id", 1), nil).Once()
+		api.On("GetPost", "post-id").Return(existing, nil).Once()
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			attachments := post.Attachments()
+			return post.Message == "" && len(attachments) == 1 && attachments[0].Text == "Succeeded"
+		})).Return(&model.Post{Id: "post-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, isStored("post-id", 2), isUpdatedPostTTL).Return(true, nil).Once()
+
+		require.NoError(t, p.createOrUpdatePost(post, "pipeline/24/62", "", 2))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("deleted post is created again", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		mockMutex(api)
+		api.On("KVGet", key).Return(recorded("post-id", 1), nil).Once()
+		api.On("GetPost", "post-id").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound}).Once()
+		api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "new-post-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, isStored("new-post-id", 2), isUpdatedPostTTL).Return(true, nil).Once()
+
+		require.NoError(t, p.createOrUpdatePost(newPost(), "pipeline/24/62", "", 2))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("queued pipeline post is delivered in place", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "Pipeline success", UpdateKey: "pipeline/24/62", UpdateRevision: 2})
+		mockMutex(api)
+		api.On("KVGet", key).Return(recorded("post-id", 1), nil).Once()
+		api.On("GetPost", "post-id").Return(&model.Post{Id: "post-id", Message: "Pipeline running"}, nil).Once()
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Message == "Pipeline success"
+		})).Return(&model.Post{Id: "post-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, isStored("post-id", 2), isUpdatedPostTTL).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+}
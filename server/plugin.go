@@ -24,6 +24,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/bot/poster"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/pkg/errors"
 	gitlabLib "github.com/xanzy/go-gitlab"
 	"golang.org/x/oauth2"
@@ -80,6 +81,17 @@ type Plugin struct {
 
 	oauthBroker *OAuthBroker
 
+	deliveryQueue *deliveryQueue
+	// queueIndexLock makes the updates of the queue indexes by this node take turns, so they only contend with other nodes.
+	queueIndexLock sync.Mutex
+
+	heldPostsFlusher *heldPostsFlusher
+
+	messageTemplates messageTemplateCache
+
+	// i18nBundle holds the translations of the plugin messages, it is nil if they couldn't be loaded.
+	i18nBundle *i18n.Bundle
+
 	WebhookHandler webhook.Webhook
 	GitlabClient   gitlab.Gitlab
 }
@@ -130,6 +142,17 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.BotUserID = botID
 
+	i18nBundle, err := i18n.InitBundle(p.API, i18nPath)
+	if err != nil {
+		p.client.Log.Warn("can't load translations, messages are in English", "err", err.Error())
+	} else {
+		p.i18nBundle = i18nBundle
+	}
+
+	if err = p.migrateSubscriptions(); err != nil {
+		return errors.Wrap(err, "failed to migrate subscriptions")
+	}
+
 	p.WebhookHandler = webhook.NewWebhook(&gitlabRetreiver{p: p})
 
 	p.poster = poster.NewPoster(&p.client.Post, p.BotUserID)
@@ -139,12 +162,25 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.flowManager = flowManager
 
+	p.deliveryQueue = newDeliveryQueue(p)
+	p.deliveryQueue.start()
+
+	p.heldPostsFlusher = newHeldPostsFlusher(p)
+	p.heldPostsFlusher.start()
+
 	return nil
 }
 
 func (p *Plugin) OnDeactivate() error {
 	p.oauthBroker.Close()
 
+	if p.deliveryQueue != nil {
+		p.deliveryQueue.stop()
+	}
+	if p.heldPostsFlusher != nil {
+		p.heldPostsFlusher.stop()
+	}
+
 	return nil
 }
 
@@ -643,6 +679,7 @@ func (p *Plugin) PostToDo(ctx context.Context, info *gitlab.UserInfo) {
 
 func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, string, error) {
 	hasTodo := false
+	locale := p.getUserLocale(user.UserID)
 
 	var notificationText, reviewText, assignmentText, mergeRequestText string
 	err := p.useGitlabClient(user, func(info *gitlab.UserInfo, token *oauth2.Token) error {
@@ -668,16 +705,28 @@ func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, stri
 			switch n.ActionName {
 			// Handle special cases where the provided "Title" value is blank
 			case NotificationActionNameMemberAccessRequest:
-				fmt.Fprintf(&notificationContent, "* %v : [%v](%v) has requested access to [%v](%v)\n", n.ActionName, n.Author.Name, n.Author.WebURL, n.Body, n.TargetURL)
+				fmt.Fprintf(&notificationContent, "* %v : %s\n", n.ActionName, p.localize(locale, webhook.MemberAccessRequestMessage, map[string]any{
+					"Requester":    n.Author.Name,
+					"RequesterURL": n.Author.WebURL,
+					"Target":       n.Body,
+					"TargetURL":    n.TargetURL,
+				}))
 			default:
 				fmt.Fprintf(&notificationContent, "* %v : [%v](%v)\n", n.ActionName, n.Target.Title, n.TargetURL)
 			}
 		}
 
 		if notificationCount == 0 {
-			notificationText += "You don't have any todos.\n"
+			notificationText += p.localize(locale, &i18n.Message{
+				ID:    "todo.todos.none",
+				Other: "You don't have any todos.",
+			}, nil) + "\n"
 		} else {
-			notificationText += fmt.Sprintf("You have %v todos:\n", notificationCount)
+			notificationText += p.localizePlural(locale, &i18n.Message{
+				ID:    "todo.todos",
+				One:   "You have {{.Count}} todo:",
+				Other: "You have {{.Count}} todos:",
+			}, notificationCount) + "\n"
 			notificationText += notificationContent.String()
 
 			hasTodo = true
@@ -685,9 +734,16 @@ func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, stri
 
 		reviews := resp.Reviews
 		if len(reviews) == 0 {
-			reviewText += "You don't have any merge requests awaiting your review.\n"
+			reviewText += p.localize(locale, &i18n.Message{
+				ID:    "todo.reviews.none",
+				Other: "You don't have any merge requests awaiting your review.",
+			}, nil) + "\n"
 		} else {
-			reviewText += fmt.Sprintf("You have %v merge requests awaiting your review:\n", len(reviews))
+			reviewText += p.localizePlural(locale, &i18n.Message{
+				ID:    "todo.reviews",
+				One:   "You have {{.Count}} merge request awaiting your review:",
+				Other: "You have {{.Count}} merge requests awaiting your review:",
+			}, len(reviews)) + "\n"
 
 			for _, pr := range reviews {
 				reviewText += fmt.Sprintf("* [%v](%v)\n", pr.Title, pr.WebURL)
@@ -698,9 +754,16 @@ func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, stri
 
 		yourAssignedIssues := resp.AssignedIssues
 		if len(yourAssignedIssues) == 0 {
-			assignmentText += "You don't have any issues awaiting your dev.\n"
+			assignmentText += p.localize(locale, &i18n.Message{
+				ID:    "todo.issues.none",
+				Other: "You don't have any issues awaiting your dev.",
+			}, nil) + "\n"
 		} else {
-			assignmentText += fmt.Sprintf("You have %v issues awaiting dev:\n", len(yourAssignedIssues))
+			assignmentText += p.localizePlural(locale, &i18n.Message{
+				ID:    "todo.issues",
+				One:   "You have {{.Count}} issue awaiting dev:",
+				Other: "You have {{.Count}} issues awaiting dev:",
+			}, len(yourAssignedIssues)) + "\n"
 
 			for _, pr := range yourAssignedIssues {
 				assignmentText += fmt.Sprintf("* [%v](%v)\n", pr.Title, pr.WebURL)
@@ -711,9 +774,16 @@ func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, stri
 
 		mergeRequests := resp.AssignedPRs
 		if len(mergeRequests) == 0 {
-			mergeRequestText += "You don't have any merge requests assigned.\n"
+			mergeRequestText += p.localize(locale, &i18n.Message{
+				ID:    "todo.merge_requests.none",
+				Other: "You don't have any merge requests assigned.",
+			}, nil) + "\n"
 		} else {
-			mergeRequestText += fmt.Sprintf("You have %v merge requests assigned:\n", len(mergeRequests))
+			mergeRequestText += p.localizePlural(locale, &i18n.Message{
+				ID:    "todo.merge_requests",
+				One:   "You have {{.Count}} merge request assigned:",
+				Other: "You have {{.Count}} merge requests assigned:",
+			}, len(mergeRequests)) + "\n"
 
 			for _, pr := range mergeRequests {
 				mergeRequestText += fmt.Sprintf("* [%v](%v)\n", pr.Title, pr.WebURL)
@@ -728,16 +798,16 @@ func (p *Plugin) GetToDo(ctx context.Context, user *gitlab.UserInfo) (bool, stri
 		return false, "", err
 	}
 
-	text := "##### To-Do list\n"
+	text := "##### " + p.localize(locale, &i18n.Message{ID: "todo.header.todos", Other: "To-Do list"}, nil) + "\n"
 	text += notificationText
 
-	text += "##### Review Requests\n"
+	text += "##### " + p.localize(locale, &i18n.Message{ID: "todo.header.reviews", Other: "Review Requests"}, nil) + "\n"
 	text += reviewText
 
-	text += "##### Issues\n"
+	text += "##### " + p.localize(locale, &i18n.Message{ID: "todo.header.issues", Other: "Issues"}, nil) + "\n"
 	text += assignmentText
 
-	text += "##### Merge Requests Assigned\n"
+	text += "##### " + p.localize(locale, &i18n.Message{ID: "todo.header.merge_requests", Other: "Merge Requests Assigned"}, nil) + "\n"
 	text += mergeRequestText
 
 	return hasTodo, text, nil
@@ -868,10 +938,14 @@ func (p *Plugin) openIssueCreateModal(userID, channelID, title string) {
 	)
 }
 
-func (p *Plugin) sendChannelSubscriptionsUpdated(subs *Subscriptions, channelID string) {
+func (p *Plugin) sendChannelSubscriptionsUpdated(channelID string) {
 	config := p.getConfiguration()
 
-	subscriptions := filterSubscriptionsByChannel(subs, channelID)
+	subscriptions, err := p.GetSubscriptionsByChannel(channelID)
+	if err != nil {
+		p.client.Log.Warn("unable to get the updated channel subscriptions", "err", err.Error())
+		return
+	}
 
 	var payload struct {
 		ChannelID     string                 `json:"channel_id"`
@@ -1261,9 +1335,3 @@ func (p *Plugin) useGitlabClient(info *gitlab.UserInfo, toRun func(info *gitlab.
 
 	return err
 }
-
-func (p *Plugin) logWarnings(warnings []string) {
-	for _, warning := range warnings {
-		p.client.Log.Warn(warning)
-	}
-}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
 )
 
 func TestIsNamespaceAllowed(t *testing.T) {
@@ -128,17 +129,13 @@ func TestNotifyUsersOfDisallowedSubscriptions(t *testing.T) {
 	}
 
 	t.Run("empty subscriptions", func(t *testing.T) {
-		emptySubs := &Subscriptions{Repositories: map[string][]*subscription.Subscription{}}
-		payload, err := json.Marshal(emptySubs)
-		require.NoError(t, err)
-
 		api := &plugintest.API{}
-		api.On("KVGet", SubscriptionsKey).Return(payload, nil).Once()
+		mockSubscriptionsStore(t, api, &Subscriptions{})
 
 		p := makePlugin(t, api, "dev-tool")
 		p.notifyUsersOfDisallowedSubscriptions()
 
-		api.AssertCalled(t, "KVGet", SubscriptionsKey)
+		api.AssertCalled(t, "KVList", 0, subscriptionsKeysPerPage)
 		api.AssertNotCalled(t, "GetDirectChannel", mock.Anything, mock.Anything)
 		api.AssertNotCalled(t, "CreatePost", mock.Anything)
 	})
@@ -147,20 +144,17 @@ func TestNotifyUsersOfDisallowedSubscriptions(t *testing.T) {
 		// dev-tool and dev-tool/team-a are allowed when GitlabGroup is "dev-tool"
 		subs := &Subscriptions{
 			Repositories: map[string][]*subscription.Subscription{
-				"dev-tool":             {{ChannelID: "ch1", CreatorID: "user1", Features: "merges", Repository: "dev-tool"}},
-				"dev-tool/team-a/repo": {{ChannelID: "ch2", CreatorID: "user2", Features: "issues", Repository: "dev-tool/team-a/repo"}},
+				"dev-tool":             {webhook.MockSubscription("ch1", "user1", "merges", "dev-tool")},
+				"dev-tool/team-a/repo": {webhook.MockSubscription("ch2", "user2", "issues", "dev-tool/team-a/repo")},
 			},
 		}
-		payload, err := json.Marshal(subs)
-		require.NoError(t, err)
-
 		api := &plugintest.API{}
-		api.On("KVGet", SubscriptionsKey).Return(payload, nil).Once()
+		mockSubscriptionsStore(t, api, subs)
 
 		p := makePlugin(t, api, "dev-tool")
 		p.notifyUsersOfDisallowedSubscriptions()
 
-		api.AssertCalled(t, "KVGet", SubscriptionsKey)
+		api.AssertCalled(t, "KVList", 0, subscriptionsKeysPerPage)
 		api.AssertNotCalled(t, "GetDirectChannel", mock.Anything, mock.Anything)
 		api.AssertNotCalled(t, "CreatePost", mock.Anything)
 	})
@@ -169,16 +163,13 @@ func TestNotifyUsersOfDisallowedSubscriptions(t *testing.T) {
 		// dev-tool allowed; other-group and dev-tool-foo disallowed
 		subs := &Subscriptions{
 			Repositories: map[string][]*subscription.Subscription{
-				"dev-tool":     {{ChannelID: "ch1", CreatorID: "user1", Features: "merges", Repository: "dev-tool"}},
-				"other-group":  {{ChannelID: "ch2", CreatorID: "user2", Features: "issues", Repository: "other-group"}},
-				"dev-tool-foo": {{ChannelID: "ch3", CreatorID: "user2", Features: "pushes", Repository: "dev-tool-foo"}},
+				"dev-tool":     {webhook.MockSubscription("ch1", "user1", "merges", "dev-tool")},
+				"other-group":  {webhook.MockSubscription("ch2", "user2", "issues", "other-group")},
+				"dev-tool-foo": {webhook.MockSubscription("ch3", "user2", "pushes", "dev-tool-foo")},
 			},
 		}
-		payload, err := json.Marshal(subs)
-		require.NoError(t, err)
-
 		api := &plugintest.API{}
-		api.On("KVGet", SubscriptionsKey).Return(payload, nil).Once()
+		mockSubscriptionsStore(t, api, subs)
 		api.On("GetChannel", "ch2").Return(&model.Channel{Id: "ch2", Name: "other-team", Type: model.ChannelTypeOpen}, nil).Once()
 		api.On("GetChannel", "ch3").Return(&model.Channel{Id: "ch3", Name: "dev-channel", Type: model.ChannelTypeOpen}, nil).Once()
 		api.On("GetDirectChannel", "user2", botUserID).Return(&model.Channel{Id: "dm-user2"}, nil).Once()
@@ -199,14 +190,11 @@ func TestNotifyUsersOfDisallowedSubscriptions(t *testing.T) {
 	t.Run("failure to send DM", func(t *testing.T) {
 		subs := &Subscriptions{
 			Repositories: map[string][]*subscription.Subscription{
-				"other-group": {{ChannelID: "ch1", CreatorID: "user1", Features: "merges", Repository: "other-group"}},
+				"other-group": {webhook.MockSubscription("ch1", "user1", "merges", "other-group")},
 			},
 		}
-		payload, err := json.Marshal(subs)
-		require.NoError(t, err)
-
 		api := &plugintest.API{}
-		api.On("KVGet", SubscriptionsKey).Return(payload, nil).Once()
+		mockSubscriptionsStore(t, api, subs)
 		api.On("GetChannel", "ch1").Return(&model.Channel{Id: "ch1", Name: "town-square", Type: model.ChannelTypeOpen}, nil).Once()
 		api.On("GetDirectChannel", "user1", botUserID).Return(&model.Channel{Id: "dm-user1"}, nil).Once()
 		api.On("CreatePost", mock.Anything).Return(nil, &model.AppError{Message: "Unable to save the Post"}).Once()
@@ -217,7 +205,7 @@ func TestNotifyUsersOfDisallowedSubscriptions(t *testing.T) {
 		p := makePlugin(t, api, "dev-tool")
 		p.notifyUsersOfDisallowedSubscriptions()
 
-		api.AssertCalled(t, "KVGet", SubscriptionsKey)
+		api.AssertCalled(t, "KVList", 0, subscriptionsKeysPerPage)
 		api.AssertCalled(t, "GetDirectChannel", "user1", botUserID)
 		api.AssertCalled(t, "CreatePost", mock.Anything)
 	})
@@ -0,0 +1,248 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+const (
+	// heldPostsKeyPrefix prefixes the KV keys holding the posts of a subscription during its quiet hours.
+	heldPostsKeyPrefix = "quietheld_"
+	// heldPostsIndexKey maps the IDs of the held posts to the end of their quiet window, so the flushes don't list the whole KV store.
+	heldPostsIndexKey = "quietheldindex"
+
+	heldPostsPollInterval = time.Minute
+	// heldPostsMaxPosts bounds the posts kept for a summary, the other ones are only counted.
+	heldPostsMaxPosts = 20
+)
+
+var (
+	errHeldPostsNotDue = errors.New("held posts are not due yet")
+	errHeldPostsGone   = errors.New("held posts were already posted")
+)
+
+// heldPosts are the posts of a subscription held during a quiet window, to be posted after a summary once it ends.
+type heldPosts struct {
+	ChannelID   string             `json:"channel_id"`
+	Repository  string             `json:"repository"`
+	Description string             `json:"description"`
+	Until       int64              `json:"until"`
+	Posts       []*pendingDelivery `json:"posts"`
+	Skipped     int                `json:"skipped,omitempty"`
+}
+
+// heldPostsID hashes the repository, as its path can be longer than what fits in a KV key.
+func heldPostsID(channelID, repository string) string {
+	hash := sha256.Sum256([]byte(repository))
+	return channelID + "_" + hex.EncodeToString(hash[:])
+}
+
+func heldPostsKey(channelID, repository string) string {
+	return heldPostsKeyPrefix + heldPostsID(channelID, repository)
+}
+
+// hold adds a post to the held ones. A post updating an earlier one, like the card of a pipeline, replaces it
+// unless it is of an earlier revision.
+func (h *heldPosts) hold(d *pendingDelivery) {
+	if d.UpdateKey != "" {
+		for i, post := range h.Posts {
+			if post.UpdateKey == d.UpdateKey {
+				if d.UpdateRevision == 0 || d.UpdateRevision > post.UpdateRevision {
+					h.Posts[i] = d
+				}
+				return
+			}
+		}
+	}
+	if len(h.Posts) < heldPostsMaxPosts {
+		h.Posts = append(h.Posts, d)
+	} else {
+		h.Skipped++
+	}
+}
+
+// holdDuringQuietHours reports whether a channel post of the subscription falls in one of its quiet windows,
+// in which case it is either dropped or held for the summary posted when the window ends.
+func (p *Plugin) holdDuringQuietHours(sub *subscription.Subscription, d *pendingDelivery, now time.Time) bool {
+	if sub == nil {
+		return false
+	}
+	until, quiet := sub.QuietUntil(now)
+	if !quiet {
+		return false
+	}
+	if !sub.QuietSummary() {
+		return true
+	}
+
+	// The index is updated first, so it has every held post, with a window ending no earlier than theirs.
+	id := heldPostsID(sub.ChannelID, sub.Repository)
+	err := p.updateQueueIndex(heldPostsIndexKey, func(index map[string]int64) {
+		index[id] = max(index[id], until.UnixMilli())
+	})
+	if err != nil {
+		p.client.Log.Warn("can't index post held during quiet hours, posting it anyway", "channel_id", sub.ChannelID, "err", err.Error())
+		return false
+	}
+
+	err = p.client.KV.SetAtomicWithRetries(heldPostsKeyPrefix+id, func(oldValue []byte) (any, error) {
+		held := &heldPosts{
+			ChannelID:   sub.ChannelID,
+			Repository:  sub.Repository,
+			Description: sub.QuietHoursDescription(),
+		}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, held); err != nil {
+				return nil, err
+			}
+		}
+		held.Until = max(held.Until, until.UnixMilli())
+		held.hold(d)
+		return held, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("can't hold post during quiet hours, posting it anyway", "channel_id", sub.ChannelID, "err", err.Error())
+		return false
+	}
+	return true
+}
+
+// flushHeldPosts posts the summaries of the quiet windows which ended, each followed by the posts it held.
+// The entries are removed atomically before being posted, so they are posted by a single node.
+func (p *Plugin) flushHeldPosts(now time.Time) {
+	ids, err := p.getDueQueueIDs(heldPostsIndexKey, now.UnixMilli())
+	if err != nil {
+		p.client.Log.Warn("can't list posts held during quiet hours", "err", err.Error())
+		return
+	}
+
+	for _, id := range ids {
+		var held *heldPosts
+		err := p.client.KV.SetAtomicWithRetries(heldPostsKeyPrefix+id, func(oldValue []byte) (any, error) {
+			held = nil
+			if oldValue == nil {
+				return nil, errHeldPostsGone
+			}
+			if err := json.Unmarshal(oldValue, &held); err != nil {
+				return nil, err
+			}
+			if held.Until > now.UnixMilli() {
+				return nil, errHeldPostsNotDue
+			}
+			return nil, nil
+		})
+		if errors.Is(err, errHeldPostsNotDue) {
+			continue
+		}
+		if err != nil && !errors.Is(err, errHeldPostsGone) {
+			p.client.Log.Warn("can't remove posts held during quiet hours", "key", heldPostsKeyPrefix+id, "err", err.Error())
+			continue
+		}
+		// The entry is kept when posts were held meanwhile for a later quiet window.
+		err = p.updateQueueIndex(heldPostsIndexKey, func(index map[string]int64) {
+			if index[id] <= now.UnixMilli() {
+				delete(index, id)
+			}
+		})
+		if err != nil {
+			p.client.Log.Warn("can't remove posts held during quiet hours from the index", "key", heldPostsKeyPrefix+id, "err", err.Error())
+		}
+		if held == nil || len(held.Posts) == 0 {
+			continue
+		}
+
+		// The posts are made here rather than by the delivery workers, which would mix up their order.
+		// The ones that fail are left to the delivery queue to retry.
+		posts := append([]*pendingDelivery{{
+			ChannelID: held.ChannelID,
+			Message:   p.heldPostsSummary(held),
+		}}, held.Posts...)
+		for _, d := range posts {
+			if err := p.deliver(d); err != nil {
+				p.client.Log.Warn("can't post held post, queueing it", "channel_id", held.ChannelID, "err", err.Error())
+				p.enqueueDelivery(d)
+			}
+		}
+	}
+}
+
+func (p *Plugin) heldPostsSummary(held *heldPosts) string {
+	config := p.getConfiguration()
+	repository := strings.TrimSuffix(held.Repository, "/")
+	count := len(held.Posts) + held.Skipped
+	locale := p.getServerLocale()
+	title := p.localize(locale, &i18n.Message{
+		ID:    "quiet_hours.summary.title",
+		Other: "#### Quiet hours summary",
+	}, nil)
+	summary := title + "\n" + p.localizeWithConfig(locale, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "quiet_hours.summary.held",
+			One:   "{{.Count}} notification of [{{.Repository}}]({{.URL}}) was held during the quiet hours of this channel, {{.Description}}.",
+			Other: "{{.Count}} notifications of [{{.Repository}}]({{.URL}}) were held during the quiet hours of this channel, {{.Description}}.",
+		},
+		TemplateData: map[string]any{
+			"Count":       count,
+			"Repository":  repository,
+			"URL":         config.GitlabURL + "/" + repository,
+			"Description": held.Description,
+		},
+		PluralCount: count,
+	})
+	if held.Skipped > 0 {
+		summary += "\n" + p.localizePlural(locale, &i18n.Message{
+			ID:    "quiet_hours.summary.skipped",
+			One:   "_{{.Count}} more notification is not shown._",
+			Other: "_{{.Count}} more notifications are not shown._",
+		}, held.Skipped)
+	}
+	return summary
+}
+
+// heldPostsFlusher periodically posts the summaries of the quiet windows which ended.
+type heldPostsFlusher struct {
+	p    *Plugin
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newHeldPostsFlusher(p *Plugin) *heldPostsFlusher {
+	return &heldPostsFlusher{
+		p:    p,
+		done: make(chan struct{}),
+	}
+}
+
+func (f *heldPostsFlusher) start() {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(heldPostsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.done:
+				return
+			case now := <-ticker.C:
+				f.p.flushHeldPosts(now)
+			}
+		}
+	}()
+}
+
+func (f *heldPostsFlusher) stop() {
+	close(f.done)
+	f.wg.Wait()
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+func newQuietHoursTestPlugin(api *plugintest.API) *Plugin {
+	p := &Plugin{configuration: &configuration{GitlabURL: "https://gitlab.example.com"}}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(api, p.Driver)
+	return p
+}
+
+func TestHoldDuringQuietHours(t *testing.T) {
+	night := time.Date(2024, 3, 6, 22, 0, 0, 0, time.UTC)
+	hello := &pendingDelivery{ChannelID: "channel1", Message: "hello"}
+
+	t.Run("post outside of the quiet hours is delivered", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		sub, err := subscription.New("channel1", "user1", `pipeline,quiet_hours:"19:00-08:00"`, "group/project")
+		require.NoError(t, err)
+
+		assert.False(t, p.holdDuringQuietHours(sub, hello, time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("post during the quiet hours is dropped", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		sub, err := subscription.New("channel1", "user1", `pipeline,quiet_hours:"19:00-08:00"`, "group/project")
+		require.NoError(t, err)
+
+		assert.True(t, p.holdDuringQuietHours(sub, hello, night))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("post during the quiet hours is held for the summary", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		sub, err := subscription.New("channel1", "user1", `pipeline,quiet_hours:"19:00-08:00",quiet_summary`, "group/project")
+		require.NoError(t, err)
+
+		key := heldPostsKey("channel1", "group/project")
+		first := &pendingDelivery{ChannelID: "channel1", Message: "first", ThreadKey: "issue/1/2"}
+		existing, err := json.Marshal(&heldPosts{ChannelID: "channel1", Repository: "group/project", Posts: []*pendingDelivery{first}})
+		require.NoError(t, err)
+		id := heldPostsID("channel1", "group/project")
+		api.On("KVGet", heldPostsIndexKey).Return([]byte(`{"`+id+`":1}`), nil).Once()
+		api.On("KVSetWithOptions", heldPostsIndexKey, mock.MatchedBy(func(value []byte) bool {
+			var index map[string]int64
+			return json.Unmarshal(value, &index) == nil && assert.ObjectsAreEqual(map[string]int64{id: time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC).UnixMilli()}, index)
+		}), mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+		api.On("KVGet", key).Return(existing, nil).Once()
+		api.On("KVSetWithOptions", key, mock.MatchedBy(func(value []byte) bool {
+			var held heldPosts
+			if json.Unmarshal(value, &held) != nil {
+				return false
+			}
+			return assert.ObjectsAreEqual([]*pendingDelivery{first, hello}, held.Posts) &&
+				held.Until == time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC).UnixMilli()
+		}), mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+
+		assert.True(t, p.holdDuringQuietHours(sub, hello, night))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("post of a channel without subscription is delivered", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+
+		assert.False(t, p.holdDuringQuietHours(nil, hello, night))
+	})
+}
+
+func TestHeldPostsHold(t *testing.T) {
+	running := &pendingDelivery{Message: "running", UpdateKey: "pipeline/1/2", UpdateRevision: 1, Attachment: &model.MessageAttachment{Title: "running"}}
+	pushed := &pendingDelivery{Message: "pushed"}
+	passed := &pendingDelivery{Message: "passed", UpdateKey: "pipeline/1/2", UpdateRevision: 3, Attachment: &model.MessageAttachment{Title: "passed"}}
+	jobPassed := &pendingDelivery{Message: "job passed", UpdateKey: "pipeline/1/2", UpdateRevision: 2}
+
+	held := &heldPosts{}
+	held.hold(running)
+	held.hold(pushed)
+	held.hold(passed)
+	held.hold(jobPassed)
+	assert.Equal(t, []*pendingDelivery{passed, pushed}, held.Posts)
+	assert.Zero(t, held.Skipped)
+
+	for range heldPostsMaxPosts {
+		held.hold(pushed)
+	}
+	assert.Len(t, held.Posts, heldPostsMaxPosts)
+	assert.Equal(t, 2, held.Skipped)
+}
+
+func TestFlushHeldPosts(t *testing.T) {
+	now := time.Date(2024, 3, 7, 8, 0, 30, 0, time.UTC)
+	key := heldPostsKey("channel1", "group/")
+
+	setup := func(t *testing.T, until time.Time) *plugintest.API {
+		api := &plugintest.API{}
+		held, err := json.Marshal(&heldPosts{
+			ChannelID:   "channel1",
+			Repository:  "group/",
+			Description: "19:00-08:00 (UTC)",
+			Until:       until.UnixMilli(),
+			Posts: []*pendingDelivery{
+				{ChannelID: "channel1", Message: "pipeline failed"},
+				{ChannelID: "channel1", Message: "pipeline fixed"},
+			},
+		})
+		require.NoError(t, err)
+		index, err := json.Marshal(map[string]int64{heldPostsID("channel1", "group/"): until.UnixMilli()})
+		require.NoError(t, err)
+		api.On("KVGet", heldPostsIndexKey).Return(index, nil)
+		if !until.After(now) {
+			api.On("KVGet", key).Return(held, nil).Once()
+			// The entry is removed from the index once its posts are posted.
+			api.On("KVSetWithOptions", heldPostsIndexKey, []byte(`{}`), mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+		}
+		return api
+	}
+
+	t.Run("summary of an ended quiet window is posted before the held posts", func(t *testing.T) {
+		api := setup(t, time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC))
+		p := newQuietHoursTestPlugin(api)
+		p.BotUserID = "bot"
+		api.On("KVSetWithOptions", key, isNilBytes, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+		var messages []string
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel1" && post.UserId == "bot"
+		})).Run(func(args mock.Arguments) {
+			messages = append(messages, args.Get(0).(*model.Post).Message)
+		}).Return(&model.Post{}, nil).Times(3)
+
+		p.flushHeldPosts(now)
+		api.AssertExpectations(t)
+		assert.Equal(t, []string{
+			"#### Quiet hours summary\n2 notifications of [group](https://gitlab.example.com/group) were held during the quiet hours of this channel, 19:00-08:00 (UTC).",
+			"pipeline failed",
+			"pipeline fixed",
+		}, messages)
+	})
+
+	t.Run("held post which can't be posted is queued", func(t *testing.T) {
+		api := setup(t, time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC))
+		p := newQuietHoursTestPlugin(api)
+		api.On("KVSetWithOptions", key, isNilBytes, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == "pipeline failed" })).Return(nil, &model.AppError{Message: "down"}).Once()
+		api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Twice()
+		api.On("LogWarn", "can't post held post, queueing it", "channel_id", "channel1", "err", mock.Anything).Once()
+		api.On("KVSetWithOptions", mock.MatchedBy(func(k string) bool { return strings.HasPrefix(k, deliveryQueueKeyPrefix) }), mock.MatchedBy(func(value []byte) bool {
+			var d pendingDelivery
+			return json.Unmarshal(value, &d) == nil && d.ChannelID == "channel1" && d.Message == "pipeline failed"
+		}), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		allowQueueIndexUpdates(api, deliveryQueueIndexKey)
+
+		p.flushHeldPosts(now)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("summary of an ongoing quiet window is kept", func(t *testing.T) {
+		api := setup(t, time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC))
+		p := newQuietHoursTestPlugin(api)
+
+		p.flushHeldPosts(now)
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything)
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+
+	t.Run("posts held meanwhile for a later quiet window stay indexed", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		id := heldPostsID("channel1", "group/")
+		due, err := json.Marshal(map[string]int64{id: time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC).UnixMilli()})
+		require.NoError(t, err)
+		later, err := json.Marshal(map[string]int64{id: time.Date(2024, 3, 8, 8, 0, 0, 0, time.UTC).UnixMilli()})
+		require.NoError(t, err)
+		api.On("KVGet", heldPostsIndexKey).Return(due, nil).Once()
+		api.On("KVGet", key).Return(nil, nil).Once()
+		api.On("KVGet", heldPostsIndexKey).Return(later, nil).Once()
+		api.On("KVSetWithOptions", heldPostsIndexKey, later, mock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+
+		p.flushHeldPosts(now)
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestHeldPostsSummarySkipped(t *testing.T) {
+	p := newQuietHoursTestPlugin(&plugintest.API{})
+	summary := p.heldPostsSummary(&heldPosts{
+		Repository:  "group/project",
+		Description: "weekends (Europe/Paris)",
+		Posts:       []*pendingDelivery{{Message: "deployed"}},
+		Skipped:     3,
+	})
+	assert.Equal(t, "#### Quiet hours summary\n4 notifications of [group/project](https://gitlab.example.com/group/project) were held during the quiet hours of this channel, weekends (Europe/Paris).\n_3 more notifications are not shown._", summary)
+}
@@ -4,150 +4,926 @@
 package subscription
 
 import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	// Embed the timezone database, as the servers running the plugin may not have one.
+	_ "time/tzdata"
 
 	"github.com/pkg/errors"
 )
 
-var allFeatures = map[string]bool{
-	"merges":                 true,
-	"jobs":                   true,
-	"issues":                 true,
-	"pushes":                 true,
-	"issue_comments":         true,
-	"merge_request_comments": true,
-	"pipeline":               true,
-	"tag":                    true,
-	"pull_reviews":           true,
-	"confidential_issues":    true,
-	"deployments":            true,
-	"releases":               true,
-	"merge_request_assigns":  true,
-	// "label:":                 true,//particular case for label:XXX
+// Feature is a kind of GitLab event a subscription notifies about, named as in the subscribe command.
+type Feature string
+
+const (
+	FeatureMerges               Feature = "merges"
+	FeatureJobs                 Feature = "jobs"
+	FeatureIssues               Feature = "issues"
+	FeaturePushes               Feature = "pushes"
+	FeatureIssueComments        Feature = "issue_comments"
+	FeatureMergeRequestComments Feature = "merge_request_comments"
+	FeaturePipeline             Feature = "pipeline"
+	FeaturePipelineFailed       Feature = "pipeline:failed"
+	FeaturePipelineFixed        Feature = "pipeline:fixed"
+	FeatureTag                  Feature = "tag"
+	FeaturePullReviews          Feature = "pull_reviews"
+	FeatureConfidentialIssues   Feature = "confidential_issues"
+	FeatureDeployments          Feature = "deployments"
+	FeatureReleases             Feature = "releases"
+	FeatureMergeRequestAssigns  Feature = "merge_request_assigns"
+	FeatureWiki                 Feature = "wiki"
+	FeatureCommitComments       Feature = "commit_comments"
+	FeatureSnippetComments      Feature = "snippet_comments"
+	FeatureFeatureFlags         Feature = "feature_flags"
+	FeatureMembers              Feature = "members"
+	FeatureReactions            Feature = "reactions"
+	FeatureMilestones           Feature = "milestones"
+	FeatureVulnerabilities      Feature = "vulnerabilities"
+)
+
+// allFeatures lists the known features, in the order they are shown to users.
+var allFeatures = []Feature{
+	FeatureMerges,
+	FeatureJobs,
+	FeatureIssues,
+	FeaturePushes,
+	FeatureIssueComments,
+	FeatureMergeRequestComments,
+	FeaturePipeline,
+	FeaturePipelineFailed,
+	FeaturePipelineFixed,
+	FeatureTag,
+	FeaturePullReviews,
+	FeatureConfidentialIssues,
+	FeatureDeployments,
+	FeatureReleases,
+	FeatureMergeRequestAssigns,
+	FeatureWiki,
+	FeatureCommitComments,
+	FeatureSnippetComments,
+	FeatureFeatureFlags,
+	FeatureMembers,
+	FeatureReactions,
+	FeatureMilestones,
+	FeatureVulnerabilities,
+}
+
+const (
+	labelPrefix           = "label:"
+	allLabelsPrefix       = "label_all:"
+	excludeLabelPrefix    = "-label:"
+	branchPrefix          = "branch:"
+	pathPrefix            = "path:"
+	targetBranchPrefix    = "target_branch:"
+	noDraftsFlag          = "no_drafts"
+	authorPrefix          = "author:"
+	excludeAuthorPrefix   = "exclude_author:"
+	excludeBotsFlag       = "exclude_bots"
+	environmentPrefix     = "environment:"
+	deploymentsPrefix     = "deployments:"
+	quietHoursPrefix      = "quiet_hours:"
+	quietWeekendsFlag     = "quiet_weekends"
+	timezonePrefix        = "timezone:"
+	quietSummaryFlag      = "quiet_summary"
+	threadsFlag           = "threads"
+	vulnerabilitiesPrefix = "vulnerabilities:"
+)
+
+// deploymentStatuses are the finished deployment statuses a deployments:<status> filter can restrict notifications to.
+var deploymentStatuses = []string{"success", "failed", "canceled"}
+
+// botUsernames are the users GitLab creates for its own features, e.g. the alert and service desk bots.
+var botUsernames = map[string]bool{
+	"alert-bot":            true,
+	"support-bot":          true,
+	"visual-review-bot":    true,
+	"ghost":                true,
+	"gitlab-security-bot":  true,
+	"gitlab-admin-bot":     true,
+	"gitlab-migration-bot": true,
+	"automation-bot":       true,
+}
+
+// accessTokenBotUsername matches the users GitLab creates for project and group access tokens, e.g. project_42_bot_1a2b3c.
+var accessTokenBotUsername = regexp.MustCompile(`^(project|group)_\d+_bot(_[0-9a-f]+)?$`)
+
+// IsBot returns true if the username is the one of a GitLab bot user.
+func IsBot(username string) bool {
+	username = strings.ToLower(username)
+	return botUsernames[username] || accessTokenBotUsername.MatchString(username)
+}
+
+// vulnerabilitySeverities ranks the severities GitLab gives to vulnerabilities, from the lowest.
+var vulnerabilitySeverities = map[string]int{
+	"info":     0,
+	"unknown":  1,
+	"low":      2,
+	"medium":   3,
+	"high":     4,
+	"critical": 5,
+}
+
+// Features is the set of features a subscription is enabled for.
+type Features map[Feature]bool
+
+// Filters narrow down the events of the subscribed features.
+type Filters struct {
+	// Labels restricts merge requests and issues to the ones with any of these labels.
+	// A * in a label matches any text, e.g. priority::* matches all the labels of the priority scope.
+	Labels []string `json:",omitempty"`
+	// AllLabels restricts merge requests and issues to the ones with all of these labels.
+	AllLabels []string `json:",omitempty"`
+	// ExcludedLabels drops the merge requests and issues with any of these labels.
+	ExcludedLabels []string `json:",omitempty"`
+	// Branches restricts pushes, pipelines, jobs and tags to the refs matching any of these globs.
+	Branches []string `json:",omitempty"`
+	// TargetBranches restricts merge requests to the ones targeting a branch matching any of these globs.
+	TargetBranches []string `json:",omitempty"`
+	// NoDrafts drops the events of draft merge requests.
+	NoDrafts bool `json:",omitempty"`
+	// Paths restricts pushes and merge requests to the ones changing a file matching any of these globs.
+	Paths []string `json:",omitempty"`
+	// Authors restricts all the events to the ones by a user whose username matches any of these globs.
+	Authors []string `json:",omitempty"`
+	// ExcludedAuthors drops the events by a user whose username matches any of these globs.
+	ExcludedAuthors []string `json:",omitempty"`
+	// ExcludeBots drops the events by the bot users of GitLab.
+	ExcludeBots bool `json:",omitempty"`
+	// Environments restricts deployments to the environments matching any of these globs.
+	Environments []string `json:",omitempty"`
+	// DeploymentStatuses restricts deployments to the ones reaching any of these statuses.
+	DeploymentStatuses []string `json:",omitempty"`
+	// VulnerabilitySeverity is the lowest severity of the notified vulnerabilities.
+	VulnerabilitySeverity string `json:",omitempty"`
+	// QuietHours is the daily HH:MM-HH:MM window during which no event is posted, possibly spanning midnight.
+	QuietHours string `json:",omitempty"`
+	// QuietWeekends mutes the events posted on Saturdays and Sundays.
+	QuietWeekends bool `json:",omitempty"`
+	// Timezone is the IANA name of the timezone of the quiet windows, UTC if empty.
+	Timezone string `json:",omitempty"`
+	// QuietSummary holds the events of the quiet windows and posts them after a summary once they end,
+	// instead of dropping them.
+	QuietSummary bool `json:",omitempty"`
+	// Threads posts the events of a merge request or issue as replies to the first post about it.
+	Threads bool `json:",omitempty"`
 }
 
 type Subscription struct {
 	ChannelID  string
 	CreatorID  string
-	Features   string
+	Features   Features
+	Filters    Filters
 	Repository string
 }
 
-// extractLabels scans a comma-separated feature string for any tokens
-// prefixed with `label:`, unquotes them, and returns all non-empty labels.
+// unquoteFilter unquotes the value of a filter token, e.g. label:"..." or branch:"...", tolerating spaces around the quotes.
 // Valid input examples include:
 //
-//	label:"bug"
-//	label: "test label"
-//	label: " with leading space"
-//	label: "with trailing space "
-//
-// Valid input pattern
+//	"bug"
+//	 "test label"
+//	 " with leading space"
+//	 "with trailing space "
+func unquoteFilter(raw string) (string, bool) {
+	unquoted, err := strconv.Unquote(strings.TrimSpace(raw))
+	return unquoted, err == nil
+}
+
+// Parse reads a comma-separated list of features and filters, as given to the subscribe command, e.g.
 //
-//	label:"bug",label: " with spaces ",label: "test label"
-func extractLabels(features string) ([]string, error) {
-	labels := []string{}
-	for t := range strings.SplitSeq(features, ",") {
-		t = strings.TrimSpace(t)
-		raw, found := strings.CutPrefix(t, "label:")
-		if found {
-			raw = strings.TrimSpace(raw)
-			unquoted, err := strconv.Unquote(raw)
+//	merges,issues,label:"bug",label: "test label"
+//	issues,label:"priority::*",label_all:"backend",-label:"wontfix"
+//	pushes,pipeline:failed,branch:"release/*"
+//	merges,pushes,exclude_author:"renovate*",exclude_bots
+//	merges,pushes,path:"services/billing/**"
+//	merges,target_branch:"main",no_drafts
+//	deployments:success,deployments:failed,environment:"production"
+//	vulnerabilities:high
+//	merges,merge_request_comments,pipeline,threads
+//	pipeline,deployments,quiet_hours:"19:00-08:00",quiet_weekends,timezone:"Europe/Paris",quiet_summary
+func Parse(features string) (Features, Filters, error) {
+	set := Features{}
+	filters := Filters{}
+	badFeatures := make([]string, 0)
+	for token := range strings.SplitSeq(features, ",") {
+		token = strings.TrimSpace(token)
+		if labels, raw, prefix := labelFilter(token, &filters); labels != nil {
+			label, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.Errorf(`each label must be wrapped in quotes, e.g. %s"bug"`, prefix)
+			}
+			if label != "" {
+				*labels = append(*labels, label)
+			}
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, branchPrefix); found {
+			branch, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`each branch must be wrapped in quotes, e.g. branch:"main"`)
+			}
+			if _, err := path.Match(branch, ""); err != nil {
+				return nil, Filters{}, errors.Errorf("invalid branch pattern %q", branch)
+			}
+			if branch != "" {
+				filters.Branches = append(filters.Branches, branch)
+			}
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, targetBranchPrefix); found {
+			branch, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`each target branch must be wrapped in quotes, e.g. target_branch:"main"`)
+			}
+			if _, err := path.Match(branch, ""); err != nil {
+				return nil, Filters{}, errors.Errorf("invalid target branch pattern %q", branch)
+			}
+			if branch != "" {
+				filters.TargetBranches = append(filters.TargetBranches, branch)
+			}
+			continue
+		}
+		if token == noDraftsFlag {
+			filters.NoDrafts = true
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, pathPrefix); found {
+			pattern, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`each path must be wrapped in quotes, e.g. path:"services/billing/**"`)
+			}
+			if !validPathPattern(pattern) {
+				return nil, Filters{}, errors.Errorf("invalid path pattern %q", pattern)
+			}
+			if pattern != "" {
+				filters.Paths = append(filters.Paths, pattern)
+			}
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, authorPrefix); found {
+			author, err := parseAuthorFilter(raw, authorPrefix)
 			if err != nil {
-				return nil, errors.New(`each label must be wrapped in quotes, e.g. label:"bug"`)
+				return nil, Filters{}, err
+			}
+			if author != "" {
+				filters.Authors = append(filters.Authors, author)
+			}
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, excludeAuthorPrefix); found {
+			author, err := parseAuthorFilter(raw, excludeAuthorPrefix)
+			if err != nil {
+				return nil, Filters{}, err
+			}
+			if author != "" {
+				filters.ExcludedAuthors = append(filters.ExcludedAuthors, author)
+			}
+			continue
+		}
+		if token == excludeBotsFlag {
+			filters.ExcludeBots = true
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, environmentPrefix); found {
+			environment, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`each environment must be wrapped in quotes, e.g. environment:"production"`)
+			}
+			if _, err := path.Match(environment, ""); err != nil {
+				return nil, Filters{}, errors.Errorf("invalid environment pattern %q", environment)
+			}
+			if environment != "" {
+				filters.Environments = append(filters.Environments, environment)
 			}
-			if unquoted != "" {
-				labels = append(labels, unquoted)
+			continue
+		}
+		if status, found := strings.CutPrefix(token, deploymentsPrefix); found {
+			if !slices.Contains(deploymentStatuses, status) {
+				return nil, Filters{}, errors.Errorf("unknown deployment status %q, expected one of success, failed or canceled", status)
+			}
+			if !slices.Contains(filters.DeploymentStatuses, status) {
+				filters.DeploymentStatuses = append(filters.DeploymentStatuses, status)
+			}
+			set[FeatureDeployments] = true
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, quietHoursPrefix); found {
+			window, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`quiet hours must be wrapped in quotes, e.g. quiet_hours:"19:00-08:00"`)
+			}
+			if _, _, err := parseQuietHours(window); err != nil {
+				return nil, Filters{}, err
+			}
+			filters.QuietHours = window
+			continue
+		}
+		if token == quietWeekendsFlag {
+			filters.QuietWeekends = true
+			continue
+		}
+		if raw, found := strings.CutPrefix(token, timezonePrefix); found {
+			timezone, ok := unquoteFilter(raw)
+			if !ok {
+				return nil, Filters{}, errors.New(`the timezone must be wrapped in quotes, e.g. timezone:"Europe/Paris"`)
 			}
+			if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+				return nil, Filters{}, errors.Errorf("unknown timezone %q", timezone)
+			}
+			filters.Timezone = timezone
+			continue
+		}
+		if token == quietSummaryFlag {
+			filters.QuietSummary = true
+			continue
+		}
+		if token == threadsFlag {
+			filters.Threads = true
+			continue
+		}
+		if severity, found := strings.CutPrefix(token, vulnerabilitiesPrefix); found {
+			rank, ok := vulnerabilitySeverities[severity]
+			if !ok {
+				return nil, Filters{}, errors.Errorf("unknown vulnerability severity %q, expected one of info, unknown, low, medium, high or critical", severity)
+			}
+			// The lowest of several thresholds wins, as it is the most inclusive one.
+			if filters.VulnerabilitySeverity == "" || rank < vulnerabilitySeverities[filters.VulnerabilitySeverity] {
+				filters.VulnerabilitySeverity = severity
+			}
+			set[FeatureVulnerabilities] = true
+			continue
+		}
+		if !slices.Contains(allFeatures, Feature(token)) {
+			badFeatures = append(badFeatures, token)
+			continue
 		}
+		set[Feature(token)] = true
 	}
-	return labels, nil
+
+	s := &Subscription{Features: set}
+	if (len(filters.Labels) > 0 || len(filters.AllLabels) > 0 || len(filters.ExcludedLabels) > 0) && !s.Merges() && !s.Issues() && !s.ConfidentialIssues() {
+		return nil, Filters{}, errors.New("label filters require 'merges' or 'issues' feature")
+	}
+	if len(filters.Branches) > 0 && !s.Pushes() && !s.Pipeline() && !s.Jobs() && !s.Tag() {
+		return nil, Filters{}, errors.New("branch filters require 'pushes', 'pipeline', 'jobs' or 'tag' feature")
+	}
+	if (len(filters.TargetBranches) > 0 || filters.NoDrafts) && !s.Merges() && !s.MergeRequestAssigns() {
+		return nil, Filters{}, errors.New("target_branch and no_drafts filters require 'merges' or 'merge_request_assigns' feature")
+	}
+	if len(filters.Paths) > 0 && !s.Pushes() && !s.Merges() {
+		return nil, Filters{}, errors.New("path filters require 'pushes' or 'merges' feature")
+	}
+	if len(filters.Environments) > 0 && !s.Deployments() {
+		return nil, Filters{}, errors.New("environment filters require 'deployments' feature")
+	}
+	if (filters.Timezone != "" || filters.QuietSummary) && filters.QuietHours == "" && !filters.QuietWeekends {
+		return nil, Filters{}, errors.New("timezone and quiet_summary require 'quiet_hours' or 'quiet_weekends'")
+	}
+	if filters.Threads && !s.Merges() && !s.Issues() && !s.ConfidentialIssues() && !s.IssueComments() && !s.MergeRequestComments() && !s.Pipeline() {
+		return nil, Filters{}, errors.New("threads require 'merges', 'issues', 'issue_comments', 'merge_request_comments' or 'pipeline' feature")
+	}
+	if len(badFeatures) > 0 {
+		return nil, Filters{}, errors.Errorf("unknown features %s", strings.Join(badFeatures, ","))
+	}
+	return set, filters, nil
 }
 
-func New(channelID, creatorID, features, repository string) (*Subscription, error) {
-	// Validate label format ― allow any number of label tokens, but each must be quoted
-	if strings.Contains(features, "label:") {
-		labels, err := extractLabels(features)
-		if err != nil {
-			return nil, err
-		}
-		if len(labels) > 0 && !strings.Contains(features, "merges") && !strings.Contains(features, "issues") {
-			return nil, errors.New("label filters require 'merges' or 'issues' feature")
+// labelFilter returns the list of filters a label:"...", label_all:"..." or -label:"..." token adds to, with the
+// raw value and the prefix of the token. The list is nil for other tokens.
+func labelFilter(token string, filters *Filters) (labels *[]string, raw string, prefix string) {
+	for _, filter := range []struct {
+		prefix string
+		labels *[]string
+	}{
+		{labelPrefix, &filters.Labels},
+		{allLabelsPrefix, &filters.AllLabels},
+		{excludeLabelPrefix, &filters.ExcludedLabels},
+	} {
+		if raw, found := strings.CutPrefix(token, filter.prefix); found {
+			return filter.labels, raw, filter.prefix
 		}
 	}
+	return nil, "", ""
+}
 
-	badFeatures := make([]string, 0)
-	for feature := range strings.SplitSeq(features, ",") {
-		if _, ok := allFeatures[feature]; !strings.HasPrefix(feature, "label:") && !ok {
-			badFeatures = append(badFeatures, feature)
+// parseQuietHours reads a HH:MM-HH:MM window into the minutes of the day it starts and ends at.
+func parseQuietHours(window string) (start, end int, err error) {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return 0, 0, errors.Errorf("invalid quiet hours %q, expected a range like 19:00-08:00", window)
+	}
+
+	minutes := make([]int, 0, 2)
+	for _, clock := range []string{from, to} {
+		t, err := time.Parse("15:04", strings.TrimSpace(clock))
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid quiet hours %q, expected a range like 19:00-08:00", window)
 		}
+		minutes = append(minutes, t.Hour()*60+t.Minute())
+	}
+	if minutes[0] == minutes[1] {
+		return 0, 0, errors.Errorf("invalid quiet hours %q, the window can't start and end at the same time", window)
 	}
+	return minutes[0], minutes[1], nil
+}
 
-	if len(badFeatures) > 0 {
-		return nil, errors.Errorf("unknown features %s", strings.Join(badFeatures, ","))
+// parseAuthorFilter reads the quoted username glob of an author:"..." or exclude_author:"..." token.
+// Usernames are matched case-insensitively, as GitLab treats them.
+func parseAuthorFilter(raw, prefix string) (string, error) {
+	author, ok := unquoteFilter(raw)
+	if !ok {
+		return "", errors.Errorf(`each author must be wrapped in quotes, e.g. %s"alice"`, prefix)
 	}
+	if _, err := path.Match(author, ""); err != nil {
+		return "", errors.Errorf("invalid author pattern %q", author)
+	}
+	return strings.ToLower(author), nil
+}
+
+func New(channelID, creatorID, features, repository string) (*Subscription, error) {
+	set, filters, err := Parse(features)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Subscription{
 		ChannelID:  channelID,
 		CreatorID:  creatorID,
-		Features:   features,
+		Features:   set,
+		Filters:    filters,
 		Repository: repository,
 	}, nil
 }
 
+// Tokens returns the features and filters of the subscription in the syntax of the subscribe command.
+func (s *Subscription) Tokens() []string {
+	tokens := []string{}
+	for _, feature := range allFeatures {
+		if !s.Features[feature] {
+			continue
+		}
+		if feature == FeatureDeployments && len(s.Filters.DeploymentStatuses) > 0 {
+			for _, status := range s.Filters.DeploymentStatuses {
+				tokens = append(tokens, deploymentsPrefix+status)
+			}
+			continue
+		}
+		if feature == FeatureVulnerabilities && s.Filters.VulnerabilitySeverity != "" {
+			tokens = append(tokens, vulnerabilitiesPrefix+s.Filters.VulnerabilitySeverity)
+			continue
+		}
+		tokens = append(tokens, string(feature))
+	}
+	for _, label := range s.Filters.Labels {
+		tokens = append(tokens, labelPrefix+strconv.Quote(label))
+	}
+	for _, label := range s.Filters.AllLabels {
+		tokens = append(tokens, allLabelsPrefix+strconv.Quote(label))
+	}
+	for _, label := range s.Filters.ExcludedLabels {
+		tokens = append(tokens, excludeLabelPrefix+strconv.Quote(label))
+	}
+	for _, branch := range s.Filters.Branches {
+		tokens = append(tokens, branchPrefix+strconv.Quote(branch))
+	}
+	for _, branch := range s.Filters.TargetBranches {
+		tokens = append(tokens, targetBranchPrefix+strconv.Quote(branch))
+	}
+	if s.Filters.NoDrafts {
+		tokens = append(tokens, noDraftsFlag)
+	}
+	for _, pattern := range s.Filters.Paths {
+		tokens = append(tokens, pathPrefix+strconv.Quote(pattern))
+	}
+	for _, author := range s.Filters.Authors {
+		tokens = append(tokens, authorPrefix+strconv.Quote(author))
+	}
+	for _, author := range s.Filters.ExcludedAuthors {
+		tokens = append(tokens, excludeAuthorPrefix+strconv.Quote(author))
+	}
+	if s.Filters.ExcludeBots {
+		tokens = append(tokens, excludeBotsFlag)
+	}
+	for _, environment := range s.Filters.Environments {
+		tokens = append(tokens, environmentPrefix+strconv.Quote(environment))
+	}
+	if s.Filters.QuietHours != "" {
+		tokens = append(tokens, quietHoursPrefix+strconv.Quote(s.Filters.QuietHours))
+	}
+	if s.Filters.QuietWeekends {
+		tokens = append(tokens, quietWeekendsFlag)
+	}
+	if s.Filters.Timezone != "" {
+		tokens = append(tokens, timezonePrefix+strconv.Quote(s.Filters.Timezone))
+	}
+	if s.Filters.QuietSummary {
+		tokens = append(tokens, quietSummaryFlag)
+	}
+	if s.Filters.Threads {
+		tokens = append(tokens, threadsFlag)
+	}
+	return tokens
+}
+
+// String returns the features and filters of the subscription as they would be given to the subscribe command.
+func (s *Subscription) String() string {
+	return strings.Join(s.Tokens(), ",")
+}
+
 func (s *Subscription) Merges() bool {
-	return strings.Contains(s.Features, "merges")
+	return s.Features[FeatureMerges]
 }
 
 func (s *Subscription) Jobs() bool {
-	return strings.Contains(s.Features, "jobs")
+	return s.Features[FeatureJobs]
 }
 
+// Issues is also true for confidential_issues subscriptions, which have always got the other issues too.
 func (s *Subscription) Issues() bool {
-	return strings.Contains(s.Features, "issues")
+	return s.Features[FeatureIssues] || s.Features[FeatureConfidentialIssues]
 }
 
 func (s *Subscription) ConfidentialIssues() bool {
-	return strings.Contains(s.Features, "confidential_issues")
+	return s.Features[FeatureConfidentialIssues]
 }
 
 func (s *Subscription) Pushes() bool {
-	return strings.Contains(s.Features, "pushes")
+	return s.Features[FeaturePushes]
 }
 
 func (s *Subscription) IssueComments() bool {
-	return strings.Contains(s.Features, "issue_comments")
+	return s.Features[FeatureIssueComments]
 }
 
 func (s *Subscription) MergeRequestComments() bool {
-	return strings.Contains(s.Features, "merge_request_comments")
+	return s.Features[FeatureMergeRequestComments]
 }
 
+// Pipeline returns true if the subscription notifies about pipelines, possibly restricted by pipeline:failed or pipeline:fixed.
 func (s *Subscription) Pipeline() bool {
-	return strings.Contains(s.Features, "pipeline")
+	return s.Features[FeaturePipeline] || s.PipelineFailed() || s.PipelineFixed()
+}
+
+// PipelineFailed returns true if the subscription restricts pipeline notifications to failed pipelines.
+func (s *Subscription) PipelineFailed() bool {
+	return s.Features[FeaturePipelineFailed]
+}
+
+// PipelineFixed returns true if the subscription restricts pipeline notifications to refs going from failed to success.
+func (s *Subscription) PipelineFixed() bool {
+	return s.Features[FeaturePipelineFixed]
 }
 
 func (s *Subscription) Tag() bool {
-	return strings.Contains(s.Features, "tag")
+	return s.Features[FeatureTag]
 }
 
 func (s *Subscription) PullReviews() bool {
-	return strings.Contains(s.Features, "pull_reviews")
+	return s.Features[FeaturePullReviews]
+}
+
+func (s *Subscription) Labels() []string {
+	return s.Filters.Labels
+}
+
+// HasLabelFilters returns true if the subscription filters merge requests and issues by label.
+func (s *Subscription) HasLabelFilters() bool {
+	return len(s.Filters.Labels) > 0 || len(s.Filters.AllLabels) > 0 || len(s.Filters.ExcludedLabels) > 0
 }
 
-func (s *Subscription) Labels() ([]string, error) {
-	labels, err := extractLabels(s.Features)
-	return labels, err
+// MatchesLabels returns true if the labels of a merge request or issue pass the label filters of the subscription:
+// one of them matches any of the label patterns if there is any, each label_all pattern is matched by one of them,
+// and none matches an excluded label pattern.
+func (s *Subscription) MatchesLabels(labels []string) bool {
+	if len(s.Filters.Labels) > 0 && !anyLabelMatches(s.Filters.Labels, labels) {
+		return false
+	}
+	for _, pattern := range s.Filters.AllLabels {
+		if !anyLabelMatches([]string{pattern}, labels) {
+			return false
+		}
+	}
+	return !anyLabelMatches(s.Filters.ExcludedLabels, labels)
+}
+
+func anyLabelMatches(patterns, labels []string) bool {
+	for _, pattern := range patterns {
+		for _, label := range labels {
+			if matchLabel(pattern, label) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchLabel returns true if the label matches the pattern, where * matches any text, including the :: of scoped labels.
+func matchLabel(pattern, label string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == label
+	}
+
+	rest, found := strings.CutPrefix(label, parts[0])
+	if !found {
+		return false
+	}
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(rest, part)
+		if index == -1 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return strings.HasSuffix(rest, last)
+}
+
+// Branches returns the branch globs the subscription is restricted to, if any.
+func (s *Subscription) Branches() []string {
+	return s.Filters.Branches
+}
+
+// MatchesBranch returns true if the subscription has no branch filter or one of its globs matches the branch.
+func (s *Subscription) MatchesBranch(branch string) bool {
+	if len(s.Filters.Branches) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.Filters.Branches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesTargetBranch returns true if the subscription has no target branch filter or one of its globs matches the branch.
+func (s *Subscription) MatchesTargetBranch(branch string) bool {
+	if len(s.Filters.TargetBranches) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.Filters.TargetBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NoDrafts returns true if the subscription leaves out draft merge requests.
+func (s *Subscription) NoDrafts() bool {
+	return s.Filters.NoDrafts
+}
+
+// MatchesPaths returns true if the subscription has no path filter or one of its globs matches one of the changed paths.
+func (s *Subscription) MatchesPaths(changedPaths []string) bool {
+	if len(s.Filters.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.Filters.Paths {
+		for _, changedPath := range changedPaths {
+			if matchPath(pattern, changedPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validPathPattern returns true if every segment of the pattern is a valid path.Match pattern.
+func validPathPattern(pattern string) bool {
+	for segment := range strings.SplitSeq(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPath returns true if the file path matches the pattern, whose ** segments match any number of directories,
+// e.g. services/billing/** matches every file under services/billing, and **/*.go every Go file.
+// The other segments are matched with path.Match.
+func matchPath(pattern, filePath string) bool {
+	return matchPathSegments(strings.Split(pattern, "/"), strings.Split(filePath, "/"))
+}
+
+func matchPathSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchPathSegments(patterns[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(patterns[0], segments[0]); !matched {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// MatchesAuthor returns true if the user who triggered an event passes the author filters of the subscription:
+// they aren't excluded, either by an exclude_author glob or by exclude_bots, and they match one of the author globs
+// if there is any. Events without a known user only pass subscriptions without author globs.
+func (s *Subscription) MatchesAuthor(username string) bool {
+	username = strings.ToLower(username)
+	if username != "" {
+		if s.Filters.ExcludeBots && IsBot(username) {
+			return false
+		}
+		for _, pattern := range s.Filters.ExcludedAuthors {
+			if matched, _ := path.Match(pattern, username); matched {
+				return false
+			}
+		}
+	}
+
+	if len(s.Filters.Authors) == 0 {
+		return true
+	}
+	for _, pattern := range s.Filters.Authors {
+		if matched, _ := path.Match(pattern, username); matched && username != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Subscription) Releases() bool {
-	return strings.Contains(s.Features, "releases")
+	return s.Features[FeatureReleases]
 }
 
 func (s *Subscription) Deployments() bool {
-	return strings.Contains(s.Features, "deployments")
+	return s.Features[FeatureDeployments]
+}
+
+// MatchesEnvironment returns true if the subscription has no environment filter or the environment matches one of its globs.
+func (s *Subscription) MatchesEnvironment(environment string) bool {
+	if len(s.Filters.Environments) == 0 {
+		return true
+	}
+	for _, pattern := range s.Filters.Environments {
+		if matched, _ := path.Match(pattern, environment); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DeploymentStatusMatches returns true if the subscription has no deployments:<status> filter or the status is one of them.
+func (s *Subscription) DeploymentStatusMatches(status string) bool {
+	return len(s.Filters.DeploymentStatuses) == 0 || slices.Contains(s.Filters.DeploymentStatuses, status)
+}
+
+// Threads returns true if the events of a merge request or issue are posted in a single thread.
+func (s *Subscription) Threads() bool {
+	return s.Filters.Threads
+}
+
+// HasQuietHours returns true if the subscription mutes its events during quiet hours or weekends.
+func (s *Subscription) HasQuietHours() bool {
+	return s.Filters.QuietHours != "" || s.Filters.QuietWeekends
+}
+
+// QuietSummary returns true if the events of the quiet windows are held and summarized once they end, rather than dropped.
+func (s *Subscription) QuietSummary() bool {
+	return s.Filters.QuietSummary
+}
+
+// QuietUntil returns the end of the quiet window the time falls in, and false if it is not in any.
+func (s *Subscription) QuietUntil(now time.Time) (time.Time, bool) {
+	if !s.HasQuietHours() {
+		return time.Time{}, false
+	}
+
+	location := time.UTC
+	if s.Filters.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Filters.Timezone); err == nil {
+			location = loc
+		}
+	}
+
+	t := now.In(location)
+	if !s.quietAt(t) {
+		return time.Time{}, false
+	}
+	// Quiet hours can follow a quiet weekend, or the other way around, so skip to the boundaries until both are over.
+	for range 8 {
+		t = s.nextQuietBoundary(t)
+		if !s.quietAt(t) {
+			break
+		}
+	}
+	return t, true
+}
+
+func (s *Subscription) quietAt(t time.Time) bool {
+	if s.Filters.QuietWeekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return true
+	}
+
+	start, end, err := parseQuietHours(s.Filters.QuietHours)
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// nextQuietBoundary returns the first time after t at which a quiet window may end: the next midnight,
+// for quiet weekends, or the next end of the quiet hours.
+func (s *Subscription) nextQuietBoundary(t time.Time) time.Time {
+	year, month, day := t.Date()
+	next := time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+
+	if _, end, err := parseQuietHours(s.Filters.QuietHours); err == nil {
+		endToday := time.Date(year, month, day, end/60, end%60, 0, 0, t.Location())
+		if !endToday.After(t) {
+			endToday = time.Date(year, month, day+1, end/60, end%60, 0, 0, t.Location())
+		}
+		if endToday.Before(next) {
+			next = endToday
+		}
+	}
+	return next
+}
+
+// QuietHoursDescription describes the quiet windows of the subscription, e.g. "19:00-08:00 and weekends (Europe/Paris)".
+func (s *Subscription) QuietHoursDescription() string {
+	windows := []string{}
+	if s.Filters.QuietHours != "" {
+		windows = append(windows, s.Filters.QuietHours)
+	}
+	if s.Filters.QuietWeekends {
+		windows = append(windows, "weekends")
+	}
+	timezone := s.Filters.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(windows, " and "), timezone)
 }
 
 func (s *Subscription) MergeRequestAssigns() bool {
-	return strings.Contains(s.Features, "merge_request_assigns")
+	return s.Features[FeatureMergeRequestAssigns]
+}
+
+func (s *Subscription) Wiki() bool {
+	return s.Features[FeatureWiki]
+}
+
+func (s *Subscription) CommitComments() bool {
+	return s.Features[FeatureCommitComments]
+}
+
+func (s *Subscription) SnippetComments() bool {
+	return s.Features[FeatureSnippetComments]
+}
+
+func (s *Subscription) FeatureFlags() bool {
+	return s.Features[FeatureFeatureFlags]
+}
+
+func (s *Subscription) Members() bool {
+	return s.Features[FeatureMembers]
+}
+
+func (s *Subscription) Reactions() bool {
+	return s.Features[FeatureReactions]
+}
+
+func (s *Subscription) Milestones() bool {
+	return s.Features[FeatureMilestones]
+}
+
+func (s *Subscription) Vulnerabilities() bool {
+	return s.Features[FeatureVulnerabilities]
+}
+
+// VulnerabilitySeverityMatches returns true if the subscription wants vulnerabilities of the severity,
+// that is if it has no vulnerabilities:<severity> filter or the severity is at least the one of the filter.
+func (s *Subscription) VulnerabilitySeverityMatches(severity string) bool {
+	minimum, ok := vulnerabilitySeverities[s.Filters.VulnerabilitySeverity]
+	if !ok {
+		return true
+	}
+
+	rank, known := vulnerabilitySeverities[strings.ToLower(severity)]
+	return known && rank >= minimum
 }
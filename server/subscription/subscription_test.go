@@ -5,6 +5,7 @@ package subscription
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,7 +14,7 @@ import (
 func TestNewSubscriptionSimple(t *testing.T) {
 	s, err := New("", "", "issues", "")
 	assert.Nil(t, err)
-	assert.Equal(t, &Subscription{Features: "issues"}, s)
+	assert.Equal(t, &Subscription{Features: Features{FeatureIssues: true}}, s)
 	assert.True(t, s.Issues())
 	assert.False(t, s.Merges())
 	assert.False(t, s.Pushes())
@@ -22,15 +23,13 @@ func TestNewSubscriptionSimple(t *testing.T) {
 	assert.False(t, s.Pipeline())
 	assert.False(t, s.Tag())
 	assert.False(t, s.PullReviews())
-	labels, err := s.Labels()
-	require.NoError(t, err)
-	assert.Equal(t, []string{}, labels)
+	assert.Empty(t, s.Labels())
 }
 
 func TestNewSubscriptionMultiple(t *testing.T) {
 	s, err := New("", "", "issues,merges", "")
 	assert.Nil(t, err)
-	assert.Equal(t, &Subscription{Features: "issues,merges"}, s)
+	assert.Equal(t, &Subscription{Features: Features{FeatureIssues: true, FeatureMerges: true}}, s)
 	assert.True(t, s.Issues())
 	assert.True(t, s.Merges())
 	assert.False(t, s.Pushes())
@@ -39,15 +38,13 @@ func TestNewSubscriptionMultiple(t *testing.T) {
 	assert.False(t, s.Pipeline())
 	assert.False(t, s.Tag())
 	assert.False(t, s.PullReviews())
-	labels, err := s.Labels()
-	require.NoError(t, err)
-	assert.Equal(t, []string{}, labels)
+	assert.Empty(t, s.Labels())
 }
 
 func TestNewSubscriptionAll(t *testing.T) {
 	s, err := New("", "", "issues,merges,pushes,issue_comments,merge_request_comments,pipeline,tag,pull_reviews", "")
 	assert.Nil(t, err)
-	assert.Equal(t, &Subscription{Features: "issues,merges,pushes,issue_comments,merge_request_comments,pipeline,tag,pull_reviews"}, s)
+	assert.Equal(t, "merges,issues,pushes,issue_comments,merge_request_comments,pipeline,tag,pull_reviews", s.String())
 	assert.True(t, s.Issues())
 	assert.True(t, s.Merges())
 	assert.True(t, s.Pushes())
@@ -56,9 +53,71 @@ func TestNewSubscriptionAll(t *testing.T) {
 	assert.True(t, s.Pipeline())
 	assert.True(t, s.Tag())
 	assert.True(t, s.PullReviews())
-	labels, err := s.Labels()
-	require.NoError(t, err)
-	assert.Equal(t, []string{}, labels)
+	assert.Empty(t, s.Labels())
+}
+
+func TestNewSubscriptionWiki(t *testing.T) {
+	s, err := New("", "", "wiki", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Wiki())
+	assert.False(t, s.Issues())
+	assert.False(t, s.Pushes())
+}
+
+func TestNewSubscriptionCommitAndSnippetComments(t *testing.T) {
+	s, err := New("", "", "commit_comments,snippet_comments", "")
+	assert.Nil(t, err)
+	assert.True(t, s.CommitComments())
+	assert.True(t, s.SnippetComments())
+	assert.False(t, s.IssueComments())
+	assert.False(t, s.MergeRequestComments())
+}
+
+func TestNewSubscriptionFeatureFlags(t *testing.T) {
+	s, err := New("", "", "feature_flags", "")
+	assert.Nil(t, err)
+	assert.True(t, s.FeatureFlags())
+	assert.False(t, s.Releases())
+}
+
+func TestNewSubscriptionMembers(t *testing.T) {
+	s, err := New("", "", "members", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Members())
+	assert.False(t, s.Merges())
+}
+
+func TestNewSubscriptionReactions(t *testing.T) {
+	s, err := New("", "", "merges,reactions", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Reactions())
+	assert.True(t, s.Merges())
+}
+
+func TestNewSubscriptionMilestones(t *testing.T) {
+	s, err := New("", "", "milestones", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Milestones())
+	assert.False(t, s.Members())
+}
+
+func TestNewSubscriptionVulnerabilities(t *testing.T) {
+	s, err := New("", "", "vulnerabilities", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Vulnerabilities())
+	assert.True(t, s.VulnerabilitySeverityMatches("info"))
+
+	s, err = New("", "", "merges,vulnerabilities:high", "")
+	assert.Nil(t, err)
+	assert.True(t, s.Vulnerabilities())
+	assert.False(t, s.VulnerabilitySeverityMatches("medium"))
+	assert.True(t, s.VulnerabilitySeverityMatches("high"))
+	assert.True(t, s.VulnerabilitySeverityMatches("Critical"))
+	assert.False(t, s.VulnerabilitySeverityMatches(""))
+
+	s, err = New("", "", "vulnerabilities:severe", "")
+	assert.Nil(t, s)
+	assert.EqualError(t, err, `unknown vulnerability severity "severe", expected one of info, unknown, low, medium, high or critical`)
 }
 
 func TestNewSubscriptionUnknown(t *testing.T) {
@@ -72,8 +131,7 @@ func TestNewSubscriptionLabel(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, s.Merges())
 	assert.True(t, s.Issues())
-	labels, err := s.Labels()
-	require.NoError(t, err)
+	labels := s.Labels()
 	assert.Equal(t, []string{"test"}, labels)
 }
 
@@ -86,16 +144,14 @@ func TestNewSubscriptionBadFormated(t *testing.T) {
 func TestNewSubscriptionMultipleLabelWithIssues(t *testing.T) {
 	s, err := New("", "", `issues,label:"1",label:"2"`, "")
 	assert.Nil(t, err)
-	labels, err := s.Labels()
-	require.NoError(t, err)
+	labels := s.Labels()
 	assert.ElementsMatch(t, []string{"1", "2"}, labels)
 }
 
 func TestNewSubscriptionMultipleLabelWithMerges(t *testing.T) {
 	s, err := New("", "", `merges,label:"1",label:"2"`, "")
 	assert.Nil(t, err)
-	labels, err := s.Labels()
-	require.NoError(t, err)
+	labels := s.Labels()
 	assert.ElementsMatch(t, []string{"1", "2"}, labels)
 }
 
@@ -107,7 +163,396 @@ func TestNewSubscriptionMultipleLabelWithoutIssuesMerges(t *testing.T) {
 func TestNewSubscriptionMultipleLabelWithSpaces(t *testing.T) {
 	s, err := New("", "", `merges,label: "1",label: "2"`, "")
 	assert.Nil(t, err)
-	labels, err := s.Labels()
-	require.NoError(t, err)
+	labels := s.Labels()
 	assert.ElementsMatch(t, []string{"1", "2"}, labels)
 }
+
+func TestNewSubscriptionLabelFilters(t *testing.T) {
+	s, err := New("", "", `issues,label:"priority::*",label_all:"backend",label_all:"bug",-label:"wontfix"`, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"priority::*"}, s.Filters.Labels)
+	assert.Equal(t, []string{"backend", "bug"}, s.Filters.AllLabels)
+	assert.Equal(t, []string{"wontfix"}, s.Filters.ExcludedLabels)
+	assert.True(t, s.HasLabelFilters())
+	assert.True(t, s.MatchesLabels([]string{"priority::high", "backend", "bug"}))
+	assert.False(t, s.MatchesLabels([]string{"backend", "bug"}))
+	assert.False(t, s.MatchesLabels([]string{"priority::high", "backend"}))
+	assert.False(t, s.MatchesLabels([]string{"priority::high", "backend", "bug", "wontfix"}))
+
+	s, err = New("", "", `merges,-label:"wip"`, "")
+	require.NoError(t, err)
+	assert.True(t, s.MatchesLabels(nil))
+	assert.False(t, s.MatchesLabels([]string{"wip"}))
+
+	s, err = New("", "", "merges", "")
+	require.NoError(t, err)
+	assert.False(t, s.HasLabelFilters())
+	assert.True(t, s.MatchesLabels(nil))
+
+	_, err = New("", "", `pushes,-label:"wip"`, "")
+	assert.EqualError(t, err, "label filters require 'merges' or 'issues' feature")
+
+	_, err = New("", "", `issues,label_all:bug`, "")
+	assert.EqualError(t, err, `each label must be wrapped in quotes, e.g. label_all:"bug"`)
+}
+
+func TestMatchLabel(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		label    string
+		expected bool
+	}{
+		{pattern: "bug", label: "bug", expected: true},
+		{pattern: "bug", label: "bugs", expected: false},
+		{pattern: "priority::*", label: "priority::high", expected: true},
+		{pattern: "priority::*", label: "priority", expected: false},
+		{pattern: "*::high", label: "severity::high", expected: true},
+		{pattern: "team::*::backend", label: "team::billing::backend", expected: true},
+		{pattern: "team::*::backend", label: "team::backend", expected: false},
+		{pattern: "*", label: "anything", expected: true},
+	} {
+		assert.Equal(t, test.expected, matchLabel(test.pattern, test.label), "%s %s", test.pattern, test.label)
+	}
+}
+
+func TestNewSubscriptionBranch(t *testing.T) {
+	s, err := New("", "", `pushes,branch:"main",branch:"release/*"`, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main", "release/*"}, s.Branches())
+	assert.True(t, s.MatchesBranch("main"))
+	assert.True(t, s.MatchesBranch("release/1.0"))
+	assert.False(t, s.MatchesBranch("feature/foo"))
+	assert.False(t, s.MatchesBranch("release/1.0/hotfix"))
+
+	s, err = New("", "", "pushes", "")
+	require.NoError(t, err)
+	assert.True(t, s.MatchesBranch("anything"))
+
+	_, err = New("", "", `pushes,branch:main`, "")
+	assert.EqualError(t, err, `each branch must be wrapped in quotes, e.g. branch:"main"`)
+
+	_, err = New("", "", `issues,branch:"main"`, "")
+	assert.EqualError(t, err, "branch filters require 'pushes', 'pipeline', 'jobs' or 'tag' feature")
+}
+
+func TestNewSubscriptionTargetBranchAndNoDrafts(t *testing.T) {
+	s, err := New("", "", `merges,target_branch:"main",target_branch:"release/*",no_drafts`, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main", "release/*"}, s.Filters.TargetBranches)
+	assert.True(t, s.NoDrafts())
+	assert.True(t, s.Merges())
+	assert.True(t, s.MatchesTargetBranch("main"))
+	assert.True(t, s.MatchesTargetBranch("release/2.0"))
+	assert.False(t, s.MatchesTargetBranch("feature/foo"))
+
+	s, err = New("", "", "merges", "")
+	require.NoError(t, err)
+	assert.False(t, s.NoDrafts())
+	assert.True(t, s.MatchesTargetBranch("anything"))
+
+	_, err = New("", "", `merges,target_branch:main`, "")
+	assert.EqualError(t, err, `each target branch must be wrapped in quotes, e.g. target_branch:"main"`)
+
+	_, err = New("", "", `merges,target_branch:"[a"`, "")
+	assert.EqualError(t, err, `invalid target branch pattern "[a"`)
+
+	_, err = New("", "", "pushes,no_drafts", "")
+	assert.EqualError(t, err, "target_branch and no_drafts filters require 'merges' or 'merge_request_assigns' feature")
+}
+
+func TestNewSubscriptionPath(t *testing.T) {
+	s, err := New("", "", `merges,pushes,path:"services/billing/**",path:"**/*.proto"`, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"services/billing/**", "**/*.proto"}, s.Filters.Paths)
+	assert.True(t, s.MatchesPaths([]string{"README.md", "services/billing/api/invoice.go"}))
+	assert.True(t, s.MatchesPaths([]string{"api/v1/billing.proto"}))
+	assert.True(t, s.MatchesPaths([]string{"billing.proto"}))
+	assert.False(t, s.MatchesPaths([]string{"services/search/main.go", "services/billing.go"}))
+	assert.False(t, s.MatchesPaths(nil))
+
+	s, err = New("", "", "pushes", "")
+	require.NoError(t, err)
+	assert.True(t, s.MatchesPaths(nil))
+
+	_, err = New("", "", `pushes,path:services/**`, "")
+	assert.EqualError(t, err, `each path must be wrapped in quotes, e.g. path:"services/billing/**"`)
+
+	_, err = New("", "", `pushes,path:"services/[a"`, "")
+	assert.EqualError(t, err, `invalid path pattern "services/[a"`)
+
+	_, err = New("", "", `issues,path:"services/**"`, "")
+	assert.EqualError(t, err, "path filters require 'pushes' or 'merges' feature")
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		filePath string
+		expected bool
+	}{
+		{pattern: "README.md", filePath: "README.md", expected: true},
+		{pattern: "*.md", filePath: "docs/README.md", expected: false},
+		{pattern: "docs/*.md", filePath: "docs/README.md", expected: true},
+		{pattern: "docs/*", filePath: "docs/api/README.md", expected: false},
+		{pattern: "docs/**", filePath: "docs/api/README.md", expected: true},
+		{pattern: "docs/**/README.md", filePath: "docs/README.md", expected: true},
+		{pattern: "docs/**/README.md", filePath: "docs/a/b/README.md", expected: true},
+		{pattern: "docs/**/README.md", filePath: "docs/a/b/INDEX.md", expected: false},
+		{pattern: "**", filePath: "any/file", expected: true},
+	} {
+		t.Run(test.pattern+" "+test.filePath, func(t *testing.T) {
+			assert.Equal(t, test.expected, matchPath(test.pattern, test.filePath))
+		})
+	}
+}
+
+func TestNewSubscriptionAuthor(t *testing.T) {
+	s, err := New("", "", `merges,pushes,author:"Alice",author:"ops-*",exclude_author:"ops-bot"`, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "ops-*"}, s.Filters.Authors)
+	assert.Equal(t, []string{"ops-bot"}, s.Filters.ExcludedAuthors)
+	assert.True(t, s.MatchesAuthor("alice"))
+	assert.True(t, s.MatchesAuthor("ALICE"))
+	assert.True(t, s.MatchesAuthor("ops-jane"))
+	assert.False(t, s.MatchesAuthor("ops-bot"))
+	assert.False(t, s.MatchesAuthor("bob"))
+	assert.False(t, s.MatchesAuthor(""))
+
+	s, err = New("", "", `merges,exclude_author:"renovate*"`, "")
+	require.NoError(t, err)
+	assert.True(t, s.MatchesAuthor("alice"))
+	assert.True(t, s.MatchesAuthor(""))
+	assert.False(t, s.MatchesAuthor("renovate-bot"))
+
+	s, err = New("", "", "merges", "")
+	require.NoError(t, err)
+	assert.True(t, s.MatchesAuthor("project_42_bot_1a2b3c"))
+
+	_, err = New("", "", `merges,author:alice`, "")
+	assert.EqualError(t, err, `each author must be wrapped in quotes, e.g. author:"alice"`)
+
+	_, err = New("", "", `merges,exclude_author:alice`, "")
+	assert.EqualError(t, err, `each author must be wrapped in quotes, e.g. exclude_author:"alice"`)
+
+	_, err = New("", "", `merges,author:"[a"`, "")
+	assert.EqualError(t, err, `invalid author pattern "[a"`)
+}
+
+func TestNewSubscriptionExcludeBots(t *testing.T) {
+	s, err := New("", "", "merges,exclude_bots", "")
+	require.NoError(t, err)
+	assert.True(t, s.Filters.ExcludeBots)
+	assert.True(t, s.Merges())
+
+	for _, username := range []string{"project_42_bot", "project_42_bot_1a2b3c", "group_7_bot_ff00", "support-bot", "Alert-Bot", "ghost"} {
+		assert.False(t, s.MatchesAuthor(username), username)
+	}
+	for _, username := range []string{"alice", "project_42_bottle", "my_project_42_bot", ""} {
+		assert.True(t, s.MatchesAuthor(username), username)
+	}
+}
+
+func TestNewSubscriptionPipelineStatusFilters(t *testing.T) {
+	s, err := New("", "", "pipeline:failed", "")
+	require.NoError(t, err)
+	assert.True(t, s.Pipeline())
+	assert.True(t, s.PipelineFailed())
+	assert.False(t, s.PipelineFixed())
+
+	s, err = New("", "", "pipeline:fixed", "")
+	require.NoError(t, err)
+	assert.True(t, s.Pipeline())
+	assert.False(t, s.PipelineFailed())
+	assert.True(t, s.PipelineFixed())
+
+	s, err = New("", "", "pipeline", "")
+	require.NoError(t, err)
+	assert.False(t, s.PipelineFailed())
+	assert.False(t, s.PipelineFixed())
+}
+
+func TestNewSubscriptionDeploymentFilters(t *testing.T) {
+	s, err := New("", "", `deployments:success,deployments:failed,deployments:success,environment:"prod*"`, "")
+	require.NoError(t, err)
+	assert.True(t, s.Deployments())
+	assert.Equal(t, []string{"success", "failed"}, s.Filters.DeploymentStatuses)
+	assert.Equal(t, []string{"prod*"}, s.Filters.Environments)
+	assert.True(t, s.DeploymentStatusMatches("success"))
+	assert.False(t, s.DeploymentStatusMatches("running"))
+	assert.True(t, s.MatchesEnvironment("production"))
+	assert.False(t, s.MatchesEnvironment("staging"))
+
+	s, err = New("", "", "deployments", "")
+	require.NoError(t, err)
+	assert.True(t, s.DeploymentStatusMatches("running"))
+	assert.True(t, s.MatchesEnvironment("review/feature-1"))
+
+	_, err = New("", "", "deployments:running", "")
+	assert.EqualError(t, err, `unknown deployment status "running", expected one of success, failed or canceled`)
+
+	_, err = New("", "", `pushes,environment:"production"`, "")
+	assert.EqualError(t, err, "environment filters require 'deployments' feature")
+
+	_, err = New("", "", `deployments,environment:production`, "")
+	assert.EqualError(t, err, `each environment must be wrapped in quotes, e.g. environment:"production"`)
+}
+
+func TestNewSubscriptionQuietHours(t *testing.T) {
+	s, err := New("", "", `pipeline,quiet_hours:"19:00-08:00",quiet_weekends,timezone:"Europe/Paris",quiet_summary`, "")
+	require.NoError(t, err)
+	assert.Equal(t, "19:00-08:00", s.Filters.QuietHours)
+	assert.True(t, s.Filters.QuietWeekends)
+	assert.Equal(t, "Europe/Paris", s.Filters.Timezone)
+	assert.True(t, s.HasQuietHours())
+	assert.True(t, s.QuietSummary())
+	assert.Equal(t, "19:00-08:00 and weekends (Europe/Paris)", s.QuietHoursDescription())
+
+	s, err = New("", "", "pipeline", "")
+	require.NoError(t, err)
+	assert.False(t, s.HasQuietHours())
+	_, quiet := s.QuietUntil(time.Now())
+	assert.False(t, quiet)
+
+	for features, expected := range map[string]string{
+		`pipeline,quiet_hours:19:00-08:00`:        `quiet hours must be wrapped in quotes, e.g. quiet_hours:"19:00-08:00"`,
+		`pipeline,quiet_hours:"19:00"`:            `invalid quiet hours "19:00", expected a range like 19:00-08:00`,
+		`pipeline,quiet_hours:"25:00-08:00"`:      `invalid quiet hours "25:00-08:00", expected a range like 19:00-08:00`,
+		`pipeline,quiet_hours:"08:00-08:00"`:      `invalid quiet hours "08:00-08:00", the window can't start and end at the same time`,
+		`pipeline,quiet_weekends,timezone:"Mars"`: `unknown timezone "Mars"`,
+		`pipeline,timezone:"Europe/Paris"`:        "timezone and quiet_summary require 'quiet_hours' or 'quiet_weekends'",
+		`pipeline,quiet_summary`:                  "timezone and quiet_summary require 'quiet_hours' or 'quiet_weekends'",
+	} {
+		_, err := New("", "", features, "")
+		assert.EqualError(t, err, expected, features)
+	}
+}
+
+func TestNewSubscriptionThreads(t *testing.T) {
+	s, err := New("", "", "merges,issue_comments,threads", "")
+	require.NoError(t, err)
+	assert.True(t, s.Threads())
+
+	s, err = New("", "", "merges", "")
+	require.NoError(t, err)
+	assert.False(t, s.Threads())
+
+	_, err = New("", "", "tag,threads", "")
+	assert.EqualError(t, err, "threads require 'merges', 'issues', 'issue_comments', 'merge_request_comments' or 'pipeline' feature")
+}
+
+func TestSubscriptionQuietUntil(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		features string
+		now      time.Time
+		until    time.Time
+		quiet    bool
+	}{
+		{
+			name:     "during working hours",
+			features: `pipeline,quiet_hours:"19:00-08:00"`,
+			now:      time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "evening of a window spanning midnight",
+			features: `pipeline,quiet_hours:"19:00-08:00"`,
+			now:      time.Date(2024, 3, 6, 21, 30, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC),
+			quiet:    true,
+		},
+		{
+			name:     "morning of a window spanning midnight",
+			features: `pipeline,quiet_hours:"19:00-08:00"`,
+			now:      time.Date(2024, 3, 7, 7, 59, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC),
+			quiet:    true,
+		},
+		{
+			name:     "window within a day",
+			features: `pipeline,quiet_hours:"12:00-14:00"`,
+			now:      time.Date(2024, 3, 6, 13, 0, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 6, 14, 0, 0, 0, time.UTC),
+			quiet:    true,
+		},
+		{
+			name:     "window in the timezone of the subscription",
+			features: `pipeline,quiet_hours:"19:00-08:00",timezone:"Europe/Paris"`,
+			now:      time.Date(2024, 3, 6, 18, 30, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 7, 8, 0, 0, 0, paris),
+			quiet:    true,
+		},
+		{
+			name:     "weekend",
+			features: `pipeline,quiet_weekends`,
+			now:      time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			quiet:    true,
+		},
+		{
+			name:     "friday evening until the end of the quiet hours of monday",
+			features: `pipeline,quiet_hours:"19:00-08:00",quiet_weekends`,
+			now:      time.Date(2024, 3, 8, 20, 0, 0, 0, time.UTC),
+			until:    time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC),
+			quiet:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := New("", "", test.features, "")
+			require.NoError(t, err)
+
+			until, quiet := s.QuietUntil(test.now)
+			assert.Equal(t, test.quiet, quiet)
+			assert.True(t, test.until.Equal(until), "expected %s, got %s", test.until, until)
+		})
+	}
+}
+
+func TestNewSubscriptionFeaturesAreNotMatchedInsideFilters(t *testing.T) {
+	s, err := New("", "", `issues,label:"merges-needed"`, "")
+	require.NoError(t, err)
+	assert.True(t, s.Issues())
+	assert.False(t, s.Merges())
+	assert.Equal(t, []string{"merges-needed"}, s.Labels())
+
+	s, err = New("", "", "confidential_issues", "")
+	require.NoError(t, err)
+	assert.True(t, s.ConfidentialIssues())
+	assert.True(t, s.Issues())
+
+	s, err = New("", "", "issues", "")
+	require.NoError(t, err)
+	assert.False(t, s.ConfidentialIssues())
+	assert.True(t, s.Issues())
+}
+
+func TestSubscriptionString(t *testing.T) {
+	for _, test := range []struct {
+		features string
+		expected string
+	}{
+		{features: "issues,merges", expected: "merges,issues"},
+		{features: `merges,label: "test label",label:"bug"`, expected: `merges,label:"test label",label:"bug"`},
+		{features: `issues,-label:"wontfix",label_all:"backend",label:"priority::*"`, expected: `issues,label:"priority::*",label_all:"backend",-label:"wontfix"`},
+		{features: `pushes,pipeline:failed,branch:"release/*"`, expected: `pushes,pipeline:failed,branch:"release/*"`},
+		{features: "vulnerabilities:high,vulnerabilities:medium", expected: "vulnerabilities:medium"},
+		{features: `no_drafts,merges,target_branch:"main"`, expected: `merges,target_branch:"main",no_drafts`},
+		{features: `quiet_summary,timezone:"UTC",quiet_weekends,pipeline`, expected: `pipeline,quiet_weekends,timezone:"UTC",quiet_summary`},
+		{features: "threads,issues,merges", expected: "merges,issues,threads"},
+		{features: `environment:"production",deployments:failed,deployments:canceled`, expected: `deployments:failed,deployments:canceled,environment:"production"`},
+		{features: `pushes,path:"services/**"`, expected: `pushes,path:"services/**"`},
+		{features: `exclude_bots,merges,exclude_author:"renovate*",author:"Ops-*"`, expected: `merges,author:"ops-*",exclude_author:"renovate*",exclude_bots`},
+	} {
+		t.Run(test.features, func(t *testing.T) {
+			s, err := New("", "", test.features, "")
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, s.String())
+
+			parsed, err := New("", "", s.String(), "")
+			require.NoError(t, err)
+			assert.Equal(t, s, parsed)
+		})
+	}
+}
@@ -5,7 +5,16 @@ package main
 
 import (
 	"context"
-
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
@@ -13,130 +22,207 @@ import (
 )
 
 const (
-	SubscriptionsKey = "subscriptions"
+	// subscriptionsRepositoryKeyPrefix prefixes the KV keys storing the subscriptions of a repository or namespace.
+	subscriptionsRepositoryKeyPrefix = "subscriptions_repository_"
+	// subscriptionsChannelKeyPrefix prefixes the KV keys indexing the repositories and namespaces a channel is subscribed to.
+	subscriptionsChannelKeyPrefix = "subscriptions_channel_"
+	// subscriptionsMigratedKey is set once the subscriptions of the single value stores below are copied to the keys above.
+	subscriptionsMigratedKey = "subscriptions_migrated"
+
+	// unshardedSubscriptionsKey stores all the subscriptions in a single value, before they were split by repository.
+	unshardedSubscriptionsKey = "subscriptions_v2"
+	// legacySubscriptionsKey stores all the subscriptions in a single value, before they had typed features.
+	legacySubscriptionsKey = "subscriptions"
+
+	subscriptionsKeysPerPage = 1000
 )
 
+// errNothingToRemove aborts a compare-and-set removal when the value doesn't have what should be removed.
+var errNothingToRemove = errors.New("nothing to remove")
+
+// Subscriptions groups subscriptions by repository or namespace path, as stored under unshardedSubscriptionsKey.
 type Subscriptions struct {
 	Repositories map[string][]*subscription.Subscription
 }
 
-func (p *Plugin) Subscribe(info *gitlab.UserInfo, namespace, project, channelID, features string) (*Subscriptions, error) {
+// legacySubscription is a subscription as stored under legacySubscriptionsKey,
+// with its features and filters in the comma-separated syntax of the subscribe command.
+type legacySubscription struct {
+	ChannelID  string
+	CreatorID  string
+	Features   string
+	Repository string
+}
+
+type legacySubscriptions struct {
+	Repositories map[string][]*legacySubscription
+}
+
+// repositorySubscriptionsKey hashes the path, as GitLab paths can be longer than what fits in a KV key.
+func repositorySubscriptionsKey(fullPath string) string {
+	hash := sha256.Sum256([]byte(fullPath))
+	return subscriptionsRepositoryKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+func channelSubscriptionsKey(channelID string) string {
+	return subscriptionsChannelKeyPrefix + channelID
+}
+
+func (p *Plugin) Subscribe(info *gitlab.UserInfo, namespace, project, channelID, features string) error {
 	if err := p.isNamespaceAllowed(namespace); err != nil {
-		return nil, err
+		return err
 	}
 
 	fullPath := fullPathFromNamespaceAndProject(namespace, project)
 	sub, err := subscription.New(channelID, info.UserID, features, fullPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	subs, err := p.AddSubscription(fullPath, sub)
-	if err != nil {
-		return nil, err
+	if project != "" && sub.Members() {
+		return errors.New("the members feature is only available for group subscriptions")
 	}
 
-	return subs, nil
+	return p.AddSubscription(fullPath, sub)
 }
 
+// GetSubscriptionsByChannel returns the subscriptions of the channel, looking up the repositories of its index.
 func (p *Plugin) GetSubscriptionsByChannel(channelID string) ([]*subscription.Subscription, error) {
-	subs, err := p.GetSubscriptions()
-	if err != nil {
+	var fullPaths []string
+	if err := p.client.KV.Get(channelSubscriptionsKey(channelID), &fullPaths); err != nil {
+		p.client.Log.Warn("can't get channel subscriptions from kvstore", "channel_id", channelID, "err", err.Error())
 		return nil, err
 	}
 
-	return filterSubscriptionsByChannel(subs, channelID), nil
-}
-
-func filterSubscriptionsByChannel(subs *Subscriptions, channelID string) []*subscription.Subscription {
-	var filteredSubs []*subscription.Subscription
-
-	for _, v := range subs.Repositories {
-		for _, s := range v {
-			if s.ChannelID == channelID {
-				filteredSubs = append(filteredSubs, s)
+	var subs []*subscription.Subscription
+	for _, fullPath := range fullPaths {
+		repoSubs, err := p.getRepositorySubscriptions(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		// The index can point to a repository the channel isn't subscribed to anymore if an unsubscription was interrupted.
+		for _, sub := range repoSubs {
+			if sub.ChannelID == channelID {
+				subs = append(subs, sub)
 			}
 		}
 	}
 
-	return filteredSubs
+	return subs, nil
+}
+
+// AddSubscription stores the subscription of the repository or namespace, replacing the one of the same channel if any.
+func (p *Plugin) AddSubscription(fullPath string, sub *subscription.Subscription) error {
+	return p.storeSubscription(fullPath, sub, true)
 }
 
-func (p *Plugin) AddSubscription(fullPath string, sub *subscription.Subscription) (*Subscriptions, error) {
-	subs, err := p.GetSubscriptions()
+func (p *Plugin) storeSubscription(fullPath string, sub *subscription.Subscription, replace bool) error {
+	err := p.client.KV.SetAtomicWithRetries(repositorySubscriptionsKey(fullPath), func(oldValue []byte) (any, error) {
+		repoSubs, err := decodeSubscriptions(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		index := slices.IndexFunc(repoSubs, func(s *subscription.Subscription) bool { return s.ChannelID == sub.ChannelID })
+		switch {
+		case index == -1:
+			repoSubs = append(repoSubs, sub)
+		case replace:
+			repoSubs[index] = sub
+		}
+		return repoSubs, nil
+	})
 	if err != nil {
-		return nil, err
+		p.client.Log.Warn("can't set subscriptions in kvstore", "err", err.Error())
+		return err
 	}
 
-	repoSubs := subs.Repositories[fullPath]
-	if repoSubs == nil {
-		repoSubs = []*subscription.Subscription{sub}
-	} else {
-		exists := false
-		for index, s := range repoSubs {
-			if s.ChannelID == sub.ChannelID {
-				repoSubs[index] = sub
-				exists = true
-				break
+	err = p.client.KV.SetAtomicWithRetries(channelSubscriptionsKey(sub.ChannelID), func(oldValue []byte) (any, error) {
+		var fullPaths []string
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &fullPaths); err != nil {
+				return nil, err
 			}
 		}
+		if !slices.Contains(fullPaths, fullPath) {
+			fullPaths = append(fullPaths, fullPath)
+		}
+		return fullPaths, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("can't set channel subscriptions in kvstore", "err", err.Error())
+		return err
+	}
 
-		if !exists {
-			repoSubs = append(repoSubs, sub)
+	return nil
+}
+
+func decodeSubscriptions(value []byte) ([]*subscription.Subscription, error) {
+	var subs []*subscription.Subscription
+	if value != nil {
+		if err := json.Unmarshal(value, &subs); err != nil {
+			return nil, err
 		}
 	}
+	return subs, nil
+}
 
-	subs.Repositories[fullPath] = repoSubs
-	return subs, p.StoreSubscriptions(subs)
+func (p *Plugin) getRepositorySubscriptions(fullPath string) ([]*subscription.Subscription, error) {
+	var subs []*subscription.Subscription
+	if err := p.client.KV.Get(repositorySubscriptionsKey(fullPath), &subs); err != nil {
+		p.client.Log.Warn("can't get subscriptions from kvstore", "repository", fullPath, "err", err.Error())
+		return nil, err
+	}
+	return subs, nil
 }
 
+// GetSubscriptions returns the subscriptions of all the repositories and namespaces.
+// It reads every repository key, so it is meant for rare maintenance tasks, not for handling events.
 func (p *Plugin) GetSubscriptions() (*Subscriptions, error) {
-	var subscriptions *Subscriptions
-
-	err := p.client.KV.Get(SubscriptionsKey, &subscriptions)
+	keys, err := listKeysWithPrefix(p.client, subscriptionsRepositoryKeyPrefix, subscriptionsKeysPerPage)
 	if err != nil {
-		p.client.Log.Warn("can't get subscriptions from kvstore", "err", err.Error())
+		p.client.Log.Warn("can't list subscriptions keys from kvstore", "err", err.Error())
 		return nil, err
 	}
 
-	if subscriptions == nil {
-		subscriptions = &Subscriptions{Repositories: map[string][]*subscription.Subscription{}}
+	subscriptions := &Subscriptions{Repositories: map[string][]*subscription.Subscription{}}
+	for _, key := range keys {
+		var subs []*subscription.Subscription
+		if err := p.client.KV.Get(key, &subs); err != nil {
+			p.client.Log.Warn("can't get subscriptions from kvstore", "err", err.Error())
+			return nil, err
+		}
+		// The key is a hash, the path is only known from the subscriptions, which are all made on it.
+		if len(subs) > 0 {
+			subscriptions.Repositories[subs[0].Repository] = subs
+		}
 	}
 
 	return subscriptions, nil
 }
 
-func (p *Plugin) StoreSubscriptions(s *Subscriptions) error {
-	if _, err := p.client.KV.Set(SubscriptionsKey, s); err != nil {
-		p.client.Log.Warn("can't set subscriptions in kvstore", "err", err.Error())
-	}
-	return nil
-}
-
 func (p *Plugin) GetSubscribedChannelsForProject(
 	ctx context.Context,
 	namespace string,
 	project string,
 	isPublicVisibility bool,
 ) []*subscription.Subscription {
-	var subsForRepo []*subscription.Subscription
-
-	subs, err := p.GetSubscriptions()
+	// Add subscriptions for the specific repo
+	fullPath := fullPathFromNamespaceAndProject(namespace, project)
+	subsForRepo, err := p.getRepositorySubscriptions(fullPath)
 	if err != nil {
 		p.client.Log.Warn("can't retrieve subscriptions", "err", err.Error())
 		return nil
 	}
 
-	// Add subscriptions for the specific repo
-	fullPath := fullPathFromNamespaceAndProject(namespace, project)
-	if subs.Repositories[fullPath] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[fullPath]...)
-	}
-
 	// Add subscriptions for the namespace
 	namespacePath := fullPathFromNamespaceAndProject(namespace, "")
-	if namespacePath != fullPath && subs.Repositories[namespacePath] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[namespacePath]...)
+	if namespacePath != fullPath {
+		subsForNamespace, err := p.getRepositorySubscriptions(namespacePath)
+		if err != nil {
+			p.client.Log.Warn("can't retrieve subscriptions", "err", err.Error())
+			return nil
+		}
+		subsForRepo = append(subsForRepo, subsForNamespace...)
 	}
 
 	if len(subsForRepo) == 0 {
@@ -154,48 +240,218 @@ func (p *Plugin) GetSubscribedChannelsForProject(
 	return subsToReturn
 }
 
+// GetSubscribedChannelsForGroup returns the subscriptions of the group itself whose creator can see it.
+func (p *Plugin) GetSubscribedChannelsForGroup(ctx context.Context, groupPath string) []*subscription.Subscription {
+	subsForGroup, err := p.getRepositorySubscriptions(fullPathFromNamespaceAndProject(groupPath, ""))
+	if err != nil {
+		p.client.Log.Warn("can't retrieve subscriptions", "err", err.Error())
+		return nil
+	}
+	if len(subsForGroup) == 0 {
+		return nil
+	}
+
+	subsToReturn := make([]*subscription.Subscription, 0, len(subsForGroup))
+	for _, sub := range subsForGroup {
+		if !p.permissionToGroup(ctx, sub.CreatorID, groupPath) {
+			continue
+		}
+		subsToReturn = append(subsToReturn, sub)
+	}
+
+	return subsToReturn
+}
+
 // Unsubscribe deletes the link between namespace/project and channelID.
 // Returns true if subscription was found, false otherwise.
-func (p *Plugin) Unsubscribe(channelID string, fullPath string) (bool, *Subscriptions, error) {
+func (p *Plugin) Unsubscribe(channelID string, fullPath string) (bool, error) {
 	if fullPath == "" {
-		return false, nil, errors.New("invalid repository")
-	}
-
-	subs, err := p.GetSubscriptions()
-	if err != nil {
-		return false, nil, err
+		return false, errors.New("invalid repository")
 	}
 
 	var removed bool
 
 	// We don't know whether fullPath is a namespace or project, so we have to check both cases
 	for _, path := range []string{fullPath, fullPath + "/"} {
-		pathSubs := subs.Repositories[path]
-		if pathSubs == nil {
+		err := p.client.KV.SetAtomicWithRetries(repositorySubscriptionsKey(path), func(oldValue []byte) (any, error) {
+			pathSubs, err := decodeSubscriptions(oldValue)
+			if err != nil {
+				return nil, err
+			}
+
+			index := slices.IndexFunc(pathSubs, func(s *subscription.Subscription) bool { return s.ChannelID == channelID })
+			if index == -1 {
+				return nil, errNothingToRemove
+			}
+			pathSubs = slices.Delete(pathSubs, index, index+1)
+			if len(pathSubs) == 0 {
+				// Setting nil deletes the key.
+				return nil, nil
+			}
+			return pathSubs, nil
+		})
+		if errors.Is(err, errNothingToRemove) {
 			continue
 		}
+		if err != nil {
+			return false, err
+		}
 
-		pathRemoved := false
-		for index, sub := range pathSubs {
-			if sub.ChannelID == channelID {
-				pathSubs = append(pathSubs[:index], pathSubs[index+1:]...)
-				pathRemoved = true
-				break
+		if err := p.removeFromChannelIndex(channelID, path); err != nil {
+			return false, err
+		}
+		removed = true
+	}
+
+	return removed, nil
+}
+
+func (p *Plugin) removeFromChannelIndex(channelID, fullPath string) error {
+	err := p.client.KV.SetAtomicWithRetries(channelSubscriptionsKey(channelID), func(oldValue []byte) (any, error) {
+		var fullPaths []string
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &fullPaths); err != nil {
+				return nil, err
 			}
 		}
+		if !slices.Contains(fullPaths, fullPath) {
+			return nil, errNothingToRemove
+		}
+		fullPaths = slices.DeleteFunc(fullPaths, func(path string) bool { return path == fullPath })
+		if len(fullPaths) == 0 {
+			return nil, nil
+		}
+		return fullPaths, nil
+	})
+	if errors.Is(err, errNothingToRemove) {
+		return nil
+	}
+	return err
+}
 
-		if pathRemoved {
-			if len(pathSubs) > 0 {
-				subs.Repositories[path] = pathSubs
-			} else {
-				delete(subs.Repositories, path)
+// migrateSubscriptions copies the subscriptions stored in a single value by earlier versions of the plugin to the
+// per repository keys. It keeps the subscriptions made since with the new keys, and leaves the single value in place
+// so that they are still there if the plugin is downgraded. The ones that can't be converted are reported to the system admins.
+func (p *Plugin) migrateSubscriptions() error {
+	mutex, err := cluster.NewMutex(p.API, "gitlab-subscriptions-migration-lock")
+	if err != nil {
+		return errors.Wrap(err, "failed to create subscriptions migration mutex")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var migrated bool
+	if err := p.client.KV.Get(subscriptionsMigratedKey, &migrated); err != nil {
+		return errors.Wrap(err, "failed to get subscriptions migration state")
+	}
+	if migrated {
+		return nil
+	}
+
+	subs, unmigrated, err := p.getUnshardedSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for fullPath, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if err := p.storeSubscription(fullPath, sub, false); err != nil {
+				return errors.Wrap(err, "failed to migrate subscription")
+			}
+			count++
+		}
+	}
+
+	if _, err := p.client.KV.Set(subscriptionsMigratedKey, true); err != nil {
+		return errors.Wrap(err, "failed to set subscriptions migration state")
+	}
+	if count > 0 {
+		p.client.Log.Info("migrated subscriptions to per repository keys", "subscriptions", count)
+	}
+	if len(unmigrated) > 0 {
+		p.reportUnmigratedSubscriptions(unmigrated)
+	}
+	return nil
+}
+
+// reportUnmigratedSubscriptions sends the system admins the list of the legacy subscriptions that couldn't be
+// migrated, so they can be created again. Failing to do so doesn't fail the migration.
+func (p *Plugin) reportUnmigratedSubscriptions(unmigrated []*legacySubscription) {
+	var list strings.Builder
+	for _, sub := range unmigrated {
+		fmt.Fprintf(&list, "* `%s` in channel `%s`: `%s`\n", sub.Repository, sub.ChannelID, sub.Features)
+	}
+
+	for page := 0; ; page++ {
+		admins, err := p.client.User.List(&model.UserGetOptions{
+			Role:    model.SystemAdminRoleId,
+			Active:  true,
+			Page:    page,
+			PerPage: subscriptionsKeysPerPage,
+		})
+		if err != nil {
+			p.client.Log.Warn("can't list system admins to report unmigrated subscriptions", "err", err.Error())
+			return
+		}
+		for _, admin := range admins {
+			message := p.localizeWithConfig(p.getUserLocale(admin.Id), &i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "subscriptions.migration_failed",
+					One:   "{{.Count}} subscription made with an earlier version of the GitLab plugin couldn't be migrated and doesn't get notifications anymore. It is still stored under the `{{.Key}}` key of the plugin. Create it again with `/gitlab subscriptions add`:\n{{.Subscriptions}}",
+					Other: "{{.Count}} subscriptions made with an earlier version of the GitLab plugin couldn't be migrated and don't get notifications anymore. They are still stored under the `{{.Key}}` key of the plugin. Create them again with `/gitlab subscriptions add`:\n{{.Subscriptions}}",
+				},
+				TemplateData: map[string]any{
+					"Count":         len(unmigrated),
+					"Key":           legacySubscriptionsKey,
+					"Subscriptions": list.String(),
+				},
+				PluralCount: len(unmigrated),
+			})
+			if err := p.CreateBotDMPost(admin.Id, message, ""); err != nil {
+				p.client.Log.Warn("can't report unmigrated subscriptions", "user_id", admin.Id, "err", err.Error())
 			}
-			removed = true
 		}
+		if len(admins) < subscriptionsKeysPerPage {
+			return
+		}
+	}
+}
+
+// getUnshardedSubscriptions reads the subscriptions from unshardedSubscriptionsKey, or from legacySubscriptionsKey
+// for versions of the plugin that didn't write the former. The legacy subscriptions whose features can't be
+// parsed anymore are returned apart; they stay in legacySubscriptionsKey, which is never removed.
+func (p *Plugin) getUnshardedSubscriptions() (*Subscriptions, []*legacySubscription, error) {
+	var subscriptions *Subscriptions
+	if err := p.client.KV.Get(unshardedSubscriptionsKey, &subscriptions); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get subscriptions")
+	}
+	if subscriptions != nil {
+		return subscriptions, nil, nil
+	}
+
+	var legacy *legacySubscriptions
+	if err := p.client.KV.Get(legacySubscriptionsKey, &legacy); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get legacy subscriptions")
+	}
+
+	subscriptions = &Subscriptions{Repositories: map[string][]*subscription.Subscription{}}
+	if legacy == nil {
+		return subscriptions, nil, nil
 	}
 
-	if !removed {
-		return false, subs, nil
+	var unmigrated []*legacySubscription
+	for fullPath, legacySubs := range legacy.Repositories {
+		for _, legacySub := range legacySubs {
+			sub, err := subscription.New(legacySub.ChannelID, legacySub.CreatorID, legacySub.Features, legacySub.Repository)
+			if err != nil {
+				p.client.Log.Warn("can't migrate subscription, reporting it to the system admins", "channel_id", legacySub.ChannelID, "repository", legacySub.Repository, "features", legacySub.Features, "err", err.Error())
+				unmigrated = append(unmigrated, legacySub)
+				continue
+			}
+			subscriptions.Repositories[fullPath] = append(subscriptions.Repositories[fullPath], sub)
+		}
 	}
-	return true, subs, p.StoreSubscriptions(subs)
+
+	return subscriptions, unmigrated, nil
 }
@@ -4,9 +4,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/pkg/errors"
@@ -16,8 +20,72 @@ import (
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
 )
 
+// mockSubscriptionsStore backs the KV calls for the subscriptions and the cluster mutexes with an in-memory store,
+// following the compare-and-set semantics of the server. The store starts with the given subscriptions and their
+// channel index, and is returned so that tests can add raw values to it.
+func mockSubscriptionsStore(t *testing.T, api *plugintest.API, subs *Subscriptions) map[string][]byte {
+	t.Helper()
+
+	store := map[string][]byte{}
+	channelPaths := map[string][]string{}
+	for fullPath, repoSubs := range subs.Repositories {
+		value, err := json.Marshal(repoSubs)
+		require.NoError(t, err)
+		store[repositorySubscriptionsKey(fullPath)] = value
+		for _, sub := range repoSubs {
+			channelPaths[sub.ChannelID] = append(channelPaths[sub.ChannelID], fullPath)
+		}
+	}
+	for channelID, fullPaths := range channelPaths {
+		value, err := json.Marshal(fullPaths)
+		require.NoError(t, err)
+		store[channelSubscriptionsKey(channelID)] = value
+	}
+
+	isStoreKey := mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "subscriptions") || strings.HasPrefix(key, "mutex_")
+	})
+	api.On("KVGet", isStoreKey).Return(func(key string) ([]byte, *model.AppError) {
+		return store[key], nil
+	}).Maybe()
+	api.On("KVSetWithOptions", isStoreKey, mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) (bool, *model.AppError) {
+			if options.Atomic {
+				current, exists := store[key]
+				if (options.OldValue == nil && exists) || (options.OldValue != nil && !bytes.Equal(current, options.OldValue)) {
+					return false, nil
+				}
+			}
+			if value == nil {
+				delete(store, key)
+			} else {
+				store[key] = value
+			}
+			return true, nil
+		}).Maybe()
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(func(page, perPage int) ([]string, *model.AppError) {
+		keys := make([]string, 0, len(store))
+		for key := range store {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		start := min(page*perPage, len(keys))
+		return keys[start:min(start+perPage, len(keys))], nil
+	}).Maybe()
+
+	return store
+}
+
+func newSubscriptionsTestPlugin(api *plugintest.API) *Plugin {
+	p := &Plugin{configuration: &configuration{}}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(api, p.Driver)
+	return p
+}
+
 func TestSubscribe(t *testing.T) {
 	testCases := []struct {
 		name                 string
@@ -45,7 +113,7 @@ func TestSubscribe(t *testing.T) {
 			expectedUpdatedSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
 					"namespace/project": {
-						{ChannelID: "channelID", CreatorID: "user_id", Features: "merges", Repository: "namespace/project"},
+						webhook.MockSubscription("channelID", "user_id", "merges", "namespace/project"),
 					},
 				},
 			},
@@ -59,7 +127,7 @@ func TestSubscribe(t *testing.T) {
 			initialSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
 					"namespace/project": {
-						{ChannelID: "channelID", CreatorID: "user_id", Features: "merges", Repository: "namespace/project"},
+						webhook.MockSubscription("channelID", "user_id", "merges", "namespace/project"),
 					},
 				},
 			},
@@ -68,8 +136,8 @@ func TestSubscribe(t *testing.T) {
 			expectedUpdatedSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
 					"namespace/project": {
-						{ChannelID: "channelID", CreatorID: "user_id", Features: "merges", Repository: "namespace/project"},
-						{ChannelID: "channelID2", CreatorID: "user_id", Features: "merges", Repository: "namespace/project"},
+						webhook.MockSubscription("channelID", "user_id", "merges", "namespace/project"),
+						webhook.MockSubscription("channelID2", "user_id", "merges", "namespace/project"),
 					},
 				},
 			},
@@ -83,13 +151,41 @@ func TestSubscribe(t *testing.T) {
 			initialSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
 					"namespace/project": {
-						{ChannelID: "channelID", CreatorID: "user_id", Features: "merges", Repository: "namespace/project"},
+						webhook.MockSubscription("channelID", "user_id", "merges", "namespace/project"),
 					},
 				},
 			},
 
 			expectedError:                errors.New("unknown features invalid"),
 			expectedUpdatedSubscriptions: nil,
+		}, {
+			name:                 "should add members for a group",
+			info:                 &gitlab.UserInfo{UserID: "user_id"},
+			namespace:            "namespace",
+			project:              "",
+			channelID:            "channelID",
+			features:             "members",
+			initialSubscriptions: &Subscriptions{Repositories: map[string][]*subscription.Subscription{}},
+
+			expectedError: nil,
+			expectedUpdatedSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"namespace/": {
+						webhook.MockSubscription("channelID", "user_id", "members", "namespace/"),
+					},
+				},
+			},
+		}, {
+			name:                 "should error on members for a project",
+			info:                 &gitlab.UserInfo{UserID: "user_id"},
+			namespace:            "namespace",
+			project:              "project",
+			channelID:            "channelID",
+			features:             "merges,members",
+			initialSubscriptions: &Subscriptions{Repositories: map[string][]*subscription.Subscription{}},
+
+			expectedError:                errors.New("the members feature is only available for group subscriptions"),
+			expectedUpdatedSubscriptions: nil,
 		},
 	}
 
@@ -97,39 +193,59 @@ func TestSubscribe(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 			m := &plugintest.API{}
-			if test.expectedError == nil {
-				initialSubscriptions, err := json.Marshal(test.initialSubscriptions)
-				require.NoError(t, err)
-
-				expectedSubscriptions, err := json.Marshal(test.expectedUpdatedSubscriptions)
-				require.NoError(t, err)
+			mockSubscriptionsStore(t, m, test.initialSubscriptions)
+			p := newSubscriptionsTestPlugin(m)
 
-				m.On("KVGet", SubscriptionsKey).Return(initialSubscriptions, nil).Once()
-				m.On("KVSetWithOptions", SubscriptionsKey, expectedSubscriptions, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
-			}
-
-			p := &Plugin{configuration: &configuration{}}
-			p.SetAPI(m)
-			p.client = pluginapi.NewClient(m, p.Driver)
-
-			updatedSubscriptions, err := p.Subscribe(test.info, test.namespace, test.project, test.channelID, test.features)
+			err := p.Subscribe(test.info, test.namespace, test.project, test.channelID, test.features)
+			expectedSubscriptions := test.expectedUpdatedSubscriptions
 			if test.expectedError == nil {
 				assert.NoError(t, err)
 			} else {
-				assert.Error(t, test.expectedError, err)
+				assert.EqualError(t, err, test.expectedError.Error())
+				expectedSubscriptions = test.initialSubscriptions
+			}
+
+			subs, err := p.GetSubscriptions()
+			require.NoError(t, err)
+			assert.Equal(t, expectedSubscriptions, subs)
+
+			channelSubs, err := p.GetSubscriptionsByChannel(test.channelID)
+			require.NoError(t, err)
+			for _, repoSubs := range expectedSubscriptions.Repositories {
+				for _, sub := range repoSubs {
+					if sub.ChannelID == test.channelID {
+						assert.Contains(t, channelSubs, sub)
+					}
+				}
 			}
-			assert.Equal(t, test.expectedUpdatedSubscriptions, updatedSubscriptions)
-			m.AssertExpectations(t)
 		})
 	}
 }
 
+func TestSubscribeReplacesSubscriptionOfChannel(t *testing.T) {
+	m := &plugintest.API{}
+	store := mockSubscriptionsStore(t, m, &Subscriptions{
+		Repositories: map[string][]*subscription.Subscription{
+			"namespace/project": {webhook.MockSubscription("channelID", "user_id", "merges", "namespace/project")},
+		},
+	})
+	p := newSubscriptionsTestPlugin(m)
+
+	err := p.Subscribe(&gitlab.UserInfo{UserID: "user_id2"}, "namespace", "project", "channelID", "issues")
+	require.NoError(t, err)
+
+	channelSubs, err := p.GetSubscriptionsByChannel("channelID")
+	require.NoError(t, err)
+	assert.Equal(t, []*subscription.Subscription{webhook.MockSubscription("channelID", "user_id2", "issues", "namespace/project")}, channelSubs)
+	assert.JSONEq(t, `["namespace/project"]`, string(store[channelSubscriptionsKey("channelID")]))
+}
+
 func TestUnsubscribe(t *testing.T) {
 	testCases := []struct {
 		name                         string
 		channelID                    string
 		repoName                     string
-		initMock                     func() *plugintest.API
+		initialSubscriptions         *Subscriptions
 		shouldDelete                 bool
 		shouldError                  bool
 		expectedUpdatedSubscriptions *Subscriptions
@@ -138,13 +254,10 @@ func TestUnsubscribe(t *testing.T) {
 			name:      "should delete existing subscription",
 			channelID: "1",
 			repoName:  "owner/project",
-			initMock: func() *plugintest.API {
-				m := &plugintest.API{}
-				kvget := `{"Repositories":{"owner/project":[{"ChannelID":"1","CreatorID":"1","Features":"all","Repository":"owner/project"}]}}`
-				kvset := `{"Repositories":{}}`
-				m.On("KVGet", SubscriptionsKey).Return([]byte(kvget), nil).Once()
-				m.On("KVSetWithOptions", SubscriptionsKey, []byte(kvset), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
-				return m
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/project": {webhook.MockSubscription("1", "1", "merges", "owner/project")},
+				},
 			},
 			shouldDelete: true,
 			shouldError:  false,
@@ -155,63 +268,76 @@ func TestUnsubscribe(t *testing.T) {
 			name:      "should keep other channel",
 			channelID: "1",
 			repoName:  "owner/project",
-			initMock: func() *plugintest.API {
-				m := &plugintest.API{}
-				kvget := `{"Repositories":{"owner/project":[{"ChannelID":"1","CreatorID":"1","Features":"all","Repository":"owner/project"},{"ChannelID":"2","CreatorID":"1","Features":"all","Repository":"owner/project"}]}}`
-				kvset := `{"Repositories":{"owner/project":[{"ChannelID":"2","CreatorID":"1","Features":"all","Repository":"owner/project"}]}}`
-				m.On("KVGet", SubscriptionsKey).Return([]byte(kvget), nil).Once()
-				m.On("KVSetWithOptions", SubscriptionsKey, []byte(kvset), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
-				return m
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/project": {
+						webhook.MockSubscription("1", "1", "merges", "owner/project"),
+						webhook.MockSubscription("2", "1", "merges", "owner/project"),
+					},
+				},
 			},
 			shouldDelete: true,
 			shouldError:  false,
 			expectedUpdatedSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
 					"owner/project": {
-						{ChannelID: "2", CreatorID: "1", Features: "all", Repository: "owner/project"},
+						webhook.MockSubscription("2", "1", "merges", "owner/project"),
 					},
 				},
 			},
+		}, {
+			name:      "should keep other repository of channel",
+			channelID: "1",
+			repoName:  "owner/project",
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/project": {webhook.MockSubscription("1", "1", "merges", "owner/project")},
+					"owner/other":   {webhook.MockSubscription("1", "1", "issues", "owner/other")},
+				},
+			},
+			shouldDelete: true,
+			shouldError:  false,
+			expectedUpdatedSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/other": {webhook.MockSubscription("1", "1", "issues", "owner/other")},
+				},
+			},
 		}, {
 			name:      "should not delete if not exist",
 			channelID: "2",
 			repoName:  "owner/project",
-			initMock: func() *plugintest.API {
-				m := &plugintest.API{}
-				kvget := `{"Repositories":{"owner/project":[{"ChannelID":"1","CreatorID":"1","Features":"all","Repository":"owner/project"}]}}`
-				m.On("KVGet", SubscriptionsKey).Return([]byte(kvget), nil).Once()
-				return m
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/project": {webhook.MockSubscription("1", "1", "merges", "owner/project")},
+				},
 			},
 			shouldDelete: false,
 			shouldError:  false,
 			expectedUpdatedSubscriptions: &Subscriptions{
 				Repositories: map[string][]*subscription.Subscription{
-					"owner/project": {
-						{ChannelID: "1", CreatorID: "1", Features: "all", Repository: "owner/project"},
-					},
+					"owner/project": {webhook.MockSubscription("1", "1", "merges", "owner/project")},
 				},
 			},
 		}, {
 			name:      "should refuse empty repo",
 			channelID: "1",
 			repoName:  "",
-			initMock: func() *plugintest.API {
-				return &plugintest.API{}
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{},
+			},
+			shouldDelete: false,
+			shouldError:  true,
+			expectedUpdatedSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{},
 			},
-			shouldDelete:                 false,
-			shouldError:                  true,
-			expectedUpdatedSubscriptions: nil,
 		}, {
 			name:      "should delete organization",
 			channelID: "1",
 			repoName:  "owner",
-			initMock: func() *plugintest.API {
-				m := &plugintest.API{}
-				kvget := `{"Repositories":{"owner/":[{"ChannelID":"1","CreatorID":"1","Features":"all","Repository":"owner/"}]}}`
-				kvset := `{"Repositories":{}}`
-				m.On("KVGet", SubscriptionsKey).Return([]byte(kvget), nil).Once()
-				m.On("KVSetWithOptions", SubscriptionsKey, []byte(kvset), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
-				return m
+			initialSubscriptions: &Subscriptions{
+				Repositories: map[string][]*subscription.Subscription{
+					"owner/": {webhook.MockSubscription("1", "1", "merges", "owner/")},
+				},
 			},
 			shouldDelete: true,
 			shouldError:  false,
@@ -224,15 +350,118 @@ func TestUnsubscribe(t *testing.T) {
 	t.Parallel()
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
-			m := test.initMock()
-			p := &Plugin{configuration: &configuration{}}
-			p.SetAPI(m)
-			p.client = pluginapi.NewClient(m, p.Driver)
-			res, updatedSubscriptions, err := p.Unsubscribe(test.channelID, test.repoName)
+			m := &plugintest.API{}
+			mockSubscriptionsStore(t, m, test.initialSubscriptions)
+			p := newSubscriptionsTestPlugin(m)
+
+			res, err := p.Unsubscribe(test.channelID, test.repoName)
 			assert.Equal(t, test.shouldDelete, res)
 			assert.Equal(t, test.shouldError, err != nil)
-			assert.Equal(t, test.expectedUpdatedSubscriptions, updatedSubscriptions)
-			m.AssertExpectations(t)
+
+			subs, err := p.GetSubscriptions()
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUpdatedSubscriptions, subs)
+
+			channelSubs, err := p.GetSubscriptionsByChannel(test.channelID)
+			require.NoError(t, err)
+			for _, sub := range channelSubs {
+				assert.NotEqual(t, fullPathFromNamespaceAndProject(test.repoName, ""), sub.Repository)
+				assert.NotEqual(t, test.repoName, sub.Repository)
+			}
 		})
 	}
 }
+
+func TestMigrateSubscriptions(t *testing.T) {
+	t.Run("converts the legacy subscriptions", func(t *testing.T) {
+		m := &plugintest.API{}
+		store := mockSubscriptionsStore(t, m, &Subscriptions{})
+		store[legacySubscriptionsKey] = []byte(`{"Repositories":{"owner/project":[{"ChannelID":"1","CreatorID":"1","Features":"issues,label:\"merges-needed\"","Repository":"owner/project"},{"ChannelID":"2","CreatorID":"1","Features":"unknown","Repository":"owner/project"}],"owner/":[{"ChannelID":"3","CreatorID":"2","Features":"pushes,vulnerabilities:high","Repository":"owner/"}]}}`)
+		m.On("LogWarn", "can't migrate subscription, reporting it to the system admins", "channel_id", "2", "repository", "owner/project", "features", "unknown", "err", "unknown features unknown").Once()
+		m.On("LogInfo", "migrated subscriptions to per repository keys", "subscriptions", 2).Once()
+		m.On("GetUsers", &model.UserGetOptions{Role: model.SystemAdminRoleId, Active: true, PerPage: subscriptionsKeysPerPage}).Return([]*model.User{{Id: "admin"}}, nil).Once()
+		m.On("GetDirectChannel", "admin", "bot").Return(&model.Channel{Id: "dm"}, nil).Once()
+		m.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dm" && post.UserId == "bot" &&
+				strings.HasPrefix(post.Message, "1 subscription made with an earlier version of the GitLab plugin couldn't be migrated") &&
+				strings.HasSuffix(post.Message, "\n* `owner/project` in channel `2`: `unknown`\n")
+		})).Return(&model.Post{}, nil).Once()
+		p := newSubscriptionsTestPlugin(m)
+		p.BotUserID = "bot"
+
+		require.NoError(t, p.migrateSubscriptions())
+
+		subs, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Equal(t, &Subscriptions{
+			Repositories: map[string][]*subscription.Subscription{
+				"owner/project": {webhook.MockSubscription("1", "1", `issues,label:"merges-needed"`, "owner/project")},
+				"owner/":        {webhook.MockSubscription("3", "2", "pushes,vulnerabilities:high", "owner/")},
+			},
+		}, subs)
+		assert.False(t, subs.Repositories["owner/project"][0].Merges())
+
+		channelSubs, err := p.GetSubscriptionsByChannel("3")
+		require.NoError(t, err)
+		assert.Equal(t, []*subscription.Subscription{webhook.MockSubscription("3", "2", "pushes,vulnerabilities:high", "owner/")}, channelSubs)
+		assert.NotNil(t, store[legacySubscriptionsKey])
+		m.AssertExpectations(t)
+	})
+
+	t.Run("keeps the subscriptions stored in the meantime", func(t *testing.T) {
+		m := &plugintest.API{}
+		store := mockSubscriptionsStore(t, m, &Subscriptions{
+			Repositories: map[string][]*subscription.Subscription{
+				"owner/project": {webhook.MockSubscription("1", "1", "pushes", "owner/project")},
+			},
+		})
+		unsharded, err := json.Marshal(&Subscriptions{
+			Repositories: map[string][]*subscription.Subscription{
+				"owner/project": {
+					webhook.MockSubscription("1", "1", "merges", "owner/project"),
+					webhook.MockSubscription("2", "1", "issues", "owner/project"),
+				},
+			},
+		})
+		require.NoError(t, err)
+		store[unshardedSubscriptionsKey] = unsharded
+		m.On("LogInfo", "migrated subscriptions to per repository keys", "subscriptions", 2).Once()
+		p := newSubscriptionsTestPlugin(m)
+
+		require.NoError(t, p.migrateSubscriptions())
+
+		subs, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Equal(t, []*subscription.Subscription{
+			webhook.MockSubscription("1", "1", "pushes", "owner/project"),
+			webhook.MockSubscription("2", "1", "issues", "owner/project"),
+		}, subs.Repositories["owner/project"])
+	})
+
+	t.Run("runs once", func(t *testing.T) {
+		m := &plugintest.API{}
+		store := mockSubscriptionsStore(t, m, &Subscriptions{})
+		store[subscriptionsMigratedKey] = []byte("true")
+		store[legacySubscriptionsKey] = []byte(`{"Repositories":{"owner/project":[{"ChannelID":"1","CreatorID":"1","Features":"merges","Repository":"owner/project"}]}}`)
+		p := newSubscriptionsTestPlugin(m)
+
+		require.NoError(t, p.migrateSubscriptions())
+
+		subs, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Empty(t, subs.Repositories)
+	})
+
+	t.Run("starts empty without legacy subscriptions", func(t *testing.T) {
+		m := &plugintest.API{}
+		store := mockSubscriptionsStore(t, m, &Subscriptions{})
+		p := newSubscriptionsTestPlugin(m)
+
+		require.NoError(t, p.migrateSubscriptions())
+
+		subs, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Empty(t, subs.Repositories)
+		assert.Equal(t, []byte("true"), store[subscriptionsMigratedKey])
+	})
+}
@@ -18,8 +18,9 @@ import (
 type SupportPacket struct {
 	Version string `yaml:"version"`
 
-	ConnectedUserCount int64 `yaml:"connected_user_count"`
-	IsOAuthConfigured  bool  `yaml:"is_oauth_configured"`
+	ConnectedUserCount         int64 `yaml:"connected_user_count"`
+	IsOAuthConfigured          bool  `yaml:"is_oauth_configured"`
+	DuplicateWebhookEventCount int64 `yaml:"duplicate_webhook_event_count"`
 }
 
 func (p *Plugin) GenerateSupportData(_ *plugin.Context) ([]*model.FileData, error) {
@@ -32,10 +33,16 @@ func (p *Plugin) GenerateSupportData(_ *plugin.Context) ([]*model.FileData, erro
 		result = multierror.Append(result, errors.Wrap(err, "failed to get the number of connected users for Support Packet"))
 	}
 
+	duplicateWebhookEventCount, err := p.getDuplicateWebhookEventCount()
+	if err != nil {
+		result = multierror.Append(result, errors.Wrap(err, "failed to get the number of duplicate webhook events for Support Packet"))
+	}
+
 	diagnostics := SupportPacket{
-		Version:            manifest.Version,
-		ConnectedUserCount: connectedUserCount,
-		IsOAuthConfigured:  config.IsOAuthConfigured(),
+		Version:                    manifest.Version,
+		ConnectedUserCount:         connectedUserCount,
+		IsOAuthConfigured:          config.IsOAuthConfigured(),
+		DuplicateWebhookEventCount: duplicateWebhookEventCount,
 	}
 	body, err := yaml.Marshal(diagnostics)
 	if err != nil {
@@ -0,0 +1,96 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
+)
+
+// messageTemplatesKey is the KV key of the message templates set by admins, by name.
+const messageTemplatesKey = "message_templates"
+
+// messageTemplateCache holds the parsed message templates, as every webhook event looks them up.
+type messageTemplateCache struct {
+	lock sync.RWMutex
+	// templates is nil until loaded from the KV store.
+	templates map[string]*template.Template
+}
+
+// getMessageTemplates returns the text of the message templates set by admins, by name.
+func (p *Plugin) getMessageTemplates() (map[string]string, error) {
+	templates := map[string]string{}
+	if err := p.client.KV.Get(messageTemplatesKey, &templates); err != nil {
+		return nil, errors.Wrap(err, "failed to load message templates")
+	}
+	return templates, nil
+}
+
+// getMessageTemplate returns the template set by admins for the message of this name, or nil if there is none.
+func (p *Plugin) getMessageTemplate(name string) *template.Template {
+	p.messageTemplates.lock.RLock()
+	templates := p.messageTemplates.templates
+	p.messageTemplates.lock.RUnlock()
+	if templates != nil {
+		return templates[name]
+	}
+
+	texts, err := p.getMessageTemplates()
+	if err != nil {
+		p.client.Log.Warn("can't load message templates, posting the default messages", "err", err.Error())
+		return nil
+	}
+	templates = map[string]*template.Template{}
+	for templateName, text := range texts {
+		tmpl, err := webhook.ParseMessageTemplate(templateName, text)
+		if err != nil {
+			p.client.Log.Warn("can't parse message template, posting the default message", "name", templateName, "err", err.Error())
+			continue
+		}
+		templates[templateName] = tmpl
+	}
+
+	p.messageTemplates.lock.Lock()
+	p.messageTemplates.templates = templates
+	p.messageTemplates.lock.Unlock()
+	return templates[name]
+}
+
+// setMessageTemplate stores the template of the message of this name, or removes it when text is empty
+// so the default message is posted again.
+func (p *Plugin) setMessageTemplate(name, text string) error {
+	err := p.client.KV.SetAtomicWithRetries(messageTemplatesKey, func(oldValue []byte) (any, error) {
+		templates := map[string]string{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &templates); err != nil {
+				return nil, err
+			}
+		}
+		if text == "" {
+			delete(templates, name)
+		} else {
+			templates[name] = text
+		}
+		return templates, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store message template")
+	}
+
+	p.clearMessageTemplates()
+	p.sendMessageToCluster(messageTemplatesUpdatedEventID, name)
+	return nil
+}
+
+// clearMessageTemplates drops the parsed templates, so they are loaded again from the KV store.
+func (p *Plugin) clearMessageTemplates() {
+	p.messageTemplates.lock.Lock()
+	p.messageTemplates.templates = nil
+	p.messageTemplates.lock.Unlock()
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMessageTemplate(t *testing.T) {
+	api := &plugintest.API{}
+	p := newQuietHoursTestPlugin(api)
+	api.On("KVGet", messageTemplatesKey).Return([]byte(`{"merge_request.merge":"{{.Title}} merged","push":"{{.Broken"}`), nil).Once()
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	tmpl := p.getMessageTemplate("merge_request.merge")
+	require.NotNil(t, tmpl)
+	assert.Equal(t, "merge_request.merge", tmpl.Name())
+	// The templates are loaded once, skipping the invalid ones.
+	assert.Nil(t, p.getMessageTemplate("push"))
+	assert.Nil(t, p.getMessageTemplate("tag.create"))
+	api.AssertExpectations(t)
+
+	// Another node updating the templates makes them load again.
+	p.HandleClusterEvent(model.PluginClusterEvent{Id: messageTemplatesUpdatedEventID})
+	api.On("KVGet", messageTemplatesKey).Return(nil, nil).Once()
+	assert.Nil(t, p.getMessageTemplate("merge_request.merge"))
+	api.AssertExpectations(t)
+}
+
+func TestSetMessageTemplate(t *testing.T) {
+	isTemplatesUpdated := mock.MatchedBy(func(event model.PluginClusterEvent) bool {
+		return event.Id == messageTemplatesUpdatedEventID
+	})
+
+	t.Run("template is added to the stored ones", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		p.messageTemplates.templates = map[string]*template.Template{}
+		api.On("KVGet", messageTemplatesKey).Return([]byte(`{"push":"pushed"}`), nil).Once()
+		api.On("KVSetWithOptions", messageTemplatesKey, []byte(`{"merge_request.merge":"merged","push":"pushed"}`), mock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+			return o.Atomic && string(o.OldValue) == `{"push":"pushed"}`
+		})).Return(true, nil).Once()
+		api.On("PublishPluginClusterEvent", isTemplatesUpdated, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, p.setMessageTemplate("merge_request.merge", "merged"))
+		assert.Nil(t, p.messageTemplates.templates, "templates are loaded again")
+		api.AssertExpectations(t)
+	})
+
+	t.Run("empty template is removed", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		api.On("KVGet", messageTemplatesKey).Return([]byte(`{"push":"pushed"}`), nil).Once()
+		api.On("KVSetWithOptions", messageTemplatesKey, []byte(`{}`), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("PublishPluginClusterEvent", isTemplatesUpdated, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, p.setMessageTemplate("push", ""))
+		api.AssertExpectations(t)
+	})
+}
+
+func TestTemplatesCommand(t *testing.T) {
+	newTemplatesCommandPlugin := func(roles string) (*Plugin, *plugintest.API, *string) {
+		api := &plugintest.API{}
+		p := newQuietHoursTestPlugin(api)
+		api.On("GetUser", "user_id").Return(&model.User{Id: "user_id", Roles: roles}, nil)
+		var response string
+		api.On("SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+			response = post.Message
+			return true
+		})).Return(&model.Post{})
+		return p, api, &response
+	}
+	execute := func(p *Plugin, command string) {
+		_, appErr := p.ExecuteCommand(nil, &model.CommandArgs{UserId: "user_id", ChannelId: "channel_id", Command: command})
+		require.Nil(t, appErr)
+	}
+
+	t.Run("templates require admin", func(t *testing.T) {
+		p, _, response := newTemplatesCommandPlugin("system_user")
+		execute(p, "/gitlab templates set push pushed")
+		assert.Equal(t, "Only System Admins are allowed to manage message templates.", *response)
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		p, _, response := newTemplatesCommandPlugin("system_admin system_user")
+		execute(p, "/gitlab templates set wiki edited")
+		assert.Equal(t, "Unknown template `wiki`, `/gitlab templates view` lists them.", *response)
+	})
+
+	t.Run("invalid template is not saved", func(t *testing.T) {
+		p, api, response := newTemplatesCommandPlugin("system_admin system_user")
+		execute(p, "/gitlab templates set push {{.Nope}}")
+		assert.Contains(t, *response, "Invalid template: ")
+		api.AssertNotCalled(t, "KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("template is saved as typed and previewed", func(t *testing.T) {
+		p, api, response := newTemplatesCommandPlugin("system_admin system_user")
+		api.On("KVGet", messageTemplatesKey).Return(nil, nil).Once()
+		api.On("KVSetWithOptions", messageTemplatesKey, []byte(`{"tag.create":":label:  {{.Title}}\n{{.Author}}"}`), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("PublishPluginClusterEvent", mock.Anything, mock.Anything).Return(nil).Once()
+
+		execute(p, "/gitlab templates set  tag.create :label:  {{.Title}}\n{{.Author}}")
+
+		assert.Equal(t, "Saved the template of `tag.create`, a sample event is now posted as:\n\n:label:  tag1\nmanland", *response)
+		api.AssertExpectations(t)
+	})
+}
+
+func TestCommandArgument(t *testing.T) {
+	assert.Equal(t, "{{.Title}}  merged\nby {{.Author}}", commandArgument(" /gitlab templates set  merge_request.merge {{.Title}}  merged\nby {{.Author}} ", 4))
+	assert.Equal(t, "", commandArgument("/gitlab templates preview push", 4))
+	assert.Equal(t, "", commandArgument("/gitlab templates preview push ", 4))
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	// threadRootKeyPrefix prefixes the KV keys mapping a merge request or issue to the root post of its thread in a channel.
+	threadRootKeyPrefix = "threadroot_"
+	// threadRootTTL is how long a thread is kept after its last reply, later events start a new one.
+	threadRootTTL = 30 * 24 * time.Hour
+)
+
+// threadRootKey hashes the thread key, as the channel ID and the key don't always fit together in a KV key.
+func threadRootKey(channelID, threadKey string) string {
+	hash := sha256.Sum256([]byte(channelID + "/" + threadKey))
+	return threadRootKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// createThreadedPost creates the post as a reply to the thread of the merge request or issue identified by threadKey,
+// or as the root of a new thread if the channel has none yet, or if its root post was deleted.
+func (p *Plugin) createThreadedPost(post *model.Post, threadKey string) error {
+	key := threadRootKey(post.ChannelId, threadKey)
+
+	var rootID string
+	if err := p.client.KV.Get(key, &rootID); err != nil {
+		return errors.Wrap(err, "failed to load thread root")
+	}
+
+	if rootID != "" {
+		post.RootId = rootID
+		err := p.client.Post.CreatePost(post)
+		if err == nil {
+			if _, err := p.client.KV.Set(key, rootID, pluginapi.SetExpiry(threadRootTTL)); err != nil {
+				p.client.Log.Warn("can't refresh thread root", "channel_id", post.ChannelId, "err", err.Error())
+			}
+			return nil
+		}
+
+		if _, getErr := p.client.Post.GetPost(rootID); !errors.Is(getErr, pluginapi.ErrNotFound) {
+			return err
+		}
+		p.client.Log.Debug("thread root was deleted, starting a new thread", "channel_id", post.ChannelId, "root_id", rootID)
+		if err := p.client.KV.Delete(key); err != nil {
+			return errors.Wrap(err, "failed to remove deleted thread root")
+		}
+		post.RootId = ""
+	}
+
+	if err := p.client.Post.CreatePost(post); err != nil {
+		return err
+	}
+	// An event handled concurrently may have started the thread in the meantime, its root is then kept.
+	if _, err := p.client.KV.Set(key, post.Id, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(threadRootTTL)); err != nil {
+		p.client.Log.Warn("can't store thread root", "channel_id", post.ChannelId, "err", err.Error())
+	}
+	return nil
+}
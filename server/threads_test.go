@@ -0,0 +1,103 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateThreadedPost(t *testing.T) {
+	key := threadRootKey("channel-id", "merge_request/24/4")
+	isThreadRootTTL := mock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.ExpireInSeconds == int64(threadRootTTL.Seconds())
+	})
+
+	t.Run("first post starts the thread", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "opened", ThreadKey: "merge_request/24/4"})
+		api.On("KVGet", key).Return(nil, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "" && post.Message == "opened"
+		})).Return(&model.Post{Id: "root-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, []byte(`"root-id"`), mock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+			return o.Atomic && o.OldValue == nil && o.ExpireInSeconds == int64(threadRootTTL.Seconds())
+		})).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("next posts reply to the thread", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "merged", ThreadKey: "merge_request/24/4"})
+		api.On("KVGet", key).Return([]byte(`"root-id"`), nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "root-id" && post.Message == "merged"
+		})).Return(&model.Post{Id: "reply-id", RootId: "root-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, []byte(`"root-id"`), isThreadRootTTL).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("deleted root starts a new thread", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "merged", ThreadKey: "merge_request/24/4"})
+		api.On("KVGet", key).Return([]byte(`"root-id"`), nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "root-id"
+		})).Return(nil, &model.AppError{Message: "invalid root id", StatusCode: http.StatusBadRequest}).Once()
+		api.On("GetPost", "root-id").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound}).Once()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("KVSetWithOptions", key, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == ""
+		})).Return(&model.Post{Id: "new-root-id"}, nil).Once()
+		api.On("KVSetWithOptions", key, []byte(`"new-root-id"`), isThreadRootTTL).Return(true, nil).Once()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, isNilBytes, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		expectQueueIndexUpdate(t, api, deliveryQueueIndexKey, isRemovedFromIndex)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+	})
+
+	t.Run("reply failing for another reason is retried", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := makeDeliveryPlugin(t, api, &pendingDelivery{ID: testDeliveryID, ChannelID: "channel-id", Message: "merged", ThreadKey: "merge_request/24/4"})
+		api.On("KVGet", key).Return([]byte(`"root-id"`), nil).Once()
+		api.On("CreatePost", mock.Anything).Return(nil, &model.AppError{Message: "db down"}).Once()
+		api.On("GetPost", "root-id").Return(&model.Post{Id: "root-id"}, nil).Once()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("KVSetWithOptions", deliveryQueueKeyPrefix+testDeliveryID, mock.MatchedBy(func(value []byte) bool {
+			return value != nil
+		}), mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+		allowQueueIndexUpdates(api, deliveryQueueIndexKey)
+
+		p.processDelivery(testDeliveryID)
+
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "KVSetWithOptions", key, isNilBytes, mock.Anything)
+	})
+}
+
+func TestThreadRootKey(t *testing.T) {
+	key := threadRootKey("channel-id", "merge_request/24/4")
+	require.LessOrEqual(t, len(key), model.KeyValueKeyMaxRunes)
+	assert.NotEqual(t, key, threadRootKey("other-channel-id", "merge_request/24/4"))
+	assert.NotEqual(t, key, threadRootKey("channel-id", "issue/24/4"))
+}
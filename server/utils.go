@@ -180,6 +180,27 @@ func getSiteURL(client *pluginapi.Client) string {
 	return strings.TrimSuffix(*siteURL, "/")
 }
 
+// listKeysWithPrefix returns every KV key starting with the prefix, reading the keys perPage at a time.
+// pluginapi filters each page after listing it, so the last page is told by the number of keys it listed,
+// not by the number of keys it kept.
+func listKeysWithPrefix(client *pluginapi.Client, prefix string, perPage int) ([]string, error) {
+	var keys []string
+	for page := 0; ; page++ {
+		listed := 0
+		pageKeys, err := client.KV.ListKeys(page, perPage, pluginapi.WithChecker(func(string) (bool, error) {
+			listed++
+			return true, nil
+		}), pluginapi.WithPrefix(prefix))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list keys - page, %d", page)
+		}
+		keys = append(keys, pageKeys...)
+		if listed < perPage {
+			return keys, nil
+		}
+	}
+}
+
 // filterLines filters lines in a string from start to end.
 func filterLines(s string, start, end int) (string, error) {
 	scanner := bufio.NewScanner(strings.NewReader(s))
@@ -6,7 +6,10 @@ package main
 import (
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseGitlabUsernamesFromText(t *testing.T) {
@@ -167,3 +170,16 @@ func TestLastN(t *testing.T) {
 		assert.Equal(t, tc.Expected, lastN(tc.Text, tc.N))
 	}
 }
+
+func TestListKeysWithPrefix(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVList", 0, 2).Return([]string{"other_1", "prefix_1"}, nil).Once()
+	api.On("KVList", 1, 2).Return([]string{"other_2", "other_3"}, nil).Once()
+	api.On("KVList", 2, 2).Return([]string{"prefix_2"}, nil).Once()
+	client := pluginapi.NewClient(api, nil)
+
+	keys, err := listKeysWithPrefix(client, "prefix_", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prefix_1", "prefix_2"}, keys)
+	api.AssertExpectations(t)
+}
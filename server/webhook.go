@@ -5,24 +5,62 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"text/template"
 	"time"
 
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/pkg/errors"
 	gitlabLib "github.com/xanzy/go-gitlab"
 	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
-
-	"github.com/mattermost/mattermost/server/public/model"
 )
 
 const (
-	webhookTimeout            = 10 * time.Second
+	webhookTimeout = 10 * time.Second
+	// webhookMaxBodySize bounds the size of webhook requests, which are read before being authenticated.
+	webhookMaxBodySize        = 25 << 20
 	eventSourceParentPipeline = "parent_pipeline"
+
+	headerGitlabEventUUID   = "X-Gitlab-Event-UUID"
+	headerGitlabWebhookUUID = "X-Gitlab-Webhook-UUID"
+
+	// webhookEventQueueKeyPrefix prefixes the KV keys of the webhook events waiting to be handled.
+	webhookEventQueueKeyPrefix = "webhookqueue_"
+	webhookEventLeaseKeyPrefix = "webhookqueuelease_"
+	// webhookEventQueueIndexKey holds the IDs of the queued webhook events, so polling the queue doesn't list the whole KV store.
+	webhookEventQueueIndexKey = "webhookqueueindex"
+
+	// webhookEventKeyPrefix prefixes the KV keys recording already handled webhook deliveries.
+	webhookEventKeyPrefix = "webhookevent_"
+	// webhookDuplicateCountKey stores how many duplicate deliveries were skipped, for the support packet.
+	webhookDuplicateCountKey = "webhook_duplicate_count"
+	webhookEventTTL          = 24 * time.Hour
+
+	// webhookThrottleKeyPrefix prefixes the KV keys recording the throttled posts recently made in a channel or DMs to a user.
+	webhookThrottleKeyPrefix = "webhookthrottle_"
+	webhookThrottleWindow    = 10 * time.Minute
+
+	// pipelineStatusKeyPrefix prefixes the KV keys recording the status of the last finished pipeline of a project ref.
+	pipelineStatusKeyPrefix = "pipelinestatus_"
+
+	// mergeRequestPathsKeyPrefix prefixes the KV keys caching the paths changed by a merge request at a commit.
+	mergeRequestPathsKeyPrefix = "mergerequestpaths_"
+	mergeRequestPathsTTL       = 24 * time.Hour
+
+	// milestoneTitleKeyPrefix prefixes the KV keys caching the titles of milestones, which events only tell by ID.
+	milestoneTitleKeyPrefix = "milestonetitle_"
+	milestoneTitleTTL       = time.Hour
 )
 
 type gitlabRetreiver struct {
@@ -39,11 +77,26 @@ func (g *gitlabRetreiver) GetJobURL(pathWithNamespace string, jobID int) string
 	return fmt.Sprintf("%s/%s/-/jobs/%d", config.GitlabURL, pathWithNamespace, jobID)
 }
 
+func (g *gitlabRetreiver) GetVulnerabilityReportURL(pathWithNamespace string) string {
+	config := g.p.getConfiguration()
+	return fmt.Sprintf("%s/%s/-/security/vulnerability_report", config.GitlabURL, pathWithNamespace)
+}
+
 func (g *gitlabRetreiver) GetUserURL(username string) string {
 	config := g.p.getConfiguration()
 	return fmt.Sprintf("%s/%s", config.GitlabURL, username)
 }
 
+func (g *gitlabRetreiver) GetGroupURL(groupPath string) string {
+	config := g.p.getConfiguration()
+	return fmt.Sprintf("%s/%s", config.GitlabURL, groupPath)
+}
+
+func (g *gitlabRetreiver) GetGroupMilestoneURL(groupPath string, milestoneIID int) string {
+	config := g.p.getConfiguration()
+	return fmt.Sprintf("%s/groups/%s/-/milestones/%d", config.GitlabURL, groupPath, milestoneIID)
+}
+
 func (g *gitlabRetreiver) GetUsernameByID(id int) string {
 	return g.p.getGitlabIDToUsernameMapping(fmt.Sprintf("%d", id))
 }
@@ -61,29 +114,221 @@ func (g *gitlabRetreiver) GetSubscribedChannelsForProject(
 	return g.p.GetSubscribedChannelsForProject(ctx, namespace, project, isPublicVisibility)
 }
 
-func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
-	config := p.getConfiguration()
+func (g *gitlabRetreiver) GetSubscribedChannelsForGroup(ctx context.Context, groupPath string) []*subscription.Subscription {
+	return g.p.GetSubscribedChannelsForGroup(ctx, groupPath)
+}
 
-	signature := r.Header.Get("X-Gitlab-Token")
-	if config.WebhookSecret != signature {
-		http.Error(w, "Not authorized", http.StatusUnauthorized)
-		return
+func (g *gitlabRetreiver) GetMilestoneIssueCounts(ctx context.Context, userID, namespace, project, milestoneTitle string) (int, int, error) {
+	info, apiErr := g.p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return 0, 0, apiErr
 	}
 
+	var opened, closed int
+	err := g.p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var err error
+		opened, closed, err = g.p.GitlabClient.GetMilestoneIssueCounts(ctx, info, token, namespace, project, milestoneTitle)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return opened, closed, nil
+}
+
+func (g *gitlabRetreiver) GetPreviousDeploymentSHA(ctx context.Context, userID, namespace, project, environment string, deploymentID int) (string, error) {
+	info, apiErr := g.p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	var sha string
+	err := g.p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var err error
+		sha, err = g.p.GitlabClient.GetPreviousDeploymentSHA(ctx, info, token, namespace, project, environment, deploymentID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+func (g *gitlabRetreiver) GetMilestoneTitle(ctx context.Context, userID, namespace, project string, milestoneID int) (string, error) {
+	return g.p.getMilestoneTitle(ctx, userID, namespace, project, milestoneID)
+}
+
+func (g *gitlabRetreiver) GetCommitAuthorUsername(ctx context.Context, userID, authorEmail string) (string, error) {
+	info, apiErr := g.p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	var username string
+	err := g.p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var err error
+		username, err = g.p.GitlabClient.GetCommitAuthorUsername(ctx, info, token, authorEmail)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return username, nil
+}
+
+func (g *gitlabRetreiver) GetMessageTemplate(name string) *template.Template {
+	return g.p.getMessageTemplate(name)
+}
+
+func (g *gitlabRetreiver) GetUserLocale(gitlabUsername string) string {
+	if gitlabUsername == "" {
+		return g.p.getServerLocale()
+	}
+	return g.p.getUserLocale(g.p.getGitlabToUserIDMapping(gitlabUsername))
+}
+
+func (g *gitlabRetreiver) GetServerLocale() string {
+	return g.p.getServerLocale()
+}
+
+func (g *gitlabRetreiver) Localize(locale string, config *i18n.LocalizeConfig) string {
+	return g.p.localizeWithConfig(locale, config)
+}
+
+func (g *gitlabRetreiver) GetMergeRequestChangedPaths(ctx context.Context, userID, namespace, project string, mergeRequestIID int, headSHA string) ([]string, error) {
+	return g.p.getMergeRequestChangedPaths(ctx, userID, namespace, project, mergeRequestIID, headSHA)
+}
+
+func (g *gitlabRetreiver) SwapPipelineStatus(projectID int, ref, status string) string {
+	return g.p.swapPipelineStatus(projectID, ref, status)
+}
+
+func (g *gitlabRetreiver) UpdatePipelineCard(projectID, pipelineID int, update *webhook.PipelineCard) *webhook.PipelineCard {
+	return g.p.updatePipelineCard(projectID, pipelineID, update)
+}
+
+// pendingWebhookEvent is a webhook request accepted by the HTTP handler that still has to be handled.
+type pendingWebhookEvent struct {
+	ID        string              `json:"id"`
+	EventType gitlabLib.EventType `json:"event_type"`
+	Body      json.RawMessage     `json:"body"`
+	CreatedAt int64               `json:"created_at"`
+}
+
+// handleWebhook authenticates a webhook request and queues its event. The event is handled by the worker of
+// the delivery queue, as looking up subscriptions and permissions calls GitLab, which could make GitLab's
+// request time out.
+func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, webhookMaxBodySize)
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Bad request body", http.StatusBadRequest)
 		return
 	}
 
 	eventType := gitlabLib.WebhookEventType(r)
-	event, err := gitlabLib.ParseWebhook(eventType, body)
+	event, parseErr := webhook.ParseWebhook(eventType, body)
+
+	namespace, err := p.webhookEventNamespace(event, body)
 	if err != nil {
-		p.client.Log.Debug("Can't parse webhook", "err", err.Error(), "header", r.Header.Get("X-Gitlab-Event"), "event", string(body))
+		p.client.Log.Warn("Rejecting webhook request naming several projects or groups", "err", err.Error())
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+	if !p.isWebhookRequestAuthorized(r, body, namespace) {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if parseErr != nil {
+		p.client.Log.Debug("Can't parse webhook", "err", parseErr.Error(), "header", r.Header.Get("X-Gitlab-Event"), "event", string(body))
 		http.Error(w, "Unable to handle request", http.StatusBadRequest)
 		return
 	}
 
+	eventKey := webhookEventKey(r)
+	if p.isDuplicateWebhookEvent(eventKey) {
+		p.client.Log.Debug("Skipping already handled webhook event", "event_uuid", r.Header.Get(headerGitlabEventUUID), "webhook_uuid", r.Header.Get(headerGitlabWebhookUUID))
+		return
+	}
+
+	if err = p.enqueueWebhookEvent(eventType, body); err != nil {
+		p.client.Log.Warn("can't enqueue webhook event", "err", err.Error())
+		// GitLab retries the delivery, which must not be skipped as a duplicate.
+		p.forgetWebhookEvent(eventKey)
+		http.Error(w, "Unable to handle request", http.StatusInternalServerError)
+		return
+	}
+}
+
+// enqueueWebhookEvent persists a webhook event to the queue and wakes up the event worker.
+func (p *Plugin) enqueueWebhookEvent(eventType gitlabLib.EventType, body []byte) error {
+	e := &pendingWebhookEvent{
+		ID:        model.NewId(),
+		EventType: eventType,
+		Body:      body,
+		CreatedAt: model.GetMillis(),
+	}
+	// The events are indexed by their creation time, so the polls hand them to the event worker in order.
+	if err := p.storeQueueEntry(webhookEventQueueKeyPrefix, webhookEventQueueIndexKey, e.ID, e, e.CreatedAt); err != nil {
+		return errors.Wrap(err, "failed to store webhook event")
+	}
+
+	if p.deliveryQueue != nil {
+		p.deliveryQueue.notifyEvent(e.ID)
+	}
+	return nil
+}
+
+// processWebhookEvent handles a queued webhook event and removes it from the queue. The event is removed even when
+// its handler fails, as handling it again would fail the same way; the posts it produced are retried by the delivery queue.
+func (p *Plugin) processWebhookEvent(id string) {
+	leaseKey := webhookEventLeaseKeyPrefix + id
+	acquired, err := p.client.KV.Set(leaseKey, []byte{1}, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(deliveryLeaseDuration))
+	if err != nil || !acquired {
+		return
+	}
+	defer func() {
+		if err := p.client.KV.Delete(leaseKey); err != nil {
+			p.client.Log.Warn("can't release webhook event lease", "id", id, "err", err.Error())
+		}
+	}()
+
+	key := webhookEventQueueKeyPrefix + id
+	var e *pendingWebhookEvent
+	if err := p.client.KV.Get(key, &e); err != nil {
+		p.client.Log.Warn("can't load queued webhook event", "id", id, "err", err.Error())
+		return
+	}
+	if e == nil {
+		p.removeFromQueueIndex(webhookEventQueueIndexKey, id)
+		return
+	}
+
+	p.handleWebhookEvent(e.EventType, e.Body)
+
+	if err := p.client.KV.Delete(key); err != nil {
+		p.client.Log.Warn("can't remove handled webhook event from queue", "id", id, "err", err.Error())
+		return
+	}
+	p.removeFromQueueIndex(webhookEventQueueIndexKey, id)
+}
+
+// handleWebhookEvent runs the handler of a webhook event and queues the posts it produces.
+func (p *Plugin) handleWebhookEvent(eventType gitlabLib.EventType, body []byte) {
+	config := p.getConfiguration()
+
+	event, err := webhook.ParseWebhook(eventType, body)
+	if err != nil {
+		p.client.Log.Debug("Can't parse webhook", "err", err.Error(), "header", string(eventType), "event", string(body))
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
 	defer cancel()
 
@@ -91,7 +336,6 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	var pathWithNamespace string
 	var handlers []*webhook.HandleWebhook
 	var errHandler error
-	var warnings []string
 	fromUser := ""
 
 	switch event := event.(type) {
@@ -99,22 +343,32 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
 		pathWithNamespace = event.Project.PathWithNamespace
 		fromUser = event.User.Username
-		handlers, warnings, errHandler = p.WebhookHandler.HandleMergeRequest(ctx, event)
+		handlers, errHandler = p.WebhookHandler.HandleMergeRequest(ctx, event)
 	case *gitlabLib.IssueEvent:
 		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
 		pathWithNamespace = event.Project.PathWithNamespace
 		fromUser = event.User.Username
-		handlers, warnings, errHandler = p.WebhookHandler.HandleIssue(ctx, event, eventType)
+		handlers, errHandler = p.WebhookHandler.HandleIssue(ctx, event, eventType)
 	case *gitlabLib.IssueCommentEvent:
 		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
 		pathWithNamespace = event.Project.PathWithNamespace
 		fromUser = event.User.Username
-		handlers, warnings, errHandler = p.WebhookHandler.HandleIssueComment(ctx, event)
+		handlers, errHandler = p.WebhookHandler.HandleIssueComment(ctx, event)
 	case *gitlabLib.MergeCommentEvent:
 		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
 		pathWithNamespace = event.Project.PathWithNamespace
 		fromUser = event.User.Username
-		handlers, warnings, errHandler = p.WebhookHandler.HandleMergeRequestComment(ctx, event)
+		handlers, errHandler = p.WebhookHandler.HandleMergeRequestComment(ctx, event)
+	case *gitlabLib.CommitCommentEvent:
+		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
+		pathWithNamespace = event.Project.PathWithNamespace
+		fromUser = event.User.Username
+		handlers, errHandler = p.WebhookHandler.HandleCommitComment(ctx, event)
+	case *gitlabLib.SnippetCommentEvent:
+		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
+		pathWithNamespace = event.Project.PathWithNamespace
+		fromUser = event.User.Username
+		handlers, errHandler = p.WebhookHandler.HandleSnippetComment(ctx, event)
 	case *gitlabLib.PushEvent:
 		repoPrivate = event.Project.Visibility == gitlabLib.PrivateVisibility
 		pathWithNamespace = event.Project.PathWithNamespace
@@ -131,7 +385,8 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		handlers, errHandler = p.WebhookHandler.HandlePipeline(ctx, event)
 	case *gitlabLib.JobEvent:
 		repoPrivate = event.Repository.Visibility == gitlabLib.PrivateVisibility
-		pathWithNamespace = event.ProjectName
+		// Jobs only tell the name of their project, its path comes from its homepage as in the jobs handler.
+		pathWithNamespace = p.gitlabPathFromURL(event.Repository.Homepage)
 		fromUser = event.User.Name
 		handlers, errHandler = p.WebhookHandler.HandleJobs(ctx, event)
 	case *gitlabLib.TagEvent:
@@ -148,8 +403,47 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		pathWithNamespace = event.Project.PathWithNamespace
 		fromUser = event.User.Username
 		handlers, errHandler = p.WebhookHandler.HandleDeployment(ctx, event)
+	case *webhook.WikiPageEvent:
+		repoPrivate = event.ProjectVisibilityLevel == webhook.PrivateVisibilityLevel
+		pathWithNamespace = event.Project.PathWithNamespace
+		fromUser = event.User.Username
+		handlers, errHandler = p.WebhookHandler.HandleWikiPage(ctx, event)
+	case *gitlabLib.FeatureFlagEvent:
+		repoPrivate = event.Project.VisibilityLevel == webhook.PrivateVisibilityLevel
+		pathWithNamespace = event.Project.PathWithNamespace
+		if event.User != nil {
+			fromUser = event.User.Username
+		}
+		handlers, errHandler = p.WebhookHandler.HandleFeatureFlag(ctx, event)
+	case *gitlabLib.MemberEvent:
+		// Member events don't tell the visibility of the group, permissionToGroup checks it for each subscription.
+		pathWithNamespace = event.GroupPath
+		handlers, errHandler = p.WebhookHandler.HandleMember(ctx, event)
+	case *webhook.EmojiEvent:
+		repoPrivate = event.Project.VisibilityLevel == webhook.PrivateVisibilityLevel
+		pathWithNamespace = event.Project.PathWithNamespace
+		if event.User != nil {
+			fromUser = event.User.Username
+		}
+		handlers, errHandler = p.WebhookHandler.HandleEmoji(ctx, event)
+	case *webhook.MilestoneEvent:
+		if event.Project.PathWithNamespace != "" {
+			repoPrivate = event.Project.VisibilityLevel == webhook.PrivateVisibilityLevel
+			pathWithNamespace = event.Project.PathWithNamespace
+		} else {
+			// Group milestone events don't tell the visibility of the group, permissionToGroup checks it for each subscription.
+			pathWithNamespace = event.Group.FullPath
+		}
+		if event.User != nil {
+			fromUser = event.User.Username
+		}
+		handlers, errHandler = p.WebhookHandler.HandleMilestone(ctx, event)
+	case *webhook.VulnerabilityEvent:
+		repoPrivate = event.Private()
+		pathWithNamespace, _ = event.ProjectPath()
+		handlers, errHandler = p.WebhookHandler.HandleVulnerability(ctx, event)
 	default:
-		p.client.Log.Debug("Event type not implemented", "type", string(gitlabLib.WebhookEventType(r)))
+		p.client.Log.Debug("Event type not implemented", "type", string(eventType))
 		return
 	}
 
@@ -167,10 +461,6 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if warnings != nil {
-		p.logWarnings(warnings)
-	}
-
 	alreadySentRefresh := make(map[string]bool)
 	p.sendRefreshIfNotAlreadySent(alreadySentRefresh, fromUser)
 	for _, res := range handlers {
@@ -184,28 +474,233 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 				if info.Settings.Notifications {
-					if err := p.CreateBotDMPost(userTo, res.Message, "custom_git_review_request"); err != nil {
-						p.client.Log.Warn("can't send dm post", "err", err.Error())
+					if res.ThrottleKey != "" && p.isWebhookPostThrottled(userTo, res.ThrottleKey) {
+						continue
 					}
+					p.enqueueDelivery(&pendingDelivery{
+						DMUserID: userTo,
+						Message:  res.Message,
+						PostType: "custom_git_review_request",
+					})
 				}
 			}
 		}
+		// The quiet hours and threads of a channel are the ones of the subscription that sent the post there.
+		channelSubs := map[string]*subscription.Subscription{}
+		for _, sub := range res.Subscriptions {
+			if _, ok := channelSubs[sub.ChannelID]; !ok {
+				channelSubs[sub.ChannelID] = sub
+			}
+		}
 		for _, to := range res.ToChannels {
+			if res.ThrottleKey != "" && p.isWebhookPostThrottled(to, res.ThrottleKey) {
+				continue
+			}
 			if len(res.Message) > 0 {
-				post := &model.Post{
-					UserId:    p.BotUserID,
-					Message:   res.Message,
-					ChannelId: to,
+				d := &pendingDelivery{
+					ChannelID:      to,
+					Message:        res.Message,
+					UpdateKey:      res.UpdateKey,
+					UpdateRevision: res.UpdateRevision,
+					Attachment:     res.Attachment,
 				}
-				if err := p.client.Post.CreatePost(post); err != nil {
-					p.client.Log.Warn("can't create post for webhook event", "err", err.Error())
+				sub := channelSubs[to]
+				if sub != nil && sub.Threads() {
+					d.ThreadKey = res.ThreadKey
 				}
+				if p.holdDuringQuietHours(sub, d, time.Now()) {
+					continue
+				}
+				p.enqueueDelivery(d)
 			}
 		}
 		p.sendRefreshIfNotAlreadySent(alreadySentRefresh, res.From)
 	}
 }
 
+// webhookEventKey returns the KV key recording the delivery identified by GitLab's UUID headers.
+// The same event sent by two different hooks (say a group and a project hook) gets two keys.
+// Requests without an event UUID, as sent by older GitLab versions, have none.
+func webhookEventKey(r *http.Request) string {
+	eventUUID := r.Header.Get(headerGitlabEventUUID)
+	if eventUUID == "" {
+		return ""
+	}
+
+	key := webhookEventKeyPrefix + eventUUID
+	if webhookUUID := r.Header.Get(headerGitlabWebhookUUID); webhookUUID != "" {
+		key += "_" + webhookUUID
+	}
+	return key
+}
+
+// isDuplicateWebhookEvent records the delivery of the event key and reports whether it was already recorded,
+// e.g. because GitLab retried after a timeout or someone used "Resend" in its UI. Requests without a key are
+// never duplicates.
+func (p *Plugin) isDuplicateWebhookEvent(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	stored, err := p.client.KV.Set(key, []byte{1}, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(webhookEventTTL))
+	if err != nil {
+		p.client.Log.Warn("can't record webhook event, handling it anyway", "err", err.Error())
+		return false
+	}
+	if stored {
+		return false
+	}
+
+	err = p.client.KV.SetAtomicWithRetries(webhookDuplicateCountKey, func(oldValue []byte) (any, error) {
+		var count int64
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &count); err != nil {
+				return nil, err
+			}
+		}
+		return count + 1, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("can't increment duplicate webhook event count", "err", err.Error())
+	}
+
+	return true
+}
+
+// isWebhookPostThrottled records a post for the throttle key in the channel, or DM to the user, and reports
+// whether one was already made during the throttle window, in which case this one should be skipped.
+func (p *Plugin) isWebhookPostThrottled(channelOrUserID, throttleKey string) bool {
+	key := webhookThrottleKeyPrefix + channelOrUserID + "_" + throttleKey
+	stored, err := p.client.KV.Set(key, []byte{1}, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(webhookThrottleWindow))
+	if err != nil {
+		p.client.Log.Warn("can't record throttled webhook post, posting it anyway", "err", err.Error())
+		return false
+	}
+	return !stored
+}
+
+// pipelineStatusKey hashes the project ref, as branch names can be longer than what fits in a KV key.
+func pipelineStatusKey(projectID int, ref string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d/%s", projectID, ref)))
+	return pipelineStatusKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// swapPipelineStatus stores the status of the last finished pipeline of a project ref and returns the previous one.
+// An empty string is returned when nothing was recorded yet or the store can't be read.
+func (p *Plugin) swapPipelineStatus(projectID int, ref, status string) string {
+	var previous string
+	err := p.client.KV.SetAtomicWithRetries(pipelineStatusKey(projectID, ref), func(oldValue []byte) (any, error) {
+		previous = ""
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &previous); err != nil {
+				return nil, err
+			}
+		}
+		return status, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("can't record the pipeline status", "project_id", projectID, "ref", ref, "err", err.Error())
+		return ""
+	}
+	return previous
+}
+
+// mergeRequestPathsKey hashes the merge request, as project paths can be longer than what fits in a KV key.
+func mergeRequestPathsKey(namespace, project string, mergeRequestIID int, headSHA string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s!%d@%s", namespace, project, mergeRequestIID, headSHA)))
+	return mergeRequestPathsKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// getMergeRequestChangedPaths returns the paths changed by a merge request, as seen by the GitLab account of the
+// Mattermost user. The paths are cached by head commit, as every event of a merge request would otherwise list its diffs.
+func (p *Plugin) getMergeRequestChangedPaths(ctx context.Context, userID, namespace, project string, mergeRequestIID int, headSHA string) ([]string, error) {
+	key := mergeRequestPathsKey(namespace, project, mergeRequestIID, headSHA)
+	var paths []string
+	if err := p.client.KV.Get(key, &paths); err != nil {
+		p.client.Log.Warn("can't get the cached merge request paths", "err", err.Error())
+	} else if paths != nil {
+		return paths, nil
+	}
+
+	info, apiErr := p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var err error
+		paths, err = p.GitlabClient.GetMergeRequestChangedPaths(ctx, info, token, namespace, project, mergeRequestIID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if paths == nil {
+		// Cache merge requests without changes too.
+		paths = []string{}
+	}
+
+	if _, err := p.client.KV.Set(key, paths, pluginapi.SetExpiry(mergeRequestPathsTTL)); err != nil {
+		p.client.Log.Warn("can't cache the merge request paths", "err", err.Error())
+	}
+	return paths, nil
+}
+
+// milestoneTitleKey hashes the milestone, as project paths can be longer than what fits in a KV key.
+func milestoneTitleKey(namespace, project string, milestoneID int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s%%%d", namespace, project, milestoneID)))
+	return milestoneTitleKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// getMilestoneTitle returns the title of a milestone of a project or of its group, as seen by the GitLab account of the
+// Mattermost user. The titles are cached for a while, as every event of a merge request or issue would otherwise fetch it.
+func (p *Plugin) getMilestoneTitle(ctx context.Context, userID, namespace, project string, milestoneID int) (string, error) {
+	key := milestoneTitleKey(namespace, project, milestoneID)
+	var title string
+	if err := p.client.KV.Get(key, &title); err != nil {
+		p.client.Log.Warn("can't get the cached milestone title", "err", err.Error())
+	} else if title != "" {
+		return title, nil
+	}
+
+	info, apiErr := p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var err error
+		title, err = p.GitlabClient.GetMilestoneTitle(ctx, info, token, namespace, project, milestoneID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := p.client.KV.Set(key, title, pluginapi.SetExpiry(milestoneTitleTTL)); err != nil {
+		p.client.Log.Warn("can't cache the milestone title", "err", err.Error())
+	}
+	return title, nil
+}
+
+// forgetWebhookEvent removes the record of a delivery that couldn't be queued, so GitLab's retry is handled.
+func (p *Plugin) forgetWebhookEvent(key string) {
+	if key == "" {
+		return
+	}
+	if err := p.client.KV.Delete(key); err != nil {
+		p.client.Log.Warn("can't remove the record of the webhook event", "key", key, "err", err.Error())
+	}
+}
+
+func (p *Plugin) getDuplicateWebhookEventCount() (int64, error) {
+	var count int64
+	if err := p.client.KV.Get(webhookDuplicateCountKey, &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (p *Plugin) sendRefreshIfNotAlreadySent(alreadySentRefresh map[string]bool, gitlabUsername string) string {
 	if len(gitlabUsername) == 0 || alreadySentRefresh[gitlabUsername] {
 		return ""
@@ -218,6 +713,45 @@ func (p *Plugin) sendRefreshIfNotAlreadySent(alreadySentRefresh map[string]bool,
 	return userMattermostID
 }
 
+// permissionToGroup returns true if the GitLab account of the Mattermost user can see the group,
+// and the group isn't private while private repositories are disabled.
+func (p *Plugin) permissionToGroup(ctx context.Context, userID, groupPath string) bool {
+	if userID == "" {
+		return false
+	}
+
+	if err := p.isNamespaceAllowed(groupPath); err != nil {
+		return false
+	}
+
+	info, apiErr := p.getGitlabUserInfoByMattermostID(userID)
+	if apiErr != nil {
+		return false
+	}
+
+	var result *gitlabLib.Group
+	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		resp, err := p.GitlabClient.GetGroup(ctx, info, token, groupPath, "")
+		if err != nil {
+			return err
+		}
+		result = resp
+		return nil
+	})
+	if result == nil || err != nil {
+		if err != nil {
+			p.client.Log.Warn("Can't get group in webhook", "err", err.Error(), "group", groupPath)
+		}
+		return false
+	}
+
+	if result.Visibility == gitlabLib.PrivateVisibility && !p.getConfiguration().EnablePrivateRepo {
+		return false
+	}
+
+	return true
+}
+
 func (p *Plugin) permissionToProject(ctx context.Context, userID, namespace, project string) bool {
 	if userID == "" {
 		return false
@@ -0,0 +1,156 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+// mergeRequestStatuses is the text of the attachment of a merge request, after the action of the event.
+var mergeRequestStatuses = map[string]*i18n.Message{
+	actionMerge:      {ID: "webhook.merge_request.status.merge", Other: "Merged"},
+	actionClose:      {ID: "webhook.merge_request.status.close", Other: "Closed"},
+	actionReopen:     {ID: "webhook.merge_request.status.reopen", Other: "Reopened"},
+	actionApproved:   {ID: "webhook.merge_request.status.approved", Other: "Approved"},
+	actionUnapproved: {ID: "webhook.merge_request.status.unapproved", Other: "Changes requested"},
+	actionUpdate:     {ID: "webhook.merge_request.status.ready", Other: "Marked as ready for review"},
+}
+
+// The titles of the attachment fields.
+var (
+	fieldTargetBranch = &i18n.Message{ID: "webhook.field.target_branch", Other: "Target Branch"}
+	fieldLabels       = &i18n.Message{ID: "webhook.field.labels", Other: "Labels"}
+	fieldAssignees    = &i18n.Message{ID: "webhook.field.assignees", Other: "Assignees"}
+	fieldReviewers    = &i18n.Message{ID: "webhook.field.reviewers", Other: "Reviewers"}
+	fieldMilestone    = &i18n.Message{ID: "webhook.field.milestone", Other: "Milestone"}
+	fieldBranch       = &i18n.Message{ID: "webhook.field.branch", Other: "Branch"}
+	fieldCommit       = &i18n.Message{ID: "webhook.field.commit", Other: "Commit"}
+)
+
+func (w *webhook) mergeRequestAttachment(ctx context.Context, event *gitlab.MergeEvent, subs []*subscription.Subscription, message, locale string) *model.MessageAttachment {
+	pr := event.ObjectAttributes
+	namespace, project := normalizeNamespacedProject(event.Project.PathWithNamespace)
+
+	color := colorOpen
+	switch pr.Action {
+	case actionMerge:
+		color = colorSuccess
+	case actionClose:
+		color = colorClosed
+	}
+	text := ""
+	if status, ok := mergeRequestStatuses[pr.Action]; ok {
+		text = w.localize(locale, status, nil)
+	}
+	if pr.Action == actionOpen {
+		text = sanitizeDescription(pr.Description)
+	}
+
+	attachment := w.eventAttachment(event.User, message, color)
+	attachment.Title = fmt.Sprintf("%s!%d %s", event.Project.PathWithNamespace, pr.IID, pr.Title)
+	attachment.TitleLink = pr.URL
+	attachment.Text = text
+	attachment.Fields = attachmentFields(
+		w.localize(locale, fieldTargetBranch, nil), pr.TargetBranch,
+		w.localize(locale, fieldLabels, nil), labelToString(event.Labels),
+		w.localize(locale, fieldAssignees, nil), w.userLinks(event.Assignees),
+		w.localize(locale, fieldReviewers, nil), w.userLinks(event.Reviewers),
+		w.localize(locale, fieldMilestone, nil), w.milestoneTitle(ctx, subs, namespace, project, pr.MilestoneID),
+	)
+	return attachment
+}
+
+func (w *webhook) issueAttachment(ctx context.Context, event *gitlab.IssueEvent, subs []*subscription.Subscription, message, status, locale string) *model.MessageAttachment {
+	issue := event.ObjectAttributes
+	namespace, project := normalizeNamespacedProject(event.Project.PathWithNamespace)
+
+	color := colorOpen
+	if issue.Action == actionClose {
+		color = colorClosed
+	}
+
+	var assignees []*gitlab.EventUser
+	if event.Assignees != nil {
+		for index := range *event.Assignees {
+			assignees = append(assignees, &(*event.Assignees)[index])
+		}
+	}
+
+	attachment := w.eventAttachment(event.User, message, color)
+	attachment.Title = fmt.Sprintf("%s#%d %s", event.Project.PathWithNamespace, issue.IID, issue.Title)
+	attachment.TitleLink = issue.URL
+	attachment.Text = status
+	attachment.Fields = attachmentFields(
+		w.localize(locale, fieldLabels, nil), labelToString(event.Labels),
+		w.localize(locale, fieldAssignees, nil), w.userLinks(assignees),
+		w.localize(locale, fieldMilestone, nil), w.milestoneTitle(ctx, subs, namespace, project, issue.MilestoneID),
+	)
+	return attachment
+}
+
+// eventAttachment returns an attachment with the user of the event as author, the message being its plain text fallback.
+func (w *webhook) eventAttachment(user *gitlab.EventUser, message, color string) *model.MessageAttachment {
+	attachment := &model.MessageAttachment{
+		Fallback: message,
+		Color:    color,
+	}
+	if user != nil {
+		attachment.AuthorName = user.Username
+		attachment.AuthorLink = w.gitlabRetreiver.GetUserURL(user.Username)
+		attachment.AuthorIcon = user.AvatarURL
+	}
+	return attachment
+}
+
+// attachmentFields returns the short fields of an attachment from title and value pairs, skipping the empty values.
+func attachmentFields(titlesAndValues ...string) []*model.MessageAttachmentField {
+	fields := []*model.MessageAttachmentField{}
+	for i := 0; i+1 < len(titlesAndValues); i += 2 {
+		if titlesAndValues[i+1] == "" {
+			continue
+		}
+		fields = append(fields, &model.MessageAttachmentField{
+			Title: titlesAndValues[i],
+			Value: titlesAndValues[i+1],
+			Short: true,
+		})
+	}
+	return fields
+}
+
+func (w *webhook) userLinks(users []*gitlab.EventUser) string {
+	links := make([]string, 0, len(users))
+	for _, user := range users {
+		links = append(links, fmt.Sprintf("[%s](%s)", user.Username, w.gitlabRetreiver.GetUserURL(user.Username)))
+	}
+	return strings.Join(links, ", ")
+}
+
+// milestoneTitle returns the title of a milestone, as events only carry its ID, or an empty string if none of the
+// subscription creators can see it.
+func (w *webhook) milestoneTitle(ctx context.Context, subs []*subscription.Subscription, namespace, project string, milestoneID int) string {
+	if milestoneID == 0 {
+		return ""
+	}
+	tried := map[string]bool{}
+	for _, sub := range subs {
+		if tried[sub.CreatorID] {
+			continue
+		}
+		tried[sub.CreatorID] = true
+		title, err := w.gitlabRetreiver.GetMilestoneTitle(ctx, sub.CreatorID, namespace, project, milestoneID)
+		if err == nil {
+			return title
+		}
+	}
+	return ""
+}
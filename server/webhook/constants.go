@@ -27,6 +27,12 @@ const (
 	statusUpdate = "update"
 	statusDelete = "delete"
 
+	// Colors of the attachments, after the state of the merge request, issue or pipeline.
+	colorOpen    = "#1f75cb"
+	colorSuccess = "#108548"
+	colorFailed  = "#dd2b0e"
+	colorClosed  = "#737278"
+
 	PrivateVisibilityLevel = 0
 	PublicVisibilityLevel  = 20
 )
@@ -7,7 +7,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 func (w *webhook) HandleDeployment(ctx context.Context, event *gitlab.DeploymentEvent) ([]*HandleWebhook, error) {
@@ -22,19 +25,21 @@ func (w *webhook) handleChannelDeployment(ctx context.Context, event *gitlab.Dep
 	senderGitlabUsername := event.User.Username
 	project := event.Project
 	res := []*HandleWebhook{}
-	message := fmt.Sprintf("### Deployment Stage: **%s**\n", event.Status)
+	locale := w.gitlabRetreiver.GetServerLocale()
+	message := w.localize(locale, &i18n.Message{ID: "webhook.deployment.channel.header", Other: "### Deployment Stage: **{{.Status}}**"}, map[string]any{"Status": event.Status}) + "\n"
 
+	status := w.localize(locale, lineStatus, map[string]any{"Status": event.Status})
 	switch event.Status {
 	case statusRunning:
-		message += fmt.Sprintf(":rocket: **Status**: %s\n", event.Status)
+		message += ":rocket: " + status + "\n"
 	case statusCreated:
-		message += fmt.Sprintf(":clock1: **Status**: %s\n", event.Status)
+		message += ":clock1: " + status + "\n"
 	case statusCanceled:
-		message += fmt.Sprintf(":no_entry_sign: **Status**: %s\n", event.Status)
+		message += ":no_entry_sign: " + status + "\n"
 	case statusSuccess:
-		message += fmt.Sprintf(":large_green_circle: **Status**: %s\n", event.Status)
+		message += ":large_green_circle: " + status + "\n"
 	case statusFailed:
-		message += fmt.Sprintf(":red_circle: **Status**: %s\n", event.Status)
+		message += ":red_circle: " + status + "\n"
 	default:
 		return res, nil
 	}
@@ -44,31 +49,77 @@ func (w *webhook) handleChannelDeployment(ctx context.Context, event *gitlab.Dep
 		return nil, err
 	}
 
-	fullNamespacePath := fmt.Sprintf("%s/%s", namespaceMetadata.Namespace, namespaceMetadata.Project)
-	message += fmt.Sprintf("**Repository**: [%s](%s)\n", fullNamespacePath, event.Project.GitHTTPURL)
-	message += fmt.Sprintf("**Triggered By**: %s\n", senderGitlabUsername)
-	message += fmt.Sprintf("**Visit deployment [here](%s)** \n", event.DeployableURL)
-
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	creatorIDs := make([]string, 0)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespaceMetadata.Namespace,
 		namespaceMetadata.Project,
 		project.VisibilityLevel == PublicVisibilityLevel,
 	)
 	for _, sub := range subs {
-		if !sub.Deployments() {
+		if !sub.Deployments() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+		if !sub.MatchesEnvironment(event.Environment) || !sub.DeploymentStatusMatches(event.Status) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+		creatorIDs = append(creatorIDs, sub.CreatorID)
+	}
+
+	fullNamespacePath := fmt.Sprintf("%s/%s", namespaceMetadata.Namespace, namespaceMetadata.Project)
+	message += w.localize(locale, lineRepository, map[string]any{"Project": fullNamespacePath, "URL": event.Project.GitHTTPURL}) + "\n"
+	if event.Environment != "" {
+		data := map[string]any{"Environment": event.Environment, "URL": event.EnvironmentExternalURL}
+		if event.EnvironmentExternalURL != "" {
+			message += w.localize(locale, &i18n.Message{ID: "webhook.deployment.channel.environment_link", Other: "**Environment**: [{{.Environment}}]({{.URL}})"}, data) + "\n"
+		} else {
+			message += w.localize(locale, &i18n.Message{ID: "webhook.deployment.channel.environment", Other: "**Environment**: {{.Environment}}"}, data) + "\n"
+		}
+	}
+	if event.ShortSHA != "" {
+		message += w.localize(locale, &i18n.Message{
+			ID:    "webhook.deployment.channel.commit",
+			Other: "**Commit**: [{{.SHA}}]({{.URL}}) {{.Title}}",
+		}, map[string]any{"SHA": event.ShortSHA, "URL": event.CommitURL, "Title": event.CommitTitle}) + "\n"
+
+		if event.Environment != "" && len(toChannels) > 0 {
+			// The previous deployment is the same for every channel, fetch it with the first subscriber able to.
+			for _, creatorID := range creatorIDs {
+				previousSHA, err := w.gitlabRetreiver.GetPreviousDeploymentSHA(ctx, creatorID, namespaceMetadata.Namespace, namespaceMetadata.Project, event.Environment, event.DeploymentID)
+				if err != nil {
+					continue
+				}
+				if previousSHA != "" {
+					message += w.localize(locale, &i18n.Message{
+						ID:    "webhook.deployment.channel.previous",
+						Other: "**Previous Deployment**: {{.SHA}} ([compare]({{.CompareURL}}))",
+					}, map[string]any{
+						"SHA":        shortCommitSHA(previousSHA),
+						"CompareURL": fmt.Sprintf("%s/-/compare/%s...%s", event.Project.WebURL, previousSHA, event.ShortSHA),
+					}) + "\n"
+				}
+				break
+			}
+		}
+	}
+	message += w.localize(locale, lineTriggeredBy, map[string]any{"User": senderGitlabUsername}) + "\n"
+	deployableURL := event.DeployableURL
+	if event.DeployableID != 0 {
+		deployableURL = w.gitlabRetreiver.GetJobURL(fullNamespacePath, event.DeployableID)
 	}
+	message += w.localize(locale, &i18n.Message{ID: "webhook.deployment.channel.visit", Other: "**Visit deployment [here]({{.URL}})** "}, map[string]any{"URL": deployableURL}) + "\n"
 
 	if len(toChannels) > 0 {
 		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
-			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
 
@@ -102,3 +102,37 @@ const DeploymentEventWithoutAction = `{
 	"deployable_url": "http://localhost:3000/myorg/myrepo/deployment/000",
 	"status": ""
 }`
+
+const DeploymentEventProduction = `{
+	"object_kind": "deployment",
+	"status": "success",
+	"status_changed_at": "2024-03-04 10:12:40 +0100",
+	"deployment_id": 15,
+	"deployable_id": 796,
+	"deployable_url": "http://localhost:3000/myorg/myrepo/-/jobs/796",
+	"environment": "production",
+	"environment_slug": "production",
+	"environment_external_url": "https://myrepo.example.com",
+	"project": {
+		"id": 24,
+		"name": "myrepo",
+		"namespace": "myorg",
+		"web_url": "http://localhost:3000/myorg/myrepo",
+		"avatar_url": null,
+		"git_ssh_url": "ssh://user@localhost:2222/myorg/myrepo.git",
+		"git_http_url": "http://localhost:3000/myorg/myrepo.git",
+		"visibility_level": 20,
+		"path_with_namespace": "myorg/myrepo",
+		"default_branch": "main",
+		"ci_config_path": null,
+		"homepage": "http://localhost:3000/myorg/myrepo"
+	},
+	"ref": "main",
+	"short_sha": "b2c3d4e5",
+	"user": {
+		"username": "testuser"
+	},
+	"user_url": "http://localhost:3000/testuser",
+	"commit_url": "http://localhost:3000/myorg/myrepo/-/commit/b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1",
+	"commit_title": "Bump billing service"
+}`
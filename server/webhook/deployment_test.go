@@ -10,6 +10,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 type testDataDeploymentStr struct {
@@ -31,7 +33,7 @@ var testDataDeployment = []testDataDeploymentStr{
 				"**Triggered By**: testuser\n" +
 				"**Visit deployment [here](http://localhost:3000/myorg/myrepo/deployment/123)** \n",
 			ToUsers:    []string{},
-			ToChannels: []string{"channel1"},
+			ToChannels: []string{MockChannelID},
 			From:       "testuser",
 		}},
 	},
@@ -46,7 +48,7 @@ var testDataDeployment = []testDataDeploymentStr{
 				"**Triggered By**: testuser\n" +
 				"**Visit deployment [here](http://localhost:3000/myorg/myrepo/deployment/456)** \n",
 			ToUsers:    []string{},
-			ToChannels: []string{"channel1"},
+			ToChannels: []string{MockChannelID},
 			From:       "testuser",
 		}},
 	},
@@ -61,10 +63,65 @@ var testDataDeployment = []testDataDeploymentStr{
 				"**Triggered By**: testuser\n" +
 				"**Visit deployment [here](http://localhost:3000/myorg/myrepo/deployment/789)** \n",
 			ToUsers:    []string{},
+			ToChannels: []string{MockChannelID},
+			From:       "testuser",
+		}},
+	},
+	{
+		testTitle: "successful deployment to production with its commit and the previous deployment",
+		fixture:   DeploymentEventProduction,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `deployments:success,environment:"prod*"`, "myorg/myrepo"),
+		}).withPreviousDeployment("a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0"),
+		res: []*HandleWebhook{{
+			Message: "### Deployment Stage: **success**\n" +
+				":large_green_circle: **Status**: success\n" +
+				"**Repository**: [myorg/myrepo](http://localhost:3000/myorg/myrepo.git)\n" +
+				"**Environment**: [production](https://myrepo.example.com)\n" +
+				"**Commit**: [b2c3d4e5](http://localhost:3000/myorg/myrepo/-/commit/b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1) Bump billing service\n" +
+				"**Previous Deployment**: a1b2c3d4 ([compare](http://localhost:3000/myorg/myrepo/-/compare/a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0...b2c3d4e5))\n" +
+				"**Triggered By**: testuser\n" +
+				"**Visit deployment [here](http://my.gitlab.com/myorg/myrepo/-/jobs/796)** \n",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "testuser",
+		}},
+	},
+	{
+		testTitle: "first deployment to production without previous deployment",
+		fixture:   DeploymentEventProduction,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "deployments", "myorg/myrepo"),
+		}),
+		res: []*HandleWebhook{{
+			Message: "### Deployment Stage: **success**\n" +
+				":large_green_circle: **Status**: success\n" +
+				"**Repository**: [myorg/myrepo](http://localhost:3000/myorg/myrepo.git)\n" +
+				"**Environment**: [production](https://myrepo.example.com)\n" +
+				"**Commit**: [b2c3d4e5](http://localhost:3000/myorg/myrepo/-/commit/b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1) Bump billing service\n" +
+				"**Triggered By**: testuser\n" +
+				"**Visit deployment [here](http://my.gitlab.com/myorg/myrepo/-/jobs/796)** \n",
+			ToUsers:    []string{},
 			ToChannels: []string{"channel1"},
 			From:       "testuser",
 		}},
 	},
+	{
+		testTitle: "deployment to another environment than the subscribed one",
+		fixture:   DeploymentEventProduction,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `deployments,environment:"staging"`, "myorg/myrepo"),
+		}),
+		res: nil,
+	},
+	{
+		testTitle: "deployment with another status than the subscribed ones",
+		fixture:   DeploymentEventProduction,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "deployments:failed,deployments:canceled", "myorg/myrepo"),
+		}),
+		res: nil,
+	},
 	{
 		testTitle:       "deployment with no action",
 		fixture:         DeploymentEventWithoutAction,
@@ -88,6 +145,8 @@ func TestDeploymentWebhook(t *testing.T) {
 			for index := range res {
 				assert.Equal(t, test.res[index].Message, res[index].Message)
 				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
 				assert.Equal(t, test.res[index].From, res[index].From)
 			}
 		})
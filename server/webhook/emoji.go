@@ -0,0 +1,194 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+const (
+	emojiEventAward        = "award"
+	awardableMergeRequest  = "MergeRequest"
+	awardableIssue         = "Issue"
+	emojiThumbsUp          = "thumbsup"
+	emojiThumbsDown        = "thumbsdown"
+	emojiThrottleKeyFormat = "%d_%s_%d"
+)
+
+// EmojiEvent represents an emoji awarded to or revoked from an issue, merge request, note or snippet.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#emoji-events
+type EmojiEvent struct {
+	ObjectKind string            `json:"object_kind"`
+	EventType  string            `json:"event_type"`
+	User       *gitlab.EventUser `json:"user"`
+	ProjectID  int               `json:"project_id"`
+	Project    struct {
+		ID                int    `json:"id"`
+		Name              string `json:"name"`
+		WebURL            string `json:"web_url"`
+		VisibilityLevel   int    `json:"visibility_level"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID            int    `json:"id"`
+		UserID        int    `json:"user_id"`
+		Name          string `json:"name"`
+		AwardableType string `json:"awardable_type"`
+		AwardableID   int    `json:"awardable_id"`
+	} `json:"object_attributes"`
+	Issue        *emojiAwardable `json:"issue"`
+	MergeRequest *emojiAwardable `json:"merge_request"`
+}
+
+// emojiAwardable holds the fields of an awarded issue or merge request used in messages.
+type emojiAwardable struct {
+	ID       int    `json:"id"`
+	IID      int    `json:"iid"`
+	Title    string `json:"title"`
+	AuthorID int    `json:"author_id"`
+	URL      string `json:"url"`
+}
+
+func (w *webhook) HandleEmoji(ctx context.Context, event *EmojiEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleDMEmoji(event)
+	if err != nil {
+		return nil, err
+	}
+	handlers2, err := w.handleChannelEmoji(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
+}
+
+// The messages of the emoji events, by awardable type.
+var (
+	emojiDMMessages = map[string]*i18n.Message{
+		awardableMergeRequest: {
+			ID:    "webhook.emoji.dm.merge_request",
+			Other: "[{{.Sender}}]({{.SenderURL}}) reacted :{{.Emoji}}: to your merge request [{{.Project}}{{.Reference}}]({{.URL}})",
+		},
+		awardableIssue: {
+			ID:    "webhook.emoji.dm.issue",
+			Other: "[{{.Sender}}]({{.SenderURL}}) reacted :{{.Emoji}}: to your issue [{{.Project}}{{.Reference}}]({{.URL}})",
+		},
+	}
+	emojiChannelMessages = map[string]*i18n.Message{
+		awardableMergeRequest: {
+			ID:    "webhook.emoji.channel.merge_request",
+			Other: "[{{.Project}}]({{.ProjectURL}}) [{{.Sender}}]({{.SenderURL}}) reacted :{{.Emoji}}: to merge request [{{.Reference}} {{.Title}}]({{.URL}})",
+		},
+		awardableIssue: {
+			ID:    "webhook.emoji.channel.issue",
+			Other: "[{{.Project}}]({{.ProjectURL}}) [{{.Sender}}]({{.SenderURL}}) reacted :{{.Emoji}}: to issue [{{.Reference}} {{.Title}}]({{.URL}})",
+		},
+	}
+)
+
+// emojiTarget returns what the emoji was awarded to, with the reference used in messages,
+// or nil when it isn't a thumbs up or down awarded to an issue or merge request.
+func emojiTarget(event *EmojiEvent) (awardable *emojiAwardable, reference string) {
+	if event.EventType != emojiEventAward || event.User == nil {
+		return nil, ""
+	}
+	if event.ObjectAttributes.Name != emojiThumbsUp && event.ObjectAttributes.Name != emojiThumbsDown {
+		return nil, ""
+	}
+
+	switch event.ObjectAttributes.AwardableType {
+	case awardableMergeRequest:
+		if event.MergeRequest == nil {
+			return nil, ""
+		}
+		return event.MergeRequest, fmt.Sprintf("!%d", event.MergeRequest.IID)
+	case awardableIssue:
+		if event.Issue == nil {
+			return nil, ""
+		}
+		return event.Issue, fmt.Sprintf("#%d", event.Issue.IID)
+	default:
+		return nil, ""
+	}
+}
+
+func (w *webhook) handleDMEmoji(event *EmojiEvent) ([]*HandleWebhook, error) {
+	awardable, reference := emojiTarget(event)
+	if awardable == nil {
+		return []*HandleWebhook{}, nil
+	}
+
+	authorGitlabUsername := w.gitlabRetreiver.GetUsernameByID(awardable.AuthorID)
+	senderGitlabUsername := event.User.Username
+	handlers := w.dmHandlers(senderGitlabUsername, []string{authorGitlabUsername}, emojiDMMessages[event.ObjectAttributes.AwardableType], map[string]any{
+		"Sender":    senderGitlabUsername,
+		"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Emoji":     event.ObjectAttributes.Name,
+		"Project":   event.Project.PathWithNamespace,
+		"Reference": reference,
+		"URL":       awardable.URL,
+	})
+	// The author gets a single DM for a burst of reactions, as they do in channels.
+	for _, handler := range handlers {
+		handler.ThrottleKey = fmt.Sprintf(emojiThrottleKeyFormat, event.Project.ID, event.ObjectAttributes.AwardableType, awardable.ID)
+	}
+	return handlers, nil
+}
+
+func (w *webhook) handleChannelEmoji(ctx context.Context, event *EmojiEvent) ([]*HandleWebhook, error) {
+	res := []*HandleWebhook{}
+	awardable, reference := emojiTarget(event)
+	if awardable == nil {
+		return res, nil
+	}
+
+	repo := event.Project
+	senderGitlabUsername := event.User.Username
+	message := w.localize(w.gitlabRetreiver.GetServerLocale(), emojiChannelMessages[event.ObjectAttributes.AwardableType], map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Emoji":      event.ObjectAttributes.Name,
+		"Reference":  reference,
+		"Title":      awardable.Title,
+		"URL":        awardable.URL,
+	})
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		repo.VisibilityLevel == PublicVisibilityLevel,
+	)
+	for _, sub := range subs {
+		if !sub.Reactions() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+			ThrottleKey:   fmt.Sprintf(emojiThrottleKeyFormat, repo.ID, event.ObjectAttributes.AwardableType, awardable.ID),
+		})
+	}
+
+	return res, nil
+}
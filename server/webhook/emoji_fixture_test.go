@@ -0,0 +1,89 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const EmojiAwardedMergeRequest = `{
+	"object_kind":"emoji",
+	"event_type":"award",
+	"user":{
+		"id":1,
+		"name":"Administrator",
+		"username":"root",
+		"avatar_url":"https://www.gravatar.com/avatar/e64c7d89f26bd1972efa854d13d7dd61?s=80&d=identicon",
+		"email":"admin@example.com"
+	},
+	"project_id":24,
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"ci_config_path":null,
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"object_attributes":{
+		"user_id":1,
+		"created_at":"2019-06-16 18:40:00 UTC",
+		"id":7,
+		"name":"thumbsup",
+		"awardable_type":"MergeRequest",
+		"awardable_id":38,
+		"updated_at":"2019-06-16 18:40:00 UTC"
+	},
+	"merge_request":{
+		"id":38,
+		"iid":4,
+		"title":"Update README.md",
+		"author_id":50,
+		"state":"opened",
+		"url":"http://localhost:3000/manland/webhook/merge_requests/4"
+	}
+}`
+
+const EmojiAwardedIssue = `{
+	"object_kind":"emoji",
+	"event_type":"award",
+	"user":{
+		"id":1,
+		"name":"Administrator",
+		"username":"root",
+		"avatar_url":"https://www.gravatar.com/avatar/e64c7d89f26bd1972efa854d13d7dd61?s=80&d=identicon",
+		"email":"admin@example.com"
+	},
+	"project_id":24,
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook"
+	},
+	"object_attributes":{
+		"user_id":1,
+		"created_at":"2019-06-16 18:40:00 UTC",
+		"id":8,
+		"name":"thumbsdown",
+		"awardable_type":"Issue",
+		"awardable_id":13,
+		"updated_at":"2019-06-16 18:40:00 UTC"
+	},
+	"issue":{
+		"id":13,
+		"iid":1,
+		"title":"test title",
+		"author_id":50,
+		"state":"opened",
+		"url":"http://localhost:3000/manland/webhook/issues/1"
+	}
+}`
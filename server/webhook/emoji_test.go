@@ -0,0 +1,134 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataEmojiStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataEmoji = []testDataEmojiStr{
+	{
+		testTitle: "root thumbs up a merge request of manland",
+		fixture:   EmojiAwardedMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,reactions", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:     "[root](http://my.gitlab.com/root) reacted :thumbsup: to your merge request [manland/webhook!4](http://localhost:3000/manland/webhook/merge_requests/4)",
+			ToUsers:     []string{"manland"},
+			ToChannels:  []string{},
+			From:        "root",
+			ThrottleKey: "24_MergeRequest_38",
+		}, {
+			Message:     "[manland/webhook](http://localhost:3000/manland/webhook) [root](http://my.gitlab.com/root) reacted :thumbsup: to merge request [!4 Update README.md](http://localhost:3000/manland/webhook/merge_requests/4)",
+			ToUsers:     []string{},
+			ToChannels:  []string{"channel1"},
+			From:        "root",
+			ThrottleKey: "24_MergeRequest_38",
+		}},
+	},
+	{
+		testTitle: "root thumbs down an issue of manland",
+		fixture:   EmojiAwardedIssue,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "issues,reactions", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:     "[root](http://my.gitlab.com/root) reacted :thumbsdown: to your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
+			ToUsers:     []string{"manland"},
+			ToChannels:  []string{},
+			From:        "root",
+			ThrottleKey: "24_Issue_13",
+		}, {
+			Message:     "[manland/webhook](http://localhost:3000/manland/webhook) [root](http://my.gitlab.com/root) reacted :thumbsdown: to issue [#1 test title](http://localhost:3000/manland/webhook/issues/1)",
+			ToUsers:     []string{},
+			ToChannels:  []string{"channel1"},
+			From:        "root",
+			ThrottleKey: "24_Issue_13",
+		}},
+	},
+	{
+		testTitle: "channel not subscribed to reactions",
+		fixture:   EmojiAwardedMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:     "[root](http://my.gitlab.com/root) reacted :thumbsup: to your merge request [manland/webhook!4](http://localhost:3000/manland/webhook/merge_requests/4)",
+			ToUsers:     []string{"manland"},
+			ToChannels:  []string{},
+			From:        "root",
+			ThrottleKey: "24_MergeRequest_38",
+		}},
+	},
+	{
+		testTitle: "other emoji",
+		fixture:   strings.Replace(EmojiAwardedMergeRequest, `"name":"thumbsup"`, `"name":"tada"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,reactions", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+	{
+		testTitle: "revoked emoji",
+		fixture:   strings.Replace(EmojiAwardedMergeRequest, `"event_type":"award"`, `"event_type":"revoke"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,reactions", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+	{
+		testTitle: "emoji on a note",
+		fixture:   strings.Replace(EmojiAwardedMergeRequest, `"awardable_type":"MergeRequest"`, `"awardable_type":"Note"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,reactions", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestEmojiWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataEmoji {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			event, err := ParseWebhook(EventTypeEmoji, []byte(test.fixture))
+			require.NoError(t, err)
+			emojiEvent, ok := event.(*EmojiEvent)
+			require.True(t, ok)
+			res, err := w.HandleEmoji(context.Background(), emojiEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+				assert.Equal(t, test.res[index].ThrottleKey, res[index].ThrottleKey)
+			}
+		})
+	}
+}
+
+func TestParseWebhookFallsBackToGitlab(t *testing.T) {
+	event, err := ParseWebhook(gitlab.EventTypeFeatureFlag, []byte(FeatureFlagActivated))
+	require.NoError(t, err)
+	assert.IsType(t, &gitlab.FeatureFlagEvent{}, event)
+}
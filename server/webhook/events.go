@@ -0,0 +1,39 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// X-Gitlab-Event headers of the events the GitLab client library doesn't know about.
+const (
+	EventTypeEmoji         gitlab.EventType = "Emoji Hook"
+	EventTypeMilestone     gitlab.EventType = "Milestone Hook"
+	EventTypeVulnerability gitlab.EventType = "Vulnerability Hook"
+)
+
+// ParseWebhook parses a webhook payload like gitlab.ParseWebhook, including the events the GitLab client library doesn't know about.
+func ParseWebhook(eventType gitlab.EventType, payload []byte) (any, error) {
+	var event any
+	switch eventType {
+	case EventTypeEmoji:
+		event = &EmojiEvent{}
+	case EventTypeMilestone:
+		event = &MilestoneEvent{}
+	case EventTypeVulnerability:
+		event = &VulnerabilityEvent{}
+	case gitlab.EventTypeWikiPage:
+		event = &WikiPageEvent{}
+	default:
+		return gitlab.ParseWebhook(eventType, payload)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+func (w *webhook) HandleFeatureFlag(ctx context.Context, event *gitlab.FeatureFlagEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleChannelFeatureFlag(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(handlers), nil
+}
+
+func (w *webhook) handleChannelFeatureFlag(ctx context.Context, event *gitlab.FeatureFlagEvent) ([]*HandleWebhook, error) {
+	repo := event.Project
+	flag := event.ObjectAttributes
+	res := []*HandleWebhook{}
+
+	locale := w.gitlabRetreiver.GetServerLocale()
+	senderGitlabUsername := ""
+	sender := w.localize(locale, &i18n.Message{ID: "webhook.feature_flag.someone", Other: "someone"}, nil)
+	if event.User != nil {
+		senderGitlabUsername = event.User.Username
+		sender = fmt.Sprintf("[%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+	}
+
+	data := map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Flag":       flag.Name,
+		"Sender":     sender,
+	}
+	message := w.localize(locale, &i18n.Message{
+		ID:    "webhook.feature_flag.channel.deactivated",
+		Other: "[{{.Project}}]({{.ProjectURL}}) Feature flag [{{.Flag}}]({{.ProjectURL}}/-/feature_flags) deactivated by {{.Sender}}",
+	}, data)
+	if flag.Active {
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.feature_flag.channel.activated",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Feature flag [{{.Flag}}]({{.ProjectURL}}/-/feature_flags) activated by {{.Sender}}",
+		}, data)
+	}
+	if flag.Description != "" {
+		message += "\n" + flag.Description
+	}
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		repo.VisibilityLevel == PublicVisibilityLevel,
+	)
+	for _, sub := range subs {
+		if !sub.FeatureFlags() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+		})
+	}
+
+	return res, nil
+}
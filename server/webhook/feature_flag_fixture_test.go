@@ -0,0 +1,40 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const FeatureFlagActivated = `{
+	"object_kind":"feature_flag",
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"ci_config_path":null,
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"user":{
+		"id":50,
+		"name":"Romain Maneschi",
+		"username":"manland",
+		"avatar_url":"https://www.gravatar.com/avatar/3bd6dcfae3a0f8f8d6cb3ec8ea5a5a3c?s=80&d=identicon",
+		"email":"manland@example.com"
+	},
+	"user_url":"http://localhost:3000/manland",
+	"object_attributes":{
+		"id":6,
+		"name":"new_checkout",
+		"description":"Use the new checkout flow",
+		"active":true
+	}
+}`
@@ -0,0 +1,83 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataFeatureFlagStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataFeatureFlag = []testDataFeatureFlagStr{
+	{
+		testTitle: "manland activates a feature flag",
+		fixture:   FeatureFlagActivated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "feature_flags", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Feature flag [new_checkout](http://localhost:3000/manland/webhook/-/feature_flags) activated by [manland](http://my.gitlab.com/manland)\nUse the new checkout flow",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "manland deactivates a feature flag",
+		fixture:   strings.Replace(FeatureFlagActivated, `"active":true`, `"active":false`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "feature_flags", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Feature flag [new_checkout](http://localhost:3000/manland/webhook/-/feature_flags) deactivated by [manland](http://my.gitlab.com/manland)\nUse the new checkout flow",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "channel not subscribed to feature flags",
+		fixture:   FeatureFlagActivated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,issues", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestFeatureFlagWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataFeatureFlag {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			featureFlagEvent := &gitlab.FeatureFlagEvent{}
+			if err := json.Unmarshal([]byte(test.fixture), featureFlagEvent); err != nil {
+				assert.Fail(t, "can't unmarshal fixture")
+			}
+			res, err := w.HandleFeatureFlag(context.Background(), featureFlagEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+)
+
+// localize returns the message in the language, filled with the data.
+func (w *webhook) localize(locale string, message *i18n.Message, data any) string {
+	return w.gitlabRetreiver.Localize(locale, &i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData:   data,
+	})
+}
+
+// localizePlural returns the plural form of the message for the count in the language, filled with the data.
+func (w *webhook) localizePlural(locale string, message *i18n.Message, count int, data any) string {
+	return w.gitlabRetreiver.Localize(locale, &i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData:   data,
+		PluralCount:    count,
+	})
+}
+
+// dmHandlers returns the handlers of a DM to the users, one per language they read in, the message being built in
+// each of them.
+func (w *webhook) dmHandlers(from string, toUsers []string, message *i18n.Message, data any) []*HandleWebhook {
+	var locales []string
+	usersByLocale := map[string][]string{}
+	for _, username := range toUsers {
+		// The sender and unknown users don't get the DM.
+		if username == from || username == "" {
+			continue
+		}
+		locale := w.gitlabRetreiver.GetUserLocale(username)
+		if _, ok := usersByLocale[locale]; !ok {
+			locales = append(locales, locale)
+		}
+		usersByLocale[locale] = append(usersByLocale[locale], username)
+	}
+	if len(locales) == 0 {
+		// The handler is kept for its sender even when nobody gets the DM.
+		locales = []string{w.gitlabRetreiver.GetServerLocale()}
+	}
+
+	handlers := make([]*HandleWebhook, 0, len(locales))
+	for _, locale := range locales {
+		handlers = append(handlers, &HandleWebhook{
+			Message: w.localize(locale, message, data),
+			ToUsers: usersByLocale[locale],
+			From:    from,
+		})
+	}
+	return handlers
+}
+
+// The lines shared by the deployment, job and release posts.
+var (
+	lineStatus      = &i18n.Message{ID: "webhook.line.status", Other: "**Status**: {{.Status}}"}
+	lineRepository  = &i18n.Message{ID: "webhook.line.repository", Other: "**Repository**: [{{.Project}}]({{.URL}})"}
+	lineTriggeredBy = &i18n.Message{ID: "webhook.line.triggered_by", Other: "**Triggered By**: {{.User}}"}
+)
@@ -0,0 +1,52 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDMHandlers(t *testing.T) {
+	message := &i18n.Message{
+		ID:    "webhook.issue.dm.close",
+		Other: "[{{.Sender}}]({{.SenderURL}}) closed your issue [{{.Project}}#{{.IID}}]({{.URL}})",
+	}
+	data := map[string]any{
+		"Sender":    "root",
+		"SenderURL": "http://my.gitlab.com/root",
+		"Project":   "manland/webhook",
+		"IID":       1,
+		"URL":       "http://localhost:3000/manland/webhook/issues/1",
+	}
+
+	t.Run("one handler per language", func(t *testing.T) {
+		w := &webhook{gitlabRetreiver: newFakeWebhook(nil).withUserLocale("manland", "de").withUserLocale("bob", "de").withUserLocale("alice", "en")}
+
+		handlers := w.dmHandlers("root", []string{"manland", "root", "", "alice", "bob"}, message, data)
+
+		assert.Equal(t, []*HandleWebhook{{
+			Message: "[root](http://my.gitlab.com/root) hat dein Issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1) geschlossen",
+			ToUsers: []string{"manland", "bob"},
+			From:    "root",
+		}, {
+			Message: "[root](http://my.gitlab.com/root) closed your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
+			ToUsers: []string{"alice"},
+			From:    "root",
+		}}, handlers)
+	})
+
+	t.Run("sender only", func(t *testing.T) {
+		w := &webhook{gitlabRetreiver: newFakeWebhook(nil).withServerLocale("ja")}
+
+		handlers := w.dmHandlers("root", []string{"root"}, message, data)
+
+		assert.Equal(t, []*HandleWebhook{{
+			Message: "[root](http://my.gitlab.com/root) があなたのイシュー [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1) をクローズしました",
+			From:    "root",
+		}}, handlers)
+	})
+}
@@ -7,40 +7,50 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
-func (w *webhook) HandleIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, []string, error) {
-	var warnings []string
+func (w *webhook) HandleIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, error) {
 	handlers, err := w.handleDMIssue(event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	handlers2, warnings, err := w.handleChannelIssue(ctx, event, eventType)
+	handlers2, err := w.handleChannelIssue(ctx, event, eventType)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	return cleanWebhookHandlers(append(handlers, handlers2...)), warnings, nil
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
 }
 
 func (w *webhook) handleDMIssue(event *gitlab.IssueEvent) ([]*HandleWebhook, error) {
 	authorGitlabUsername := w.gitlabRetreiver.GetUsernameByID(event.ObjectAttributes.AuthorID)
 	senderGitlabUsername := event.User.Username
 
-	message := ""
-
+	var message *i18n.Message
 	switch event.ObjectAttributes.Action {
 	case actionOpen:
 		if event.Assignees != nil && len(*event.Assignees) > 0 {
-			message = fmt.Sprintf("[%s](%s) assigned you to issue [%s#%v](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Project.PathWithNamespace, event.ObjectAttributes.IID, event.ObjectAttributes.URL)
+			message = &i18n.Message{
+				ID:    "webhook.issue.dm.assign",
+				Other: "[{{.Sender}}]({{.SenderURL}}) assigned you to issue [{{.Project}}#{{.IID}}]({{.URL}})",
+			}
 		}
 	case actionClose:
-		message = fmt.Sprintf("[%s](%s) closed your issue [%s#%v](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Project.PathWithNamespace, event.ObjectAttributes.IID, event.ObjectAttributes.URL)
+		message = &i18n.Message{
+			ID:    "webhook.issue.dm.close",
+			Other: "[{{.Sender}}]({{.SenderURL}}) closed your issue [{{.Project}}#{{.IID}}]({{.URL}})",
+		}
 	case actionReopen:
-		message = fmt.Sprintf("[%s](%s) reopened your issue [%s#%v](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Project.PathWithNamespace, event.ObjectAttributes.IID, event.ObjectAttributes.URL)
+		message = &i18n.Message{
+			ID:    "webhook.issue.dm.reopen",
+			Other: "[{{.Sender}}]({{.SenderURL}}) reopened your issue [{{.Project}}#{{.IID}}]({{.URL}})",
+		}
 	}
 
-	if message != "" {
+	if message != nil {
 		toUsers := []string{}
 		if event.Assignees != nil {
 			for _, assignee := range *event.Assignees {
@@ -49,82 +59,155 @@ func (w *webhook) handleDMIssue(event *gitlab.IssueEvent) ([]*HandleWebhook, err
 		}
 		toUsers = append(toUsers, authorGitlabUsername)
 
-		handlers := []*HandleWebhook{{
-			Message: message,
-			ToUsers: toUsers,
-			From:    senderGitlabUsername,
-		}}
+		handlers := w.dmHandlers(senderGitlabUsername, toUsers, message, map[string]any{
+			"Sender":    senderGitlabUsername,
+			"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			"Project":   event.Project.PathWithNamespace,
+			"IID":       event.ObjectAttributes.IID,
+			"URL":       event.ObjectAttributes.URL,
+		})
 
-		if mention := w.handleMention(mentionDetails{
+		handlers = append(handlers, w.handleMention(mentionDetails{
 			senderUsername:    senderGitlabUsername,
 			pathWithNamespace: event.Project.PathWithNamespace,
 			IID:               fmt.Sprintf("%d", event.ObjectAttributes.IID),
 			URL:               event.ObjectAttributes.URL,
 			body:              sanitizeDescription(event.ObjectAttributes.Description),
-		}); mention != nil {
-			handlers = append(handlers, mention)
-		}
+		})...)
 		return handlers, nil
 	}
 	return []*HandleWebhook{}, nil
 }
 
-func (w *webhook) handleChannelIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, []string, error) {
+func (w *webhook) handleChannelIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, error) {
 	issue := event.ObjectAttributes
 	senderGitlabUsername := event.User.Username
 	repo := event.Project
 	res := []*HandleWebhook{}
 
 	message := ""
-	var warnings []string
-
+	status := ""
+	labelsChanged := issue.Action == actionUpdate && !sameLabels(event.Changes.Labels.Current, event.Changes.Labels.Previous)
+
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"IID":        issue.IID,
+		"Title":      issue.Title,
+		"URL":        issue.URL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+	}
 	switch issue.Action {
 	case actionOpen:
-		message = fmt.Sprintf("#### %s\n##### [%s#%v](%s)\n###### new issue by [%s](%s) on [%s](%s)\n\n%s", issue.Title, repo.PathWithNamespace, issue.IID, issue.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), issue.CreatedAt, issue.URL, sanitizeDescription(issue.Description))
+		data["CreatedAt"] = issue.CreatedAt
+		data["Description"] = sanitizeDescription(issue.Description)
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.issue.channel.open",
+			Other: "#### {{.Title}}\n##### [{{.Project}}#{{.IID}}]({{.URL}})\n###### new issue by [{{.Sender}}]({{.SenderURL}}) on [{{.CreatedAt}}]({{.URL}})\n\n{{.Description}}",
+		}, data)
+		status = sanitizeDescription(issue.Description)
 	case actionClose:
-		message = fmt.Sprintf("[%s](%s) Issue [%s](%s) closed by [%s](%s)", repo.PathWithNamespace, repo.WebURL, issue.Title, issue.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.issue.channel.close",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Issue [{{.Title}}]({{.URL}}) closed by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+		status = w.localize(locale, &i18n.Message{ID: "webhook.issue.status.close", Other: "Closed"}, nil)
 	case actionReopen:
-		message = fmt.Sprintf("[%s](%s) Issue [%s](%s) reopened by [%s](%s)", repo.PathWithNamespace, repo.WebURL, issue.Title, issue.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.issue.channel.reopen",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Issue [{{.Title}}]({{.URL}}) reopened by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+		status = w.localize(locale, &i18n.Message{ID: "webhook.issue.status.reopen", Other: "Reopened"}, nil)
 	case actionUpdate:
-		if len(event.Changes.Labels.Current) > 0 && !sameLabels(event.Changes.Labels.Current, event.Changes.Labels.Previous) {
-			message = fmt.Sprintf("#### %s\n##### [%s#%v](%s)\n###### issue labeled `%s` by [%s](%s) on [%s](%s)\n\n%s", issue.Title, repo.PathWithNamespace, issue.IID, issue.URL, labelToString(event.Changes.Labels.Current), event.User.Username, w.gitlabRetreiver.GetUserURL(event.User.Username), issue.UpdatedAt, issue.URL, sanitizeDescription(issue.Description))
+		if labelsChanged {
+			data["UpdatedAt"] = issue.UpdatedAt
+			data["Description"] = sanitizeDescription(issue.Description)
+			data["Labels"] = labelToString(event.Changes.Labels.Current)
+			if len(event.Changes.Labels.Current) == 0 {
+				message = w.localize(locale, &i18n.Message{
+					ID:    "webhook.issue.channel.unlabeled",
+					Other: "#### {{.Title}}\n##### [{{.Project}}#{{.IID}}]({{.URL}})\n###### issue unlabeled by [{{.Sender}}]({{.SenderURL}}) on [{{.UpdatedAt}}]({{.URL}})\n\n{{.Description}}",
+				}, data)
+				status = w.localize(locale, &i18n.Message{ID: "webhook.issue.status.unlabeled", Other: "Unlabeled"}, nil)
+			} else {
+				message = w.localize(locale, &i18n.Message{
+					ID:    "webhook.issue.channel.labeled",
+					Other: "#### {{.Title}}\n##### [{{.Project}}#{{.IID}}]({{.URL}})\n###### issue labeled `{{.Labels}}` by [{{.Sender}}]({{.SenderURL}}) on [{{.UpdatedAt}}]({{.URL}})\n\n{{.Description}}",
+				}, data)
+				status = w.localize(locale, &i18n.Message{ID: "webhook.issue.status.labeled", Other: "Labeled `{{.Labels}}`"}, data)
+			}
 		}
 	}
 
 	if len(message) > 0 {
 		toChannels := make([]string, 0)
+		channelSubs := []*subscription.Subscription{}
 		namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 		subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 			ctx, namespace, project,
 			repo.Visibility == gitlab.PublicVisibility,
 		)
 		for _, sub := range subs {
-			if !sub.Issues() {
+			if eventType == gitlab.EventConfidentialIssue {
+				if !sub.ConfidentialIssues() {
+					continue
+				}
+			} else if !sub.Issues() {
 				continue
 			}
 
-			if eventType == gitlab.EventConfidentialIssue && !sub.ConfidentialIssues() {
+			if !eventLabelsMatchSub(sub, event.Labels) {
 				continue
 			}
 
-			labels, err := sub.Labels()
-			if err != nil {
-				warnings = append(warnings, err.Error())
-			} else if len(labels) > 0 && !containsAnyLabel(event.Labels, labels) {
+			// Subscriptions filtering by label get label changes making the issue newly pass their filters,
+			// the other ones get the label changes leaving labels on the issue.
+			if labelsChanged {
+				if sub.HasLabelFilters() {
+					if !labelsNewlyMatchSub(sub, event.Changes.Labels.Previous, event.Changes.Labels.Current) {
+						continue
+					}
+				} else if len(event.Changes.Labels.Current) == 0 {
+					continue
+				}
+			}
+
+			if !sub.MatchesAuthor(senderGitlabUsername) {
 				continue
 			}
 
 			toChannels = append(toChannels, sub.ChannelID)
+			channelSubs = append(channelSubs, sub)
 		}
 
 		if len(toChannels) > 0 {
-			res = append(res, &HandleWebhook{
-				From:       senderGitlabUsername,
+			var templated bool
+			message, templated = w.renderMessage("issue."+issue.Action, &MessageTemplateData{
 				Message:    message,
-				ToUsers:    []string{},
-				ToChannels: toChannels,
+				Event:      event,
+				Author:     senderGitlabUsername,
+				AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+				Project:    repo.PathWithNamespace,
+				ProjectURL: repo.WebURL,
+				Title:      issue.Title,
+				URL:        issue.URL,
 			})
+			handler := &HandleWebhook{
+				From:          senderGitlabUsername,
+				Message:       message,
+				ToUsers:       []string{},
+				ToChannels:    toChannels,
+				Subscriptions: channelSubs,
+				ThreadKey:     issueThreadKey(event.Project.ID, issue.IID),
+			}
+			// The wording set by admins replaces the attachment, which would otherwise hide it.
+			if !templated {
+				handler.Attachment = w.issueAttachment(ctx, event, channelSubs, message, status, locale)
+			}
+			res = append(res, handler)
 		}
 	}
-	return res, warnings, nil
+	return res, nil
 }
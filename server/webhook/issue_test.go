@@ -9,7 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
@@ -20,7 +22,6 @@ type testDataIssueStr struct {
 	fixture         string
 	gitlabRetreiver *fakeWebhook
 	res             []*HandleWebhook
-	warnings        []string
 }
 
 var testDataIssue = []testDataIssueStr{
@@ -28,7 +29,7 @@ var testDataIssue = []testDataIssueStr{
 		testTitle: "root open issue with manland assignee and display in channel1",
 		fixture:   NewIssue,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) assigned you to issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
@@ -41,13 +42,12 @@ var testDataIssue = []testDataIssueStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root open issue with manland assignee and display in channel1 (subgroup)",
 		fixture:   strings.ReplaceAll(NewIssue, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) assigned you to issue [manland/subgroup/webhook#1](http://localhost:3000/manland/subgroup/webhook/issues/1)",
@@ -60,13 +60,30 @@ var testDataIssue = []testDataIssueStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
-		warnings: []string{},
+	},
+	{
+		testTitle: "root open issue with manland assignee in German and display in channel1 in Japanese",
+		fixture:   NewIssue,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
+		}).withUserLocale("manland", "de").withServerLocale("ja"),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) hat dir das Issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1) zugewiesen",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "#### test new issue\n##### [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)\n###### [root](http://my.gitlab.com/root) による新しいイシュー ([2019-04-06 21:03:04 UTC](http://localhost:3000/manland/webhook/issues/1))\n\nhello world!",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
 	},
 	{
 		testTitle: "root open unassigned issue and display in channel",
 		fixture:   NewIssueUnassigned,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "#### new issue\n##### [manland/webhook#2](http://localhost:3000/manland/webhook/issues/2)\n###### new issue by [root](http://my.gitlab.com/root) on [2019-04-06 21:13:03 UTC](http://localhost:3000/manland/webhook/issues/2)\n\nHello world",
@@ -74,13 +91,12 @@ var testDataIssue = []testDataIssueStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}}, // no DM message because root don't received its own action and manland is not assigned
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland close issue of root",
 		fixture:   CloseIssue,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) closed your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
@@ -93,13 +109,12 @@ var testDataIssue = []testDataIssueStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland reopen issue of root and display in channel",
 		fixture:   ReopenIssue,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message: "[manland](http://my.gitlab.com/manland) reopened your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
@@ -111,25 +126,6 @@ var testDataIssue = []testDataIssueStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
-	},
-	{
-		testTitle: "manland reopen issue of root and display in channel with subscription label warning",
-		fixture:   ReopenIssue,
-		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues,label:1", Repository: "manland/webhook"},
-		}),
-		res: []*HandleWebhook{{
-			Message: "[manland](http://my.gitlab.com/manland) reopened your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)",
-			ToUsers: []string{"root"},
-			From:    "manland",
-		}, {
-			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Issue [test new issue](http://localhost:3000/manland/webhook/issues/1) reopened by [manland](http://my.gitlab.com/manland)",
-			ToUsers:    []string{},
-			ToChannels: []string{"channel1"},
-			From:       "manland",
-		}},
-		warnings: []string{"each label must be wrapped in quotes, e.g. label:\"bug\""},
 	},
 }
 
@@ -142,10 +138,9 @@ func TestIssueWebhook(t *testing.T) {
 			if err := json.Unmarshal([]byte(test.fixture), issueEvent); err != nil {
 				assert.Fail(t, "can't unmarshal fixture")
 			}
-			res, warnings, err := w.HandleIssue(context.Background(), issueEvent, gitlab.EventTypeIssue)
+			res, err := w.HandleIssue(context.Background(), issueEvent, gitlab.EventTypeIssue)
 			assert.Empty(t, err)
 			assert.Equal(t, len(test.res), len(res))
-			assert.ElementsMatch(t, test.warnings, warnings)
 			for index := range res {
 				assert.Equal(t, test.res[index].Message, res[index].Message)
 				assert.EqualValues(t, test.res[index].ToUsers, res[index].ToUsers)
@@ -154,3 +149,88 @@ func TestIssueWebhook(t *testing.T) {
 		})
 	}
 }
+
+func TestIssueWebhookLabelFilters(t *testing.T) {
+	t.Parallel()
+	message := "#### test new issue\n##### [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1)\n###### issue labeled `priority::high, backend` by [manland](http://my.gitlab.com/manland) on [2019-04-06 21:08:00 UTC](http://localhost:3000/manland/webhook/issues/1)\n\nhello world!"
+	tests := []struct {
+		name       string
+		fixture    string
+		subs       []*subscription.Subscription
+		toChannels []string
+	}{
+		{
+			name:    "subscription without label filters gets the label change",
+			fixture: LabelIssue,
+			subs: []*subscription.Subscription{
+				MockSubscription("channel1", "1", "issues", "manland/webhook"),
+			},
+			toChannels: []string{"channel1"},
+		},
+		{
+			name:    "wildcard label filter newly matched while the already matching one is skipped",
+			fixture: LabelIssue,
+			subs: []*subscription.Subscription{
+				MockSubscription("channel1", "1", `issues,label:"priority::*"`, "manland/webhook"),
+				MockSubscription("channel2", "1", `issues,label:"backend"`, "manland/webhook"),
+			},
+			toChannels: []string{"channel1"},
+		},
+		{
+			name:    "all-of label filter newly matched while the excluded label one is skipped",
+			fixture: LabelIssue,
+			subs: []*subscription.Subscription{
+				MockSubscription("channel1", "1", `issues,label_all:"backend",label_all:"priority::high"`, "manland/webhook"),
+				MockSubscription("channel2", "1", `issues,-label:"priority::high"`, "manland/webhook"),
+			},
+			toChannels: []string{"channel1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWebhook(newFakeWebhook(tt.subs))
+			issueEvent := &gitlab.IssueEvent{}
+			err := json.Unmarshal([]byte(tt.fixture), issueEvent)
+			assert.NoError(t, err)
+
+			res, err := w.HandleIssue(context.Background(), issueEvent, gitlab.EventTypeIssue)
+			assert.NoError(t, err)
+
+			var channelRes *HandleWebhook
+			for _, r := range res {
+				if len(r.ToChannels) > 0 {
+					channelRes = r
+				}
+			}
+			if assert.NotNil(t, channelRes) {
+				assert.Equal(t, message, channelRes.Message)
+				assert.Equal(t, tt.toChannels, channelRes.ToChannels)
+			}
+		})
+	}
+}
+
+func TestIssueAttachment(t *testing.T) {
+	t.Parallel()
+	w := NewWebhook(newFakeWebhook([]*subscription.Subscription{
+		MockSubscription("channel1", "1", "issues", "manland/webhook"),
+	}))
+	issueEvent := &gitlab.IssueEvent{}
+	require.NoError(t, json.Unmarshal([]byte(CloseIssue), issueEvent))
+
+	res, err := w.HandleIssue(context.Background(), issueEvent, gitlab.EventTypeIssue)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	attachment := res[1].Attachment
+	require.NotNil(t, attachment)
+	assert.Equal(t, res[1].Message, attachment.Fallback)
+	assert.Equal(t, colorClosed, attachment.Color)
+	assert.Equal(t, "manland/webhook#1 test new issue", attachment.Title)
+	assert.Equal(t, "http://localhost:3000/manland/webhook/issues/1", attachment.TitleLink)
+	assert.Equal(t, "Closed", attachment.Text)
+	assert.Equal(t, []*model.MessageAttachmentField{
+		{Title: "Assignees", Value: "[manland](http://my.gitlab.com/manland)", Short: true},
+	}, attachment.Fields, "the milestone is skipped when the issue has none")
+}
@@ -7,7 +7,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 func (w *webhook) HandleJobs(ctx context.Context, event *gitlab.JobEvent) ([]*HandleWebhook, error) {
@@ -15,29 +18,31 @@ func (w *webhook) HandleJobs(ctx context.Context, event *gitlab.JobEvent) ([]*Ha
 	if err != nil {
 		return nil, err
 	}
-	return cleanWebhookHandlers(handlers), nil
+	handlers2, err := w.handleChannelJobPipeline(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
 }
 
 func (w *webhook) handleChannelJob(ctx context.Context, event *gitlab.JobEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Name
 	repo := event.Repository
 	res := []*HandleWebhook{}
-	message := ""
-	message = fmt.Sprintf("### Pipeline Job Stage: **%s**\n", event.BuildStage)
+	locale := w.gitlabRetreiver.GetServerLocale()
+	message := w.localize(locale, &i18n.Message{ID: "webhook.job.channel.header", Other: "### Pipeline Job Stage: **{{.Stage}}**"}, map[string]any{"Stage": event.BuildStage}) + "\n"
 
+	status := w.localize(locale, lineStatus, map[string]any{"Status": event.BuildStatus})
 	switch event.BuildStatus {
 	case statusRunning:
-		message += fmt.Sprintf(":rocket: **Status**: %s\n", event.BuildStatus)
+		message += ":rocket: " + status + "\n"
 	case statusPending:
-		message = fmt.Sprintf("### Pipeline Job Stage: **%s**\n", event.BuildStage)
-		message += fmt.Sprintf(":clock1: **Status**: %s\n", event.BuildStatus)
+		message += ":clock1: " + status + "\n"
 	case statusSuccess:
-		message = fmt.Sprintf("### Pipeline Job Stage: **%s**\n", event.BuildStage)
-		message += fmt.Sprintf(":large_green_circle: **Status**: %s\n", event.BuildStatus)
+		message += ":large_green_circle: " + status + "\n"
 	case statusFailed:
-		message = fmt.Sprintf("### Pipeline Job Stage: **%s**\n", event.BuildStage)
-		message += fmt.Sprintf(":red_circle: **Status**: %s\n", event.BuildStatus)
-		message += fmt.Sprintf("**Reason Failed**: %s\n", event.BuildFailureReason)
+		message += ":red_circle: " + status + "\n"
+		message += w.localize(locale, &i18n.Message{ID: "webhook.job.channel.failure_reason", Other: "**Reason Failed**: {{.Reason}}"}, map[string]any{"Reason": event.BuildFailureReason}) + "\n"
 	default:
 		return res, nil
 	}
@@ -46,29 +51,84 @@ func (w *webhook) handleChannelJob(ctx context.Context, event *gitlab.JobEvent)
 		return nil, err
 	}
 	fullNamespacePath := fmt.Sprintf("%s/%s", namespaceMetadata.Namespace, namespaceMetadata.Project)
-	message += fmt.Sprintf("**Repository**: [%s](%s)\n", fullNamespacePath, event.Repository.GitHTTPURL)
-	message += fmt.Sprintf("**Triggered By**: %s\n", senderGitlabUsername)
-	message += fmt.Sprintf("**Visit job [here](%s)** \n", w.gitlabRetreiver.GetJobURL(fullNamespacePath, event.BuildID))
+	message += w.localize(locale, lineRepository, map[string]any{"Project": fullNamespacePath, "URL": event.Repository.GitHTTPURL}) + "\n"
+	message += w.localize(locale, lineTriggeredBy, map[string]any{"User": senderGitlabUsername}) + "\n"
+	message += w.localize(locale, &i18n.Message{ID: "webhook.job.channel.visit", Other: "**Visit job [here]({{.URL}})** "}, map[string]any{"URL": w.gitlabRetreiver.GetJobURL(fullNamespacePath, event.BuildID)}) + "\n"
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespaceMetadata.Namespace, namespaceMetadata.Project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
 	for _, sub := range subs {
-		if !sub.Jobs() {
+		if !sub.Jobs() || !sub.MatchesBranch(event.Ref) || !sub.MatchesAuthor(event.User.Username) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 	if len(toChannels) > 0 {
 		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
-			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
 
 	return res, nil
 }
+
+// handleChannelJobPipeline updates the job table of the post of the pipeline the job belongs to,
+// in the channels which got the post of the running pipeline.
+func (w *webhook) handleChannelJobPipeline(ctx context.Context, event *gitlab.JobEvent) ([]*HandleWebhook, error) {
+	res := []*HandleWebhook{}
+	if event.PipelineID == 0 {
+		return res, nil
+	}
+
+	namespaceMetadata, err := normalizeNamespacedProjectByHomepage(event.Repository.Homepage)
+	if err != nil {
+		return nil, err
+	}
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespaceMetadata.Namespace, namespaceMetadata.Project,
+		event.Repository.Visibility == gitlab.PublicVisibility,
+	)
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	for _, sub := range subs {
+		// pipeline:failed and pipeline:fixed subscriptions only get the post of finished pipelines, which is not updated afterwards.
+		if !sub.Pipeline() || sub.PipelineFailed() || sub.PipelineFixed() || !sub.MatchesBranch(event.Ref) || !sub.MatchesAuthor(event.User.Username) {
+			continue
+		}
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+	if len(toChannels) == 0 {
+		return res, nil
+	}
+
+	card := w.gitlabRetreiver.UpdatePipelineCard(event.ProjectID, event.PipelineID, &PipelineCard{
+		Jobs: []PipelineJob{{ID: event.BuildID, Stage: event.BuildStage, Name: event.BuildName, Status: event.BuildStatus}},
+	})
+	// The post is created by the pipeline event, until then the job status is only recorded.
+	if card.Header == "" {
+		return res, nil
+	}
+
+	res = append(res, &HandleWebhook{
+		From:           event.User.Name,
+		Message:        card.Message(w.jobTableColumns(w.gitlabRetreiver.GetServerLocale())),
+		ToUsers:        []string{},
+		ToChannels:     toChannels,
+		Subscriptions:  matchedSubs,
+		UpdateKey:      pipelineUpdateKey(event.ProjectID, event.PipelineID),
+		UpdateRevision: card.Revision,
+		Attachment:     card.MessageAttachment(w.jobTableColumns(w.gitlabRetreiver.GetServerLocale())),
+	})
+	return res, nil
+}
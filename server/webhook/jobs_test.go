@@ -27,7 +27,7 @@ var testDataJobs = []testDataJobsStr{
 		testTitle: "root start a job in running",
 		fixture:   JobRunning,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "jobs", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "jobs", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "### Pipeline Job Stage: **test**\n:rocket: **Status**: running\n**Repository**: [gitlab-org/gitlab-test](http://192.168.64.1:3005/gitlab-org/gitlab-test.git)\n**Triggered By**: User\n**Visit job [here](http://my.gitlab.com/gitlab-org/gitlab-test/-/jobs/1977)** \n",
@@ -40,7 +40,7 @@ var testDataJobs = []testDataJobsStr{
 		testTitle: "root start a job in pending",
 		fixture:   JobPending,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "jobs", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "jobs", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "### Pipeline Job Stage: **test**\n:clock1: **Status**: pending\n**Repository**: [gitlab-org/gitlab-test](http://192.168.64.1:3005/gitlab-org/gitlab-test.git)\n**Triggered By**: User\n**Visit job [here](http://my.gitlab.com/gitlab-org/gitlab-test/-/jobs/1977)** \n",
@@ -53,7 +53,7 @@ var testDataJobs = []testDataJobsStr{
 		testTitle: "root start a job in running (subgroup)",
 		fixture:   strings.ReplaceAll(JobRunning, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "jobs", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "jobs", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "### Pipeline Job Stage: **test**\n:rocket: **Status**: running\n**Repository**: [gitlab-org/gitlab-test](http://192.168.64.1:3005/gitlab-org/gitlab-test.git)\n**Triggered By**: User\n**Visit job [here](http://my.gitlab.com/gitlab-org/gitlab-test/-/jobs/1977)** \n",
@@ -66,7 +66,7 @@ var testDataJobs = []testDataJobsStr{
 		testTitle: "root start a job in success",
 		fixture:   JobSuccess,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "jobs", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "jobs", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "### Pipeline Job Stage: **test**\n:large_green_circle: **Status**: success\n**Repository**: [gitlab-org/gitlab-test](http://192.168.64.1:3005/gitlab-org/gitlab-test.git)\n**Triggered By**: User\n**Visit job [here](http://my.gitlab.com/gitlab-org/gitlab-test/-/jobs/1977)** \n",
@@ -79,7 +79,7 @@ var testDataJobs = []testDataJobsStr{
 		testTitle: "root start a job in failed",
 		fixture:   JobFailed,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "jobs", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "jobs", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "### Pipeline Job Stage: **test**\n:red_circle: **Status**: failed\n**Reason Failed**: script_failure\n**Repository**: [gitlab-org/gitlab-test](http://192.168.64.1:3005/gitlab-org/gitlab-test.git)\n**Triggered By**: User\n**Visit job [here](http://my.gitlab.com/gitlab-org/gitlab-test/-/jobs/1977)** \n",
@@ -88,6 +88,14 @@ var testDataJobs = []testDataJobsStr{
 			From:       "User",
 		}},
 	},
+	{
+		testTitle: "root start a job on a filtered out branch",
+		fixture:   JobRunning,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `jobs,branch:"main"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
 }
 
 func TestJobWebhook(t *testing.T) {
@@ -0,0 +1,112 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+const (
+	memberEventAddToGroup          = "user_add_to_group"
+	memberEventUpdateForGroup      = "user_update_for_group"
+	memberEventRemoveFromGroup     = "user_remove_from_group"
+	memberEventAccessRequestGroup  = "user_access_request_to_group"
+	memberEventAccessRequestDenied = "user_access_request_denied_for_group"
+)
+
+// MemberAccessRequestMessage describes a request of a user to access a group or project, as in the todos.
+var MemberAccessRequestMessage = &i18n.Message{
+	ID:    "webhook.member.access_request",
+	Other: "[{{.Requester}}]({{.RequesterURL}}) has requested access to [{{.Target}}]({{.TargetURL}})",
+}
+
+func (w *webhook) HandleMember(ctx context.Context, event *gitlab.MemberEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleChannelMember(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(handlers), nil
+}
+
+func (w *webhook) handleChannelMember(ctx context.Context, event *gitlab.MemberEvent) ([]*HandleWebhook, error) {
+	res := []*HandleWebhook{}
+
+	groupURL := w.gitlabRetreiver.GetGroupURL(event.GroupPath)
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Group":        event.GroupPath,
+		"GroupURL":     groupURL,
+		"Member":       event.UserName,
+		"MemberURL":    w.gitlabRetreiver.GetUserURL(event.UserUsername),
+		"Access":       event.GroupAccess,
+		"Requester":    event.UserName,
+		"RequesterURL": w.gitlabRetreiver.GetUserURL(event.UserUsername),
+		"Target":       event.GroupPath,
+		"TargetURL":    groupURL,
+	}
+
+	var message string
+	switch event.EventName {
+	case memberEventAddToGroup:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.member.channel.add",
+			Other: "[{{.Group}}]({{.GroupURL}}) [{{.Member}}]({{.MemberURL}}) was added to the group as {{.Access}}",
+		}, data)
+	case memberEventUpdateForGroup:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.member.channel.update",
+			Other: "[{{.Group}}]({{.GroupURL}}) The access of [{{.Member}}]({{.MemberURL}}) to the group was changed to {{.Access}}",
+		}, data)
+	case memberEventRemoveFromGroup:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.member.channel.remove",
+			Other: "[{{.Group}}]({{.GroupURL}}) [{{.Member}}]({{.MemberURL}}) was removed from the group",
+		}, data)
+	case memberEventAccessRequestGroup:
+		message = w.localize(locale, MemberAccessRequestMessage, data)
+	case memberEventAccessRequestDenied:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.member.channel.denied",
+			Other: "[{{.Group}}]({{.GroupURL}}) The request of [{{.Member}}]({{.MemberURL}}) to access the group was denied",
+		}, data)
+	default:
+		return res, nil
+	}
+
+	if event.ExpiresAt != nil && event.EventName != memberEventRemoveFromGroup && event.EventName != memberEventAccessRequestDenied {
+		message += w.localize(locale, &i18n.Message{
+			ID:    "webhook.member.channel.expires",
+			Other: ", until {{.Date}}",
+		}, map[string]any{"Date": event.ExpiresAt.Format("2006-01-02")})
+	}
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForGroup(ctx, event.GroupPath)
+	for _, sub := range subs {
+		if !sub.Members() || !sub.MatchesAuthor(event.UserUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          "",
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const MemberAddedToGroup = `{
+	"created_at":"2020-12-11T04:57:22Z",
+	"updated_at":"2020-12-11T04:57:22Z",
+	"group_name":"webhook group",
+	"group_path":"manland/platform",
+	"group_id":100,
+	"user_username":"test_user",
+	"user_name":"Test User",
+	"user_email":"testuser@webhooktest.com",
+	"user_id":64,
+	"group_access":"Guest",
+	"group_plan":null,
+	"expires_at":"2020-12-14T00:00:00Z",
+	"event_name":"user_add_to_group"
+}`
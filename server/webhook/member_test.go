@@ -0,0 +1,130 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataMemberStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataMember = []testDataMemberStr{
+	{
+		testTitle: "test_user is added to a group",
+		fixture:   MemberAddedToGroup,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://my.gitlab.com/manland/platform) [Test User](http://my.gitlab.com/test_user) was added to the group as Guest, until 2020-12-14",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "test_user access is updated",
+		fixture:   strings.Replace(strings.Replace(MemberAddedToGroup, `"event_name":"user_add_to_group"`, `"event_name":"user_update_for_group"`, 1), `"expires_at":"2020-12-14T00:00:00Z"`, `"expires_at":null`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://my.gitlab.com/manland/platform) The access of [Test User](http://my.gitlab.com/test_user) to the group was changed to Guest",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "test_user is removed from a group",
+		fixture:   strings.Replace(MemberAddedToGroup, `"event_name":"user_add_to_group"`, `"event_name":"user_remove_from_group"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://my.gitlab.com/manland/platform) [Test User](http://my.gitlab.com/test_user) was removed from the group",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "test_user requests access to a group",
+		fixture:   strings.Replace(strings.Replace(MemberAddedToGroup, `"event_name":"user_add_to_group"`, `"event_name":"user_access_request_to_group"`, 1), `"expires_at":"2020-12-14T00:00:00Z"`, `"expires_at":null`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[Test User](http://my.gitlab.com/test_user) has requested access to [manland/platform](http://my.gitlab.com/manland/platform)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "access request of test_user is denied",
+		fixture:   strings.Replace(MemberAddedToGroup, `"event_name":"user_add_to_group"`, `"event_name":"user_access_request_denied_for_group"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://my.gitlab.com/manland/platform) The request of [Test User](http://my.gitlab.com/test_user) to access the group was denied",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "unknown member event",
+		fixture:   strings.Replace(MemberAddedToGroup, `"event_name":"user_add_to_group"`, `"event_name":"user_add_to_project"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "members", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{},
+	},
+	{
+		testTitle: "channel not subscribed to members",
+		fixture:   MemberAddedToGroup,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,issues", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestMemberWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataMember {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			memberEvent := &gitlab.MemberEvent{}
+			if err := json.Unmarshal([]byte(test.fixture), memberEvent); err != nil {
+				assert.Fail(t, "can't unmarshal fixture")
+			}
+			res, err := w.HandleMember(context.Background(), memberEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+			}
+		})
+	}
+}
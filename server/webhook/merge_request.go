@@ -6,23 +6,24 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"regexp"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
-func (w *webhook) HandleMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, []string, error) {
-	var warnings []string
+func (w *webhook) HandleMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, error) {
 	handlers, err := w.handleDMMergeRequest(event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	handlers2, warnings, err := w.handleChannelMergeRequest(ctx, event)
+	handlers2, err := w.handleChannelMergeRequest(ctx, event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	return cleanWebhookHandlers(append(handlers, handlers2...)), warnings, nil
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
 }
 
 func (w *webhook) handleDMMergeRequest(event *gitlab.MergeEvent) ([]*HandleWebhook, error) {
@@ -34,52 +35,62 @@ func (w *webhook) handleDMMergeRequest(event *gitlab.MergeEvent) ([]*HandleWebho
 		toUsers = append(toUsers, w.gitlabRetreiver.GetUsernameByID(assigneeID))
 	}
 
-	message := ""
+	data := map[string]any{
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"IID":        event.ObjectAttributes.IID,
+		"URL":        event.ObjectAttributes.URL,
+		"Project":    event.ObjectAttributes.Target.PathWithNamespace,
+		"ProjectURL": event.Repository.Homepage,
+	}
 	handlers := []*HandleWebhook{}
 	switch event.ObjectAttributes.State {
 	case stateOpened:
 		switch event.ObjectAttributes.Action {
 		case actionOpen:
-			message = fmt.Sprintf("[%s](%s) requested your review on [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-			handlers = []*HandleWebhook{{
-				Message: message,
-				ToUsers: toUsers,
-				From:    senderGitlabUsername,
-			}}
+			handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+				ID:    "webhook.merge_request.dm.open",
+				Other: "[{{.Sender}}]({{.SenderURL}}) requested your review on [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+			}, data)
 		case actionReopen:
-			message = fmt.Sprintf("[%s](%s) reopened your merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-			handlers = []*HandleWebhook{{
-				Message: message,
-				ToUsers: toUsers,
-				From:    senderGitlabUsername,
-			}}
+			handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+				ID:    "webhook.merge_request.dm.reopen",
+				Other: "[{{.Sender}}]({{.SenderURL}}) reopened your merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+			}, data)
 		case actionUpdate:
 			// Not going to show notification in case of commit push.
 			if event.ObjectAttributes.OldRev != "" {
 				break
 			}
 
+			if markedReadyForReview(event) {
+				reviewers := []string{}
+				for _, reviewerID := range event.ObjectAttributes.ReviewerIDs {
+					reviewers = append(reviewers, w.gitlabRetreiver.GetUsernameByID(reviewerID))
+				}
+				handlers = append(handlers, w.dmHandlers(senderGitlabUsername, append(reviewers, toUsers...), &i18n.Message{
+					ID:    "webhook.merge_request.dm.ready",
+					Other: "[{{.Sender}}]({{.SenderURL}}) marked merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}}) as ready for review",
+				}, data)...)
+			}
+
 			// Handle change in assignees
 			if event.Changes.Assignees.Current != nil || event.Changes.Assignees.Previous != nil {
 				newlyAssigned := w.calculateUserDiffs(event.Changes.Assignees.Previous, event.Changes.Assignees.Current)
 				newlyUnassigned := w.calculateUserDiffs(event.Changes.Assignees.Current, event.Changes.Assignees.Previous)
 
 				if len(newlyAssigned) != 0 {
-					message = fmt.Sprintf("[%s](%s) assigned you to merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-					handlers = append(handlers, &HandleWebhook{
-						Message: message,
-						ToUsers: newlyAssigned,
-						From:    senderGitlabUsername,
-					})
+					handlers = append(handlers, w.dmHandlers(senderGitlabUsername, newlyAssigned, &i18n.Message{
+						ID:    "webhook.merge_request.dm.assign",
+						Other: "[{{.Sender}}]({{.SenderURL}}) assigned you to merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+					}, data)...)
 				}
 
 				if len(newlyUnassigned) != 0 {
-					message = fmt.Sprintf("[%s](%s) unassigned you from merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-					handlers = append(handlers, &HandleWebhook{
-						Message: message,
-						ToUsers: newlyUnassigned,
-						From:    senderGitlabUsername,
-					})
+					handlers = append(handlers, w.dmHandlers(senderGitlabUsername, newlyUnassigned, &i18n.Message{
+						ID:    "webhook.merge_request.dm.unassign",
+						Other: "[{{.Sender}}]({{.SenderURL}}) unassigned you from merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+					}, data)...)
 				}
 			}
 
@@ -88,102 +99,127 @@ func (w *webhook) handleDMMergeRequest(event *gitlab.MergeEvent) ([]*HandleWebho
 				updatedCurrentUsers := w.calculateUserDiffs(event.Changes.Reviewers.Previous, event.Changes.Reviewers.Current)
 
 				if len(updatedCurrentUsers) != 0 {
-					message = fmt.Sprintf("[%s](%s) requested your review on merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-					handlers = append(handlers, &HandleWebhook{
-						Message: message,
-						ToUsers: updatedCurrentUsers,
-						From:    senderGitlabUsername,
-					})
+					handlers = append(handlers, w.dmHandlers(senderGitlabUsername, updatedCurrentUsers, &i18n.Message{
+						ID:    "webhook.merge_request.dm.review",
+						Other: "[{{.Sender}}]({{.SenderURL}}) requested your review on merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+					}, data)...)
 				}
 			}
 		case actionApproved:
-			message = fmt.Sprintf("[%s](%s) approved your merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-			handlers = []*HandleWebhook{{
-				Message: message,
-				ToUsers: toUsers,
-				From:    senderGitlabUsername,
-			}}
+			handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+				ID:    "webhook.merge_request.dm.approved",
+				Other: "[{{.Sender}}]({{.SenderURL}}) approved your merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+			}, data)
 		case actionUnapproved:
-			message = fmt.Sprintf("[%s](%s) requested changes to your merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-			handlers = []*HandleWebhook{{
-				Message: message,
-				ToUsers: toUsers,
-				From:    senderGitlabUsername,
-			}}
+			handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+				ID:    "webhook.merge_request.dm.unapproved",
+				Other: "[{{.Sender}}]({{.SenderURL}}) requested changes to your merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+			}, data)
 		}
 	case stateClosed:
-		message = fmt.Sprintf("[%s](%s) closed your merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-		handlers = []*HandleWebhook{{
-			Message: message,
-			ToUsers: toUsers,
-			From:    senderGitlabUsername,
-		}}
+		handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+			ID:    "webhook.merge_request.dm.close",
+			Other: "[{{.Sender}}]({{.SenderURL}}) closed your merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+		}, data)
 	case stateMerged:
 		if event.ObjectAttributes.Action == actionMerge {
-			message = fmt.Sprintf("[%s](%s) merged your merge request [#%d](%s) in [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.ObjectAttributes.IID, event.ObjectAttributes.URL, event.ObjectAttributes.Target.PathWithNamespace, event.Repository.Homepage)
-			handlers = []*HandleWebhook{{
-				Message: message,
-				ToUsers: toUsers,
-				From:    senderGitlabUsername,
-			}}
+			handlers = w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+				ID:    "webhook.merge_request.dm.merge",
+				Other: "[{{.Sender}}]({{.SenderURL}}) merged your merge request [#{{.IID}}]({{.URL}}) in [{{.Project}}]({{.ProjectURL}})",
+			}, data)
 		}
 	}
 
 	if len(handlers) > 0 {
-		if mention := w.handleMention(mentionDetails{
+		handlers = append(handlers, w.handleMention(mentionDetails{
 			senderUsername:    senderGitlabUsername,
 			pathWithNamespace: event.Project.PathWithNamespace,
 			IID:               fmt.Sprintf("%d", event.ObjectAttributes.IID),
 			URL:               event.ObjectAttributes.URL,
 			body:              sanitizeDescription(event.ObjectAttributes.Description),
-		}); mention != nil {
-			handlers = append(handlers, mention)
-		}
+		})...)
 		return handlers, nil
 	}
 	return []*HandleWebhook{{From: senderGitlabUsername}}, nil
 }
 
-func (w *webhook) handleChannelMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, []string, error) {
+func (w *webhook) handleChannelMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Username
 	pr := event.ObjectAttributes
 	repo := event.Project
 	res := []*HandleWebhook{}
-	var warnings []string
 	message := ""
 	var assignMessages []string
 
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"IID":        pr.IID,
+		"Title":      pr.Title,
+		"URL":        pr.URL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+	}
 	switch pr.Action {
 	case actionOpen:
-		message = fmt.Sprintf("#### %s\n##### [%s!%v](%s) new merge-request by [%s](%s) on [%s](%s)\n\n%s", pr.Title, repo.PathWithNamespace, pr.IID, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), pr.CreatedAt, pr.URL, sanitizeDescription(pr.Description))
+		data["CreatedAt"] = pr.CreatedAt
+		data["Description"] = sanitizeDescription(pr.Description)
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.open",
+			Other: "#### {{.Title}}\n##### [{{.Project}}!{{.IID}}]({{.URL}}) new merge-request by [{{.Sender}}]({{.SenderURL}}) on [{{.CreatedAt}}]({{.URL}})\n\n{{.Description}}",
+		}, data)
 	case actionMerge:
-		message = fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was merged by [%s](%s)", repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.merge",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was merged by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
 	case actionClose:
-		message = fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was closed by [%s](%s)", repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.close",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was closed by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
 	case actionReopen:
-		message = fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was reopened by [%s](%s)", repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.reopen",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was reopened by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
 	case actionApproved:
-		message = fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was approved by [%s](%s)", repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.approved",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was approved by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
 	case actionUnapproved:
-		message = fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) changes were requested by [%s](%s)", repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.unapproved",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) changes were requested by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
 	case actionUpdate:
+		if markedReadyForReview(event) {
+			message = w.localize(locale, &i18n.Message{
+				ID:    "webhook.merge_request.channel.ready",
+				Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was marked as ready for review by [{{.Sender}}]({{.SenderURL}})",
+			}, data)
+		}
 		if event.Changes.Assignees.Current != nil || event.Changes.Assignees.Previous != nil {
 			newlyAssigned := w.calculateUserDiffs(event.Changes.Assignees.Previous, event.Changes.Assignees.Current)
 			newlyUnassigned := w.calculateUserDiffs(event.Changes.Assignees.Current, event.Changes.Assignees.Previous)
 
 			for _, username := range newlyAssigned {
-				msg := fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was assigned to [%s](%s) by [%s](%s)",
-					repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL,
-					username, w.gitlabRetreiver.GetUserURL(username),
-					senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
-				assignMessages = append(assignMessages, msg)
+				data["User"] = username
+				data["UserURL"] = w.gitlabRetreiver.GetUserURL(username)
+				assignMessages = append(assignMessages, w.localize(locale, &i18n.Message{
+					ID:    "webhook.merge_request.channel.assign",
+					Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was assigned to [{{.User}}]({{.UserURL}}) by [{{.Sender}}]({{.SenderURL}})",
+				}, data))
 			}
 			for _, username := range newlyUnassigned {
-				msg := fmt.Sprintf("[%s](%s) Merge request [!%v %s](%s) was unassigned from [%s](%s) by [%s](%s)",
-					repo.PathWithNamespace, repo.WebURL, pr.IID, pr.Title, pr.URL,
-					username, w.gitlabRetreiver.GetUserURL(username),
-					senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername))
-				assignMessages = append(assignMessages, msg)
+				data["User"] = username
+				data["UserURL"] = w.gitlabRetreiver.GetUserURL(username)
+				assignMessages = append(assignMessages, w.localize(locale, &i18n.Message{
+					ID:    "webhook.merge_request.channel.unassign",
+					Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was unassigned from [{{.User}}]({{.UserURL}}) by [{{.Sender}}]({{.SenderURL}})",
+				}, data))
 			}
 		}
 	}
@@ -193,38 +229,94 @@ func (w *webhook) handleChannelMergeRequest(ctx context.Context, event *gitlab.M
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
+	subs = filterSubscriptionsByTargetBranchAndDraft(subs, pr.TargetBranch, pr.Draft || pr.WorkInProgress)
+	subs = w.filterSubscriptionsByMergeRequestPaths(ctx, subs, namespace, project, pr.IID, pr.LastCommit.ID)
 
 	if len(message) > 0 {
-		toChannels, ws := filterChannelsByFeature(subs, event.Labels, (*subscription.Subscription).Merges)
-		warnings = append(warnings, ws...)
+		toChannels, matchedSubs := filterChannelsByFeature(subs, event.Labels, senderGitlabUsername, (*subscription.Subscription).Merges)
 		if len(toChannels) > 0 {
-			res = append(res, &HandleWebhook{
-				From:       senderGitlabUsername,
+			var templated bool
+			message, templated = w.renderMessage("merge_request."+pr.Action, &MessageTemplateData{
 				Message:    message,
-				ToUsers:    []string{},
-				ToChannels: toChannels,
+				Event:      event,
+				Author:     senderGitlabUsername,
+				AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+				Project:    repo.PathWithNamespace,
+				ProjectURL: repo.WebURL,
+				Title:      pr.Title,
+				URL:        pr.URL,
+			})
+			handler := &HandleWebhook{
+				From:          senderGitlabUsername,
+				Message:       message,
+				ToUsers:       []string{},
+				ToChannels:    toChannels,
+				Subscriptions: matchedSubs,
+			}
+			// The wording set by admins replaces the attachment, which would otherwise hide it.
+			if !templated {
+				handler.Attachment = w.mergeRequestAttachment(ctx, event, matchedSubs, message, locale)
+			}
+			res = append(res, handler)
+		}
+	}
+
+	// Label changes are only posted to the subscriptions whose label filters the merge request newly passes.
+	if pr.Action == actionUpdate && !sameLabels(event.Changes.Labels.Current, event.Changes.Labels.Previous) {
+		data["Labels"] = labelToString(event.Changes.Labels.Current)
+		labelMessage := w.localize(locale, &i18n.Message{
+			ID:    "webhook.merge_request.channel.labeled",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was labeled `{{.Labels}}` by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+		if len(event.Changes.Labels.Current) == 0 {
+			labelMessage = w.localize(locale, &i18n.Message{
+				ID:    "webhook.merge_request.channel.unlabeled",
+				Other: "[{{.Project}}]({{.ProjectURL}}) Merge request [!{{.IID}} {{.Title}}]({{.URL}}) was unlabeled by [{{.Sender}}]({{.SenderURL}})",
+			}, data)
+		}
+
+		toChannels := []string{}
+		matchedSubs := make([]*subscription.Subscription, 0)
+		for _, sub := range subs {
+			if !sub.Merges() || !sub.MatchesAuthor(senderGitlabUsername) || !labelsNewlyMatchSub(sub, event.Changes.Labels.Previous, event.Changes.Labels.Current) {
+				continue
+			}
+			toChannels = append(toChannels, sub.ChannelID)
+			matchedSubs = append(matchedSubs, sub)
+		}
+		if len(toChannels) > 0 {
+			res = append(res, &HandleWebhook{
+				From:          senderGitlabUsername,
+				Message:       labelMessage,
+				ToUsers:       []string{},
+				ToChannels:    toChannels,
+				Subscriptions: matchedSubs,
 			})
 		}
 	}
 
 	if len(assignMessages) > 0 {
-		toChannels, ws := filterChannelsByFeature(subs, event.Labels, func(sub *subscription.Subscription) bool {
+		toChannels, matchedSubs := filterChannelsByFeature(subs, event.Labels, senderGitlabUsername, func(sub *subscription.Subscription) bool {
 			return sub.Merges() || sub.MergeRequestAssigns()
 		})
-		warnings = append(warnings, ws...)
 		if len(toChannels) > 0 {
 			for _, msg := range assignMessages {
 				res = append(res, &HandleWebhook{
-					From:       senderGitlabUsername,
-					Message:    msg,
-					ToUsers:    []string{},
-					ToChannels: toChannels,
+					From:          senderGitlabUsername,
+					Message:       msg,
+					ToUsers:       []string{},
+					ToChannels:    toChannels,
+					Subscriptions: matchedSubs,
 				})
 			}
 		}
 	}
 
-	return res, warnings, nil
+	for _, handler := range res {
+		handler.ThreadKey = mergeRequestThreadKey(repo.ID, pr.IID)
+	}
+
+	return res, nil
 }
 
 // calculateUserDiffs function takes previousUsers and currentUsers of an event,
@@ -244,3 +336,64 @@ func (w *webhook) calculateUserDiffs(previousUsers, currentUsers []*gitlab.Event
 
 	return updatedCurrentUsers
 }
+
+// draftTitlePrefix matches the title prefixes GitLab uses to mark merge requests as drafts.
+var draftTitlePrefix = regexp.MustCompile(`(?i)^\s*(\[draft\]|\(draft\)|draft:|draft\s-\s|\[wip\]|wip:)`)
+
+// markedReadyForReview returns true if the update event turns a draft merge request into a ready one.
+// GitLab versions not reporting the draft change still report the title losing its draft prefix.
+func markedReadyForReview(event *gitlab.MergeEvent) bool {
+	if event.Changes.Draft.Previous && !event.Changes.Draft.Current {
+		return true
+	}
+	title := event.Changes.Title
+	return draftTitlePrefix.MatchString(title.Previous) && title.Current != "" && !draftTitlePrefix.MatchString(title.Current)
+}
+
+// filterSubscriptionsByTargetBranchAndDraft drops the subscriptions whose target branch filters don't match the
+// merge request, and the ones leaving out drafts when the merge request is one.
+func filterSubscriptionsByTargetBranchAndDraft(subs []*subscription.Subscription, targetBranch string, draft bool) []*subscription.Subscription {
+	res := make([]*subscription.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		if !sub.MatchesTargetBranch(targetBranch) || (draft && sub.NoDrafts()) {
+			continue
+		}
+		res = append(res, sub)
+	}
+	return res
+}
+
+// filterSubscriptionsByMergeRequestPaths drops the subscriptions whose path filters match none of the files changed
+// by the merge request. The changes are only looked up when a subscription has path filters, with the first of their
+// creators able to. If none is, the subscriptions with path filters are dropped.
+func (w *webhook) filterSubscriptionsByMergeRequestPaths(ctx context.Context, subs []*subscription.Subscription, namespace, project string, mergeRequestIID int, headSHA string) []*subscription.Subscription {
+	var changedPaths []string
+	lookedUp := false
+	res := make([]*subscription.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		if len(sub.Filters.Paths) == 0 {
+			res = append(res, sub)
+			continue
+		}
+
+		if !lookedUp {
+			lookedUp = true
+			for _, creator := range subs {
+				if len(creator.Filters.Paths) == 0 {
+					continue
+				}
+				paths, err := w.gitlabRetreiver.GetMergeRequestChangedPaths(ctx, creator.CreatorID, namespace, project, mergeRequestIID, headSHA)
+				if err != nil {
+					continue
+				}
+				changedPaths = paths
+				break
+			}
+		}
+
+		if sub.MatchesPaths(changedPaths) {
+			res = append(res, sub)
+		}
+	}
+	return res
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
@@ -20,15 +22,123 @@ type testDataMergeRequestStr struct {
 	fixture         string
 	gitlabRetreiver *fakeWebhook
 	res             []*HandleWebhook
-	warnings        []string
 }
 
 var testDataMergeRequest = []testDataMergeRequestStr{
+	{
+		testTitle: "root mark merge request as ready for review",
+		fixture:   MarkedReadyMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `merges,target_branch:"mas*",no_drafts`, "manland/webhook"),
+			MockSubscription("channel2", "1", `merges,target_branch:"release/*"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) marked merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook) as ready for review",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Merge request [!4 Master-2](http://localhost:3000/manland/webhook/merge_requests/4) was marked as ready for review by [root](http://my.gitlab.com/root)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
+	},
+	{
+		testTitle: "root label merge request and display in channel1 only whose label filter newly matches",
+		fixture:   LabelMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `merges,label:"priority::*"`, "manland/webhook"),
+			MockSubscription("channel2", "1", `merges,label:"backend"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "",
+			ToUsers:    []string{},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Merge request [!4 Master-2](http://localhost:3000/manland/webhook/merge_requests/4) was labeled `priority::high, backend` by [root](http://my.gitlab.com/root)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
+	},
+	{
+		testTitle: "root open draft merge request for manland and display in channel2 only",
+		fixture:   strings.Replace(OpenMergeRequest, `"merge_status":"unchecked",`, `"merge_status":"unchecked","draft":true,`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,no_drafts", "manland/webhook"),
+			MockSubscription("channel2", "1", "merges", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "#### Master\n##### [manland/webhook!4](http://localhost:3000/manland/webhook/merge_requests/4) new merge-request by [root](http://my.gitlab.com/root) on [2019-04-03 21:07:32 UTC](http://localhost:3000/manland/webhook/merge_requests/4)\n\ntest open merge request",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel2"},
+			From:       "root",
+		}},
+	},
+	{
+		testTitle: "root open merge request changing paths of channel1 only",
+		fixture:   OpenMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `merges,path:"services/billing/**"`, "manland/webhook"),
+			MockSubscription("channel2", "1", `merges,path:"services/search/**"`, "manland/webhook"),
+			MockSubscription("channel3", "2", `merges,path:"docs/**"`, "manland/webhook"),
+		}).withMergeRequestPaths("README.md", "services/billing/invoice.go"),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "#### Master\n##### [manland/webhook!4](http://localhost:3000/manland/webhook/merge_requests/4) new merge-request by [root](http://my.gitlab.com/root) on [2019-04-03 21:07:32 UTC](http://localhost:3000/manland/webhook/merge_requests/4)\n\ntest open merge request",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
+	},
+	{
+		testTitle: "root open merge request with paths nobody can look up",
+		fixture:   OpenMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "2", `merges,path:"**"`, "manland/webhook"),
+			MockSubscription("channel2", "2", "merges", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}, {
+			Message:    "#### Master\n##### [manland/webhook!4](http://localhost:3000/manland/webhook/merge_requests/4) new merge-request by [root](http://my.gitlab.com/root) on [2019-04-03 21:07:32 UTC](http://localhost:3000/manland/webhook/merge_requests/4)\n\ntest open merge request",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel2"},
+			From:       "root",
+		}},
+	},
+	{
+		testTitle: "root open merge request for manland with root excluded from channel1",
+		fixture:   OpenMergeRequest,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `merges,exclude_author:"ro*"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
+		}},
+	},
 	{
 		testTitle: "root open merge request for manland and display in channel1",
 		fixture:   OpenMergeRequest,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -41,13 +151,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root open merge request for manland and display in channel1 (subgroup)",
 		fixture:   strings.ReplaceAll(OpenMergeRequest, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/subgroup/webhook/merge_requests/4) in [manland/subgroup/webhook](http://localhost:3000/manland/subgroup/webhook)",
@@ -60,13 +169,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland close merge request of root and display in channel1",
 		fixture:   CloseMergeRequestByAssignee,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) closed your merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -79,13 +187,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland reopened merge request of root and display in channel1",
 		fixture:   ReopenMerge,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) reopened your merge request [#1](http://localhost:3000/manland/webhook/merge_requests/1) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -98,7 +205,6 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle:       "root assign manland to the merge-request",
@@ -118,7 +224,6 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle:       "root assign manland as reviewer to the merge-request",
@@ -132,7 +237,6 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle:       "user assign manland as assignee to the merge-request",
@@ -152,7 +256,6 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "user",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle:       "user assign itself to the merge-request",
@@ -172,7 +275,6 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "user",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle:       "root unassign manland from the merge-request",
@@ -186,13 +288,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root assign manland to merge-request and display in channel1 with merges subscription",
 		fixture:   RootAssignMergeRequestWithChannel,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{
 			{
@@ -220,13 +321,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root assign manland to merge-request and display in channel1 with merge_request_assigns subscription",
 		fixture:   RootAssignMergeRequestWithChannel,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merge_request_assigns", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merge_request_assigns", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{
 			{
@@ -254,13 +354,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root assign manland to merge-request but no channel notification without matching subscription",
 		fixture:   RootAssignMergeRequestWithChannel,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{
 			{
@@ -276,13 +375,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 				From:       "root",
 			},
 		},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland merge root merge-request and display in channel1",
 		fixture:   MergeRequestMerged,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) merged your merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -295,13 +393,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland approve root merge-request and display in channel1",
 		fixture:   ApproveMergeRequest,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) approved your merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -314,13 +411,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "manland unapprove root merge-request and display in channel1",
 		fixture:   strings.ReplaceAll(ApproveMergeRequest, "approved", "unapproved"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) requested changes to your merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -333,13 +429,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root close its own MR without assignee and display in channel1",
 		fixture:   CloseMergeRequestByCreator,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merges", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) closed your merge request [#1](http://localhost:3000/manland/webhook/merge_requests/1) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -352,13 +447,12 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
-		warnings: []string{},
 	},
 	{
 		testTitle: "root open merge request for manland + channel but not subscription to merges",
 		fixture:   OpenMergeRequest,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issues", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issues", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[root](http://my.gitlab.com/root) requested your review on [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
@@ -366,29 +460,31 @@ var testDataMergeRequest = []testDataMergeRequestStr{
 			ToChannels: []string{},
 			From:       "root",
 		}},
-		warnings: []string{},
-	},
-	{
-		testTitle: "manland approve root merge-request with subscription label warning",
-		fixture:   ApproveMergeRequest,
-		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merges,label:1", Repository: "manland/webhook"},
-		}),
-		res: []*HandleWebhook{{
-			Message:    "[manland](http://my.gitlab.com/manland) approved your merge request [#4](http://localhost:3000/manland/webhook/merge_requests/4) in [manland/webhook](http://localhost:3000/manland/webhook)",
-			ToUsers:    []string{"root"},
-			ToChannels: []string{},
-			From:       "manland",
-		}, {
-			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Merge request [!4 Master](http://localhost:3000/manland/webhook/merge_requests/4) was approved by [manland](http://my.gitlab.com/manland)",
-			ToUsers:    []string{},
-			ToChannels: []string{"channel1"},
-			From:       "manland",
-		}},
-		warnings: []string{"each label must be wrapped in quotes, e.g. label:\"bug\""},
 	},
 }
 
+func TestMarkedReadyForReview(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		changes  string
+		expected bool
+	}{
+		{name: "draft change", changes: `{"draft":{"previous":true,"current":false}}`, expected: true},
+		{name: "marked as draft", changes: `{"draft":{"previous":false,"current":true}}`, expected: false},
+		{name: "draft prefix removed from title", changes: `{"title":{"previous":"Draft: Fix","current":"Fix"}}`, expected: true},
+		{name: "WIP prefix removed from title", changes: `{"title":{"previous":"[WIP] Fix","current":"Fix"}}`, expected: true},
+		{name: "title of a draft changed", changes: `{"title":{"previous":"Draft: Fix","current":"Draft: Fix bug"}}`, expected: false},
+		{name: "title changed", changes: `{"title":{"previous":"Fix","current":"Fix bug"}}`, expected: false},
+		{name: "no changes", changes: `{}`, expected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			event := &gitlab.MergeEvent{}
+			require.NoError(t, json.Unmarshal([]byte(`{"changes":`+test.changes+`}`), event))
+			assert.Equal(t, test.expected, markedReadyForReview(event))
+		})
+	}
+}
+
 func TestMergeRequestWebhook(t *testing.T) {
 	t.Parallel()
 	for _, test := range testDataMergeRequest {
@@ -398,16 +494,45 @@ func TestMergeRequestWebhook(t *testing.T) {
 			if err := json.Unmarshal([]byte(test.fixture), mergeEvent); err != nil {
 				assert.Fail(t, "can't unmarshal fixture")
 			}
-			res, warnings, err := w.HandleMergeRequest(context.Background(), mergeEvent)
+			res, err := w.HandleMergeRequest(context.Background(), mergeEvent)
 			assert.Empty(t, err)
 			assert.Equal(t, len(test.res), len(res))
-			assert.ElementsMatch(t, test.warnings, warnings)
 			for index := range res {
 				assert.Equal(t, test.res[index].Message, res[index].Message)
 				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
 				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
 				assert.Equal(t, test.res[index].From, res[index].From)
 			}
 		})
 	}
 }
+
+func TestMergeRequestAttachment(t *testing.T) {
+	t.Parallel()
+	w := NewWebhook(newFakeWebhook([]*subscription.Subscription{
+		MockSubscription("channel1", "1", "merges", "manland/webhook"),
+	}).withMilestone("v1.0"))
+	mergeEvent := &gitlab.MergeEvent{}
+	fixture := strings.Replace(MergeRequestMerged, `"milestone_id":null`, `"milestone_id":3`, 1)
+	require.NoError(t, json.Unmarshal([]byte(fixture), mergeEvent))
+
+	res, err := w.HandleMergeRequest(context.Background(), mergeEvent)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Nil(t, res[0].Attachment, "DMs are not posted as attachments")
+
+	attachment := res[1].Attachment
+	require.NotNil(t, attachment)
+	assert.Equal(t, res[1].Message, attachment.Fallback)
+	assert.Equal(t, colorSuccess, attachment.Color)
+	assert.Equal(t, "manland", attachment.AuthorName)
+	assert.Equal(t, "http://my.gitlab.com/manland", attachment.AuthorLink)
+	assert.Equal(t, "manland/webhook!4 Master", attachment.Title)
+	assert.Equal(t, "http://localhost:3000/manland/webhook/merge_requests/4", attachment.TitleLink)
+	assert.Equal(t, "Merged", attachment.Text)
+	assert.Equal(t, []*model.MessageAttachmentField{
+		{Title: "Target Branch", Value: "master", Short: true},
+		{Title: "Milestone", Value: "v1.0", Short: true},
+	}, attachment.Fields)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+// MilestoneEvent represents a milestone of a project or group being created, closed, reopened or deleted.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#milestone-events
+type MilestoneEvent struct {
+	ObjectKind string            `json:"object_kind"`
+	EventType  string            `json:"event_type"`
+	User       *gitlab.EventUser `json:"user"`
+	Project    struct {
+		ID                int    `json:"id"`
+		Name              string `json:"name"`
+		WebURL            string `json:"web_url"`
+		VisibilityLevel   int    `json:"visibility_level"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	Group struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		FullPath string `json:"full_path"`
+		WebURL   string `json:"web_url"`
+	} `json:"group"`
+	ObjectAttributes struct {
+		ID          int    `json:"id"`
+		IID         int    `json:"iid"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		DueDate     string `json:"due_date"`
+		StartDate   string `json:"start_date"`
+		ProjectID   int    `json:"project_id"`
+		GroupID     int    `json:"group_id"`
+	} `json:"object_attributes"`
+	Action string `json:"action"`
+}
+
+func (w *webhook) HandleMilestone(ctx context.Context, event *MilestoneEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleChannelMilestone(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(handlers), nil
+}
+
+func (w *webhook) handleChannelMilestone(ctx context.Context, event *MilestoneEvent) ([]*HandleWebhook, error) {
+	res := []*HandleWebhook{}
+	milestone := event.ObjectAttributes
+
+	var fullPath, webURL, milestoneURL, namespace, project string
+	var subs []*subscription.Subscription
+	if event.Project.PathWithNamespace != "" {
+		fullPath, webURL = event.Project.PathWithNamespace, event.Project.WebURL
+		milestoneURL = fmt.Sprintf("%s/-/milestones/%d", webURL, milestone.IID)
+		namespace, project = normalizeNamespacedProject(fullPath)
+		subs = w.gitlabRetreiver.GetSubscribedChannelsForProject(
+			ctx, namespace, project,
+			event.Project.VisibilityLevel == PublicVisibilityLevel,
+		)
+	} else if event.Group.FullPath != "" {
+		fullPath, webURL = event.Group.FullPath, event.Group.WebURL
+		if webURL != "" {
+			milestoneURL = fmt.Sprintf("%s/-/milestones/%d", webURL, milestone.IID)
+		} else {
+			// The group pages are under /groups, unlike the group URL made without the payload.
+			webURL = w.gitlabRetreiver.GetGroupURL(fullPath)
+			milestoneURL = w.gitlabRetreiver.GetGroupMilestoneURL(fullPath, milestone.IID)
+		}
+		namespace = fullPath
+		subs = w.gitlabRetreiver.GetSubscribedChannelsForGroup(ctx, fullPath)
+	} else {
+		return res, nil
+	}
+
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Path":         fullPath,
+		"URL":          webURL,
+		"Milestone":    milestone.Title,
+		"MilestoneURL": milestoneURL,
+		"By":           "",
+	}
+	senderGitlabUsername := ""
+	if event.User != nil {
+		senderGitlabUsername = event.User.Username
+		data["By"] = w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.by",
+			Other: " by [{{.Sender}}]({{.SenderURL}})",
+		}, map[string]any{"Sender": senderGitlabUsername, "SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername)})
+	}
+
+	var message string
+	switch event.Action {
+	case statusCreate:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.create",
+			Other: "[{{.Path}}]({{.URL}}) Milestone [{{.Milestone}}]({{.MilestoneURL}}) created{{.By}}",
+		}, data)
+	case actionClose:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.close",
+			Other: "[{{.Path}}]({{.URL}}) Milestone [{{.Milestone}}]({{.MilestoneURL}}) closed{{.By}}",
+		}, data)
+	case actionReopen:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.reopen",
+			Other: "[{{.Path}}]({{.URL}}) Milestone [{{.Milestone}}]({{.MilestoneURL}}) reopened{{.By}}",
+		}, data)
+	case statusDelete:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.delete",
+			Other: "[{{.Path}}]({{.URL}}) Milestone **{{.Milestone}}** deleted{{.By}}",
+		}, data)
+	default:
+		return res, nil
+	}
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	creatorIDs := make([]string, 0)
+	for _, sub := range subs {
+		if !sub.Milestones() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+		creatorIDs = append(creatorIDs, sub.CreatorID)
+	}
+
+	if len(toChannels) == 0 {
+		return res, nil
+	}
+
+	if milestone.DueDate != "" && event.Action != statusDelete {
+		message += "\n" + w.localize(locale, &i18n.Message{
+			ID:    "webhook.milestone.channel.due_date",
+			Other: "**Due date**: {{.Date}}",
+		}, map[string]any{"Date": milestone.DueDate})
+	}
+	if event.Action != statusDelete {
+		// The counts are the same for every channel, fetch them with the first subscriber able to.
+		for _, creatorID := range creatorIDs {
+			opened, closed, err := w.gitlabRetreiver.GetMilestoneIssueCounts(ctx, creatorID, namespace, project, milestone.Title)
+			if err != nil {
+				continue
+			}
+			message += "\n" + w.localize(locale, &i18n.Message{
+				ID:    "webhook.milestone.channel.issues",
+				Other: "**Issues**: {{.Opened}} open, {{.Closed}} closed",
+			}, map[string]any{"Opened": opened, "Closed": closed})
+			break
+		}
+	}
+
+	res = append(res, &HandleWebhook{
+		From:          senderGitlabUsername,
+		Message:       message,
+		ToUsers:       []string{},
+		ToChannels:    toChannels,
+		Subscriptions: matchedSubs,
+	})
+
+	return res, nil
+}
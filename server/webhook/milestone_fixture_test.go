@@ -0,0 +1,73 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const MilestoneCreated = `{
+	"object_kind":"milestone",
+	"event_type":"milestone",
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"ci_config_path":null,
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"object_attributes":{
+		"id":61,
+		"iid":10,
+		"title":"v1.0",
+		"description":"First stable release",
+		"state":"active",
+		"created_at":"2019-06-16 18:40:00 UTC",
+		"updated_at":"2019-06-16 18:40:00 UTC",
+		"due_date":"2019-07-01",
+		"start_date":"2019-06-17",
+		"group_id":null,
+		"project_id":24
+	},
+	"action":"create"
+}`
+
+const GroupMilestoneClosed = `{
+	"object_kind":"milestone",
+	"event_type":"milestone",
+	"group":{
+		"id":100,
+		"name":"platform",
+		"full_path":"manland/platform",
+		"web_url":"http://localhost:3000/groups/manland/platform"
+	},
+	"user":{
+		"id":50,
+		"name":"Romain Maneschi",
+		"username":"manland",
+		"avatar_url":"https://www.gravatar.com/avatar/3bd6dcfae3a0f8f8d6cb3ec8ea5a5a3c?s=80&d=identicon",
+		"email":"manland@example.com"
+	},
+	"object_attributes":{
+		"id":62,
+		"iid":3,
+		"title":"Q3",
+		"description":"",
+		"state":"closed",
+		"created_at":"2019-06-16 18:40:00 UTC",
+		"updated_at":"2019-06-16 18:45:00 UTC",
+		"due_date":null,
+		"start_date":null,
+		"group_id":100,
+		"project_id":null
+	},
+	"action":"close"
+}`
@@ -0,0 +1,135 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataMilestoneStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataMilestone = []testDataMilestoneStr{
+	{
+		testTitle: "project milestone created",
+		fixture:   MilestoneCreated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "milestones", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Milestone [v1.0](http://localhost:3000/manland/webhook/-/milestones/10) created\n**Due date**: 2019-07-01\n**Issues**: 3 open, 5 closed",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "issue counts from the first subscriber able to read them",
+		fixture:   strings.Replace(MilestoneCreated, `"action":"create"`, `"action":"reopen"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "2", "milestones", "manland/webhook"),
+			MockSubscription("channel2", "1", "milestones", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Milestone [v1.0](http://localhost:3000/manland/webhook/-/milestones/10) reopened\n**Due date**: 2019-07-01\n**Issues**: 3 open, 5 closed",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1", "channel2"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "issue counts unavailable",
+		fixture:   strings.Replace(MilestoneCreated, `"action":"create"`, `"action":"close"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "2", "milestones", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Milestone [v1.0](http://localhost:3000/manland/webhook/-/milestones/10) closed\n**Due date**: 2019-07-01",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "project milestone deleted",
+		fixture:   strings.Replace(MilestoneCreated, `"action":"create"`, `"action":"delete"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "milestones", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Milestone **v1.0** deleted",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "group milestone closed by manland",
+		fixture:   GroupMilestoneClosed,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "milestones", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://localhost:3000/groups/manland/platform) Milestone [Q3](http://localhost:3000/groups/manland/platform/-/milestones/3) closed by [manland](http://my.gitlab.com/manland)\n**Issues**: 3 open, 5 closed",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "group milestone without web URL closed by manland",
+		fixture:   strings.Replace(GroupMilestoneClosed, `"web_url":"http://localhost:3000/groups/manland/platform"`, `"web_url":""`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "milestones", "manland/platform/"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/platform](http://my.gitlab.com/manland/platform) Milestone [Q3](http://my.gitlab.com/groups/manland/platform/-/milestones/3) closed by [manland](http://my.gitlab.com/manland)\n**Issues**: 3 open, 5 closed",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "channel not subscribed to milestones",
+		fixture:   MilestoneCreated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,issues", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestMilestoneWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataMilestone {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			event, err := ParseWebhook(EventTypeMilestone, []byte(test.fixture))
+			require.NoError(t, err)
+			milestoneEvent, ok := event.(*MilestoneEvent)
+			require.True(t, ok)
+			res, err := w.HandleMilestone(context.Background(), milestoneEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.ElementsMatch(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+			}
+		})
+	}
+}
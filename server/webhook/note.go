@@ -6,166 +6,511 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
-func (w *webhook) HandleIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, []string, error) {
-	var warnings []string
+// noteContextLines is the number of diff lines shown above the line a note is positioned on.
+const noteContextLines = 3
+
+func (w *webhook) HandleIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, error) {
 	handlers, err := w.handleDMIssueComment(event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	handlers2, warnings, err := w.handleChannelIssueComment(ctx, event)
+	handlers2, err := w.handleChannelIssueComment(ctx, event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	return cleanWebhookHandlers(append(handlers, handlers2...)), warnings, nil
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
 }
 
 func (w *webhook) handleDMIssueComment(event *gitlab.IssueCommentEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Username
-	message := fmt.Sprintf("[%s](%s) commented on your issue [%s#%v](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Project.PathWithNamespace, event.Issue.IID, event.ObjectAttributes.URL)
-
 	toUsers := make([]string, len(event.Issue.AssigneeIDs)+1)
 	for index, assigneeID := range event.Issue.AssigneeIDs {
 		toUsers[index] = w.gitlabRetreiver.GetUsernameByID(assigneeID)
 	}
 	toUsers[len(toUsers)-1] = w.gitlabRetreiver.GetUsernameByID(event.Issue.AuthorID)
 
-	handlers := []*HandleWebhook{
-		{
-			Message: message,
-			ToUsers: toUsers,
-			From:    senderGitlabUsername,
-		},
-	}
+	handlers := w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+		ID:    "webhook.issue_comment.dm",
+		Other: "[{{.Sender}}]({{.SenderURL}}) commented on your issue [{{.Project}}#{{.IID}}]({{.URL}})",
+	}, map[string]any{
+		"Sender":    senderGitlabUsername,
+		"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Project":   event.Project.PathWithNamespace,
+		"IID":       event.Issue.IID,
+		"URL":       event.ObjectAttributes.URL,
+	})
 
-	if mention := w.handleMention(mentionDetails{
+	handlers = append(handlers, w.handleMention(mentionDetails{
 		senderUsername:    senderGitlabUsername,
 		pathWithNamespace: event.Project.PathWithNamespace,
 		IID:               fmt.Sprintf("%d", event.Issue.IID),
 		URL:               event.ObjectAttributes.URL,
 		body:              event.ObjectAttributes.Description,
-	}); mention != nil {
-		handlers = append(handlers, mention)
-	}
+	})...)
 
 	return handlers, nil
 }
 
-func (w *webhook) handleChannelIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, []string, error) {
+func (w *webhook) handleChannelIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Username
 	repo := event.Project
 	body := event.ObjectAttributes.Description
 	res := []*HandleWebhook{}
 
-	message := fmt.Sprintf("[%s](%s) New comment by [%s](%s) on [#%v %s](%s):\n\n%s", repo.PathWithNamespace, repo.WebURL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Issue.IID, event.Issue.Title, event.ObjectAttributes.URL, body)
+	message := w.localize(w.gitlabRetreiver.GetServerLocale(), &i18n.Message{
+		ID:    "webhook.issue_comment.channel",
+		Other: "[{{.Project}}]({{.ProjectURL}}) New comment by [{{.Sender}}]({{.SenderURL}}) on [#{{.IID}} {{.Title}}]({{.URL}}):\n\n{{.Body}}",
+	}, map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"IID":        event.Issue.IID,
+		"Title":      event.Issue.Title,
+		"URL":        event.ObjectAttributes.URL,
+		"Body":       body,
+	})
 
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
-	var warnings []string
 	for _, sub := range subs {
-		if !sub.IssueComments() {
+		if !sub.IssueComments() || !sub.MatchesAuthor(senderGitlabUsername) {
 			continue
 		}
 
-		ok, warning := anyEventLabelInSubs(sub, event.Issue.Labels)
-		if !ok {
-			if len(warning) > 0 {
-				warnings = append(warnings, warning)
-			}
+		if !eventLabelsMatchSub(sub, event.Issue.Labels) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
+		message, _ = w.renderMessage("issue_comment", &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Author:     senderGitlabUsername,
+			AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			Project:    repo.PathWithNamespace,
+			ProjectURL: repo.WebURL,
+			Title:      event.Issue.Title,
+			URL:        event.ObjectAttributes.URL,
+		})
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+			ThreadKey:     issueThreadKey(event.ProjectID, event.Issue.IID),
 		})
 	}
-	return res, warnings, nil
+	return res, nil
 }
 
-func (w *webhook) HandleMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, []string, error) {
-	var warnings []string
+func (w *webhook) HandleMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, error) {
 	handlers, err := w.handleDMMergeRequestComment(event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	handlers2, warnings, err := w.handleChannelMergeRequestComment(ctx, event)
+	handlers2, err := w.handleChannelMergeRequestComment(ctx, event)
 	if err != nil {
-		return nil, warnings, err
+		return nil, err
 	}
-	return cleanWebhookHandlers(append(handlers, handlers2...)), warnings, nil
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
 }
 
 func (w *webhook) handleDMMergeRequestComment(event *gitlab.MergeCommentEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Username
-	message := fmt.Sprintf("[%s](%s) commented on your merge request [%s#%v](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Project.PathWithNamespace, event.MergeRequest.IID, event.ObjectAttributes.URL)
+	toUsers := []string{w.gitlabRetreiver.GetUsernameByID(event.MergeRequest.AssigneeID), w.gitlabRetreiver.GetUsernameByID(event.MergeRequest.AuthorID)}
+	handlers := w.dmHandlers(senderGitlabUsername, toUsers, &i18n.Message{
+		ID:    "webhook.merge_request_comment.dm",
+		Other: "[{{.Sender}}]({{.SenderURL}}) commented on your merge request [{{.Project}}#{{.IID}}]({{.URL}})",
+	}, map[string]any{
+		"Sender":    senderGitlabUsername,
+		"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Project":   event.Project.PathWithNamespace,
+		"IID":       event.MergeRequest.IID,
+		"URL":       event.ObjectAttributes.URL,
+	})
 
-	handlers := []*HandleWebhook{{
-		Message: message,
-		ToUsers: []string{w.gitlabRetreiver.GetUsernameByID(event.MergeRequest.AssigneeID), w.gitlabRetreiver.GetUsernameByID(event.MergeRequest.AuthorID)},
-		From:    senderGitlabUsername,
-	}}
-
-	if mention := w.handleMention(mentionDetails{
+	handlers = append(handlers, w.handleMention(mentionDetails{
 		senderUsername:    senderGitlabUsername,
 		pathWithNamespace: event.Project.PathWithNamespace,
 		IID:               fmt.Sprintf("%d", event.MergeRequest.IID),
 		URL:               event.ObjectAttributes.URL,
 		body:              event.ObjectAttributes.Description,
-	}); mention != nil {
-		handlers = append(handlers, mention)
-	}
+	})...)
 	return handlers, nil
 }
 
-func (w *webhook) handleChannelMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, []string, error) {
+func (w *webhook) handleChannelMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, error) {
 	senderGitlabUsername := event.User.Username
 	repo := event.Project
 	body := event.ObjectAttributes.Description
 	res := []*HandleWebhook{}
 
-	message := fmt.Sprintf("[%s](%s) New comment by [%s](%s) on [#%v %s](%s):\n\n%s", repo.PathWithNamespace, repo.WebURL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.MergeRequest.IID, event.MergeRequest.Title, event.ObjectAttributes.URL, body)
-	var warnings []string
+	message := w.localize(w.gitlabRetreiver.GetServerLocale(), &i18n.Message{
+		ID:    "webhook.merge_request_comment.channel",
+		Other: "[{{.Project}}]({{.ProjectURL}}) New comment by [{{.Sender}}]({{.SenderURL}}) on [#{{.IID}} {{.Title}}]({{.URL}}):\n\n{{.Body}}",
+	}, map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"IID":        event.MergeRequest.IID,
+		"Title":      event.MergeRequest.Title,
+		"URL":        event.ObjectAttributes.URL,
+		"Body":       body,
+	})
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
 	for _, sub := range subs {
-		if !sub.MergeRequestComments() {
+		if !sub.MergeRequestComments() || !sub.MatchesAuthor(senderGitlabUsername) {
 			continue
 		}
 
-		ok, warning := anyEventLabelInSubs(sub, event.MergeRequest.Labels)
-		if !ok {
-			if len(warning) > 0 {
-				warnings = append(warnings, warning)
-			}
+		if !eventLabelsMatchSub(sub, event.MergeRequest.Labels) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
+		message, _ = w.renderMessage("merge_request_comment", &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Author:     senderGitlabUsername,
+			AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			Project:    repo.PathWithNamespace,
+			ProjectURL: repo.WebURL,
+			Title:      event.MergeRequest.Title,
+			URL:        event.ObjectAttributes.URL,
+		})
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+			ThreadKey:     mergeRequestThreadKey(event.ProjectID, event.MergeRequest.IID),
+		})
+	}
+	return res, nil
+}
+
+func (w *webhook) HandleCommitComment(ctx context.Context, event *gitlab.CommitCommentEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleDMCommitComment(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	handlers2, err := w.handleChannelCommitComment(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
+}
+
+func (w *webhook) handleDMCommitComment(ctx context.Context, event *gitlab.CommitCommentEvent) ([]*HandleWebhook, error) {
+	senderGitlabUsername := event.User.Username
+	handlers := []*HandleWebhook{}
+	if event.Commit == nil {
+		return handlers, nil
+	}
+	shortSHA := shortCommitSHA(event.Commit.ID)
+
+	if author := w.commitAuthorUsername(ctx, event); author != "" {
+		handlers = append(handlers, w.dmHandlers(senderGitlabUsername, []string{author}, &i18n.Message{
+			ID:    "webhook.commit_comment.dm",
+			Other: "[{{.Sender}}]({{.SenderURL}}) commented on your commit [{{.Project}}@{{.SHA}}]({{.URL}})",
+		}, map[string]any{
+			"Sender":    senderGitlabUsername,
+			"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			"Project":   event.Project.PathWithNamespace,
+			"SHA":       shortSHA,
+			"URL":       event.ObjectAttributes.URL,
+		})...)
+	}
+
+	handlers = append(handlers, w.handleMention(mentionDetails{
+		senderUsername:    senderGitlabUsername,
+		pathWithNamespace: event.Project.PathWithNamespace,
+		IID:               shortSHA,
+		URL:               event.ObjectAttributes.URL,
+		body:              event.ObjectAttributes.Note,
+	})...)
+
+	return handlers, nil
+}
+
+// commitAuthorUsername returns the GitLab username of the author of the commented commit, or an empty string if none
+// of the subscription creators of the project can find it. Commits only carry the author email, which anyone can set,
+// so it is only trusted when GitLab shows it on the profile of a user.
+func (w *webhook) commitAuthorUsername(ctx context.Context, event *gitlab.CommitCommentEvent) string {
+	if event.Commit.Author.Email == "" {
+		return ""
+	}
+	namespace, project := normalizeNamespacedProject(event.Project.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		event.Project.Visibility == gitlab.PublicVisibility,
+	)
+	tried := map[string]bool{}
+	for _, sub := range subs {
+		if tried[sub.CreatorID] {
+			continue
+		}
+		tried[sub.CreatorID] = true
+		username, err := w.gitlabRetreiver.GetCommitAuthorUsername(ctx, sub.CreatorID, event.Commit.Author.Email)
+		if err == nil {
+			return username
+		}
+	}
+	return ""
+}
+
+func (w *webhook) handleChannelCommitComment(ctx context.Context, event *gitlab.CommitCommentEvent) ([]*HandleWebhook, error) {
+	senderGitlabUsername := event.User.Username
+	repo := event.Project
+	res := []*HandleWebhook{}
+	if event.Commit == nil {
+		return res, nil
+	}
+
+	message := w.localize(w.gitlabRetreiver.GetServerLocale(), &i18n.Message{
+		ID:    "webhook.commit_comment.channel",
+		Other: "[{{.Project}}]({{.ProjectURL}}) New comment by [{{.Sender}}]({{.SenderURL}}) on commit [{{.SHA}} {{.Title}}]({{.URL}}):",
+	}, map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"SHA":        shortCommitSHA(event.Commit.ID),
+		"Title":      event.Commit.Title,
+		"URL":        event.ObjectAttributes.URL,
+	}) + "\n\n"
+	if lineContext := noteLineContext(event.ObjectAttributes.StDiff, event.ObjectAttributes.LineCode); lineContext != "" {
+		message += lineContext + "\n"
+	}
+	message += event.ObjectAttributes.Note
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		repo.Visibility == gitlab.PublicVisibility,
+	)
+	for _, sub := range subs {
+		if !sub.CommitComments() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+		})
+	}
+	return res, nil
+}
+
+func (w *webhook) HandleSnippetComment(ctx context.Context, event *gitlab.SnippetCommentEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleDMSnippetComment(event)
+	if err != nil {
+		return nil, err
+	}
+	handlers2, err := w.handleChannelSnippetComment(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(append(handlers, handlers2...)), nil
+}
+
+func (w *webhook) handleDMSnippetComment(event *gitlab.SnippetCommentEvent) ([]*HandleWebhook, error) {
+	senderGitlabUsername := event.User.Username
+	handlers := []*HandleWebhook{}
+	if event.Snippet == nil {
+		return handlers, nil
+	}
+	snippetID := fmt.Sprintf("$%d", event.Snippet.ID)
+
+	handlers = append(handlers, w.dmHandlers(senderGitlabUsername, []string{w.gitlabRetreiver.GetUsernameByID(event.Snippet.AuthorID)}, &i18n.Message{
+		ID:    "webhook.snippet_comment.dm",
+		Other: "[{{.Sender}}]({{.SenderURL}}) commented on your snippet [{{.Project}}{{.SnippetID}}]({{.URL}})",
+	}, map[string]any{
+		"Sender":    senderGitlabUsername,
+		"SenderURL": w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Project":   event.Project.PathWithNamespace,
+		"SnippetID": snippetID,
+		"URL":       event.ObjectAttributes.URL,
+	})...)
+
+	handlers = append(handlers, w.handleMention(mentionDetails{
+		senderUsername:    senderGitlabUsername,
+		pathWithNamespace: event.Project.PathWithNamespace,
+		IID:               snippetID,
+		URL:               event.ObjectAttributes.URL,
+		body:              event.ObjectAttributes.Note,
+	})...)
+
+	return handlers, nil
+}
+
+func (w *webhook) handleChannelSnippetComment(ctx context.Context, event *gitlab.SnippetCommentEvent) ([]*HandleWebhook, error) {
+	senderGitlabUsername := event.User.Username
+	repo := event.Project
+	res := []*HandleWebhook{}
+	if event.Snippet == nil {
+		return res, nil
+	}
+
+	message := w.localize(w.gitlabRetreiver.GetServerLocale(), &i18n.Message{
+		ID:    "webhook.snippet_comment.channel",
+		Other: "[{{.Project}}]({{.ProjectURL}}) New comment by [{{.Sender}}]({{.SenderURL}}) on snippet [${{.SnippetID}} {{.Title}}]({{.URL}}):\n\n{{.Body}}",
+	}, map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"SnippetID":  event.Snippet.ID,
+		"Title":      event.Snippet.Title,
+		"URL":        event.ObjectAttributes.URL,
+		"Body":       event.ObjectAttributes.Note,
+	})
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		repo.Visibility == gitlab.PublicVisibility,
+	)
+	for _, sub := range subs {
+		if !sub.SnippetComments() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
-	return res, warnings, nil
+	return res, nil
+}
+
+func shortCommitSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// noteLineContext renders the lines of the diff a note is positioned on, up to the commented line,
+// as a diff code block. It returns an empty string for notes not positioned on a diff.
+//
+// GitLab identifies the commented line with a line code of the form "<file hash>_<old line>_<new line>".
+func noteLineContext(diff *gitlab.Diff, lineCode string) string {
+	if diff == nil || diff.Diff == "" || lineCode == "" {
+		return ""
+	}
+
+	parts := strings.Split(lineCode, "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	oldLine, oldErr := strconv.Atoi(parts[len(parts)-2])
+	newLine, newErr := strconv.Atoi(parts[len(parts)-1])
+	if oldErr != nil || newErr != nil {
+		return ""
+	}
+
+	var lines []string
+	target := -1
+	currentOld, currentNew := 0, 0
+	for line := range strings.SplitSeq(diff.Diff, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			var oldStart, newStart int
+			if _, err := fmt.Sscanf(line, "@@ -%d", &oldStart); err != nil {
+				return ""
+			}
+			if i := strings.Index(line, " +"); i >= 0 {
+				if _, err := fmt.Sscanf(line[i:], " +%d", &newStart); err != nil {
+					return ""
+				}
+			}
+			currentOld, currentNew = oldStart, newStart
+			lines = nil
+			continue
+		}
+		if strings.HasPrefix(line, `\`) || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+
+		lines = append(lines, line)
+		switch {
+		case strings.HasPrefix(line, "+"):
+			if currentNew == newLine {
+				target = len(lines) - 1
+			}
+			currentNew++
+		case strings.HasPrefix(line, "-"):
+			if currentOld == oldLine {
+				target = len(lines) - 1
+			}
+			currentOld++
+		default:
+			if currentNew == newLine && currentOld == oldLine {
+				target = len(lines) - 1
+			}
+			currentOld++
+			currentNew++
+		}
+		if target >= 0 {
+			break
+		}
+	}
+	if target < 0 {
+		return ""
+	}
+
+	start := max(target-noteContextLines, 0)
+	path := diff.NewPath
+	if path == "" {
+		path = diff.OldPath
+	}
+	return fmt.Sprintf("`%s`\n```diff\n%s\n```", path, strings.Join(lines[start:target+1], "\n"))
 }
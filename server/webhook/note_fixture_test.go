@@ -233,3 +233,140 @@ const IssueComment = `{
 			"assignee_id":50
 		}
 		}`
+
+const CommitComment = `{
+	"object_kind":"note",
+	"event_type":"note",
+	"user":{
+		"id":1,
+		"name":"Administrator",
+		"username":"root",
+		"avatar_url":"https://www.gravatar.com/avatar/e64c7d89f26bd1972efa854d13d7dd61?s=80&d=identicon",
+		"email":"admin@example.com"
+	},
+	"project_id":24,
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"object_attributes":{
+		"id":1243,
+		"note":"This should use the new name",
+		"noteable_type":"Commit",
+		"author_id":1,
+		"created_at":"2019-06-16 18:25:43 UTC",
+		"updated_at":"2019-06-16 18:25:43 UTC",
+		"project_id":24,
+		"attachment":null,
+		"line_code":"1063b1f4e7de6ebf9bd4cf3aa4b1bb9b0d4b1ef6_3_4",
+		"commit_id":"cfe32cf61b73a0d5e9f13e774abde7ff789b1660",
+		"noteable_id":null,
+		"system":false,
+		"st_diff":{
+			"diff":"@@ -1,4 +1,5 @@\n # webhook\n-Old description\n+New description\n+\n+See the wiki.\n more text\n",
+			"new_path":"README.md",
+			"old_path":"README.md",
+			"a_mode":"100644",
+			"b_mode":"100644",
+			"new_file":false,
+			"renamed_file":false,
+			"deleted_file":false
+		},
+		"url":"http://localhost:3000/manland/webhook/commit/cfe32cf61b73a0d5e9f13e774abde7ff789b1660#note_1243"
+	},
+	"repository":{
+		"name":"webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"description":"",
+		"homepage":"http://localhost:3000/manland/webhook"
+	},
+	"commit":{
+		"id":"cfe32cf61b73a0d5e9f13e774abde7ff789b1660",
+		"title":"Update README.md",
+		"message":"Update README.md\n",
+		"timestamp":"2019-06-16T18:20:00+02:00",
+		"url":"http://localhost:3000/manland/webhook/commit/cfe32cf61b73a0d5e9f13e774abde7ff789b1660",
+		"author":{
+			"name":"Romain Maneschi",
+			"email":"manland@example.com"
+		}
+	}
+}`
+
+const SnippetComment = `{
+	"object_kind":"note",
+	"event_type":"note",
+	"user":{
+		"id":1,
+		"name":"Administrator",
+		"username":"root",
+		"avatar_url":"https://www.gravatar.com/avatar/e64c7d89f26bd1972efa854d13d7dd61?s=80&d=identicon",
+		"email":"admin@example.com"
+	},
+	"project_id":24,
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"object_attributes":{
+		"id":1245,
+		"note":"Is this snippet still used?",
+		"noteable_type":"Snippet",
+		"author_id":1,
+		"created_at":"2019-06-16 18:30:00 UTC",
+		"updated_at":"2019-06-16 18:30:00 UTC",
+		"project_id":24,
+		"attachment":null,
+		"line_code":null,
+		"commit_id":"",
+		"noteable_id":53,
+		"system":false,
+		"st_diff":null,
+		"url":"http://localhost:3000/manland/webhook/-/snippets/53#note_1245"
+	},
+	"repository":{
+		"name":"webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"description":"",
+		"homepage":"http://localhost:3000/manland/webhook"
+	},
+	"snippet":{
+		"id":53,
+		"title":"Deploy script",
+		"content":"make deploy",
+		"author_id":50,
+		"project_id":24,
+		"created_at":"2019-06-16 18:00:00 UTC",
+		"updated_at":"2019-06-16 18:00:00 UTC",
+		"file_name":"deploy.sh",
+		"expires_at":null,
+		"type":"ProjectSnippet",
+		"visibility_level":20
+	}
+}`
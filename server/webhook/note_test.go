@@ -21,7 +21,6 @@ type testDataNoteStr struct {
 	kind            string
 	gitlabRetreiver *fakeWebhook
 	res             []*HandleWebhook
-	warnings        []string
 }
 
 var testDataNote = []testDataNoteStr{
@@ -30,7 +29,7 @@ var testDataNote = []testDataNoteStr{
 		kind:      "issue",
 		fixture:   IssueComment,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issue_comments", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "issue_comments", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) commented on your issue [manland/webhook#1](http://localhost:3000/manland/webhook/issues/1#note_997)",
@@ -43,13 +42,12 @@ var testDataNote = []testDataNoteStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	}, {
 		testTitle: "manland comment issue of root (subgroup)",
 		kind:      "issue",
 		fixture:   strings.ReplaceAll(IssueComment, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issue_comments", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "issue_comments", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) commented on your issue [manland/subgroup/webhook#1](http://localhost:3000/manland/subgroup/webhook/issues/1#note_997)",
@@ -62,13 +60,12 @@ var testDataNote = []testDataNoteStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	}, {
 		testTitle: "manland comment merge request of root",
 		kind:      "mr",
 		fixture:   MergeRequestComment,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merge_request_comments", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "merge_request_comments", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland](http://my.gitlab.com/manland) commented on your merge request [manland/webhook#6](http://localhost:3000/manland/webhook/merge_requests/6#note_999)",
@@ -81,35 +78,68 @@ var testDataNote = []testDataNoteStr{
 			ToChannels: []string{"channel1"},
 			From:       "manland",
 		}},
-		warnings: []string{},
 	}, {
-		testTitle: "manland comment issue of root (subgroup) with subscription label warning",
-		kind:      "issue",
-		fixture:   strings.ReplaceAll(IssueComment, "manland/webhook", "manland/subgroup/webhook"),
+		testTitle: "root comment a line of a commit of manland",
+		kind:      "commit",
+		fixture:   CommitComment,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "issue_comments,label:", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "commit_comments", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
-			Message:    "[manland](http://my.gitlab.com/manland) commented on your issue [manland/subgroup/webhook#1](http://localhost:3000/manland/subgroup/webhook/issues/1#note_997)",
-			ToUsers:    []string{"root"},
+			Message:    "[root](http://my.gitlab.com/root) commented on your commit [manland/webhook@cfe32cf6](http://localhost:3000/manland/webhook/commit/cfe32cf61b73a0d5e9f13e774abde7ff789b1660#note_1243)",
+			ToUsers:    []string{"manland"},
 			ToChannels: []string{},
-			From:       "manland",
+			From:       "root",
+		}, {
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New comment by [root](http://my.gitlab.com/root) on commit [cfe32cf6 Update README.md](http://localhost:3000/manland/webhook/commit/cfe32cf61b73a0d5e9f13e774abde7ff789b1660#note_1243):\n\n`README.md`\n```diff\n-Old description\n+New description\n+\n+See the wiki.\n```\nThis should use the new name",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
 		}},
-		warnings: []string{"each label must be wrapped in quotes, e.g. label:\"bug\""},
 	}, {
-		testTitle: "manland comment merge request of root with subscription label warning",
-		kind:      "mr",
-		fixture:   MergeRequestComment,
+		testTitle: "root comment a commit of an unknown author",
+		kind:      "commit",
+		fixture:   strings.ReplaceAll(strings.ReplaceAll(CommitComment, "manland@example.com", "someone@example.com"), `"line_code":"1063b1f4e7de6ebf9bd4cf3aa4b1bb9b0d4b1ef6_3_4"`, `"line_code":null`),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "merge_request_comments,label:", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "commit_comments", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
-			Message:    "[manland](http://my.gitlab.com/manland) commented on your merge request [manland/webhook#6](http://localhost:3000/manland/webhook/merge_requests/6#note_999)",
-			ToUsers:    []string{"root"},
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New comment by [root](http://my.gitlab.com/root) on commit [cfe32cf6 Update README.md](http://localhost:3000/manland/webhook/commit/cfe32cf61b73a0d5e9f13e774abde7ff789b1660#note_1243):\n\nThis should use the new name",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
+	}, {
+		testTitle: "root comment a snippet of manland",
+		kind:      "snippet",
+		fixture:   SnippetComment,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "snippet_comments", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) commented on your snippet [manland/webhook$53](http://localhost:3000/manland/webhook/-/snippets/53#note_1245)",
+			ToUsers:    []string{"manland"},
 			ToChannels: []string{},
-			From:       "manland",
+			From:       "root",
+		}, {
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New comment by [root](http://my.gitlab.com/root) on snippet [$53 Deploy script](http://localhost:3000/manland/webhook/-/snippets/53#note_1245):\n\nIs this snippet still used?",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "root",
+		}},
+	}, {
+		testTitle: "channel not subscribed to snippet comments",
+		kind:      "snippet",
+		fixture:   SnippetComment,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "issue_comments", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[root](http://my.gitlab.com/root) commented on your snippet [manland/webhook$53](http://localhost:3000/manland/webhook/-/snippets/53#note_1245)",
+			ToUsers:    []string{"manland"},
+			ToChannels: []string{},
+			From:       "root",
 		}},
-		warnings: []string{"each label must be wrapped in quotes, e.g. label:\"bug\""},
 	},
 }
 
@@ -120,23 +150,34 @@ func TestNoteWebhook(t *testing.T) {
 			w := NewWebhook(test.gitlabRetreiver)
 			var res []*HandleWebhook
 			var err error
-			var warnings []string
-			if test.kind == "issue" {
+			switch test.kind {
+			case "issue":
 				issueCommentEvent := &gitlab.IssueCommentEvent{}
 				if err = json.Unmarshal([]byte(test.fixture), issueCommentEvent); err != nil {
 					assert.Fail(t, "can't unmarshal fixture")
 				}
-				res, warnings, err = w.HandleIssueComment(context.Background(), issueCommentEvent)
-			} else {
+				res, err = w.HandleIssueComment(context.Background(), issueCommentEvent)
+			case "commit":
+				commitCommentEvent := &gitlab.CommitCommentEvent{}
+				if err = json.Unmarshal([]byte(test.fixture), commitCommentEvent); err != nil {
+					assert.Fail(t, "can't unmarshal fixture")
+				}
+				res, err = w.HandleCommitComment(context.Background(), commitCommentEvent)
+			case "snippet":
+				snippetCommentEvent := &gitlab.SnippetCommentEvent{}
+				if err = json.Unmarshal([]byte(test.fixture), snippetCommentEvent); err != nil {
+					assert.Fail(t, "can't unmarshal fixture")
+				}
+				res, err = w.HandleSnippetComment(context.Background(), snippetCommentEvent)
+			default:
 				mergeCommentEvent := &gitlab.MergeCommentEvent{}
 				if err = json.Unmarshal([]byte(test.fixture), mergeCommentEvent); err != nil {
 					assert.Fail(t, "can't unmarshal fixture")
 				}
-				res, warnings, err = w.HandleMergeRequestComment(context.Background(), mergeCommentEvent)
+				res, err = w.HandleMergeRequestComment(context.Background(), mergeCommentEvent)
 			}
 			assert.Empty(t, err)
 			assert.Equal(t, len(test.res), len(res))
-			assert.ElementsMatch(t, test.warnings, warnings)
 			for index := range res {
 				assert.Equal(t, test.res[index].Message, res[index].Message)
 				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
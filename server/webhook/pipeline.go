@@ -6,10 +6,121 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
+// PipelineCard is the content of the post of a pipeline, updated in place as its pipeline and job events come in.
+type PipelineCard struct {
+	// Header is the line describing the pipeline, empty until a pipeline event was received.
+	Header string `json:"header,omitempty"`
+	// Attachment is the rich version of the header, set along with it.
+	Attachment *model.MessageAttachment `json:"attachment,omitempty"`
+	Stages     []string                 `json:"stages,omitempty"`
+	Jobs       []PipelineJob            `json:"jobs,omitempty"`
+	// Revision counts the updates of the card, so the posts of an earlier state can be told apart from the latest one.
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// PipelineJob is a row of the job table of a pipeline post.
+type PipelineJob struct {
+	ID     int    `json:"id"`
+	Stage  string `json:"stage"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Merge applies an update to the card. An update with a header comes from a pipeline event, which lists every job
+// of the pipeline, so it replaces the whole card. Otherwise its jobs replace the ones with the same ID.
+func (c *PipelineCard) Merge(update *PipelineCard) {
+	if update.Header != "" {
+		*c = PipelineCard{Header: update.Header, Attachment: update.Attachment, Stages: update.Stages, Jobs: slices.Clone(update.Jobs)}
+		return
+	}
+	for _, job := range update.Jobs {
+		index := slices.IndexFunc(c.Jobs, func(j PipelineJob) bool { return j.ID == job.ID })
+		if index == -1 {
+			c.Jobs = append(c.Jobs, job)
+		} else {
+			c.Jobs[index] = job
+		}
+	}
+}
+
+// Message returns the header of the card followed by the table of its jobs, ordered by stage then by name.
+// columns is the header row of the table, in the language of the post.
+func (c *PipelineCard) Message(columns string) string {
+	if len(c.Jobs) == 0 {
+		return c.Header
+	}
+	return c.Header + "\n\n" + c.jobTable(columns)
+}
+
+// MessageAttachment returns the attachment of the card with the table of its jobs, the message being its fallback.
+func (c *PipelineCard) MessageAttachment(columns string) *model.MessageAttachment {
+	if c.Attachment == nil {
+		return nil
+	}
+	attachment := *c.Attachment
+	attachment.Fallback = c.Message(columns)
+	if len(c.Jobs) > 0 {
+		attachment.Text += "\n\n" + c.jobTable(columns)
+	}
+	return &attachment
+}
+
+func (c *PipelineCard) jobTable(columns string) string {
+	stageIndex := func(stage string) int {
+		if index := slices.Index(c.Stages, stage); index != -1 {
+			return index
+		}
+		return len(c.Stages)
+	}
+	jobs := slices.Clone(c.Jobs)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if stageIndex(jobs[i].Stage) != stageIndex(jobs[j].Stage) {
+			return stageIndex(jobs[i].Stage) < stageIndex(jobs[j].Stage)
+		}
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	var table strings.Builder
+	table.WriteString(columns + "\n|:------|:----|:-------|\n")
+	for _, job := range jobs {
+		fmt.Fprintf(&table, "| %s | %s | %s %s |\n", job.Stage, job.Name, jobStatusIcon(job.Status), job.Status)
+	}
+	return strings.TrimSuffix(table.String(), "\n")
+}
+
+// jobTableColumns returns the header row of the job table of the pipeline posts, in the language.
+func (w *webhook) jobTableColumns(locale string) string {
+	return w.localize(locale, &i18n.Message{ID: "webhook.pipeline.job_table", Other: "| Stage | Job | Status |"}, nil)
+}
+
+func jobStatusIcon(status string) string {
+	switch status {
+	case statusRunning:
+		return ":rocket:"
+	case statusPending, statusCreated:
+		return ":clock1:"
+	case statusSuccess:
+		return ":large_green_circle:"
+	case statusFailed:
+		return ":red_circle:"
+	case statusCanceled:
+		return ":no_entry_sign:"
+	default:
+		return ":white_circle:"
+	}
+}
+
 func (w *webhook) HandlePipeline(ctx context.Context, event *gitlab.PipelineEvent) ([]*HandleWebhook, error) {
 	handlers, err := w.handleDMPipeline(event)
 	if err != nil {
@@ -29,24 +140,25 @@ func (w *webhook) handleDMPipeline(event *gitlab.PipelineEvent) ([]*HandleWebhoo
 	handlers := []*HandleWebhook{}
 
 	if event.ObjectAttributes.Status == statusFailed {
-		message := fmt.Sprintf("[%s](%s) Your pipeline has failed for %s [%s](%s)", repo.PathWithNamespace, repo.WebURL, event.Commit.Message, "View Pipeline", w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID))
-		handlers = append(handlers, &HandleWebhook{
-			Message:    message,
-			From:       "", // don't put senderGitlabUsername because we filter message where from == to
-			ToUsers:    []string{senderGitlabUsername},
-			ToChannels: []string{},
-		})
+		// don't put senderGitlabUsername as sender because we filter message where from == to
+		handlers = append(handlers, w.dmHandlers("", []string{senderGitlabUsername}, &i18n.Message{
+			ID:    "webhook.pipeline.dm.failed",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Your pipeline has failed for {{.Commit}} [View Pipeline]({{.PipelineURL}})",
+		}, map[string]any{
+			"Project":     repo.PathWithNamespace,
+			"ProjectURL":  repo.WebURL,
+			"Commit":      event.Commit.Message,
+			"PipelineURL": w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID),
+		})...)
 	}
 
-	if mention := w.handleMention(mentionDetails{
+	handlers = append(handlers, w.handleMention(mentionDetails{
 		senderUsername:    senderGitlabUsername,
 		pathWithNamespace: event.Project.PathWithNamespace,
 		IID:               fmt.Sprintf("%d", event.ObjectAttributes.ID),
 		URL:               event.Commit.URL,
 		body:              event.Commit.Message,
-	}); mention != nil {
-		handlers = append(handlers, mention)
-	}
+	})...)
 
 	return handlers, nil
 }
@@ -55,41 +167,143 @@ func (w *webhook) handleChannelPipeline(ctx context.Context, event *gitlab.Pipel
 	senderGitlabUsername := event.User.Username
 	repo := event.Project
 	res := []*HandleWebhook{}
-	message := ""
+	status := event.ObjectAttributes.Status
+	ref := event.ObjectAttributes.Ref
 
-	switch event.ObjectAttributes.Status {
-	case statusRunning:
-		message = fmt.Sprintf("[%s](%s) New pipeline from %s by [%s](%s) for %s [%s](%s)", repo.PathWithNamespace, repo.WebURL, event.ObjectAttributes.Source, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Commit.Message, "View Pipeline", w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID))
-	case statusSuccess:
-		message = fmt.Sprintf("[%s](%s) Pipeline by [%s](%s) success for %s [%s](%s)", repo.PathWithNamespace, repo.WebURL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Commit.Message, "View Pipeline", w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID))
-	case statusFailed:
-		message = fmt.Sprintf("[%s](%s) Pipeline by [%s](%s) fail for %s [%s](%s)", repo.PathWithNamespace, repo.WebURL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Commit.Message, "View Pipeline", w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID))
-	default:
+	if status != statusRunning && status != statusSuccess && status != statusFailed {
 		return res, nil
 	}
 
-	toChannels := make([]string, 0)
 	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
+
+	// Only finished pipelines are recorded, so a ref is fixed when a success follows a failure whatever ran in between.
+	fixed := false
+	if status != statusRunning && hasPipelineSubscription(subs) {
+		fixed = w.gitlabRetreiver.SwapPipelineStatus(repo.ID, ref, status) == statusFailed && status == statusSuccess
+	}
+
+	message := ""
+	templateName := "pipeline." + status
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":     repo.PathWithNamespace,
+		"ProjectURL":  repo.WebURL,
+		"Sender":      senderGitlabUsername,
+		"SenderURL":   w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Source":      event.ObjectAttributes.Source,
+		"Ref":         ref,
+		"Commit":      event.Commit.Message,
+		"ID":          event.ObjectAttributes.ID,
+		"PipelineURL": w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID),
+	}
+	attachment := w.eventAttachment(event.User, "", colorOpen)
+	switch {
+	case status == statusRunning:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.pipeline.channel.running",
+			Other: "[{{.Project}}]({{.ProjectURL}}) New pipeline from {{.Source}} by [{{.Sender}}]({{.SenderURL}}) for {{.Commit}} [View Pipeline]({{.PipelineURL}})",
+		}, data)
+		attachment.Text = w.localize(locale, &i18n.Message{ID: "webhook.pipeline.status.running", Other: "Running"}, nil)
+	case fixed:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.pipeline.channel.fixed",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Pipeline by [{{.Sender}}]({{.SenderURL}}) fixed `{{.Ref}}` for {{.Commit}} [View Pipeline]({{.PipelineURL}})",
+		}, data)
+		templateName = "pipeline.fixed"
+		attachment.Text = w.localize(locale, &i18n.Message{ID: "webhook.pipeline.status.fixed", Other: "Fixed"}, nil)
+		attachment.Color = colorSuccess
+	case status == statusSuccess:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.pipeline.channel.success",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Pipeline by [{{.Sender}}]({{.SenderURL}}) success for {{.Commit}} [View Pipeline]({{.PipelineURL}})",
+		}, data)
+		attachment.Text = w.localize(locale, &i18n.Message{ID: "webhook.pipeline.status.success", Other: "Succeeded"}, nil)
+		attachment.Color = colorSuccess
+	case status == statusFailed:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.pipeline.channel.failed",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Pipeline by [{{.Sender}}]({{.SenderURL}}) fail for {{.Commit}} [View Pipeline]({{.PipelineURL}})",
+		}, data)
+		attachment.Text = w.localize(locale, &i18n.Message{ID: "webhook.pipeline.status.failed", Other: "Failed"}, nil)
+		attachment.Color = colorFailed
+	}
+	attachment.Title = w.localize(locale, &i18n.Message{ID: "webhook.pipeline.title", Other: "{{.Project}} pipeline #{{.ID}}"}, data)
+	attachment.TitleLink = w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID)
+	commitTitle, _, _ := strings.Cut(strings.TrimSpace(event.Commit.Message), "\n")
+	attachment.Fields = attachmentFields(
+		w.localize(locale, fieldBranch, nil), ref,
+		w.localize(locale, fieldCommit, nil), commitTitle,
+	)
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	for _, sub := range subs {
-		if !sub.Pipeline() {
+		if !sub.Pipeline() || !sub.MatchesBranch(ref) || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+		// pipeline:failed and pipeline:fixed narrow the subscription down to broken and repaired builds.
+		if (sub.PipelineFailed() || sub.PipelineFixed()) && !(sub.PipelineFailed() && status == statusFailed) && !(sub.PipelineFixed() && fixed) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
+		header, templated := w.renderMessage(templateName, &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Author:     senderGitlabUsername,
+			AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			Project:    repo.PathWithNamespace,
+			ProjectURL: repo.WebURL,
+			Title:      commitTitle,
+			URL:        w.gitlabRetreiver.GetPipelineURL(repo.PathWithNamespace, event.ObjectAttributes.ID),
 		})
+		// The wording set by admins replaces the attachment, which would otherwise hide it.
+		if templated {
+			attachment = nil
+		}
+		update := &PipelineCard{
+			Header:     header,
+			Attachment: attachment,
+			Stages:     event.ObjectAttributes.Stages,
+		}
+		for _, build := range event.Builds {
+			update.Jobs = append(update.Jobs, PipelineJob{ID: build.ID, Stage: build.Stage, Name: build.Name, Status: build.Status})
+		}
+		card := w.gitlabRetreiver.UpdatePipelineCard(repo.ID, event.ObjectAttributes.ID, update)
+
+		handler := &HandleWebhook{
+			From:           senderGitlabUsername,
+			Message:        card.Message(w.jobTableColumns(locale)),
+			ToUsers:        []string{},
+			ToChannels:     toChannels,
+			Subscriptions:  matchedSubs,
+			UpdateKey:      pipelineUpdateKey(repo.ID, event.ObjectAttributes.ID),
+			UpdateRevision: card.Revision,
+			Attachment:     card.MessageAttachment(w.jobTableColumns(locale)),
+		}
+		// Merge request pipelines go to the thread of their merge request.
+		if event.MergeRequest.IID != 0 {
+			handler.ThreadKey = mergeRequestThreadKey(repo.ID, event.MergeRequest.IID)
+		}
+		res = append(res, handler)
 	}
 
 	return res, nil
 }
+
+func hasPipelineSubscription(subs []*subscription.Subscription) bool {
+	for _, sub := range subs {
+		if sub.Pipeline() {
+			return true
+		}
+	}
+	return false
+}
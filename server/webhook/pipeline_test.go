@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
@@ -27,17 +28,17 @@ var testDataPipeline = []testDataPipelineStr{
 		testTitle: "root start a pipeline in pending",
 		fixture:   PipelinePending,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pipeline", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pipeline", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{}, // we don't care about pending pipeline
 	}, {
 		testTitle: "root start a pipeline in running",
 		fixture:   PipelineRun,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pipeline", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pipeline", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
-			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)",
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |",
 			ToUsers:    []string{}, // No DM because user know he has launch a pipeline
 			ToChannels: []string{"channel1"},
 			From:       "root",
@@ -46,10 +47,10 @@ var testDataPipeline = []testDataPipelineStr{
 		testTitle: "root start a pipeline in running (subgroup)",
 		fixture:   strings.ReplaceAll(PipelineRun, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pipeline", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "pipeline", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
-			Message:    "[manland/subgroup/webhook](http://localhost:3000/manland/subgroup/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/subgroup/webhook/-/pipelines/62)",
+			Message:    "[manland/subgroup/webhook](http://localhost:3000/manland/subgroup/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/subgroup/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |",
 			ToUsers:    []string{}, // No DM because user know he has launch a pipeline
 			ToChannels: []string{"channel1"},
 			From:       "root",
@@ -58,7 +59,7 @@ var testDataPipeline = []testDataPipelineStr{
 		testTitle: "root fail a pipeline",
 		fixture:   PipelineFail,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pipeline", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pipeline", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Your pipeline has failed for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)",
@@ -66,7 +67,7 @@ var testDataPipeline = []testDataPipelineStr{
 			ToChannels: []string{},
 			From:       "",
 		}, {
-			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) fail for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)",
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) fail for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :red_circle: failed |",
 			ToUsers:    []string{},
 			ToChannels: []string{},
 			From:       "root",
@@ -75,14 +76,21 @@ var testDataPipeline = []testDataPipelineStr{
 		testTitle: "root success a pipeline",
 		fixture:   PipelineSuccess,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pipeline", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pipeline", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
-			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) success for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)",
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) success for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :large_green_circle: success |",
 			ToUsers:    []string{},
 			ToChannels: []string{"channel1"},
 			From:       "root",
 		}},
+	}, {
+		testTitle: "root start a pipeline on a filtered out branch",
+		fixture:   PipelineRun,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pipeline,branch:"release/*"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
 	},
 }
 
@@ -106,3 +114,128 @@ func TestPipelineWebhook(t *testing.T) {
 		})
 	}
 }
+
+func TestPipelineStatusFilterWebhook(t *testing.T) {
+	t.Parallel()
+	w := NewWebhook(newFakeWebhook([]*subscription.Subscription{
+		MockSubscription("all", "1", "pipeline", "manland/webhook"),
+		MockSubscription("failed", "1", "pipeline:failed", "manland/webhook"),
+		MockSubscription("fixed", "1", "pipeline:fixed", "manland/webhook"),
+		MockSubscription("both", "1", "pipeline:failed,pipeline:fixed", "manland/webhook"),
+	}))
+
+	for _, step := range []struct {
+		fixture    string
+		message    string
+		toChannels []string
+	}{{
+		fixture:    PipelineRun,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |",
+		toChannels: []string{"all"},
+	}, {
+		fixture:    PipelineSuccess,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) success for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :large_green_circle: success |",
+		toChannels: []string{"all"},
+	}, {
+		fixture:    PipelineFail,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) fail for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :red_circle: failed |",
+		toChannels: []string{"all", "failed", "both"},
+	}, {
+		fixture:    PipelineRun,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |",
+		toChannels: []string{"all"},
+	}, {
+		fixture:    PipelineSuccess,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) fixed `master` for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :large_green_circle: success |",
+		toChannels: []string{"all", "fixed", "both"},
+	}, {
+		fixture:    PipelineSuccess,
+		message:    "[manland/webhook](http://localhost:3000/manland/webhook) Pipeline by [root](http://my.gitlab.com/root) success for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :large_green_circle: success |",
+		toChannels: []string{"all"},
+	}} {
+		pipelineEvent := &gitlab.PipelineEvent{}
+		require.NoError(t, json.Unmarshal([]byte(step.fixture), pipelineEvent))
+		res, err := w.HandlePipeline(context.Background(), pipelineEvent)
+		require.NoError(t, err)
+		require.NotEmpty(t, res)
+		// the channel notification comes after the DM sent for failed pipelines
+		assert.Equal(t, step.message, res[len(res)-1].Message)
+		assert.ElementsMatch(t, step.toChannels, res[len(res)-1].ToChannels)
+	}
+}
+
+func TestPipelineCardWebhook(t *testing.T) {
+	t.Parallel()
+	w := NewWebhook(newFakeWebhook([]*subscription.Subscription{
+		MockSubscription("all", "1", "pipeline", "manland/webhook"),
+		MockSubscription("failed", "1", "pipeline:failed", "manland/webhook"),
+	}))
+	updateKey := "pipeline/24/62"
+	// Jobs of the pipeline of the PipelineRun fixture.
+	jobOfPipeline := func(fixture string) *gitlab.JobEvent {
+		jobEvent := &gitlab.JobEvent{}
+		fixture = strings.Replace(fixture, `"pipeline_id": 2366`, `"pipeline_id": 62`, 1)
+		fixture = strings.Replace(fixture, `"project_id": 380`, `"project_id": 24`, 1)
+		require.NoError(t, json.Unmarshal([]byte(fixture), jobEvent))
+		return jobEvent
+	}
+
+	// The job status is recorded until the pipeline post is created.
+	res, err := w.HandleJobs(context.Background(), jobOfPipeline(JobPending))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	pipelineEvent := &gitlab.PipelineEvent{}
+	require.NoError(t, json.Unmarshal([]byte(PipelineRun), pipelineEvent))
+	res, err = w.HandlePipeline(context.Background(), pipelineEvent)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, updateKey, res[0].UpdateKey)
+	assert.Equal(t, []string{"all"}, res[0].ToChannels)
+	assert.Equal(t, "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n"+
+		"| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |", res[0].Message)
+
+	res, err = w.HandleJobs(context.Background(), jobOfPipeline(JobFailed))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, updateKey, res[0].UpdateKey)
+	assert.Equal(t, []string{"all"}, res[0].ToChannels)
+	assert.Equal(t, "[manland/webhook](http://localhost:3000/manland/webhook) New pipeline from merge_request_event by [root](http://my.gitlab.com/root) for Start gitlab-ci\n [View Pipeline](http://my.gitlab.com/manland/webhook/-/pipelines/62)\n\n"+
+		"| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |\n| test | test | :red_circle: failed |", res[0].Message)
+
+	attachment := res[0].Attachment
+	require.NotNil(t, attachment)
+	assert.Equal(t, res[0].Message, attachment.Fallback)
+	assert.Equal(t, colorOpen, attachment.Color)
+	assert.Equal(t, "manland/webhook pipeline #62", attachment.Title)
+	assert.Equal(t, "http://my.gitlab.com/manland/webhook/-/pipelines/62", attachment.TitleLink)
+	assert.Equal(t, "Running\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |\n| test | test | :red_circle: failed |", attachment.Text)
+}
+
+func TestPipelineCardMessage(t *testing.T) {
+	card := &PipelineCard{}
+	card.Merge(&PipelineCard{Jobs: []PipelineJob{{ID: 4, Stage: "test", Name: "lint", Status: statusPending}}})
+	assert.Equal(t, "", card.Header)
+
+	card.Merge(&PipelineCard{
+		Header: "Pipeline running",
+		Stages: []string{"build", "test", "deploy"},
+		Jobs: []PipelineJob{
+			{ID: 3, Stage: "deploy", Name: "pages", Status: statusCreated},
+			{ID: 2, Stage: "test", Name: "unit", Status: statusPending},
+			{ID: 4, Stage: "test", Name: "lint", Status: statusPending},
+			{ID: 1, Stage: "build", Name: "compile", Status: statusRunning},
+		},
+	})
+	card.Merge(&PipelineCard{Jobs: []PipelineJob{{ID: 1, Stage: "build", Name: "compile", Status: statusSuccess}}})
+	card.Merge(&PipelineCard{Jobs: []PipelineJob{{ID: 5, Stage: "cleanup", Name: "prune", Status: "manual"}}})
+
+	assert.Equal(t, "Pipeline running\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n"+
+		"| build | compile | :large_green_circle: success |\n"+
+		"| test | lint | :clock1: pending |\n"+
+		"| test | unit | :clock1: pending |\n"+
+		"| deploy | pages | :clock1: created |\n"+
+		"| cleanup | prune | :white_circle: manual |", card.Message("| Stage | Job | Status |"))
+
+	assert.Equal(t, "Pipeline without jobs", (&PipelineCard{Header: "Pipeline without jobs"}).Message("| Stage | Job | Status |"))
+}
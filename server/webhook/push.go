@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 func (w *webhook) HandlePush(ctx context.Context, event *gitlab.PushEvent) ([]*HandleWebhook, error) {
@@ -28,15 +31,13 @@ func (w *webhook) handleDMPush(event *gitlab.PushEvent) ([]*HandleWebhook, error
 	handlers := []*HandleWebhook{}
 
 	for _, commit := range event.Commits {
-		if mention := w.handleMention(mentionDetails{
+		handlers = append(handlers, w.handleMention(mentionDetails{
 			senderUsername:    senderGitlabUsername,
 			pathWithNamespace: event.Project.PathWithNamespace,
 			IID:               commit.ID,
 			URL:               commit.URL,
 			body:              commit.Message,
-		}); mention != nil {
-			handlers = append(handlers, mention)
-		}
+		})...)
 	}
 
 	return handlers, nil
@@ -51,40 +52,73 @@ func (w *webhook) handleChannelPush(ctx context.Context, event *gitlab.PushEvent
 		return nil, nil
 	}
 
-	plural := "commits"
-
-	if event.TotalCommitsCount == 1 {
-		plural = "commit"
-	}
-
+	locale := w.gitlabRetreiver.GetServerLocale()
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "[%s](%s) has pushed %d %s to [%s](%s)", senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.TotalCommitsCount, plural, event.Project.PathWithNamespace, event.Project.WebURL)
-
+	sb.WriteString(w.localizePlural(locale, &i18n.Message{
+		ID:    "webhook.push.channel",
+		One:   "[{{.Sender}}]({{.SenderURL}}) has pushed {{.Count}} commit to [{{.Project}}]({{.ProjectURL}})",
+		Other: "[{{.Sender}}]({{.SenderURL}}) has pushed {{.Count}} commits to [{{.Project}}]({{.ProjectURL}})",
+	}, event.TotalCommitsCount, map[string]any{
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Count":      event.TotalCommitsCount,
+		"Project":    event.Project.PathWithNamespace,
+		"ProjectURL": event.Project.WebURL,
+	}))
+
+	// GitLab only sends the last 20 commits of a push, so the files changed by older ones are unknown.
+	var changedPaths []string
 	for _, commit := range event.Commits {
-		fmt.Fprintf(&sb, "\n%s [%s](%s)", commit.Message, "View Commit", commit.URL)
+		sb.WriteString("\n" + w.localize(locale, &i18n.Message{
+			ID:    "webhook.push.commit",
+			Other: "{{.Message}} [View Commit]({{.URL}})",
+		}, map[string]any{
+			"Message": commit.Message,
+			"URL":     commit.URL,
+		}))
+		changedPaths = append(changedPaths, commit.Added...)
+		changedPaths = append(changedPaths, commit.Modified...)
+		changedPaths = append(changedPaths, commit.Removed...)
 	}
 	message := sb.String()
 
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
 	for _, sub := range subs {
-		if !sub.Pushes() {
+		if !sub.Pushes() || !sub.MatchesBranch(strings.TrimPrefix(event.Ref, "refs/heads/")) || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+		if !sub.MatchesPaths(changedPaths) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
+		branch := strings.TrimPrefix(event.Ref, "refs/heads/")
+		message, _ = w.renderMessage("push", &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Author:     senderGitlabUsername,
+			AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			Project:    repo.PathWithNamespace,
+			ProjectURL: repo.WebURL,
+			Title:      branch,
+			URL:        fmt.Sprintf("%s/-/tree/%s", repo.WebURL, branch),
+		})
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
 
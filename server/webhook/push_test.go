@@ -27,7 +27,7 @@ var testDataPush = []testDataPushStr{
 		testTitle: "manland push 1 commit",
 		fixture:   PushEvent,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pushes", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pushes", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message: "[manland](http://my.gitlab.com/manland) has pushed 1 commit to [manland/webhook](http://localhost:3000/manland/webhook)\n" +
@@ -40,7 +40,7 @@ var testDataPush = []testDataPushStr{
 		testTitle: "manland push 1 commit (subgroup)",
 		fixture:   strings.ReplaceAll(PushEvent, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pushes", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "pushes", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message: "[manland](http://my.gitlab.com/manland) has pushed 1 commit to [manland/subgroup/webhook](http://localhost:3000/manland/subgroup/webhook)\n" +
@@ -53,7 +53,7 @@ var testDataPush = []testDataPushStr{
 		testTitle: "manland push 2 commits",
 		fixture:   pushEventWithTwoCommits,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pushes", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pushes", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message: "[manland](http://my.gitlab.com/manland) has pushed 2 commits to [manland/webhook](http://localhost:3000/manland/webhook)\n" +
@@ -67,7 +67,69 @@ var testDataPush = []testDataPushStr{
 		testTitle: "manland push 0 commits",
 		fixture:   pushEventWithoutCommits,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "pushes", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "pushes", "manland/webhook"),
+		}),
+		res: nil,
+	}, {
+		testTitle: "manland push 1 commit to a matching branch",
+		fixture:   PushEvent,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pushes,branch:"mas*"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message: "[manland](http://my.gitlab.com/manland) has pushed 1 commit to [manland/webhook](http://localhost:3000/manland/webhook)\n" +
+				"really cool commit\n [View Commit](http://localhost:3000/manland/webhook/commit/c30217b62542c586fdbadc7b5ee762bfdca10663)",
+			ToUsers:    []string{}, // No DM because user know he has push commits
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	}, {
+		testTitle: "manland push 1 commit to a filtered out branch",
+		fixture:   PushEvent,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pushes,branch:"release/*"`, "manland/webhook"),
+		}),
+		res: nil,
+	}, {
+		testTitle: "manland push 1 commit with a matching author",
+		fixture:   PushEvent,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pushes,author:"man*"`, "manland/webhook"),
+			MockSubscription("channel2", "1", `pushes,author:"root"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message: "[manland](http://my.gitlab.com/manland) has pushed 1 commit to [manland/webhook](http://localhost:3000/manland/webhook)\n" +
+				"really cool commit\n [View Commit](http://localhost:3000/manland/webhook/commit/c30217b62542c586fdbadc7b5ee762bfdca10663)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	}, {
+		testTitle: "manland push 1 commit with an excluded author",
+		fixture:   PushEvent,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pushes,exclude_author:"manland"`, "manland/webhook"),
+		}),
+		res: nil,
+	}, {
+		testTitle: "manland push 1 commit changing a matching path",
+		fixture:   PushEvent,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `pushes,path:"**/*.md"`, "manland/webhook"),
+			MockSubscription("channel2", "1", `pushes,path:"services/billing/**"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message: "[manland](http://my.gitlab.com/manland) has pushed 1 commit to [manland/webhook](http://localhost:3000/manland/webhook)\n" +
+				"really cool commit\n [View Commit](http://localhost:3000/manland/webhook/commit/c30217b62542c586fdbadc7b5ee762bfdca10663)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	}, {
+		testTitle: "project access token bot push 1 commit with bots excluded",
+		fixture:   strings.ReplaceAll(PushEvent, `"user_username":"manland"`, `"user_username":"project_3_bot_5f1e"`),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "pushes,exclude_bots", "manland/webhook"),
 		}),
 		res: nil,
 	},
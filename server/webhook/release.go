@@ -7,7 +7,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 func (w *webhook) HandleRelease(ctx context.Context, event *gitlab.ReleaseEvent) ([]*HandleWebhook, error) {
@@ -21,15 +24,22 @@ func (w *webhook) HandleRelease(ctx context.Context, event *gitlab.ReleaseEvent)
 func (w *webhook) handleChannelRelease(ctx context.Context, event *gitlab.ReleaseEvent) ([]*HandleWebhook, error) {
 	project := event.Project
 	res := []*HandleWebhook{}
-	message := fmt.Sprintf("### Release: **%s**\n", event.Action)
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Action": event.Action,
+		"Name":   event.Name,
+		"URL":    event.URL,
+	}
+	message := w.localize(locale, &i18n.Message{ID: "webhook.release.channel.header", Other: "### Release: **{{.Action}}**"}, data) + "\n"
 
+	status := w.localize(locale, lineStatus, map[string]any{"Status": event.Action})
 	switch event.Action {
 	case statusCreate:
-		message += fmt.Sprintf(":new: **Status**: %s\n", event.Action)
+		message += ":new: " + status + "\n"
 	case statusUpdate:
-		message += fmt.Sprintf(":arrows_counterclockwise: **Status**: %s\n", event.Action)
+		message += ":arrows_counterclockwise: " + status + "\n"
 	case statusDelete:
-		message += fmt.Sprintf(":red_circle: **Status**: %s\n", event.Action)
+		message += ":red_circle: " + status + "\n"
 	default:
 		return res, nil
 	}
@@ -40,33 +50,45 @@ func (w *webhook) handleChannelRelease(ctx context.Context, event *gitlab.Releas
 	}
 
 	fullNamespacePath := fmt.Sprintf("%s/%s", namespaceMetadata.Namespace, namespaceMetadata.Project)
-	message += fmt.Sprintf("**Repository**: [%s](%s)\n", fullNamespacePath, event.Project.GitHTTPURL)
+	message += w.localize(locale, lineRepository, map[string]any{"Project": fullNamespacePath, "URL": event.Project.GitHTTPURL}) + "\n"
 	if event.Action != statusDelete {
-		message += fmt.Sprintf("**Release**: [%s](%s)\n", event.Name, event.URL)
+		message += w.localize(locale, &i18n.Message{ID: "webhook.release.channel.release", Other: "**Release**: [{{.Name}}]({{.URL}})"}, data) + "\n"
 	} else {
-		message += fmt.Sprintf("**Release**: %s\n", event.Name)
+		message += w.localize(locale, &i18n.Message{ID: "webhook.release.channel.deleted_release", Other: "**Release**: {{.Name}}"}, data) + "\n"
 	}
 
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespaceMetadata.Namespace,
 		namespaceMetadata.Project,
 		project.VisibilityLevel == PublicVisibilityLevel,
 	)
 	for _, sub := range subs {
-		if !sub.Releases() {
+		// Release events don't tell who made the release.
+		if !sub.Releases() || !sub.MatchesAuthor("") {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       "",
+		message, _ = w.renderMessage("release."+event.Action, &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Project:    fullNamespacePath,
+			ProjectURL: event.Project.WebURL,
+			Title:      event.Name,
+			URL:        event.URL,
+		})
+		res = append(res, &HandleWebhook{
+			From:          "",
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
 
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 func (w *webhook) HandleTag(ctx context.Context, event *gitlab.TagEvent) ([]*HandleWebhook, error) {
@@ -30,15 +33,13 @@ func (w *webhook) handleDMTag(event *gitlab.TagEvent) ([]*HandleWebhook, error)
 	tagName := tagNames[len(tagNames)-1]
 	URL := fmt.Sprintf("%s/-/tags/%s", event.Project.WebURL, tagName)
 
-	if mention := w.handleMention(mentionDetails{
+	handlers = append(handlers, w.handleMention(mentionDetails{
 		senderUsername:    senderGitlabUsername,
 		pathWithNamespace: event.Project.PathWithNamespace,
 		IID:               tagName,
 		URL:               URL,
 		body:              event.Message,
-	}); mention != nil {
-		handlers = append(handlers, mention)
-	}
+	})...)
 
 	return handlers, nil
 }
@@ -55,33 +56,64 @@ func (w *webhook) handleChannelTag(ctx context.Context, event *gitlab.TagEvent)
 		event.Message = fmt.Sprintf(": %s", event.Message)
 	}
 
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Tag":        tagName,
+		"URL":        URL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+		"Message":    event.Message,
+	}
 	var message string
+	templateName := "tag.create"
 	if len(event.Commits) > 0 {
-		message = fmt.Sprintf("[%s](%s) New tag [%s](%s) by [%s](%s)%s", repo.PathWithNamespace, repo.WebURL, tagName, URL, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Message)
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.tag.channel.create",
+			Other: "[{{.Project}}]({{.ProjectURL}}) New tag [{{.Tag}}]({{.URL}}) by [{{.Sender}}]({{.SenderURL}}){{.Message}}",
+		}, data)
 	} else {
-		message = fmt.Sprintf("[%s](%s): %s Tag deleted by [%s](%s)%s", repo.PathWithNamespace, repo.WebURL, tagName, senderGitlabUsername, w.gitlabRetreiver.GetUserURL(senderGitlabUsername), event.Message)
+		templateName = "tag.delete"
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.tag.channel.delete",
+			Other: "[{{.Project}}]({{.ProjectURL}}): {{.Tag}} Tag deleted by [{{.Sender}}]({{.SenderURL}}){{.Message}}",
+		}, data)
 	}
 
 	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
 	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
 	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
 		ctx, namespace, project,
 		repo.Visibility == gitlab.PublicVisibility,
 	)
 	for _, sub := range subs {
-		if !sub.Tag() {
+		if !sub.Tag() || !sub.MatchesBranch(strings.TrimPrefix(event.Ref, "refs/tags/")) || !sub.MatchesAuthor(senderGitlabUsername) {
 			continue
 		}
 
 		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
 	}
 
 	if len(toChannels) > 0 {
-		res = append(res, &HandleWebhook{
-			From:       senderGitlabUsername,
+		message, _ = w.renderMessage(templateName, &MessageTemplateData{
 			Message:    message,
-			ToUsers:    []string{},
-			ToChannels: toChannels,
+			Event:      event,
+			Author:     senderGitlabUsername,
+			AuthorURL:  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+			Project:    repo.PathWithNamespace,
+			ProjectURL: repo.WebURL,
+			Title:      tagName,
+			URL:        URL,
+		})
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
 		})
 	}
 
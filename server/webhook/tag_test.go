@@ -27,7 +27,7 @@ var testDataTag = []testDataTagStr{
 		testTitle: "manland create a tag",
 		fixture:   SimpleTag,
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "tag", Repository: "manland/webhook"},
+			MockSubscription("channel1", "1", "tag", "manland/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) New tag [tag1](http://localhost:3000/manland/webhook/-/tags/tag1) by [manland](http://my.gitlab.com/manland): Really beautiful tag",
@@ -40,7 +40,7 @@ var testDataTag = []testDataTagStr{
 		testTitle: "manland create a tag (subgroup)",
 		fixture:   strings.ReplaceAll(SimpleTag, "manland/webhook", "manland/subgroup/webhook"),
 		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
-			{ChannelID: "channel1", CreatorID: "1", Features: "tag", Repository: "manland/subgroup/webhook"},
+			MockSubscription("channel1", "1", "tag", "manland/subgroup/webhook"),
 		}),
 		res: []*HandleWebhook{{
 			Message:    "[manland/subgroup/webhook](http://localhost:3000/manland/subgroup/webhook) New tag [tag1](http://localhost:3000/manland/subgroup/webhook/-/tags/tag1) by [manland](http://my.gitlab.com/manland): Really beautiful tag",
@@ -49,6 +49,14 @@ var testDataTag = []testDataTagStr{
 			From:       "manland",
 		}},
 	},
+	{
+		testTitle: "manland create a tag not matching the branch filter",
+		fixture:   SimpleTag,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", `tag,branch:"v*"`, "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
 }
 
 func TestTagWebhook(t *testing.T) {
@@ -0,0 +1,260 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/pkg/errors"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+// samples holds the events the message templates are previewed with, taken from the test fixtures.
+//
+//go:embed samples/*.json
+var samples embed.FS
+
+const previewChannelID = "preview"
+
+// MessageTemplate is a message posted to the subscribed channels whose wording admins can change.
+type MessageTemplate struct {
+	// Name is the event type, followed by the action when the event has several, e.g. merge_request.merge.
+	Name        string
+	Description *i18n.Message
+}
+
+// MessageTemplates lists the messages admins can set a template for, sorted by name.
+var MessageTemplates = []MessageTemplate{
+	{Name: "issue.close", Description: &i18n.Message{ID: "webhook.template.issue.close", Other: "Issue closed"}},
+	{Name: "issue.open", Description: &i18n.Message{ID: "webhook.template.issue.open", Other: "Issue opened"}},
+	{Name: "issue.reopen", Description: &i18n.Message{ID: "webhook.template.issue.reopen", Other: "Issue reopened"}},
+	{Name: "issue.update", Description: &i18n.Message{ID: "webhook.template.issue.update", Other: "Issue labeled or unlabeled"}},
+	{Name: "issue_comment", Description: &i18n.Message{ID: "webhook.template.issue_comment", Other: "New comment on an issue"}},
+	{Name: "merge_request.approved", Description: &i18n.Message{ID: "webhook.template.merge_request.approved", Other: "Merge request approved"}},
+	{Name: "merge_request.close", Description: &i18n.Message{ID: "webhook.template.merge_request.close", Other: "Merge request closed"}},
+	{Name: "merge_request.merge", Description: &i18n.Message{ID: "webhook.template.merge_request.merge", Other: "Merge request merged"}},
+	{Name: "merge_request.open", Description: &i18n.Message{ID: "webhook.template.merge_request.open", Other: "Merge request opened"}},
+	{Name: "merge_request.reopen", Description: &i18n.Message{ID: "webhook.template.merge_request.reopen", Other: "Merge request reopened"}},
+	{Name: "merge_request.unapproved", Description: &i18n.Message{ID: "webhook.template.merge_request.unapproved", Other: "Changes requested on a merge request"}},
+	{Name: "merge_request.update", Description: &i18n.Message{ID: "webhook.template.merge_request.update", Other: "Merge request marked as ready for review"}},
+	{Name: "merge_request_comment", Description: &i18n.Message{ID: "webhook.template.merge_request_comment", Other: "New comment on a merge request"}},
+	{Name: "pipeline.failed", Description: &i18n.Message{ID: "webhook.template.pipeline.failed", Other: "Pipeline failed, above the table of its jobs"}},
+	{Name: "pipeline.fixed", Description: &i18n.Message{ID: "webhook.template.pipeline.fixed", Other: "Pipeline succeeded after a failure on the same branch, above the table of its jobs"}},
+	{Name: "pipeline.running", Description: &i18n.Message{ID: "webhook.template.pipeline.running", Other: "Pipeline started, above the table of its jobs"}},
+	{Name: "pipeline.success", Description: &i18n.Message{ID: "webhook.template.pipeline.success", Other: "Pipeline succeeded, above the table of its jobs"}},
+	{Name: "push", Description: &i18n.Message{ID: "webhook.template.push", Other: "Commits pushed"}},
+	{Name: "release.create", Description: &i18n.Message{ID: "webhook.template.release.create", Other: "Release created"}},
+	{Name: "release.delete", Description: &i18n.Message{ID: "webhook.template.release.delete", Other: "Release deleted"}},
+	{Name: "release.update", Description: &i18n.Message{ID: "webhook.template.release.update", Other: "Release updated"}},
+	{Name: "tag.create", Description: &i18n.Message{ID: "webhook.template.tag.create", Other: "Tag created"}},
+	{Name: "tag.delete", Description: &i18n.Message{ID: "webhook.template.tag.delete", Other: "Tag deleted"}},
+}
+
+// IsMessageTemplate tells whether admins can set a template for the message of this name.
+func IsMessageTemplate(name string) bool {
+	return slices.ContainsFunc(MessageTemplates, func(t MessageTemplate) bool {
+		return t.Name == name
+	})
+}
+
+// MessageTemplateData is what message templates are executed with.
+type MessageTemplateData struct {
+	// Message is the message posted when no template is set.
+	Message string
+	// Event is the GitLab event, e.g. a *gitlab.MergeEvent for the merge_request templates.
+	Event      any
+	Author     string
+	AuthorURL  string
+	Project    string
+	ProjectURL string
+	// Title and URL are those of what the event is about: the merge request, issue, commit of the pipeline,
+	// branch, tag or release.
+	Title string
+	URL   string
+}
+
+// ParseMessageTemplate parses the text/template of a message.
+func ParseMessageTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Parse(text)
+}
+
+// ExecuteMessageTemplate returns the message built by the template, which must not be empty.
+func ExecuteMessageTemplate(tmpl *template.Template, data *MessageTemplateData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return "", errors.New("the template builds an empty message")
+	}
+	return sb.String(), nil
+}
+
+// renderMessage returns the message built by the template admins set for the event, or the default one of the data
+// if there is none or it fails, along with whether the template was used.
+func (w *webhook) renderMessage(name string, data *MessageTemplateData) (string, bool) {
+	tmpl := w.gitlabRetreiver.GetMessageTemplate(name)
+	if tmpl == nil {
+		return data.Message, false
+	}
+	message, err := ExecuteMessageTemplate(tmpl, data)
+	if err != nil {
+		if w.onTemplateError != nil {
+			w.onTemplateError(err)
+		}
+		return data.Message, false
+	}
+	return message, true
+}
+
+// PreviewMessageTemplate returns the message posted for the sample event of the template name,
+// built by tmpl or by default when it is nil.
+func PreviewMessageTemplate(ctx context.Context, g GitlabRetreiver, name string, tmpl *template.Template) (string, error) {
+	kind, action, _ := strings.Cut(name, ".")
+	handle, ok := sampleHandlers[kind]
+	if !ok || !IsMessageTemplate(name) {
+		return "", errors.Errorf("unknown message template %q", name)
+	}
+	data, err := samples.ReadFile("samples/" + kind + ".json")
+	if err != nil {
+		return "", err
+	}
+
+	preview := &previewRetreiver{GitlabRetreiver: g, name: name, tmpl: tmpl}
+	if name == "pipeline.fixed" {
+		preview.previousPipelineStatus = statusFailed
+	}
+	var templateErr error
+	w := &webhook{gitlabRetreiver: preview, onTemplateError: func(err error) { templateErr = err }}
+
+	res, err := handle(ctx, w, data, action)
+	if err != nil {
+		return "", err
+	}
+	if templateErr != nil {
+		return "", templateErr
+	}
+	for _, handler := range res {
+		if slices.Contains(handler.ToChannels, previewChannelID) {
+			return handler.Message, nil
+		}
+	}
+	return "", errors.New("the sample event posts no message")
+}
+
+// sampleHandlers unmarshal the sample event of a kind of templates, set the action of the template and handle it.
+var sampleHandlers = map[string]func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error){
+	"merge_request": func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error) {
+		event := &gitlab.MergeEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		event.ObjectAttributes.Action = action
+		if action == actionUpdate {
+			event.Changes.Draft.Previous = true
+		}
+		return w.handleChannelMergeRequest(ctx, event)
+	},
+	"issue": func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error) {
+		event := &gitlab.IssueEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		event.ObjectAttributes.Action = action
+		if action == actionUpdate {
+			event.Labels = []*gitlab.EventLabel{{Title: "bug"}}
+			event.Changes.Labels.Current = event.Labels
+		}
+		return w.handleChannelIssue(ctx, event, gitlab.EventTypeIssue)
+	},
+	"issue_comment": func(ctx context.Context, w *webhook, data []byte, _ string) ([]*HandleWebhook, error) {
+		event := &gitlab.IssueCommentEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		return w.handleChannelIssueComment(ctx, event)
+	},
+	"merge_request_comment": func(ctx context.Context, w *webhook, data []byte, _ string) ([]*HandleWebhook, error) {
+		event := &gitlab.MergeCommentEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		return w.handleChannelMergeRequestComment(ctx, event)
+	},
+	"pipeline": func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error) {
+		event := &gitlab.PipelineEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		event.ObjectAttributes.Status = action
+		if action == "fixed" {
+			event.ObjectAttributes.Status = statusSuccess
+		}
+		return w.handleChannelPipeline(ctx, event)
+	},
+	"push": func(ctx context.Context, w *webhook, data []byte, _ string) ([]*HandleWebhook, error) {
+		event := &gitlab.PushEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		return w.handleChannelPush(ctx, event)
+	},
+	"tag": func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error) {
+		event := &gitlab.TagEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		if action == statusDelete {
+			event.Commits = nil
+		}
+		return w.handleChannelTag(ctx, event)
+	},
+	"release": func(ctx context.Context, w *webhook, data []byte, action string) ([]*HandleWebhook, error) {
+		event := &gitlab.ReleaseEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, err
+		}
+		event.Action = action
+		return w.handleChannelRelease(ctx, event)
+	},
+}
+
+// previewRetreiver subscribes a preview channel to the sample events, without recording anything about them.
+type previewRetreiver struct {
+	GitlabRetreiver
+	name                   string
+	tmpl                   *template.Template
+	previousPipelineStatus string
+}
+
+func (r *previewRetreiver) GetSubscribedChannelsForProject(ctx context.Context, namespace, project string, isPublicVisibility bool) []*subscription.Subscription {
+	sub, err := subscription.New(previewChannelID, "", "merges,issues,issue_comments,merge_request_comments,pipeline,pushes,tag,releases", namespace+"/"+project)
+	if err != nil {
+		return nil
+	}
+	return []*subscription.Subscription{sub}
+}
+
+func (r *previewRetreiver) SwapPipelineStatus(projectID int, ref, status string) string {
+	return r.previousPipelineStatus
+}
+
+func (r *previewRetreiver) UpdatePipelineCard(projectID, pipelineID int, update *PipelineCard) *PipelineCard {
+	return update
+}
+
+func (r *previewRetreiver) GetMessageTemplate(name string) *template.Template {
+	if name != r.name {
+		return nil
+	}
+	return r.tmpl
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+func TestMessageTemplates(t *testing.T) {
+	assert.True(t, slices.IsSortedFunc(MessageTemplates, func(a, b MessageTemplate) int {
+		return strings.Compare(a.Name, b.Name)
+	}))
+	assert.True(t, IsMessageTemplate("merge_request.merge"))
+	assert.False(t, IsMessageTemplate("merge_request"))
+	assert.False(t, IsMessageTemplate("wiki"))
+}
+
+func TestRenderMessageTemplate(t *testing.T) {
+	t.Parallel()
+	subs := []*subscription.Subscription{
+		MockSubscription("channel1", "1", "merges", "manland/webhook"),
+	}
+	handleMerged := func(t *testing.T, f *fakeWebhook) *HandleWebhook {
+		mergeEvent := &gitlab.MergeEvent{}
+		require.NoError(t, json.Unmarshal([]byte(MergeRequestMerged), mergeEvent))
+		res, err := NewWebhook(f).HandleMergeRequest(context.Background(), mergeEvent)
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		return res[1]
+	}
+
+	t.Run("template replaces the message and its attachment", func(t *testing.T) {
+		res := handleMerged(t, newFakeWebhook(subs).withMessageTemplate("merge_request.merge",
+			":tada: [{{.Title}}]({{.URL}}) merged into `{{.Event.ObjectAttributes.TargetBranch}}` by {{.Author}}"))
+
+		assert.Equal(t, ":tada: [Master](http://localhost:3000/manland/webhook/merge_requests/4) merged into `master` by manland", res.Message)
+		assert.Nil(t, res.Attachment)
+	})
+
+	t.Run("template of another action is ignored", func(t *testing.T) {
+		res := handleMerged(t, newFakeWebhook(subs).withMessageTemplate("merge_request.close", "closed"))
+
+		assert.Equal(t, "[manland/webhook](http://localhost:3000/manland/webhook) Merge request [!4 Master](http://localhost:3000/manland/webhook/merge_requests/4) was merged by [manland](http://my.gitlab.com/manland)", res.Message)
+		assert.NotNil(t, res.Attachment)
+	})
+
+	t.Run("failing template falls back to the default message", func(t *testing.T) {
+		res := handleMerged(t, newFakeWebhook(subs).withMessageTemplate("merge_request.merge", "{{.Event.Unknown}}"))
+
+		assert.Equal(t, "[manland/webhook](http://localhost:3000/manland/webhook) Merge request [!4 Master](http://localhost:3000/manland/webhook/merge_requests/4) was merged by [manland](http://my.gitlab.com/manland)", res.Message)
+		assert.NotNil(t, res.Attachment)
+	})
+
+	t.Run("template can wrap the default message", func(t *testing.T) {
+		res := handleMerged(t, newFakeWebhook(subs).withMessageTemplate("merge_request.merge", "{{.Message}} cc @release-team"))
+
+		assert.True(t, strings.HasSuffix(res.Message, "was merged by [manland](http://my.gitlab.com/manland) cc @release-team"))
+	})
+}
+
+func TestPreviewMessageTemplate(t *testing.T) {
+	t.Parallel()
+	g := newFakeWebhook(nil)
+
+	for _, messageTemplate := range MessageTemplates {
+		t.Run(messageTemplate.Name, func(t *testing.T) {
+			message, err := PreviewMessageTemplate(context.Background(), g, messageTemplate.Name, nil)
+			require.NoError(t, err)
+			assert.NotEmpty(t, message)
+
+			tmpl, err := ParseMessageTemplate(messageTemplate.Name, "{{.Project}}: {{.Title}}")
+			require.NoError(t, err)
+			message, err = PreviewMessageTemplate(context.Background(), g, messageTemplate.Name, tmpl)
+			require.NoError(t, err)
+			assert.Regexp(t, `^(manland/webhook|myorg/myrepo): \S`, message)
+		})
+	}
+
+	t.Run("pipeline header is shown above its jobs", func(t *testing.T) {
+		tmpl, err := ParseMessageTemplate("pipeline.fixed", "{{.Title}} fixed")
+		require.NoError(t, err)
+		message, err := PreviewMessageTemplate(context.Background(), g, "pipeline.fixed", tmpl)
+		require.NoError(t, err)
+		assert.Equal(t, "Start gitlab-ci fixed\n\n| Stage | Job | Status |\n|:------|:----|:-------|\n| deploy | pages | :rocket: running |", message)
+	})
+
+	t.Run("template errors are returned", func(t *testing.T) {
+		tmpl, err := ParseMessageTemplate("merge_request.merge", "{{.Event.Unknown}}")
+		require.NoError(t, err)
+		_, err = PreviewMessageTemplate(context.Background(), g, "merge_request.merge", tmpl)
+		assert.ErrorContains(t, err, "Unknown")
+
+		tmpl, err = ParseMessageTemplate("merge_request.merge", "{{if false}}merged{{end}}")
+		require.NoError(t, err)
+		_, err = PreviewMessageTemplate(context.Background(), g, "merge_request.merge", tmpl)
+		assert.EqualError(t, err, "the template builds an empty message")
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		_, err := PreviewMessageTemplate(context.Background(), g, "wiki", nil)
+		assert.EqualError(t, err, `unknown message template "wiki"`)
+	})
+}
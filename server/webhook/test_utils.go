@@ -14,6 +14,15 @@ const (
 
 func GetMockSubscriptions(feature string) []*subscription.Subscription {
 	return []*subscription.Subscription{
-		{ChannelID: MockChannelID, CreatorID: "1", Features: feature, Repository: MockOrgFullName},
+		MockSubscription(MockChannelID, "1", feature, MockOrgFullName),
 	}
 }
+
+// MockSubscription builds a subscription from features written as in the subscribe command, panicking if they are invalid.
+func MockSubscription(channelID, creatorID, features, repository string) *subscription.Subscription {
+	sub, err := subscription.New(channelID, creatorID, features, repository)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
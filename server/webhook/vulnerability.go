@@ -0,0 +1,197 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+const (
+	vulnerabilityStateDetected  = "detected"
+	vulnerabilityStateConfirmed = "confirmed"
+	vulnerabilityStateResolved  = "resolved"
+	vulnerabilityStateDismissed = "dismissed"
+)
+
+// VulnerabilityEvent represents a vulnerability found by a security scanner being created or changing state.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#vulnerability-events
+type VulnerabilityEvent struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		ID                int    `json:"id"`
+		Name              string `json:"name"`
+		WebURL            string `json:"web_url"`
+		VisibilityLevel   *int   `json:"visibility_level"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		URL        string `json:"url"`
+		Title      string `json:"title"`
+		State      string `json:"state"`
+		ProjectID  int    `json:"project_id"`
+		Severity   string `json:"severity"`
+		ReportType string `json:"report_type"`
+		Location   struct {
+			File string `json:"file"`
+		} `json:"location"`
+		Identifiers []struct {
+			Name         string `json:"name"`
+			ExternalID   string `json:"external_id"`
+			ExternalType string `json:"external_type"`
+			URL          string `json:"url"`
+		} `json:"identifiers"`
+	} `json:"object_attributes"`
+}
+
+// ProjectPath returns the path and URL of the project of the vulnerability. Events without a project block
+// still link to the vulnerability, whose URL is in the project, e.g. https://gitlab.com/group/project/-/security/vulnerabilities/1.
+func (e *VulnerabilityEvent) ProjectPath() (pathWithNamespace string, webURL string) {
+	if e.Project.PathWithNamespace != "" {
+		return e.Project.PathWithNamespace, e.Project.WebURL
+	}
+
+	webURL, _, found := strings.Cut(e.ObjectAttributes.URL, "/-/")
+	if !found {
+		return "", ""
+	}
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return "", ""
+	}
+	return strings.Trim(u.Path, "/"), webURL
+}
+
+// Private returns true unless the event tells the project is public or internal.
+func (e *VulnerabilityEvent) Private() bool {
+	return e.Project.VisibilityLevel == nil || *e.Project.VisibilityLevel == PrivateVisibilityLevel
+}
+
+// Public returns true only if the event tells the project is public; internal projects aren't.
+func (e *VulnerabilityEvent) Public() bool {
+	return e.Project.VisibilityLevel != nil && *e.Project.VisibilityLevel == PublicVisibilityLevel
+}
+
+func (w *webhook) HandleVulnerability(ctx context.Context, event *VulnerabilityEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleChannelVulnerability(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(handlers), nil
+}
+
+func (w *webhook) handleChannelVulnerability(ctx context.Context, event *VulnerabilityEvent) ([]*HandleWebhook, error) {
+	res := []*HandleWebhook{}
+	vulnerability := event.ObjectAttributes
+
+	pathWithNamespace, webURL := event.ProjectPath()
+	if pathWithNamespace == "" {
+		return res, nil
+	}
+
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":    pathWithNamespace,
+		"ProjectURL": webURL,
+		"Title":      vulnerability.Title,
+		"URL":        vulnerability.URL,
+		"Severity":   vulnerability.Severity,
+		"Scanner":    strings.ReplaceAll(vulnerability.ReportType, "_", " "),
+	}
+
+	var message string
+	switch vulnerability.State {
+	case vulnerabilityStateDetected:
+		if vulnerability.ReportType != "" {
+			message = w.localize(locale, &i18n.Message{
+				ID:    "webhook.vulnerability.channel.detected_by",
+				Other: "[{{.Project}}]({{.ProjectURL}}) :rotating_light: New **{{.Severity}}** vulnerability [{{.Title}}]({{.URL}}) detected by {{.Scanner}}",
+			}, data)
+		} else {
+			message = w.localize(locale, &i18n.Message{
+				ID:    "webhook.vulnerability.channel.detected",
+				Other: "[{{.Project}}]({{.ProjectURL}}) :rotating_light: New **{{.Severity}}** vulnerability [{{.Title}}]({{.URL}})",
+			}, data)
+		}
+	case vulnerabilityStateConfirmed:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.vulnerability.channel.confirmed",
+			Other: "[{{.Project}}]({{.ProjectURL}}) **{{.Severity}}** vulnerability [{{.Title}}]({{.URL}}) confirmed",
+		}, data)
+	case vulnerabilityStateResolved:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.vulnerability.channel.resolved",
+			Other: "[{{.Project}}]({{.ProjectURL}}) **{{.Severity}}** vulnerability [{{.Title}}]({{.URL}}) resolved",
+		}, data)
+	case vulnerabilityStateDismissed:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.vulnerability.channel.dismissed",
+			Other: "[{{.Project}}]({{.ProjectURL}}) **{{.Severity}}** vulnerability [{{.Title}}]({{.URL}}) dismissed",
+		}, data)
+	default:
+		return res, nil
+	}
+
+	if vulnerability.Location.File != "" {
+		message += "\n" + w.localize(locale, &i18n.Message{
+			ID:    "webhook.vulnerability.channel.location",
+			Other: "**Location**: `{{.File}}`",
+		}, map[string]any{"File": vulnerability.Location.File})
+	}
+	identifiers := []string{}
+	for _, identifier := range vulnerability.Identifiers {
+		if identifier.ExternalType != "cve" && identifier.ExternalType != "cwe" {
+			continue
+		}
+		if identifier.URL != "" {
+			identifiers = append(identifiers, fmt.Sprintf("[%s](%s)", identifier.Name, identifier.URL))
+		} else {
+			identifiers = append(identifiers, identifier.Name)
+		}
+	}
+	if len(identifiers) > 0 {
+		message += "\n" + w.localize(locale, &i18n.Message{
+			ID:    "webhook.vulnerability.channel.identifiers",
+			Other: "**Identifiers**: {{.Identifiers}}",
+		}, map[string]any{"Identifiers": strings.Join(identifiers, ", ")})
+	}
+	message += "\n" + w.localize(locale, &i18n.Message{
+		ID:    "webhook.vulnerability.channel.report",
+		Other: "[View the vulnerability report]({{.URL}})",
+	}, map[string]any{"URL": w.gitlabRetreiver.GetVulnerabilityReportURL(pathWithNamespace)})
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(pathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(ctx, namespace, project, event.Public())
+	for _, sub := range subs {
+		// Vulnerabilities are found by scanners, not users, so they only pass subscriptions without author globs.
+		if !sub.Vulnerabilities() || !sub.VulnerabilitySeverityMatches(vulnerability.Severity) || !sub.MatchesAuthor("") {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          "",
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+		})
+	}
+
+	return res, nil
+}
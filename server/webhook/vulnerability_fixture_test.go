@@ -0,0 +1,54 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const VulnerabilityDetected = `{
+	"object_kind":"vulnerability",
+	"object_attributes":{
+		"url":"http://localhost:3000/manland/webhook/-/security/vulnerabilities/1",
+		"title":"REXML DoS vulnerability",
+		"state":"detected",
+		"project_id":24,
+		"location":{
+			"file":"Gemfile.lock",
+			"dependency":{
+				"package":{
+					"name":"rexml"
+				},
+				"version":"3.3.1"
+			}
+		},
+		"cvss":[
+			{
+				"vector":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H",
+				"vendor":"NVD"
+			}
+		],
+		"severity":"high",
+		"severity_overridden":false,
+		"identifiers":[
+			{
+				"name":"Gemnasium-29dce398-220a-4315-8c84-16cd8b6d9b05",
+				"external_id":"29dce398-220a-4315-8c84-16cd8b6d9b05",
+				"external_type":"gemnasium",
+				"url":"https://gitlab.com/gitlab-org/security-products/gemnasium-db/-/blob/master/gem/rexml/CVE-2024-41123.yml"
+			},
+			{
+				"name":"CVE-2024-41123",
+				"external_id":"CVE-2024-41123",
+				"external_type":"cve",
+				"url":"https://www.cve.org/CVERecord?id=CVE-2024-41123"
+			}
+		],
+		"issues":[],
+		"report_type":"dependency_scanning",
+		"confirmed_at":null,
+		"confirmed_by_id":null,
+		"dismissed_at":null,
+		"dismissed_by_id":null,
+		"resolved_on_default_branch":false,
+		"created_at":"2019-06-16T18:40:00.413Z",
+		"updated_at":"2019-06-16T18:40:00.413Z"
+	}
+}`
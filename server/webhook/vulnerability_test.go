@@ -0,0 +1,109 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataVulnerabilityStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataVulnerability = []testDataVulnerabilityStr{
+	{
+		testTitle: "high vulnerability detected",
+		fixture:   VulnerabilityDetected,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "vulnerabilities", "manland/webhook"),
+			MockSubscription("channel2", "1", "vulnerabilities:high", "manland/webhook"),
+			MockSubscription("channel3", "1", "vulnerabilities:critical", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) :rotating_light: New **high** vulnerability [REXML DoS vulnerability](http://localhost:3000/manland/webhook/-/security/vulnerabilities/1) detected by dependency scanning\n**Location**: `Gemfile.lock`\n**Identifiers**: [CVE-2024-41123](https://www.cve.org/CVERecord?id=CVE-2024-41123)\n[View the vulnerability report](http://my.gitlab.com/manland/webhook/-/security/vulnerability_report)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1", "channel2"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "critical vulnerability dismissed",
+		fixture:   strings.Replace(strings.Replace(VulnerabilityDetected, `"state":"detected"`, `"state":"dismissed"`, 1), `"severity":"high"`, `"severity":"critical"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel3", "1", "vulnerabilities:critical", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) **critical** vulnerability [REXML DoS vulnerability](http://localhost:3000/manland/webhook/-/security/vulnerabilities/1) dismissed\n**Location**: `Gemfile.lock`\n**Identifiers**: [CVE-2024-41123](https://www.cve.org/CVERecord?id=CVE-2024-41123)\n[View the vulnerability report](http://my.gitlab.com/manland/webhook/-/security/vulnerability_report)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel3"},
+			From:       "",
+		}},
+	},
+	{
+		testTitle: "channel not subscribed to vulnerabilities",
+		fixture:   VulnerabilityDetected,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,issues", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestVulnerabilityWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataVulnerability {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			event, err := ParseWebhook(EventTypeVulnerability, []byte(test.fixture))
+			require.NoError(t, err)
+			vulnerabilityEvent, ok := event.(*VulnerabilityEvent)
+			require.True(t, ok)
+			res, err := w.HandleVulnerability(context.Background(), vulnerabilityEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.ElementsMatch(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+			}
+		})
+	}
+}
+
+func TestVulnerabilityEventProjectPath(t *testing.T) {
+	event := &VulnerabilityEvent{}
+	event.ObjectAttributes.URL = "https://gitlab.example.com/group/subgroup/project/-/security/vulnerabilities/1"
+	pathWithNamespace, webURL := event.ProjectPath()
+	assert.Equal(t, "group/subgroup/project", pathWithNamespace)
+	assert.Equal(t, "https://gitlab.example.com/group/subgroup/project", webURL)
+	assert.True(t, event.Private())
+	assert.False(t, event.Public())
+
+	internal := 10
+	event.Project.VisibilityLevel = &internal
+	assert.False(t, event.Private())
+	assert.False(t, event.Public())
+
+	event.Project.PathWithNamespace = "group/other"
+	event.Project.WebURL = "https://gitlab.example.com/group/other"
+	public := PublicVisibilityLevel
+	event.Project.VisibilityLevel = &public
+	pathWithNamespace, webURL = event.ProjectPath()
+	assert.Equal(t, "group/other", pathWithNamespace)
+	assert.Equal(t, "https://gitlab.example.com/group/other", webURL)
+	assert.False(t, event.Private())
+	assert.True(t, event.Public())
+}
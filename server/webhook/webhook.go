@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"text/template"
 
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/xanzy/go-gitlab"
 )
@@ -22,14 +25,50 @@ type GitlabRetreiver interface {
 	// GetPipelineURL return the url of this pipeline depending on the instance and project path
 	GetPipelineURL(pathWithNamespace string, pipelineID int) string
 	GetJobURL(pathWithNamespace string, jobID int) string
+	// GetVulnerabilityReportURL return the url of the vulnerability report of this project depending on the instance
+	GetVulnerabilityReportURL(pathWithNamespace string) string
 	// GetUserURL return the url of this GitLab user depending on domain3 instance (e.g. https://gitlab.com/username)
 	GetUserURL(username string) string
+	// GetGroupURL return the url of this GitLab group depending on the instance (e.g. https://gitlab.com/group/subgroup)
+	GetGroupURL(groupPath string) string
+	// GetGroupMilestoneURL return the url of a milestone of this GitLab group depending on the instance
+	GetGroupMilestoneURL(groupPath string, milestoneIID int) string
 	// GetUsernameById return a username by GitLab id
 	GetUsernameByID(id int) string
 	// ParseGitlabUsernamesFromText from a text return an array of username
 	ParseGitlabUsernamesFromText(text string) []string
 	// GetSubscribedChannelsForProject returns all subscriptions for given project.
 	GetSubscribedChannelsForProject(ctx context.Context, namespace, project string, isPublicVisibility bool) []*subscription.Subscription
+	// GetSubscribedChannelsForGroup returns the subscriptions of the given group itself, not of its projects.
+	GetSubscribedChannelsForGroup(ctx context.Context, groupPath string) []*subscription.Subscription
+	// GetMilestoneIssueCounts returns the number of open and closed issues of a milestone of a project, or of a group when project is empty,
+	// as seen by the GitLab account of the Mattermost user.
+	GetMilestoneIssueCounts(ctx context.Context, userID, namespace, project, milestoneTitle string) (opened int, closed int, err error)
+	// GetMergeRequestChangedPaths returns the paths of the files changed by a merge request at its head commit,
+	// as seen by the GitLab account of the Mattermost user.
+	GetMergeRequestChangedPaths(ctx context.Context, userID, namespace, project string, mergeRequestIID int, headSHA string) ([]string, error)
+	// GetPreviousDeploymentSHA returns the commit of the last successful deployment to the environment of a project before the given one,
+	// or an empty string if there is none, as seen by the GitLab account of the Mattermost user.
+	GetPreviousDeploymentSHA(ctx context.Context, userID, namespace, project, environment string, deploymentID int) (string, error)
+	// SwapPipelineStatus records the status of the last finished pipeline of a project ref and returns the one recorded before, if any.
+	SwapPipelineStatus(projectID int, ref, status string) string
+	// UpdatePipelineCard merges the update into the recorded card of a pipeline and returns the result.
+	// The update alone is returned when nothing was recorded yet or the store can't be updated.
+	UpdatePipelineCard(projectID, pipelineID int, update *PipelineCard) *PipelineCard
+	// GetMilestoneTitle returns the title of a milestone of a project or of its group, as seen by the GitLab account of the Mattermost user.
+	GetMilestoneTitle(ctx context.Context, userID, namespace, project string, milestoneID int) (string, error)
+	// GetCommitAuthorUsername returns the username of the GitLab user showing the author email of a commit on their profile,
+	// or an empty string if there is none, as seen by the GitLab account of the Mattermost user.
+	GetCommitAuthorUsername(ctx context.Context, userID, authorEmail string) (string, error)
+	// GetMessageTemplate returns the template admins set for the message of this name, or nil to post the default one.
+	GetMessageTemplate(name string) *template.Template
+	// GetUserLocale returns the language of the Mattermost user connected to this GitLab user, or the default one of the server.
+	GetUserLocale(gitlabUsername string) string
+	// GetServerLocale returns the default language of the Mattermost server, in which messages are posted to channels
+	// as Mattermost has no channel or team language.
+	GetServerLocale() string
+	// Localize returns the message of the config in the language.
+	Localize(locale string, config *i18n.LocalizeConfig) string
 }
 
 type HandleWebhook struct {
@@ -37,23 +76,50 @@ type HandleWebhook struct {
 	From       string
 	ToUsers    []string
 	ToChannels []string
+	// ThrottleKey, when set, limits the posts in a channel or DMs to a user to one per key for a while, so bursts of similar events don't flood them.
+	ThrottleKey string
+	// ThreadKey, when set, identifies the merge request or issue the post is about, so the channels subscribed with threads
+	// get all the posts of a merge request or issue in a single thread.
+	ThreadKey string
+	// UpdateKey, when set, identifies the post to update in place: the first post with a key is created in each channel,
+	// the next ones with the same key replace its message.
+	UpdateKey string
+	// UpdateRevision, when set, orders the posts of an UpdateKey: a post is skipped in the channels where the post
+	// of a later revision was already made, as the posts aren't always delivered in the order of their events.
+	UpdateRevision int64
+	// Attachment, when set, is the rich version of the message posted to the channels, Message being its plain text fallback.
+	Attachment *model.MessageAttachment
+	// Subscriptions are the subscriptions that sent the post to ToChannels. When a channel is subscribed to both
+	// the project and its namespace, the first one listed for the channel is the one whose settings apply to the post.
+	Subscriptions []*subscription.Subscription
 }
 
 type Webhook interface {
-	HandleIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, []string, error)
-	HandleMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, []string, error)
-	HandleIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, []string, error)
-	HandleMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, []string, error)
+	HandleIssue(ctx context.Context, event *gitlab.IssueEvent, eventType gitlab.EventType) ([]*HandleWebhook, error)
+	HandleMergeRequest(ctx context.Context, event *gitlab.MergeEvent) ([]*HandleWebhook, error)
+	HandleIssueComment(ctx context.Context, event *gitlab.IssueCommentEvent) ([]*HandleWebhook, error)
+	HandleMergeRequestComment(ctx context.Context, event *gitlab.MergeCommentEvent) ([]*HandleWebhook, error)
 	HandlePipeline(ctx context.Context, event *gitlab.PipelineEvent) ([]*HandleWebhook, error)
 	HandleTag(ctx context.Context, event *gitlab.TagEvent) ([]*HandleWebhook, error)
 	HandlePush(ctx context.Context, event *gitlab.PushEvent) ([]*HandleWebhook, error)
 	HandleJobs(ctx context.Context, event *gitlab.JobEvent) ([]*HandleWebhook, error)
 	HandleRelease(ctx context.Context, event *gitlab.ReleaseEvent) ([]*HandleWebhook, error)
 	HandleDeployment(ctx context.Context, event *gitlab.DeploymentEvent) ([]*HandleWebhook, error)
+	HandleWikiPage(ctx context.Context, event *WikiPageEvent) ([]*HandleWebhook, error)
+	HandleFeatureFlag(ctx context.Context, event *gitlab.FeatureFlagEvent) ([]*HandleWebhook, error)
+	HandleMember(ctx context.Context, event *gitlab.MemberEvent) ([]*HandleWebhook, error)
+	HandleEmoji(ctx context.Context, event *EmojiEvent) ([]*HandleWebhook, error)
+	HandleMilestone(ctx context.Context, event *MilestoneEvent) ([]*HandleWebhook, error)
+	HandleVulnerability(ctx context.Context, event *VulnerabilityEvent) ([]*HandleWebhook, error)
+	HandleCommitComment(ctx context.Context, event *gitlab.CommitCommentEvent) ([]*HandleWebhook, error)
+	HandleSnippetComment(ctx context.Context, event *gitlab.SnippetCommentEvent) ([]*HandleWebhook, error)
 }
 
 type webhook struct {
 	gitlabRetreiver GitlabRetreiver
+	// onTemplateError, when set, is called with the errors of the message templates, which otherwise fall back silently
+	// to the default messages.
+	onTemplateError func(err error)
 }
 
 func NewWebhook(g GitlabRetreiver) Webhook {
@@ -92,13 +158,34 @@ func cleanWebhookHandlerTo(handler *HandleWebhook) *HandleWebhook {
 	}
 
 	return &HandleWebhook{
-		From:       handler.From,
-		Message:    handler.Message,
-		ToUsers:    cleanedUsers,
-		ToChannels: cleanedChannels,
+		From:           handler.From,
+		Message:        handler.Message,
+		ToUsers:        cleanedUsers,
+		ToChannels:     cleanedChannels,
+		ThrottleKey:    handler.ThrottleKey,
+		ThreadKey:      handler.ThreadKey,
+		UpdateKey:      handler.UpdateKey,
+		UpdateRevision: handler.UpdateRevision,
+		Attachment:     handler.Attachment,
+		Subscriptions:  handler.Subscriptions,
 	}
 }
 
+// mergeRequestThreadKey returns the ThreadKey of the posts about a merge request.
+func mergeRequestThreadKey(projectID, mergeRequestIID int) string {
+	return fmt.Sprintf("merge_request/%d/%d", projectID, mergeRequestIID)
+}
+
+// pipelineUpdateKey returns the UpdateKey of the posts about a pipeline.
+func pipelineUpdateKey(projectID, pipelineID int) string {
+	return fmt.Sprintf("pipeline/%d/%d", projectID, pipelineID)
+}
+
+// issueThreadKey returns the ThreadKey of the posts about an issue.
+func issueThreadKey(projectID, issueIID int) string {
+	return fmt.Sprintf("issue/%d/%d", projectID, issueIID)
+}
+
 type mentionDetails struct {
 	senderUsername    string
 	pathWithNamespace string
@@ -107,16 +194,23 @@ type mentionDetails struct {
 	body              string
 }
 
-func (w *webhook) handleMention(m mentionDetails) *HandleWebhook {
+// handleMention returns the handlers of the DMs to the users mentioned in the body, if any.
+func (w *webhook) handleMention(m mentionDetails) []*HandleWebhook {
 	mentionedUsernames := w.gitlabRetreiver.ParseGitlabUsernamesFromText(m.body)
-	if len(mentionedUsernames) > 0 {
-		return &HandleWebhook{
-			From:    m.senderUsername,
-			Message: fmt.Sprintf("[%s](%s) mentioned you on [%s#%s](%s):\n>%s", m.senderUsername, w.gitlabRetreiver.GetUserURL(m.senderUsername), m.pathWithNamespace, m.IID, m.URL, m.body),
-			ToUsers: mentionedUsernames,
-		}
+	if len(mentionedUsernames) == 0 {
+		return nil
 	}
-	return nil
+	return w.dmHandlers(m.senderUsername, mentionedUsernames, &i18n.Message{
+		ID:    "webhook.mention",
+		Other: "[{{.Sender}}]({{.SenderURL}}) mentioned you on [{{.Project}}#{{.IID}}]({{.URL}}):\n>{{.Body}}",
+	}, map[string]any{
+		"Sender":    m.senderUsername,
+		"SenderURL": w.gitlabRetreiver.GetUserURL(m.senderUsername),
+		"Project":   m.pathWithNamespace,
+		"IID":       m.IID,
+		"URL":       m.URL,
+		"Body":      m.body,
+	})
 }
 
 func sameLabels(a []*gitlab.EventLabel, b []*gitlab.EventLabel) bool {
@@ -131,58 +225,44 @@ func sameLabels(a []*gitlab.EventLabel, b []*gitlab.EventLabel) bool {
 	return true
 }
 
-func containsLabel(a []*gitlab.EventLabel, labelName string) bool {
-	for _, l := range a {
-		if l != nil && l.Title == labelName {
-			return true
-		}
-	}
-	return false
-}
-
-func containsAnyLabel(a []*gitlab.EventLabel, labels []string) bool {
-	for _, name := range labels {
-		if containsLabel(a, name) {
-			return true
-		}
-	}
-	return false
-}
-
 func filterChannelsByFeature(
 	subs []*subscription.Subscription,
 	eventLabels []*gitlab.EventLabel,
+	senderGitlabUsername string,
 	featureCheck func(*subscription.Subscription) bool,
-) ([]string, []string) {
+) ([]string, []*subscription.Subscription) {
 	var channels []string
-	var warnings []string
+	var matched []*subscription.Subscription
 	for _, sub := range subs {
-		if !featureCheck(sub) {
-			continue
-		}
-
-		labels, err := sub.Labels()
-		if err != nil {
-			warnings = append(warnings, err.Error())
-		} else if len(labels) > 0 && !containsAnyLabel(eventLabels, labels) {
+		if !featureCheck(sub) || !eventLabelsMatchSub(sub, eventLabels) || !sub.MatchesAuthor(senderGitlabUsername) {
 			continue
 		}
 
 		channels = append(channels, sub.ChannelID)
+		matched = append(matched, sub)
 	}
-	return channels, warnings
+	return channels, matched
 }
 
-func anyEventLabelInSubs(sub *subscription.Subscription, eventLabels []*gitlab.EventLabel) (bool, string) {
-	labels, err := sub.Labels()
-	var warning string
-	if err != nil {
-		warning = err.Error()
-		return false, warning
-	} else if len(labels) > 0 && !containsAnyLabel(eventLabels, labels) {
-		return false, warning
+// eventLabelsMatchSub returns true if the labels of the event pass the label filters of the subscription.
+func eventLabelsMatchSub(sub *subscription.Subscription, eventLabels []*gitlab.EventLabel) bool {
+	return sub.MatchesLabels(labelTitles(eventLabels))
+}
+
+// labelsNewlyMatchSub returns true if a label change makes a merge request or issue pass the label filters of the
+// subscription, which it didn't before.
+func labelsNewlyMatchSub(sub *subscription.Subscription, previous, current []*gitlab.EventLabel) bool {
+	return sub.HasLabelFilters() && !eventLabelsMatchSub(sub, previous) && eventLabelsMatchSub(sub, current)
+}
+
+func labelTitles(a []*gitlab.EventLabel) []string {
+	titles := make([]string, 0, len(a))
+	for _, l := range a {
+		if l != nil {
+			titles = append(titles, l.Title)
+		}
 	}
-	return true, warning
+	return titles
 }
 
 func labelToString(a []*gitlab.EventLabel) string {
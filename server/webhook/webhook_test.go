@@ -5,24 +5,86 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"text/template"
 
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/text/language"
 
 	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 )
 
 type fakeWebhook struct {
-	subs []*subscription.Subscription
+	subs               []*subscription.Subscription
+	pipelineStatuses   map[string]string
+	pipelineCards      map[string]*PipelineCard
+	mergeRequestPaths  []string
+	previousDeployment string
+	milestoneTitle     string
+	messageTemplates   map[string]*template.Template
+	userLocales        map[string]string
+	serverLocale       string
+	// publicVisibility records whether the last project the subscriptions were read for is public.
+	publicVisibility bool
 }
 
 func newFakeWebhook(subs []*subscription.Subscription) *fakeWebhook {
 	return &fakeWebhook{
-		subs: subs,
+		subs:             subs,
+		pipelineStatuses: map[string]string{},
+		pipelineCards:    map[string]*PipelineCard{},
+		messageTemplates: map[string]*template.Template{},
+		userLocales:      map[string]string{},
+		serverLocale:     "en",
 	}
 }
 
+// withMergeRequestPaths sets the paths changed by the merge requests of the events, as seen by the user "1".
+func (f *fakeWebhook) withMergeRequestPaths(paths ...string) *fakeWebhook {
+	f.mergeRequestPaths = paths
+	return f
+}
+
+// withPreviousDeployment sets the commit of the deployment preceding the ones of the events, as seen by the user "1".
+func (f *fakeWebhook) withPreviousDeployment(sha string) *fakeWebhook {
+	f.previousDeployment = sha
+	return f
+}
+
+// withMilestone sets the title of the milestones of the events, as seen by the user "1".
+func (f *fakeWebhook) withMilestone(title string) *fakeWebhook {
+	f.milestoneTitle = title
+	return f
+}
+
+// withMessageTemplate sets the template of the message of this name, panicking if it is invalid.
+func (f *fakeWebhook) withMessageTemplate(name, text string) *fakeWebhook {
+	f.messageTemplates[name] = template.Must(ParseMessageTemplate(name, text))
+	return f
+}
+
+// withUserLocale sets the language of the Mattermost user connected to this GitLab user, the others reading English.
+func (f *fakeWebhook) withUserLocale(username, locale string) *fakeWebhook {
+	f.userLocales[username] = locale
+	return f
+}
+
+// withServerLocale sets the default language of the Mattermost server, English otherwise.
+func (f *fakeWebhook) withServerLocale(locale string) *fakeWebhook {
+	f.serverLocale = locale
+	return f
+}
+
 func (*fakeWebhook) GetPipelineURL(pathWithNamespace string, pipelineID int) string {
 	return fmt.Sprintf("http://my.gitlab.com/%s/-/pipelines/%d", pathWithNamespace, pipelineID)
 }
@@ -31,10 +93,22 @@ func (*fakeWebhook) GetJobURL(pathWithNamespace string, jobID int) string {
 	return fmt.Sprintf("http://my.gitlab.com/%s/-/jobs/%d", pathWithNamespace, jobID)
 }
 
+func (*fakeWebhook) GetVulnerabilityReportURL(pathWithNamespace string) string {
+	return fmt.Sprintf("http://my.gitlab.com/%s/-/security/vulnerability_report", pathWithNamespace)
+}
+
 func (*fakeWebhook) GetUserURL(username string) string {
 	return fmt.Sprintf("http://my.gitlab.com/%s", username)
 }
 
+func (*fakeWebhook) GetGroupURL(groupPath string) string {
+	return fmt.Sprintf("http://my.gitlab.com/%s", groupPath)
+}
+
+func (*fakeWebhook) GetGroupMilestoneURL(groupPath string, milestoneIID int) string {
+	return fmt.Sprintf("http://my.gitlab.com/groups/%s/-/milestones/%d", groupPath, milestoneIID)
+}
+
 func (*fakeWebhook) GetUsernameByID(id int) string {
 	switch id {
 	case 1:
@@ -53,9 +127,107 @@ func (*fakeWebhook) ParseGitlabUsernamesFromText(body string) []string {
 }
 
 func (f *fakeWebhook) GetSubscribedChannelsForProject(ctx context.Context, namespace, project string, isPublicVisibility bool) []*subscription.Subscription {
+	f.publicVisibility = isPublicVisibility
+	return f.subs
+}
+
+func (f *fakeWebhook) GetSubscribedChannelsForGroup(ctx context.Context, groupPath string) []*subscription.Subscription {
 	return f.subs
 }
 
+func (*fakeWebhook) GetMilestoneIssueCounts(ctx context.Context, userID, namespace, project, milestoneTitle string) (int, int, error) {
+	if userID != "1" {
+		return 0, 0, errors.New("not connected")
+	}
+	return 3, 5, nil
+}
+
+func (f *fakeWebhook) GetMergeRequestChangedPaths(ctx context.Context, userID, namespace, project string, mergeRequestIID int, headSHA string) ([]string, error) {
+	if userID != "1" {
+		return nil, errors.New("not connected")
+	}
+	return f.mergeRequestPaths, nil
+}
+
+func (f *fakeWebhook) GetPreviousDeploymentSHA(ctx context.Context, userID, namespace, project, environment string, deploymentID int) (string, error) {
+	if userID != "1" {
+		return "", errors.New("not connected")
+	}
+	return f.previousDeployment, nil
+}
+
+func (f *fakeWebhook) GetMilestoneTitle(ctx context.Context, userID, namespace, project string, milestoneID int) (string, error) {
+	if userID != "1" {
+		return "", errors.New("not connected")
+	}
+	return f.milestoneTitle, nil
+}
+
+func (*fakeWebhook) GetCommitAuthorUsername(ctx context.Context, userID, authorEmail string) (string, error) {
+	if userID != "1" {
+		return "", errors.New("not connected")
+	}
+	if authorEmail == "manland@example.com" {
+		return "manland", nil
+	}
+	return "", nil
+}
+
+func (f *fakeWebhook) GetMessageTemplate(name string) *template.Template {
+	return f.messageTemplates[name]
+}
+
+func (f *fakeWebhook) GetUserLocale(gitlabUsername string) string {
+	if locale, ok := f.userLocales[gitlabUsername]; ok {
+		return locale
+	}
+	return f.serverLocale
+}
+
+func (f *fakeWebhook) GetServerLocale() string {
+	return f.serverLocale
+}
+
+// testBundle holds the translations shipped with the plugin.
+var testBundle = sync.OnceValue(func() *goi18n.Bundle {
+	bundle := goi18n.NewBundle(language.English)
+	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+	files, _ := filepath.Glob("../../assets/i18n/active.*.json")
+	for _, file := range files {
+		if filepath.Base(file) != "active.en.json" {
+			bundle.MustLoadMessageFile(file)
+		}
+	}
+	return bundle
+})
+
+func (*fakeWebhook) Localize(locale string, config *i18n.LocalizeConfig) string {
+	text, err := goi18n.NewLocalizer(testBundle(), locale).Localize(config)
+	if text == "" {
+		panic(err)
+	}
+	return text
+}
+
+func (f *fakeWebhook) SwapPipelineStatus(projectID int, ref, status string) string {
+	key := fmt.Sprintf("%d/%s", projectID, ref)
+	previous := f.pipelineStatuses[key]
+	f.pipelineStatuses[key] = status
+	return previous
+}
+
+func (f *fakeWebhook) UpdatePipelineCard(projectID, pipelineID int, update *PipelineCard) *PipelineCard {
+	key := fmt.Sprintf("%d/%d", projectID, pipelineID)
+	card, ok := f.pipelineCards[key]
+	if !ok {
+		card = &PipelineCard{}
+		f.pipelineCards[key] = card
+	}
+	card.Merge(update)
+	result := *card
+	return &result
+}
+
 type testDataNormalizeNamespacedProjectStr struct {
 	Title                  string
 	InputNamespace         string
@@ -122,3 +294,98 @@ func TestNormalizeNamespacedProjectByHomePate(t *testing.T) {
 		})
 	}
 }
+
+func TestThreadKey(t *testing.T) {
+	subs := []*subscription.Subscription{
+		MockSubscription("channel1", "1", "merges,issues,issue_comments,merge_request_comments,pipeline,threads", "manland/webhook"),
+	}
+
+	for _, test := range []struct {
+		name      string
+		handle    func(w Webhook) ([]*HandleWebhook, error)
+		threadKey string
+	}{
+		{
+			name: "merge request",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.MergeEvent{}
+				require.NoError(t, json.Unmarshal([]byte(OpenMergeRequest), event))
+				return w.HandleMergeRequest(context.Background(), event)
+			},
+			threadKey: "merge_request/24/4",
+		},
+		{
+			name: "merge request comment",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.MergeCommentEvent{}
+				require.NoError(t, json.Unmarshal([]byte(MergeRequestComment), event))
+				return w.HandleMergeRequestComment(context.Background(), event)
+			},
+			threadKey: "merge_request/24/6",
+		},
+		{
+			name: "issue",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.IssueEvent{}
+				require.NoError(t, json.Unmarshal([]byte(NewIssue), event))
+				return w.HandleIssue(context.Background(), event, gitlab.EventTypeIssue)
+			},
+			threadKey: "issue/24/1",
+		},
+		{
+			name: "issue comment",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.IssueCommentEvent{}
+				require.NoError(t, json.Unmarshal([]byte(IssueComment), event))
+				return w.HandleIssueComment(context.Background(), event)
+			},
+			threadKey: "issue/24/1",
+		},
+		{
+			name: "merge request pipeline",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.PipelineEvent{}
+				fixture := strings.Replace(PipelineSuccess, `"object_kind":"pipeline",`, `"object_kind":"pipeline","merge_request":{"id":9,"iid":4},`, 1)
+				require.NoError(t, json.Unmarshal([]byte(fixture), event))
+				return w.HandlePipeline(context.Background(), event)
+			},
+			threadKey: "merge_request/24/4",
+		},
+		{
+			name: "branch pipeline",
+			handle: func(w Webhook) ([]*HandleWebhook, error) {
+				event := &gitlab.PipelineEvent{}
+				require.NoError(t, json.Unmarshal([]byte(PipelineSuccess), event))
+				return w.HandlePipeline(context.Background(), event)
+			},
+			threadKey: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.handle(NewWebhook(newFakeWebhook(subs)))
+			require.NoError(t, err)
+
+			channelPosts := 0
+			for _, handler := range res {
+				if len(handler.ToChannels) == 0 {
+					continue
+				}
+				channelPosts++
+				assert.Equal(t, test.threadKey, handler.ThreadKey, handler.Message)
+			}
+			assert.NotZero(t, channelPosts)
+		})
+	}
+}
+
+// assertChannelSubscriptions checks that the handler tells the subscription which sent its post to each of its channels.
+func assertChannelSubscriptions(t *testing.T, handler *HandleWebhook) {
+	t.Helper()
+	channels := make([]string, 0, len(handler.Subscriptions))
+	for _, sub := range handler.Subscriptions {
+		channels = append(channels, sub.ChannelID)
+	}
+	for _, channelID := range handler.ToChannels {
+		assert.Contains(t, channels, channelID)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+// wikiExcerptLength is the maximum number of characters of a wiki page quoted in a message.
+const wikiExcerptLength = 300
+
+// WikiPageEvent is a wiki page event with the visibility level of its project, which GitLab sends but the GitLab
+// client library doesn't read.
+type WikiPageEvent struct {
+	gitlab.WikiPageEvent
+	ProjectVisibilityLevel int `json:"-"`
+}
+
+func (e *WikiPageEvent) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.WikiPageEvent); err != nil {
+		return err
+	}
+	var payload struct {
+		Project struct {
+			VisibilityLevel int `json:"visibility_level"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+	e.ProjectVisibilityLevel = payload.Project.VisibilityLevel
+	return nil
+}
+
+func (w *webhook) HandleWikiPage(ctx context.Context, event *WikiPageEvent) ([]*HandleWebhook, error) {
+	handlers, err := w.handleChannelWikiPage(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return cleanWebhookHandlers(handlers), nil
+}
+
+func (w *webhook) handleChannelWikiPage(ctx context.Context, event *WikiPageEvent) ([]*HandleWebhook, error) {
+	senderGitlabUsername := event.User.Username
+	repo := event.Project
+	page := event.ObjectAttributes
+	res := []*HandleWebhook{}
+
+	locale := w.gitlabRetreiver.GetServerLocale()
+	data := map[string]any{
+		"Project":    repo.PathWithNamespace,
+		"ProjectURL": repo.WebURL,
+		"Title":      page.Title,
+		"URL":        page.URL,
+		"DiffURL":    page.DiffURL,
+		"Sender":     senderGitlabUsername,
+		"SenderURL":  w.gitlabRetreiver.GetUserURL(senderGitlabUsername),
+	}
+
+	var message string
+	switch page.Action {
+	case statusCreate:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.wiki.channel.create",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Wiki page [{{.Title}}]({{.URL}}) created by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+	case statusUpdate:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.wiki.channel.update",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Wiki page [{{.Title}}]({{.URL}}) updated by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+		if page.DiffURL != "" {
+			message += w.localize(locale, &i18n.Message{
+				ID:    "webhook.wiki.channel.changes",
+				Other: " ([see changes]({{.DiffURL}}))",
+			}, data)
+		}
+	case statusDelete:
+		message = w.localize(locale, &i18n.Message{
+			ID:    "webhook.wiki.channel.delete",
+			Other: "[{{.Project}}]({{.ProjectURL}}) Wiki page **{{.Title}}** deleted by [{{.Sender}}]({{.SenderURL}})",
+		}, data)
+	default:
+		return res, nil
+	}
+
+	if page.Action != statusDelete {
+		if excerpt := wikiExcerpt(page.Content); excerpt != "" {
+			message += "\n" + excerpt
+		}
+	}
+
+	toChannels := make([]string, 0)
+	matchedSubs := make([]*subscription.Subscription, 0)
+	namespace, project := normalizeNamespacedProject(repo.PathWithNamespace)
+	subs := w.gitlabRetreiver.GetSubscribedChannelsForProject(
+		ctx, namespace, project,
+		event.ProjectVisibilityLevel == PublicVisibilityLevel,
+	)
+	for _, sub := range subs {
+		if !sub.Wiki() || !sub.MatchesAuthor(senderGitlabUsername) {
+			continue
+		}
+
+		toChannels = append(toChannels, sub.ChannelID)
+		matchedSubs = append(matchedSubs, sub)
+	}
+
+	if len(toChannels) > 0 {
+		res = append(res, &HandleWebhook{
+			From:          senderGitlabUsername,
+			Message:       message,
+			ToUsers:       []string{},
+			ToChannels:    toChannels,
+			Subscriptions: matchedSubs,
+		})
+	}
+
+	return res, nil
+}
+
+// wikiExcerpt returns the beginning of a wiki page as a quote, without any HTML it may contain.
+func wikiExcerpt(content string) string {
+	excerpt := sanitizeDescription(content)
+	if excerpt == "" {
+		return ""
+	}
+
+	if runes := []rune(excerpt); len(runes) > wikiExcerptLength {
+		excerpt = strings.TrimSpace(string(runes[:wikiExcerptLength])) + "…"
+	}
+
+	lines := strings.Split(excerpt, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
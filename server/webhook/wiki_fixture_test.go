@@ -0,0 +1,49 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+const WikiPageCreated = `{
+	"object_kind":"wiki_page",
+	"user":{
+		"id":50,
+		"name":"Romain Maneschi",
+		"username":"manland",
+		"avatar_url":"https://www.gravatar.com/avatar/c6b552a4cd47f7cf1701ea5b650cd2e3?s=80&d=identicon",
+		"email":"admin@example.com"
+	},
+	"project":{
+		"id":24,
+		"name":"webhook",
+		"description":"",
+		"web_url":"http://localhost:3000/manland/webhook",
+		"avatar_url":null,
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.git",
+		"namespace":"manland",
+		"visibility_level":20,
+		"path_with_namespace":"manland/webhook",
+		"default_branch":"master",
+		"homepage":"http://localhost:3000/manland/webhook",
+		"url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.git",
+		"http_url":"http://localhost:3000/manland/webhook.git"
+	},
+	"wiki":{
+		"web_url":"http://localhost:3000/manland/webhook/-/wikis/home",
+		"git_ssh_url":"ssh://rmaneschi@localhost:2222/manland/webhook.wiki.git",
+		"git_http_url":"http://localhost:3000/manland/webhook.wiki.git",
+		"path_with_namespace":"manland/webhook.wiki",
+		"default_branch":"main"
+	},
+	"object_attributes":{
+		"title":"Getting started",
+		"content":"How to <b>install</b> the webhook.\n<script>alert('hi')</script>Run the setup command.",
+		"format":"markdown",
+		"message":"Create Getting started",
+		"slug":"getting-started",
+		"url":"http://localhost:3000/manland/webhook/-/wikis/getting-started",
+		"action":"create",
+		"diff_url":"http://localhost:3000/manland/webhook/-/wikis/getting-started/diff?version_id=7e8b8e9d"
+	}
+}`
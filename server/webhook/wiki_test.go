@@ -0,0 +1,124 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
+)
+
+type testDataWikiPageStr struct {
+	testTitle       string
+	fixture         string
+	gitlabRetreiver *fakeWebhook
+	res             []*HandleWebhook
+}
+
+var testDataWikiPage = []testDataWikiPageStr{
+	{
+		testTitle: "manland creates a wiki page",
+		fixture:   WikiPageCreated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "wiki", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Wiki page [Getting started](http://localhost:3000/manland/webhook/-/wikis/getting-started) created by [manland](http://my.gitlab.com/manland)\n> How to install the webhook.\n> Run the setup command.",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "manland updates a wiki page",
+		fixture:   strings.Replace(WikiPageCreated, `"action":"create"`, `"action":"update"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "wiki", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Wiki page [Getting started](http://localhost:3000/manland/webhook/-/wikis/getting-started) updated by [manland](http://my.gitlab.com/manland) ([see changes](http://localhost:3000/manland/webhook/-/wikis/getting-started/diff?version_id=7e8b8e9d))\n> How to install the webhook.\n> Run the setup command.",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "manland deletes a wiki page",
+		fixture:   strings.Replace(WikiPageCreated, `"action":"create"`, `"action":"delete"`, 1),
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "wiki", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{{
+			Message:    "[manland/webhook](http://localhost:3000/manland/webhook) Wiki page **Getting started** deleted by [manland](http://my.gitlab.com/manland)",
+			ToUsers:    []string{},
+			ToChannels: []string{"channel1"},
+			From:       "manland",
+		}},
+	},
+	{
+		testTitle: "channel not subscribed to wiki",
+		fixture:   WikiPageCreated,
+		gitlabRetreiver: newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "merges,issues", "manland/webhook"),
+		}),
+		res: []*HandleWebhook{},
+	},
+}
+
+func TestWikiPageWebhook(t *testing.T) {
+	t.Parallel()
+	for _, test := range testDataWikiPage {
+		t.Run(test.testTitle, func(t *testing.T) {
+			w := NewWebhook(test.gitlabRetreiver)
+			wikiEvent := &WikiPageEvent{}
+			if err := json.Unmarshal([]byte(test.fixture), wikiEvent); err != nil {
+				assert.Fail(t, "can't unmarshal fixture")
+			}
+			res, err := w.HandleWikiPage(context.Background(), wikiEvent)
+			assert.Empty(t, err)
+			assert.Equal(t, len(test.res), len(res))
+			for index := range res {
+				assert.Equal(t, test.res[index].Message, res[index].Message)
+				assert.Equal(t, test.res[index].ToUsers, res[index].ToUsers)
+				assert.Equal(t, test.res[index].ToChannels, res[index].ToChannels)
+				assertChannelSubscriptions(t, res[index])
+				assert.Equal(t, test.res[index].From, res[index].From)
+			}
+		})
+	}
+}
+
+// Wiki page events only tell the visibility level of their project, the subscriptions of a public one don't need
+// their creator to have access to it.
+func TestWikiPageProjectVisibility(t *testing.T) {
+	for _, test := range []struct {
+		fixture string
+		public  bool
+	}{
+		{fixture: WikiPageCreated, public: true},
+		{fixture: strings.Replace(WikiPageCreated, `"visibility_level":20`, `"visibility_level":0`, 1), public: false},
+	} {
+		gitlabRetreiver := newFakeWebhook([]*subscription.Subscription{
+			MockSubscription("channel1", "1", "wiki", "manland/webhook"),
+		})
+		event, err := ParseWebhook(gitlab.EventTypeWikiPage, []byte(test.fixture))
+		require.NoError(t, err)
+
+		_, err = NewWebhook(gitlabRetreiver).HandleWikiPage(context.Background(), event.(*WikiPageEvent))
+		require.NoError(t, err)
+		assert.Equal(t, test.public, gitlabRetreiver.publicVisibility)
+	}
+}
+
+func TestWikiExcerpt(t *testing.T) {
+	assert.Equal(t, "", wikiExcerpt("<p></p>"))
+	assert.Equal(t, "> "+strings.Repeat("a", wikiExcerptLength)+"…", wikiExcerpt(strings.Repeat("a", wikiExcerptLength+10)))
+}
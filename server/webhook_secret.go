@@ -0,0 +1,594 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	"github.com/pkg/errors"
+	gitlabLib "github.com/xanzy/go-gitlab"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
+)
+
+const (
+	webhookSecretKeyPrefix = "webhooksecret_"
+
+	// webhookSecretRotationGracePeriod is how long the previous secret and signing token keep being
+	// accepted after a rotation, so deliveries GitLab already queued or retries still get through.
+	webhookSecretRotationGracePeriod = 24 * time.Hour
+	// webhookSignatureTolerance is the maximum allowed clock difference for signed requests.
+	webhookSignatureTolerance = 5 * time.Minute
+
+	headerGitlabToken      = "X-Gitlab-Token"
+	headerWebhookID        = "webhook-id"
+	headerWebhookTimestamp = "webhook-timestamp"
+	headerWebhookSignature = "webhook-signature"
+
+	signingTokenPrefix = "whsec_"
+)
+
+// errWebhookSecretMismatch is returned when a hook is added with a token other than the secret of its namespace,
+// which would replace the secret the existing hooks of the namespace use.
+var errWebhookSecretMismatch = errors.New("the token differs from the webhook secret of the namespace")
+
+var signingTokenErrorMessage = &i18n.Message{
+	ID:    "command.webhook.signing_token.error",
+	Other: "Failed to store the signing token.",
+}
+
+// webhookSecret holds the credentials GitLab uses for the webhooks of one project or group.
+// Once a namespace has one, requests for it are no longer accepted with the plugin-wide secret,
+// so a leaked secret only compromises the hooks of that namespace.
+type webhookSecret struct {
+	Namespace string `json:"namespace"`
+	// Secret is compared to the X-Gitlab-Token header.
+	Secret string `json:"secret,omitempty"`
+	// SigningToken is used to verify the HMAC signature in the webhook-signature header.
+	// When set, unsigned requests are rejected.
+	SigningToken string `json:"signing_token,omitempty"`
+
+	PreviousSecret                string `json:"previous_secret,omitempty"`
+	PreviousSecretExpiresAt       int64  `json:"previous_expires_at,omitempty"`
+	PreviousSigningToken          string `json:"previous_signing_token,omitempty"`
+	PreviousSigningTokenExpiresAt int64  `json:"previous_signing_token_expires_at,omitempty"`
+
+	// AllowLegacySecret keeps accepting the plugin-wide secret for hooks that were created
+	// before the namespace got its own secret, until they are rotated.
+	AllowLegacySecret bool `json:"allow_legacy_secret,omitempty"`
+	// LegacySecretExpiresAt keeps accepting the plugin-wide secret for a grace period after the namespace got
+	// its own secret, for the hooks of its projects and subgroups which don't have their own secret yet.
+	LegacySecretExpiresAt int64 `json:"legacy_secret_expires_at,omitempty"`
+}
+
+// rotate makes secret and signingToken the current credentials. Credentials being replaced
+// stay valid for webhookSecretRotationGracePeriod.
+func (s *webhookSecret) rotate(secret, signingToken string) {
+	expiresAt := time.Now().Add(webhookSecretRotationGracePeriod).UnixMilli()
+	if s.Secret != "" && s.Secret != secret {
+		s.PreviousSecret = s.Secret
+		s.PreviousSecretExpiresAt = expiresAt
+	}
+	if s.SigningToken != "" && s.SigningToken != signingToken {
+		s.PreviousSigningToken = s.SigningToken
+		s.PreviousSigningTokenExpiresAt = expiresAt
+	}
+	s.Secret = secret
+	s.SigningToken = signingToken
+}
+
+// accepts reports whether the request carries valid credentials for this namespace.
+func (s *webhookSecret) accepts(r *http.Request, body []byte, legacySecret string, now time.Time) bool {
+	nowMillis := now.UnixMilli()
+
+	tokenValid := s.Secret == ""
+	if !tokenValid {
+		token := r.Header.Get(headerGitlabToken)
+		tokenValid = secretsEqual(token, s.Secret) ||
+			(s.PreviousSecretExpiresAt > nowMillis && secretsEqual(token, s.PreviousSecret)) ||
+			((s.AllowLegacySecret || s.LegacySecretExpiresAt > nowMillis) && secretsEqual(token, legacySecret))
+	}
+	if !tokenValid {
+		return false
+	}
+
+	if s.SigningToken == "" {
+		return true
+	}
+	return verifyWebhookSignature(r, body, s.SigningToken, now) ||
+		(s.PreviousSigningTokenExpiresAt > nowMillis && s.PreviousSigningToken != "" && verifyWebhookSignature(r, body, s.PreviousSigningToken, now))
+}
+
+func secretsEqual(given, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+// verifyWebhookSignature checks the HMAC-SHA256 signature GitLab adds to requests of hooks with a signing token.
+// The signed content is "<webhook-id>.<webhook-timestamp>.<body>", following the Standard Webhooks specification.
+func verifyWebhookSignature(r *http.Request, body []byte, signingToken string, now time.Time) bool {
+	id := r.Header.Get(headerWebhookID)
+	timestamp := r.Header.Get(headerWebhookTimestamp)
+	signatures := r.Header.Get(headerWebhookSignature)
+	if id == "" || timestamp == "" || signatures == "" {
+		return false
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if diff := now.Sub(time.Unix(seconds, 0)); diff > webhookSignatureTolerance || diff < -webhookSignatureTolerance {
+		return false
+	}
+
+	key := []byte(signingToken)
+	if strings.HasPrefix(signingToken, signingTokenPrefix) {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(signingToken, signingTokenPrefix)); err == nil {
+			key = decoded
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(signature, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSecretKey hashes the namespace, as GitLab paths can be longer than what fits in a KV key.
+func webhookSecretKey(namespace string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(namespace)))
+	return webhookSecretKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+func (p *Plugin) getWebhookSecret(namespace string) (*webhookSecret, error) {
+	var secret *webhookSecret
+	if err := p.client.KV.Get(webhookSecretKey(namespace), &secret); err != nil {
+		return nil, errors.Wrap(err, "failed to load webhook secret")
+	}
+	return secret, nil
+}
+
+// updateWebhookSecret applies update to the stored credentials of namespace, creating them if needed.
+func (p *Plugin) updateWebhookSecret(namespace string, update func(secret *webhookSecret)) error {
+	err := p.client.KV.SetAtomicWithRetries(webhookSecretKey(namespace), func(oldValue []byte) (any, error) {
+		secret := &webhookSecret{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, secret); err != nil {
+				return nil, err
+			}
+		}
+		secret.Namespace = namespace
+		update(secret)
+		return secret, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store webhook secret")
+	}
+	return nil
+}
+
+// webhookPayloadPaths returns the paths of the projects and groups named by the fields of a raw webhook payload
+// which the handlers look subscriptions up with.
+func (p *Plugin) webhookPayloadPaths(body []byte) []string {
+	var payload struct {
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+			Homepage          string `json:"homepage"`
+		} `json:"project"`
+		Repository struct {
+			Homepage string `json:"homepage"`
+		} `json:"repository"`
+		Group struct {
+			FullPath string `json:"full_path"`
+		} `json:"group"`
+		GroupPath string `json:"group_path"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, path := range []string{
+		payload.Project.PathWithNamespace,
+		p.gitlabPathFromURL(payload.Project.Homepage),
+		p.gitlabPathFromURL(payload.Repository.Homepage),
+		payload.Group.FullPath,
+		payload.GroupPath,
+	} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// gitlabPathFromURL returns the path of the project or group of a GitLab URL, without the path of GitLab itself
+// when it is served under one.
+func (p *Plugin) gitlabPathFromURL(webURL string) string {
+	if webURL == "" {
+		return ""
+	}
+	gitlabURL := strings.TrimSuffix(p.getConfiguration().GitlabURL, "/") + "/"
+	if strings.HasPrefix(webURL, gitlabURL) {
+		return strings.Trim(strings.TrimPrefix(webURL, gitlabURL), "/")
+	}
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// webhookEventPath returns the path of the project or group whose subscriptions the handler of the event looks up.
+func (p *Plugin) webhookEventPath(event any) string {
+	switch event := event.(type) {
+	case *gitlabLib.MergeEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.IssueEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.IssueCommentEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.MergeCommentEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.CommitCommentEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.SnippetCommentEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.PushEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.PipelineEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.TagEvent:
+		return event.Project.PathWithNamespace
+	case *webhook.WikiPageEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.FeatureFlagEvent:
+		return event.Project.PathWithNamespace
+	case *webhook.EmojiEvent:
+		return event.Project.PathWithNamespace
+	case *gitlabLib.JobEvent:
+		return p.gitlabPathFromURL(event.Repository.Homepage)
+	case *gitlabLib.ReleaseEvent:
+		return p.gitlabPathFromURL(event.Project.Homepage)
+	case *gitlabLib.DeploymentEvent:
+		return p.gitlabPathFromURL(event.Project.Homepage)
+	case *gitlabLib.MemberEvent:
+		return event.GroupPath
+	case *webhook.MilestoneEvent:
+		if event.Project.PathWithNamespace != "" {
+			return event.Project.PathWithNamespace
+		}
+		return event.Group.FullPath
+	case *webhook.VulnerabilityEvent:
+		pathWithNamespace, _ := event.ProjectPath()
+		return pathWithNamespace
+	default:
+		return ""
+	}
+}
+
+// webhookEventNamespace returns the path of the project or group a webhook request is authenticated for: the one
+// the handler of its event posts about. A payload naming other projects or groups is rejected, as it could be
+// authenticated for one namespace and posted to the channels of another. Requests whose event couldn't be parsed,
+// which aren't handled, are authenticated for the first namespace of the payload.
+func (p *Plugin) webhookEventNamespace(event any, body []byte) (string, error) {
+	payloadPaths := p.webhookPayloadPaths(body)
+	if event == nil {
+		if len(payloadPaths) == 0 {
+			return "", nil
+		}
+		return payloadPaths[0], nil
+	}
+
+	namespace := p.webhookEventPath(event)
+	for _, path := range payloadPaths {
+		if !strings.EqualFold(path, namespace) {
+			return "", errors.Errorf("webhook event is about %q but its payload names %q", namespace, path)
+		}
+	}
+	return namespace, nil
+}
+
+// isWebhookRequestAuthorized checks the credentials of a webhook request against the secrets of the namespace
+// it is about and of every parent group, as group hooks also send the events of their projects.
+// Namespaces without their own secret use the plugin-wide one.
+func (p *Plugin) isWebhookRequestAuthorized(r *http.Request, body []byte, namespace string) bool {
+	config := p.getConfiguration()
+	now := time.Now()
+
+	var secrets []*webhookSecret
+	for namespace != "" {
+		secret, err := p.getWebhookSecret(namespace)
+		if err != nil {
+			p.client.Log.Warn("can't load webhook secret", "namespace", namespace, "err", err.Error())
+			return false
+		}
+		if secret != nil {
+			secrets = append(secrets, secret)
+		}
+
+		i := strings.LastIndex(namespace, "/")
+		if i < 0 {
+			break
+		}
+		namespace = namespace[:i]
+	}
+
+	if len(secrets) == 0 {
+		return secretsEqual(r.Header.Get(headerGitlabToken), config.WebhookSecret)
+	}
+
+	for _, secret := range secrets {
+		if secret.accepts(r, body, config.WebhookSecret, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMattermostHooks returns the hooks of a project or group that deliver to this Mattermost server.
+func (p *Plugin) getMattermostHooks(ctx context.Context, info *gitlab.UserInfo, group, project string) ([]*gitlab.WebhookInfo, error) {
+	var hooks []*gitlab.WebhookInfo
+	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		var resp []*gitlab.WebhookInfo
+		var err error
+		if project != "" {
+			resp, err = p.GitlabClient.GetProjectHooks(ctx, info, token, group, project)
+		} else {
+			resp, err = p.GitlabClient.GetGroupHooks(ctx, info, token, group)
+		}
+		if err != nil {
+			return err
+		}
+		hooks = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	siteURL := getSiteURL(p.client)
+	var mattermostHooks []*gitlab.WebhookInfo
+	for _, hook := range hooks {
+		if strings.Contains(hook.URL, siteURL) {
+			mattermostHooks = append(mattermostHooks, hook)
+		}
+	}
+	return mattermostHooks, nil
+}
+
+// webhookSecretForNewHook returns the secret a new hook for the project or group should use and records it.
+// The namespace keeps using its existing secret, an explicit token must be that secret, or gets the token or
+// a newly generated secret. When a namespace gets its first secret while hooks using the plugin-wide secret
+// already exist, these keep working until the namespace is rotated, and the returned note says so.
+// The hooks of its projects and subgroups get the grace period of a rotation to be given their own secret.
+func (p *Plugin) webhookSecretForNewHook(ctx context.Context, info *gitlab.UserInfo, group, project, token string) (string, string, error) {
+	namespace := namespaceFromGroupAndProject(group, project)
+	existing, err := p.getWebhookSecret(namespace)
+	if err != nil {
+		return "", "", err
+	}
+
+	if existing != nil && existing.Secret != "" {
+		if token != "" && !secretsEqual(token, existing.Secret) {
+			return "", "", errWebhookSecretMismatch
+		}
+		return existing.Secret, "", nil
+	}
+
+	secret := token
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return "", "", errors.Wrap(err, "failed to generate webhook secret")
+		}
+	}
+
+	var note string
+	allowLegacySecret := false
+	if existing == nil {
+		hooks, err := p.getMattermostHooks(ctx, info, group, project)
+		if errors.Is(err, gitlab.ErrForbidden) {
+			return "", "", err
+		}
+		if err != nil {
+			p.client.Log.Debug("Unable to fetch existing webhooks, keeping the plugin-wide secret valid", "namespace", namespace, "err", err.Error())
+		}
+		if err != nil || len(hooks) > 0 {
+			allowLegacySecret = true
+			note = "\n" + p.localizeForUser(info.UserID, &i18n.Message{
+				ID:    "command.webhook.legacy_secret",
+				Other: "Existing webhooks of `{{.Namespace}}` still use the secret from the plugin settings. Run `/gitlab webhook rotate {{.Namespace}}` to give them their own secret.",
+			}, map[string]any{"Namespace": namespace})
+		}
+	}
+
+	if existing == nil && project == "" {
+		note += "\n" + p.localizeForUser(info.UserID, &i18n.Message{
+			ID:    "command.webhook.legacy_secret_group",
+			Other: "Webhooks of the projects and subgroups of `{{.Namespace}}` using the secret from the plugin settings keep working for {{.GracePeriod}}. Run `/gitlab webhook rotate` for each of them to give them their own secret.",
+		}, map[string]any{"Namespace": namespace, "GracePeriod": webhookSecretRotationGracePeriod.String()})
+	}
+
+	err = p.updateWebhookSecret(namespace, func(s *webhookSecret) {
+		s.rotate(secret, s.SigningToken)
+		if existing == nil {
+			s.AllowLegacySecret = allowLegacySecret
+			s.LegacySecretExpiresAt = time.Now().Add(webhookSecretRotationGracePeriod).UnixMilli()
+		}
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, note, nil
+}
+
+// rotateWebhookSecret generates a new secret for the project or group and sets it on every hook
+// delivering to this Mattermost server. The previous secret keeps working during the grace period.
+func (p *Plugin) rotateWebhookSecret(ctx context.Context, locale string, info *gitlab.UserInfo, group, project string) string {
+	namespace := namespaceFromGroupAndProject(group, project)
+
+	hooks, err := p.getMattermostHooks(ctx, info, group, project)
+	if err != nil {
+		if errors.Is(err, gitlab.ErrForbidden) {
+			return p.webhookPermissionMessage(locale, group, project)
+		}
+		return err.Error()
+	}
+	if len(hooks) == 0 {
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.rotate.no_webhooks",
+			Other: "No webhooks delivering to Mattermost found for `{{.Namespace}}`.",
+		}, map[string]any{"Namespace": namespace})
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.rotate.generate_error",
+			Other: "Failed to generate a new secret.",
+		}, nil)
+	}
+	err = p.updateWebhookSecret(namespace, func(s *webhookSecret) {
+		s.rotate(secret, s.SigningToken)
+	})
+	if err != nil {
+		p.client.Log.Warn("can't store rotated webhook secret", "namespace", namespace, "err", err.Error())
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.rotate.store_error",
+			Other: "Failed to store the new secret.",
+		}, nil)
+	}
+
+	var failed []string
+	for _, hook := range hooks {
+		err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+			if project != "" {
+				return p.GitlabClient.SetProjectHookToken(ctx, info, token, group, project, hook, secret)
+			}
+			return p.GitlabClient.SetGroupHookToken(ctx, info, token, group, hook, secret)
+		})
+		if err != nil {
+			p.client.Log.Warn("can't update webhook secret in GitLab", "namespace", namespace, "hook_id", hook.ID, "err", err.Error())
+			failed = append(failed, fmt.Sprintf("* `%s` (ID %d)", hook.URL, hook.ID))
+		}
+	}
+
+	if len(failed) > 0 {
+		return p.localize(locale, &i18n.Message{
+			ID:    "command.webhook.rotate.partial",
+			Other: "Rotated the secret of `{{.Namespace}}`, but these webhooks couldn't be updated:\n{{.Webhooks}}\nSet their secret token to `{{.Secret}}` in GitLab within {{.GracePeriod}}, after that the previous secret stops working.",
+		}, map[string]any{
+			"Namespace":   namespace,
+			"Webhooks":    strings.Join(failed, "\n"),
+			"Secret":      secret,
+			"GracePeriod": webhookSecretRotationGracePeriod.String(),
+		})
+	}
+
+	err = p.updateWebhookSecret(namespace, func(s *webhookSecret) {
+		s.AllowLegacySecret = false
+	})
+	if err != nil {
+		p.client.Log.Warn("can't store rotated webhook secret", "namespace", namespace, "err", err.Error())
+	}
+
+	return p.localize(locale, &i18n.Message{
+		ID:    "command.webhook.rotate.done",
+		Other: "Rotated the secret of {{.Count}} webhook(s) for `{{.Namespace}}`. The previous secret stays valid for {{.GracePeriod}}.",
+	}, map[string]any{
+		"Count":       len(hooks),
+		"Namespace":   namespace,
+		"GracePeriod": webhookSecretRotationGracePeriod.String(),
+	})
+}
+
+// setWebhookSigningToken records the signing token configured for the hooks of a project or group in GitLab.
+// From then on, requests for the namespace must carry a valid signature.
+func (p *Plugin) setWebhookSigningToken(ctx context.Context, locale string, info *gitlab.UserInfo, group, project, signingToken string) string {
+	namespace := namespaceFromGroupAndProject(group, project)
+
+	// Listing the hooks makes sure the user is allowed to manage them.
+	if _, err := p.getMattermostHooks(ctx, info, group, project); err != nil {
+		if errors.Is(err, gitlab.ErrForbidden) {
+			return p.webhookPermissionMessage(locale, group, project)
+		}
+		return err.Error()
+	}
+
+	existing, err := p.getWebhookSecret(namespace)
+	if err != nil {
+		p.client.Log.Warn("can't load webhook secret", "namespace", namespace, "err", err.Error())
+		return p.localize(locale, signingTokenErrorMessage, nil)
+	}
+
+	err = p.updateWebhookSecret(namespace, func(s *webhookSecret) {
+		if existing == nil {
+			// Hooks of the namespace were created with the plugin-wide secret.
+			s.AllowLegacySecret = true
+		}
+		s.rotate(s.Secret, signingToken)
+	})
+	if err != nil {
+		p.client.Log.Warn("can't store webhook signing token", "namespace", namespace, "err", err.Error())
+		return p.localize(locale, signingTokenErrorMessage, nil)
+	}
+
+	return p.localize(locale, &i18n.Message{
+		ID:    "command.webhook.signing_token.done",
+		Other: "Requests for `{{.Namespace}}` now need a valid signature. A previous signing token stays valid for {{.GracePeriod}}.",
+	}, map[string]any{
+		"Namespace":   namespace,
+		"GracePeriod": webhookSecretRotationGracePeriod.String(),
+	})
+}
+
+func (p *Plugin) resolveWebhookNamespace(ctx context.Context, info *gitlab.UserInfo, namespace string, enablePrivateRepo bool) (string, string, error) {
+	var group, project string
+	err := p.useGitlabClient(info, func(info *gitlab.UserInfo, token *oauth2.Token) error {
+		respGroup, respProject, err := p.GitlabClient.ResolveNamespaceAndProject(ctx, info, token, namespace, enablePrivateRepo)
+		if err != nil {
+			return err
+		}
+		group = respGroup
+		project = respProject
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := p.isNamespaceAllowed(namespaceFromGroupAndProject(group, project)); err != nil {
+		return "", "", err
+	}
+	return group, project, nil
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2019-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gitlabLib "github.com/xanzy/go-gitlab"
+
+	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
+)
+
+const testSigningToken = "whsec_c2lnbmluZy1rZXk="
+
+func signWebhookRequest(t *testing.T, body string, signingToken string, timestamp time.Time) (id, ts, signature string) {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(signingToken[len(signingTokenPrefix):])
+	require.NoError(t, err)
+
+	id = "msg_1"
+	ts = strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + ts + "." + body))
+	return id, ts, "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := `{"object_kind":"push"}`
+	now := time.Now()
+
+	for name, test := range map[string]struct {
+		signedBody   string
+		signedAt     time.Time
+		signingToken string
+		extraSig     string
+		want         bool
+	}{
+		"valid signature":             {signedBody: body, signedAt: now, signingToken: testSigningToken, want: true},
+		"one of several signatures":   {signedBody: body, signedAt: now, signingToken: testSigningToken, extraSig: "v1,Zm9v ", want: true},
+		"tampered body":               {signedBody: `{"object_kind":"tag_push"}`, signedAt: now, signingToken: testSigningToken},
+		"other signing token":         {signedBody: body, signedAt: now, signingToken: "whsec_b3RoZXIta2V5"},
+		"timestamp outside tolerance": {signedBody: body, signedAt: now.Add(-time.Hour), signingToken: testSigningToken},
+		"timestamp too far in future": {signedBody: body, signedAt: now.Add(time.Hour), signingToken: testSigningToken},
+		"timestamp inside tolerance":  {signedBody: body, signedAt: now.Add(-time.Minute), signingToken: testSigningToken, want: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			id, ts, signature := signWebhookRequest(t, test.signedBody, test.signingToken, test.signedAt)
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set(headerWebhookID, id)
+			r.Header.Set(headerWebhookTimestamp, ts)
+			r.Header.Set(headerWebhookSignature, test.extraSig+signature)
+
+			assert.Equal(t, test.want, verifyWebhookSignature(r, []byte(body), testSigningToken, now))
+		})
+	}
+
+	t.Run("missing headers", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		assert.False(t, verifyWebhookSignature(r, []byte(body), testSigningToken, now))
+	})
+}
+
+func TestWebhookSecretAccepts(t *testing.T) {
+	now := time.Now()
+	withToken := func(token string) *http.Request {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set(headerGitlabToken, token)
+		return r
+	}
+
+	t.Run("current secret", func(t *testing.T) {
+		s := &webhookSecret{Secret: "current"}
+		assert.True(t, s.accepts(withToken("current"), nil, "global", now))
+		assert.False(t, s.accepts(withToken("global"), nil, "global", now))
+		assert.False(t, s.accepts(withToken(""), nil, "global", now))
+	})
+
+	t.Run("rotated secret stays valid during the grace period", func(t *testing.T) {
+		s := &webhookSecret{Secret: "old"}
+		s.rotate("new", "")
+
+		assert.True(t, s.accepts(withToken("new"), nil, "global", now))
+		assert.True(t, s.accepts(withToken("old"), nil, "global", now))
+		assert.False(t, s.accepts(withToken("old"), nil, "global", now.Add(webhookSecretRotationGracePeriod+time.Minute)))
+	})
+
+	t.Run("legacy secret", func(t *testing.T) {
+		s := &webhookSecret{Secret: "current", AllowLegacySecret: true}
+		assert.True(t, s.accepts(withToken("global"), nil, "global", now))
+	})
+
+	t.Run("legacy secret during the grace period of a first secret", func(t *testing.T) {
+		s := &webhookSecret{Secret: "current", LegacySecretExpiresAt: now.Add(time.Hour).UnixMilli()}
+		assert.True(t, s.accepts(withToken("global"), nil, "global", now))
+		assert.False(t, s.accepts(withToken("global"), nil, "global", now.Add(2*time.Hour)))
+	})
+
+	t.Run("rotating the signing token keeps the grace period of the previous secret", func(t *testing.T) {
+		s := &webhookSecret{Secret: "old"}
+		s.rotate("new", "")
+		s.PreviousSecretExpiresAt = now.Add(time.Hour).UnixMilli()
+		s.rotate("new", testSigningToken)
+		s.rotate("new", "whsec_bmV3LXNpZ25pbmcta2V5")
+
+		assert.Equal(t, now.Add(time.Hour).UnixMilli(), s.PreviousSecretExpiresAt)
+		assert.Greater(t, s.PreviousSigningTokenExpiresAt, now.Add(webhookSecretRotationGracePeriod-time.Minute).UnixMilli())
+		assert.False(t, s.accepts(withToken("old"), nil, "global", now.Add(2*time.Hour)))
+	})
+
+	t.Run("signing token requires a signature", func(t *testing.T) {
+		body := `{"object_kind":"push"}`
+		s := &webhookSecret{Secret: "current", SigningToken: testSigningToken}
+
+		r := withToken("current")
+		assert.False(t, s.accepts(r, []byte(body), "global", now))
+
+		id, ts, signature := signWebhookRequest(t, body, testSigningToken, now)
+		r.Header.Set(headerWebhookID, id)
+		r.Header.Set(headerWebhookTimestamp, ts)
+		r.Header.Set(headerWebhookSignature, signature)
+		assert.True(t, s.accepts(r, []byte(body), "global", now))
+
+		r.Header.Set(headerGitlabToken, "wrong")
+		assert.False(t, s.accepts(r, []byte(body), "global", now))
+	})
+}
+
+func TestIsWebhookRequestAuthorized(t *testing.T) {
+	projectSecret, err := json.Marshal(&webhookSecret{Namespace: "group/project", Secret: "project-secret"})
+	require.NoError(t, err)
+	groupSecret, err := json.Marshal(&webhookSecret{Namespace: "group", Secret: "group-secret"})
+	require.NoError(t, err)
+	groupSecretInGracePeriod, err := json.Marshal(&webhookSecret{Namespace: "group", Secret: "group-secret", LegacySecretExpiresAt: time.Now().Add(time.Hour).UnixMilli()})
+	require.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		eventType gitlabLib.EventType
+		body      string
+		token     string
+		secrets   map[string][]byte
+		want      bool
+	}{
+		"namespace without secret uses the plugin-wide secret": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"group/project"}}`,
+			token:     "global",
+			want:      true,
+		},
+		"namespace with secret rejects the plugin-wide secret": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"group/project"}}`,
+			token:     "global",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+		},
+		"namespace with secret": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"Group/Project"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+			want:      true,
+		},
+		"secret of another project": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"group/other"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+		},
+		"secret of the parent group": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"group/project"}}`,
+			token:     "group-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret, "group": groupSecret},
+			want:      true,
+		},
+		"job event": {
+			eventType: gitlabLib.EventTypeJob,
+			body:      `{"object_kind":"build","repository":{"homepage":"https://gitlab.example.com/group/project"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+			want:      true,
+		},
+		"job event with the project of the repository": {
+			eventType: gitlabLib.EventTypeJob,
+			body:      `{"object_kind":"build","project":{"path_with_namespace":"group/project"},"repository":{"homepage":"https://gitlab.example.com/group/project"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+			want:      true,
+		},
+		"job event of another project authenticated with the project secret": {
+			eventType: gitlabLib.EventTypeJob,
+			body:      `{"object_kind":"build","project":{"path_with_namespace":"group/project"},"repository":{"homepage":"https://gitlab.example.com/group/other"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+		},
+		"member event of another group authenticated with the project secret": {
+			eventType: gitlabLib.EventTypeMember,
+			body:      `{"event_name":"user_add_to_group","project":{"path_with_namespace":"group/project"},"group_path":"other"}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+		},
+		"project without secret during the grace period of the first secret of its group": {
+			eventType: gitlabLib.EventTypePush,
+			body:      `{"project":{"path_with_namespace":"group/other"}}`,
+			token:     "global",
+			secrets:   map[string][]byte{"group": groupSecretInGracePeriod},
+			want:      true,
+		},
+		"group milestone event": {
+			eventType: webhook.EventTypeMilestone,
+			body:      `{"object_kind":"milestone","group":{"full_path":"group"}}`,
+			token:     "group-secret",
+			secrets:   map[string][]byte{"group": groupSecret},
+			want:      true,
+		},
+		"vulnerability event": {
+			eventType: webhook.EventTypeVulnerability,
+			body:      `{"object_kind":"vulnerability","object_attributes":{"url":"https://gitlab.example.com/group/project/-/security/vulnerabilities/1"}}`,
+			token:     "project-secret",
+			secrets:   map[string][]byte{"group/project": projectSecret},
+			want:      true,
+		},
+		"unparsable body": {
+			body:  ``,
+			token: "bad",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{configuration: &configuration{WebhookSecret: "global", GitlabURL: "https://gitlab.example.com"}}
+			p.SetAPI(api)
+			p.client = pluginapi.NewClient(api, p.Driver)
+			for _, namespace := range []string{"group/project", "group/other", "group", "other"} {
+				api.On("KVGet", webhookSecretKey(namespace)).Return(test.secrets[namespace], nil).Maybe()
+			}
+
+			r := httptest.NewRequest("POST", "/", bytes.NewBufferString(test.body))
+			r.Header.Set(headerGitlabToken, test.token)
+
+			event, _ := webhook.ParseWebhook(test.eventType, []byte(test.body))
+			namespace, err := p.webhookEventNamespace(event, []byte(test.body))
+			assert.Equal(t, test.want, err == nil && p.isWebhookRequestAuthorized(r, []byte(test.body), namespace))
+		})
+	}
+}
+
+func TestWebhookSecretForNewHook(t *testing.T) {
+	existing, err := json.Marshal(&webhookSecret{Namespace: "group/project", Secret: "project-secret"})
+	require.NoError(t, err)
+	info := &gitlab.UserInfo{UserID: "user-id"}
+
+	t.Run("existing secret is reused", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVGet", webhookSecretKey("group/project")).Return(existing, nil).Once()
+
+		secret, note, err := p.webhookSecretForNewHook(context.Background(), info, "group", "project", "")
+		require.NoError(t, err)
+		assert.Equal(t, "project-secret", secret)
+		assert.Empty(t, note)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("same token as the existing secret", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVGet", webhookSecretKey("group/project")).Return(existing, nil).Once()
+
+		secret, _, err := p.webhookSecretForNewHook(context.Background(), info, "group", "project", "project-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "project-secret", secret)
+	})
+
+	t.Run("token replacing the existing secret is refused", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := &Plugin{configuration: &configuration{}}
+		p.SetAPI(api)
+		p.client = pluginapi.NewClient(api, p.Driver)
+		api.On("KVGet", webhookSecretKey("group/project")).Return(existing, nil).Once()
+
+		_, _, err := p.webhookSecretForNewHook(context.Background(), info, "group", "project", "other-secret")
+		assert.ErrorIs(t, err, errWebhookSecretMismatch)
+		api.AssertNotCalled(t, "KVSetWithOptions")
+	})
+}
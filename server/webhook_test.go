@@ -6,43 +6,51 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	testifymock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	gitlabLib "github.com/xanzy/go-gitlab"
+	"go.uber.org/mock/gomock"
 
+	"github.com/mattermost/mattermost-plugin-gitlab/server/gitlab"
+	mocks "github.com/mattermost/mattermost-plugin-gitlab/server/gitlab/mocks"
+	"github.com/mattermost/mattermost-plugin-gitlab/server/subscription"
 	"github.com/mattermost/mattermost-plugin-gitlab/server/webhook"
 )
 
 type fakeWebhookHandler struct{}
 
-func (fakeWebhookHandler) HandleIssue(_ context.Context, _ *gitlabLib.IssueEvent, _ gitlabLib.EventType) ([]*webhook.HandleWebhook, []string, error) {
+func (fakeWebhookHandler) HandleIssue(_ context.Context, _ *gitlabLib.IssueEvent, _ gitlabLib.EventType) ([]*webhook.HandleWebhook, error) {
 	return []*webhook.HandleWebhook{{
 		Message: "hello",
 		From:    "test",
 		ToUsers: []string{"unknown"},
-	}}, []string{}, nil
+	}}, nil
 }
 
-func (fakeWebhookHandler) HandleMergeRequest(_ context.Context, _ *gitlabLib.MergeEvent) ([]*webhook.HandleWebhook, []string, error) {
+func (fakeWebhookHandler) HandleMergeRequest(_ context.Context, _ *gitlabLib.MergeEvent) ([]*webhook.HandleWebhook, error) {
 	return []*webhook.HandleWebhook{{
 		Message:    "hello",
 		From:       "test",
 		ToChannels: []string{"town-square"},
-	}}, []string{}, nil
+	}}, nil
 }
 
-func (fakeWebhookHandler) HandleIssueComment(_ context.Context, _ *gitlabLib.IssueCommentEvent) ([]*webhook.HandleWebhook, []string, error) {
-	return nil, []string{}, nil
+func (fakeWebhookHandler) HandleIssueComment(_ context.Context, _ *gitlabLib.IssueCommentEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
 }
 
-func (fakeWebhookHandler) HandleMergeRequestComment(_ context.Context, _ *gitlabLib.MergeCommentEvent) ([]*webhook.HandleWebhook, []string, error) {
-	return nil, []string{}, nil
+func (fakeWebhookHandler) HandleMergeRequestComment(_ context.Context, _ *gitlabLib.MergeCommentEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
 }
 
 func (fakeWebhookHandler) HandlePipeline(_ context.Context, _ *gitlabLib.PipelineEvent) ([]*webhook.HandleWebhook, error) {
@@ -65,6 +73,38 @@ func (fakeWebhookHandler) HandleDeployment(_ context.Context, _ *gitlabLib.Deplo
 	return nil, nil
 }
 
+func (fakeWebhookHandler) HandleCommitComment(_ context.Context, _ *gitlabLib.CommitCommentEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleSnippetComment(_ context.Context, _ *gitlabLib.SnippetCommentEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleWikiPage(_ context.Context, _ *webhook.WikiPageEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleFeatureFlag(_ context.Context, _ *gitlabLib.FeatureFlagEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleMember(_ context.Context, _ *gitlabLib.MemberEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleEmoji(_ context.Context, _ *webhook.EmojiEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleMilestone(_ context.Context, _ *webhook.MilestoneEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
+func (fakeWebhookHandler) HandleVulnerability(_ context.Context, _ *webhook.VulnerabilityEvent) ([]*webhook.HandleWebhook, error) {
+	return nil, nil
+}
+
 func (fakeWebhookHandler) HandleRelease(_ context.Context, _ *gitlabLib.ReleaseEvent) ([]*webhook.HandleWebhook, error) {
 	return nil, nil
 }
@@ -79,6 +119,16 @@ func TestHandleWebhookBadSecret(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 }
 
+func TestHandleWebhookBodyTooLarge(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat(" ", webhookMaxBodySize+1)))
+	req.Header.Add("X-Gitlab-Token", "secret")
+	w := httptest.NewRecorder()
+	p.handleWebhook(w, req)
+	resp := w.Result()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
 func TestHandleWebhookBadBody(t *testing.T) {
 	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
 	mock := &plugintest.API{}
@@ -94,15 +144,19 @@ func TestHandleWebhookBadBody(t *testing.T) {
 	mock.AssertCalled(t, "LogDebug", "Can't parse webhook", "err", "unexpected event type: ", "header", "", "event", "{}")
 }
 
-func TestHandleWebhookWithKnowAuthorButUnknowToUser(t *testing.T) {
+func TestHandleWebhookQueuesEvent(t *testing.T) {
 	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
 
 	mock := &plugintest.API{}
-	mock.On("KVGet", "test_gitlabusername").Return([]byte("1"), nil).Once()
-	mock.On("KVGet", "unknown_gitlabusername").Return(nil, nil).Once()
-	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
-	mock.On("LogInfo", "new msg", "message", "hello", "from", "test").Return(nil)
-	mock.On("LogInfo", "userFrom", "from", "1").Return(nil)
+	mock.On("KVSetWithOptions", testifymock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, webhookEventQueueKeyPrefix) }), testifymock.MatchedBy(func(value []byte) bool {
+		var e pendingWebhookEvent
+		return json.Unmarshal(value, &e) == nil && e.EventType == gitlabLib.EventTypeIssue && string(e.Body) == `{"user":{"username":"test"}}`
+	}), testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	mock.On("KVGet", webhookEventQueueIndexKey).Return(nil, nil).Once()
+	mock.On("KVSetWithOptions", webhookEventQueueIndexKey, testifymock.MatchedBy(func(value []byte) bool {
+		var index map[string]int64
+		return json.Unmarshal(value, &index) == nil && len(index) == 1
+	}), testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
 	p.SetAPI(mock)
 	p.client = pluginapi.NewClient(mock, p.Driver)
 
@@ -115,6 +169,49 @@ func TestHandleWebhookWithKnowAuthorButUnknowToUser(t *testing.T) {
 	resp := w.Result()
 
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mock.AssertExpectations(t)
+	// The event is handled by the event worker.
+	mock.AssertNotCalled(t, "KVGet", "test_gitlabusername")
+}
+
+func TestProcessWebhookEvent(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
+
+	mock := &plugintest.API{}
+	data, err := json.Marshal(&pendingWebhookEvent{ID: "eventid", EventType: gitlabLib.EventTypeIssue, Body: []byte(`{"user": {"username":"test"}}`)})
+	require.NoError(t, err)
+	mock.On("KVSetWithOptions", webhookEventLeaseKeyPrefix+"eventid", []byte{1}, testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+	mock.On("KVGet", webhookEventQueueKeyPrefix+"eventid").Return(data, nil).Once()
+	mock.On("KVGet", "test_gitlabusername").Return([]byte("1"), nil).Once()
+	mock.On("KVGet", "unknown_gitlabusername").Return(nil, nil).Once()
+	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
+	mock.On("LogInfo", "new msg", "message", "hello", "from", "test").Return(nil)
+	mock.On("KVSetWithOptions", webhookEventQueueKeyPrefix+"eventid", isNilBytes, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	mock.On("KVGet", webhookEventQueueIndexKey).Return([]byte(`{"eventid":1,"otherid":2}`), nil).Once()
+	mock.On("KVSetWithOptions", webhookEventQueueIndexKey, []byte(`{"otherid":2}`), testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool { return o.Atomic })).Return(true, nil).Once()
+	mock.On("KVSetWithOptions", webhookEventLeaseKeyPrefix+"eventid", isNilBytes, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.processWebhookEvent("eventid")
+
+	mock.AssertExpectations(t)
+}
+
+func TestHandleWebhookEventWithKnowAuthorButUnknowToUser(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
+
+	mock := &plugintest.API{}
+	mock.On("KVGet", "test_gitlabusername").Return([]byte("1"), nil).Once()
+	mock.On("KVGet", "unknown_gitlabusername").Return(nil, nil).Once()
+	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
+	mock.On("LogInfo", "new msg", "message", "hello", "from", "test").Return(nil)
+	mock.On("LogInfo", "userFrom", "from", "1").Return(nil)
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.handleWebhookEvent(gitlabLib.EventTypeIssue, []byte(`{"user": {"username":"test"}}`))
+
 	mock.AssertCalled(t, "KVGet", "test_gitlabusername")
 	mock.AssertCalled(t, "KVGet", "unknown_gitlabusername")
 	mock.AssertNumberOfCalls(t, "KVGet", 2)
@@ -122,7 +219,7 @@ func TestHandleWebhookWithKnowAuthorButUnknowToUser(t *testing.T) {
 	mock.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
 }
 
-func TestHandleWebhookToChannel(t *testing.T) {
+func TestHandleWebhookEventToChannel(t *testing.T) {
 	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
 
 	mock := &plugintest.API{}
@@ -130,64 +227,305 @@ func TestHandleWebhookToChannel(t *testing.T) {
 	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
 	mock.On("LogInfo", "new msg", "message", "hello", "from", "test").Return(nil)
 	mock.On("LogInfo", "userFrom", "from", "1").Return(nil)
-	mock.On("CreatePost", &model.Post{Id: "", CreateAt: 0, UpdateAt: 0, EditAt: 0, DeleteAt: 0, IsPinned: false, UserId: "", ChannelId: "town-square", RootId: "", OriginalId: "", Message: "hello", MessageSource: "", Type: "", Hashtags: "", Filenames: model.StringArray(nil), FileIds: model.StringArray(nil), PendingPostId: "", HasReactions: false, Metadata: (*model.PostMetadata)(nil)}).Return(&model.Post{}, nil)
+	mock.On("KVSetWithOptions", testifymock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, deliveryQueueKeyPrefix) }), testifymock.MatchedBy(func(value []byte) bool {
+		var d pendingDelivery
+		return json.Unmarshal(value, &d) == nil && d.ChannelID == "town-square" && d.Message == "hello"
+	}), testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	allowQueueIndexUpdates(mock, deliveryQueueIndexKey)
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.handleWebhookEvent(gitlabLib.EventTypeMergeRequest, []byte(`{"user": {"username":"test"}}`))
+
+	mock.AssertCalled(t, "KVGet", "test_gitlabusername")
+	// The other read and write update the index of the delivery queue.
+	mock.AssertNumberOfCalls(t, "KVGet", 2)
+	mock.AssertCalled(t, "PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"})
+	mock.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
+	mock.AssertNumberOfCalls(t, "KVSetWithOptions", 2)
+}
+
+// threadedWebhookHandler posts merge requests to the channel of its subscription, in the thread of the merge request.
+type threadedWebhookHandler struct {
+	fakeWebhookHandler
+	sub *subscription.Subscription
+}
+
+func (h threadedWebhookHandler) HandleMergeRequest(_ context.Context, _ *gitlabLib.MergeEvent) ([]*webhook.HandleWebhook, error) {
+	return []*webhook.HandleWebhook{{
+		Message:       "hello",
+		From:          "test",
+		ToChannels:    []string{h.sub.ChannelID},
+		ThreadKey:     "merge_request/1/2",
+		Subscriptions: []*subscription.Subscription{h.sub},
+	}}, nil
+}
+
+func TestHandleWebhookEventUsesMatchedSubscription(t *testing.T) {
+	sub, err := subscription.New("town-square", "user1", "merges,threads", "group/")
+	require.NoError(t, err)
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: threadedWebhookHandler{sub: sub}}
+
+	mock := &plugintest.API{}
+	mock.On("KVGet", "test_gitlabusername").Return(nil, nil)
+	mock.On("LogInfo", "new msg", "message", "hello", "from", "test").Return(nil)
+	mock.On("KVSetWithOptions", testifymock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, deliveryQueueKeyPrefix) }), testifymock.MatchedBy(func(value []byte) bool {
+		var d pendingDelivery
+		return json.Unmarshal(value, &d) == nil && d.ChannelID == "town-square" && d.ThreadKey == "merge_request/1/2"
+	}), testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	allowQueueIndexUpdates(mock, deliveryQueueIndexKey)
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.handleWebhookEvent(gitlabLib.EventTypeMergeRequest, []byte(`{"user": {"username":"test"}, "project": {"path_with_namespace": "group/project"}}`))
+
+	mock.AssertExpectations(t)
+}
+
+func TestHandleWebhookEventForChildPipelineNotficationDisabled(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret", EnableChildPipelineNotifications: false}, WebhookHandler: fakeWebhookHandler{}}
+
+	mock := &plugintest.API{}
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.handleWebhookEvent(gitlabLib.EventTypePipeline, []byte(`{"user": {"username":"test"}, "object_attributes": {"source":"parent_pipeline"}}`))
+
+	mock.AssertNotCalled(t, "KVGet", "test_gitlabusername")
+}
+
+func TestHandleWebhookEventForChildPipelineNotficationEnabled(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret", EnableChildPipelineNotifications: true}, WebhookHandler: fakeWebhookHandler{}}
+
+	mock := &plugintest.API{}
+	mock.On("KVGet", "test_gitlabusername").Return([]byte("1"), nil).Once()
+	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	p.handleWebhookEvent(gitlabLib.EventTypePipeline, []byte(`{"user": {"username":"test"}, "object_attributes": {"source":"parent_pipeline"}}`))
+
+	mock.AssertCalled(t, "KVGet", "test_gitlabusername")
+	mock.AssertNumberOfCalls(t, "KVGet", 1)
+	mock.AssertCalled(t, "PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"})
+	mock.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
+}
+
+func TestHandleWebhookDuplicateEvent(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
+
+	mock := &plugintest.API{}
+	mock.On("KVSetWithOptions", webhookEventKeyPrefix+"event-uuid_webhook-uuid", []byte{1}, testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.Atomic && o.OldValue == nil && o.ExpireInSeconds > 0
+	})).Return(false, nil).Once()
+	mock.On("KVGet", webhookDuplicateCountKey).Return([]byte("41"), nil).Once()
+	mock.On("KVSetWithOptions", webhookDuplicateCountKey, []byte("42"), testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	mock.On("LogDebug", "Skipping already handled webhook event", "event_uuid", "event-uuid", "webhook_uuid", "webhook-uuid").Return(nil)
 	p.SetAPI(mock)
 	p.client = pluginapi.NewClient(mock, p.Driver)
 
 	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"user": {"username":"test"}}`))
 	req.Header.Add("X-Gitlab-Token", "secret")
 	req.Header.Add("X-Gitlab-Event", string(gitlabLib.EventTypeMergeRequest))
+	req.Header.Add("X-Gitlab-Event-UUID", "event-uuid")
+	req.Header.Add("X-Gitlab-Webhook-UUID", "webhook-uuid")
 	w := httptest.NewRecorder()
 
 	p.handleWebhook(w, req)
 	resp := w.Result()
 
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	mock.AssertCalled(t, "KVGet", "test_gitlabusername")
-	mock.AssertNumberOfCalls(t, "KVGet", 1)
-	mock.AssertCalled(t, "PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"})
-	mock.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
-	mock.AssertNumberOfCalls(t, "CreatePost", 1)
+	mock.AssertExpectations(t)
+	mock.AssertNotCalled(t, "KVGet", "test_gitlabusername")
 }
 
-func TestHandleWebhookForChildPipelineNotficationDisabled(t *testing.T) {
-	p := &Plugin{configuration: &configuration{WebhookSecret: "secret", EnableChildPipelineNotifications: false}, WebhookHandler: fakeWebhookHandler{}}
+func TestHandleWebhookFirstDeliveryOfEvent(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
 
 	mock := &plugintest.API{}
+	mock.On("KVSetWithOptions", webhookEventKeyPrefix+"event-uuid", []byte{1}, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	mock.On("KVSetWithOptions", testifymock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, webhookEventQueueKeyPrefix) }), testifymock.Anything, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	allowQueueIndexUpdates(mock, webhookEventQueueIndexKey)
 	p.SetAPI(mock)
 	p.client = pluginapi.NewClient(mock, p.Driver)
 
-	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"user": {"username":"test"}, "object_attributes": {"source":"parent_pipeline"}}`))
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"user": {"username":"test"}}`))
 	req.Header.Add("X-Gitlab-Token", "secret")
-	req.Header.Add("X-Gitlab-Event", string(gitlabLib.EventTypePipeline))
+	req.Header.Add("X-Gitlab-Event", string(gitlabLib.EventTypeIssue))
+	req.Header.Add("X-Gitlab-Event-UUID", "event-uuid")
 	w := httptest.NewRecorder()
 
 	p.handleWebhook(w, req)
 	resp := w.Result()
 
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mock.AssertExpectations(t)
 }
 
-func TestHandleWebhookForChildPipelineNotficationEnabled(t *testing.T) {
-	p := &Plugin{configuration: &configuration{WebhookSecret: "secret", EnableChildPipelineNotifications: true}, WebhookHandler: fakeWebhookHandler{}}
+func TestHandleWebhookEventNotQueued(t *testing.T) {
+	p := &Plugin{configuration: &configuration{WebhookSecret: "secret"}, WebhookHandler: fakeWebhookHandler{}}
 
 	mock := &plugintest.API{}
-	mock.On("KVGet", "test_gitlabusername").Return([]byte("1"), nil).Once()
-	mock.On("PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"}).Return(nil).Once()
+	mock.On("KVSetWithOptions", webhookEventKeyPrefix+"event-uuid", []byte{1}, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
+	mock.On("KVSetWithOptions", testifymock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, webhookEventQueueKeyPrefix) }), testifymock.Anything, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(false, &model.AppError{Message: "db down"}).Once()
+	mock.On("LogWarn", "can't enqueue webhook event", "err", testifymock.Anything).Return(nil)
+	// The delivery is forgotten so GitLab's retry isn't skipped.
+	mock.On("KVSetWithOptions", webhookEventKeyPrefix+"event-uuid", isNilBytes, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil).Once()
 	p.SetAPI(mock)
 	p.client = pluginapi.NewClient(mock, p.Driver)
 
-	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"user": {"username":"test"}, "object_attributes": {"source":"parent_pipeline"}}`))
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"user": {"username":"test"}}`))
 	req.Header.Add("X-Gitlab-Token", "secret")
-	req.Header.Add("X-Gitlab-Event", string(gitlabLib.EventTypePipeline))
+	req.Header.Add("X-Gitlab-Event", string(gitlabLib.EventTypeIssue))
+	req.Header.Add("X-Gitlab-Event-UUID", "event-uuid")
 	w := httptest.NewRecorder()
 
 	p.handleWebhook(w, req)
 	resp := w.Result()
 
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	mock.AssertCalled(t, "KVGet", "test_gitlabusername")
-	mock.AssertNumberOfCalls(t, "KVGet", 1)
-	mock.AssertCalled(t, "PublishWebSocketEvent", WsEventRefresh, map[string]any(nil), &model.WebsocketBroadcast{UserId: "1"})
-	mock.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	mock.AssertExpectations(t)
+}
+
+func TestPermissionToGroup(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		visibility        gitlabLib.VisibilityValue
+		enablePrivateRepo bool
+		want              bool
+	}{
+		{name: "public group", visibility: gitlabLib.PublicVisibility, want: true},
+		{name: "internal group", visibility: gitlabLib.InternalVisibility, want: true},
+		{name: "private group with private repositories disabled", visibility: gitlabLib.PrivateVisibility, want: false},
+		{name: "private group with private repositories enabled", visibility: gitlabLib.PrivateVisibility, enablePrivateRepo: true, want: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockedClient := mocks.NewMockGitlab(mockCtrl)
+			mockedClient.EXPECT().GetGroup(gomock.Any(), gomock.Any(), gomock.Any(), "group", "").Return(&gitlabLib.Group{FullPath: "group", Visibility: test.visibility}, nil)
+
+			p := &Plugin{
+				configuration: &configuration{EncryptionKey: testEncryptionKey, EnablePrivateRepo: test.enablePrivateRepo},
+				GitlabClient:  mockedClient,
+			}
+
+			info, err := json.Marshal(&gitlab.UserInfo{UserID: "user_id"})
+			require.NoError(t, err)
+			encryptedToken, err := encrypt([]byte(testEncryptionKey), testGitlabToken)
+			require.NoError(t, err)
+
+			mock := &plugintest.API{}
+			mock.On("KVGet", "user_id"+GitlabUserInfoKey).Return(info, nil)
+			mock.On("KVGet", "user_id_usertoken").Return([]byte(encryptedToken), nil)
+			p.SetAPI(mock)
+			p.client = pluginapi.NewClient(mock, p.Driver)
+
+			assert.Equal(t, test.want, p.permissionToGroup(context.Background(), "user_id", "group"))
+		})
+	}
+}
+
+func TestIsWebhookPostThrottled(t *testing.T) {
+	p := &Plugin{configuration: &configuration{}}
+
+	mock := &plugintest.API{}
+	key := webhookThrottleKeyPrefix + "channel1_24_MergeRequest_38"
+	mock.On("KVSetWithOptions", key, []byte{1}, testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.Atomic && o.OldValue == nil && o.ExpireInSeconds == int64(webhookThrottleWindow.Seconds())
+	})).Return(true, nil).Once()
+	mock.On("KVSetWithOptions", key, []byte{1}, testifymock.AnythingOfType("model.PluginKVSetOptions")).Return(false, nil).Once()
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	assert.False(t, p.isWebhookPostThrottled("channel1", "24_MergeRequest_38"))
+	assert.True(t, p.isWebhookPostThrottled("channel1", "24_MergeRequest_38"))
+	mock.AssertExpectations(t)
+}
+
+func TestSwapPipelineStatus(t *testing.T) {
+	p := &Plugin{configuration: &configuration{}}
+
+	mock := &plugintest.API{}
+	key := pipelineStatusKey(24, "main")
+	mock.On("KVGet", key).Return(nil, nil).Once()
+	mock.On("KVSetWithOptions", key, []byte(`"failed"`), testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.Atomic && o.OldValue == nil
+	})).Return(true, nil).Once()
+	mock.On("KVGet", key).Return([]byte(`"failed"`), nil).Once()
+	mock.On("KVSetWithOptions", key, []byte(`"success"`), testifymock.MatchedBy(func(o model.PluginKVSetOptions) bool {
+		return o.Atomic && string(o.OldValue) == `"failed"`
+	})).Return(true, nil).Once()
+	p.SetAPI(mock)
+	p.client = pluginapi.NewClient(mock, p.Driver)
+
+	assert.Equal(t, "", p.swapPipelineStatus(24, "main", "failed"))
+	assert.Equal(t, "failed", p.swapPipelineStatus(24, "main", "success"))
+	assert.NotEqual(t, key, pipelineStatusKey(24, "master"))
+	mock.AssertExpectations(t)
+}
+
+func TestGetMergeRequestChangedPaths(t *testing.T) {
+	t.Run("uses the cached paths", func(t *testing.T) {
+		p := &Plugin{configuration: &configuration{}}
+
+		mock := &plugintest.API{}
+		key := mergeRequestPathsKey("group", "project", 4, "abc")
+		mock.On("KVGet", key).Return([]byte(`["services/billing/main.go"]`), nil).Once()
+		p.SetAPI(mock)
+		p.client = pluginapi.NewClient(mock, p.Driver)
+
+		paths, err := p.getMergeRequestChangedPaths(context.Background(), "user_id", "group", "project", 4, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"services/billing/main.go"}, paths)
+		assert.NotEqual(t, key, mergeRequestPathsKey("group", "project", 4, "def"))
+		mock.AssertExpectations(t)
+	})
+
+	t.Run("fails for a user not connected to GitLab", func(t *testing.T) {
+		p := &Plugin{configuration: &configuration{}}
+
+		mock := &plugintest.API{}
+		mock.On("KVGet", mergeRequestPathsKey("group", "project", 4, "abc")).Return(nil, nil).Once()
+		mock.On("KVGet", "user_id"+GitlabUserInfoKey).Return(nil, nil).Once()
+		mock.On("KVGet", "user_id"+GitlabMigrationTokenKey).Return(nil, nil).Once()
+		p.SetAPI(mock)
+		p.client = pluginapi.NewClient(mock, p.Driver)
+
+		_, err := p.getMergeRequestChangedPaths(context.Background(), "user_id", "group", "project", 4, "abc")
+		assert.Error(t, err)
+		mock.AssertExpectations(t)
+	})
+}
+
+func TestGetMilestoneTitle(t *testing.T) {
+	t.Run("uses the cached title", func(t *testing.T) {
+		p := &Plugin{configuration: &configuration{}}
+
+		mock := &plugintest.API{}
+		key := milestoneTitleKey("group", "project", 12)
+		mock.On("KVGet", key).Return([]byte(`"v1.0"`), nil).Once()
+		p.SetAPI(mock)
+		p.client = pluginapi.NewClient(mock, p.Driver)
+
+		title, err := p.getMilestoneTitle(context.Background(), "user_id", "group", "project", 12)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.0", title)
+		assert.NotEqual(t, key, milestoneTitleKey("group", "project", 13))
+		mock.AssertExpectations(t)
+	})
+
+	t.Run("fails for a user not connected to GitLab", func(t *testing.T) {
+		p := &Plugin{configuration: &configuration{}}
+
+		mock := &plugintest.API{}
+		mock.On("KVGet", milestoneTitleKey("group", "project", 12)).Return(nil, nil).Once()
+		mock.On("KVGet", "user_id"+GitlabUserInfoKey).Return(nil, nil).Once()
+		mock.On("KVGet", "user_id"+GitlabMigrationTokenKey).Return(nil, nil).Once()
+		p.SetAPI(mock)
+		p.client = pluginapi.NewClient(mock, p.Driver)
+
+		_, err := p.getMilestoneTitle(context.Background(), "user_id", "group", "project", 12)
+		assert.Error(t, err)
+		mock.AssertExpectations(t)
+	})
 }